@@ -0,0 +1,33 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// linkGoroot atomically points the goroot symlink to dir, through a relative
+// link so the prefix can be relocated.
+func linkGoroot(goroot, dir string) error {
+	target, err := filepath.Rel(filepath.Dir(goroot), dir)
+	if err != nil {
+		return err
+	}
+
+	link := goroot + ".go-dl-link"
+	os.Remove(link)
+	if err := os.Symlink(target, link); err != nil {
+		return err
+	}
+	if err := os.Rename(link, goroot); err != nil {
+		os.Remove(link)
+		return err
+	}
+	return nil
+}
+
+// addToUserPath does nothing, the shell profile sets the PATH on unix.
+func addToUserPath(dir string) error {
+	return nil
+}
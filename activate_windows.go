@@ -0,0 +1,52 @@
+//go:build windows
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// linkGoroot points the goroot junction to dir. Symlinks need administrator
+// rights on windows while junctions don't, they can't be replaced atomically
+// though.
+func linkGoroot(goroot, dir string) error {
+	if fi, err := os.Lstat(goroot); err == nil {
+		if fi.Mode()&(os.ModeSymlink|os.ModeIrregular) == 0 {
+			return fmt.Errorf("%s is not a junction", goroot)
+		}
+		if err := os.Remove(goroot); err != nil {
+			return err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	out, err := exec.Command("cmd", "/c", "mklink", "/J", goroot, dir).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("unable to create the junction %s: %w: %s", goroot, err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+// addToUserPath appends dir to the PATH of the user registry when missing.
+// [Environment]::SetEnvironmentVariable broadcasts WM_SETTINGCHANGE so new
+// shells see the change without logging out.
+func addToUserPath(dir string) error {
+	quoted := "'" + strings.ReplaceAll(dir, "'", "''") + "'"
+	script := fmt.Sprintf(`$dir = %s
+$path = [Environment]::GetEnvironmentVariable('Path', 'User')
+if (-not (($path -split ';') -contains $dir)) {
+	[Environment]::SetEnvironmentVariable('Path', (($path.TrimEnd(';'), $dir) -join ';').TrimStart(';'), 'User')
+}`, quoted)
+
+	out, err := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("unable to update the user PATH: %w: %s", err, bytes.TrimSpace(out))
+	}
+	return nil
+}
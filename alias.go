@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"regexp"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/blckfalcon/go-dl/versions"
+)
+
+// aliasName matches the names of the aliases, they start with a letter so
+// they can't be mistaken for versions.
+var aliasName = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.-]*$`)
+
+// resolveAlias returns the version or constraint query is an alias of, or
+// query itself.
+func (c *cli) resolveAlias(query string) string {
+	if target, ok := c.aliases[query]; ok {
+		return target
+	}
+	return query
+}
+
+// checkAlias reports why name can't be an alias of target.
+func checkAlias(name, target string) error {
+	switch {
+	case !aliasName.MatchString(name):
+		return fmt.Errorf("invalid alias name %q, expected a letter followed by letters, digits, '.', '_' or '-'", name)
+	case name == "ls" || name == "rm" || isChannel(name) || versions.IsValid(name):
+		return fmt.Errorf("%q is reserved and can't be an alias", name)
+	}
+	if isChannel(target) {
+		return nil
+	}
+	if _, err := versions.ParseConstraint(target); err != nil {
+		return fmt.Errorf("invalid alias target %q: %w", target, err)
+	}
+	return nil
+}
+
+// alias defines, lists and removes the names given to versions in the
+// configuration.
+func (c *cli) alias(args []string) error {
+	fs := flag.NewFlagSet("alias", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-dl alias <name> <version constraint> | go-dl alias ls | go-dl alias rm <name>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	switch {
+	case fs.NArg() == 1 && fs.Arg(0) == "ls":
+		names := make([]string, 0, len(c.aliases))
+		for name := range c.aliases {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		w := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+		for _, name := range names {
+			fmt.Fprintf(w, "%s\t%s\n", name, c.aliases[name])
+		}
+		return w.Flush()
+
+	case fs.NArg() == 2 && fs.Arg(0) == "rm":
+		name := fs.Arg(1)
+		if _, ok := c.aliases[name]; !ok {
+			return fmt.Errorf("no alias %q", name)
+		}
+		aliases := map[string]string{}
+		for n, target := range c.aliases {
+			if n != name {
+				aliases[n] = target
+			}
+		}
+		return c.saveAliases(aliases)
+
+	case fs.NArg() == 2:
+		name, target := fs.Arg(0), fs.Arg(1)
+		if err := checkAlias(name, target); err != nil {
+			return err
+		}
+		aliases := map[string]string{name: target}
+		for n, t := range c.aliases {
+			if n != name {
+				aliases[n] = t
+			}
+		}
+		if err := c.saveAliases(aliases); err != nil {
+			return err
+		}
+		fmt.Fprintf(c.stdout, "%s is an alias of %s\n", name, target)
+		return nil
+	}
+
+	fs.Usage()
+	return errors.New("alias expects a name and a version, ls or rm")
+}
+
+// saveAliases replaces the aliases of the configuration.
+func (c *cli) saveAliases(aliases map[string]string) error {
+	if c.config == "" {
+		return errors.New("no configuration file to store the aliases in")
+	}
+	if err := setConfigValue(c.config, "aliases", aliases); err != nil {
+		return wrapPermission(err)
+	}
+	c.aliases = aliases
+	return nil
+}
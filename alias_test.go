@@ -0,0 +1,61 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestAlias(t *testing.T) {
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n"})
+	c, out := newTestCLI(t, archive)
+	c.prefix = t.TempDir()
+	c.config = filepath.Join(t.TempDir(), "config.json")
+
+	for _, args := range [][]string{{"alias", "work", "1.22"}, {"alias", "legacy", "oldstable"}} {
+		if err := c.run(args); err != nil {
+			t.Fatal(err)
+		}
+	}
+	config, err := loadConfig(c.config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"work": "1.22", "legacy": "oldstable"}; !reflect.DeepEqual(config.Aliases, want) {
+		t.Errorf("Expected the aliases %v to be saved, got %v", want, config.Aliases)
+	}
+
+	out.Reset()
+	if err := c.run([]string{"alias", "ls"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "legacy  oldstable\nwork    1.22\n"; out.String() != want {
+		t.Errorf("Expected %q, got %q", want, out.String())
+	}
+
+	if err := c.run([]string{"install", "work"}); err != nil {
+		t.Fatalf("Expected the alias to be installed, got %v", err)
+	}
+	if v, err := installedVersion(c.goroot()); err != nil || v != "go1.22.1" {
+		t.Errorf("Expected go1.22.1 to be installed, got %q (%v)", v, err)
+	}
+
+	if err := c.run([]string{"alias", "rm", "work"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.aliases["work"]; ok {
+		t.Errorf("Expected the alias to be removed, got %v", c.aliases)
+	}
+
+	for _, args := range [][]string{
+		{"alias", "go1.22", "1.22"},
+		{"alias", "stable", "1.22"},
+		{"alias", "1work", "1.22"},
+		{"alias", "work", "not a version"},
+		{"alias", "rm", "missing"},
+	} {
+		if err := c.run(args); err == nil {
+			t.Errorf("Expected an error for %v", args)
+		}
+	}
+}
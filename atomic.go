@@ -0,0 +1,186 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// journalSuffix names the journal recording an atomic write in progress, as
+// "<sha256> <temporary file>", next to the written file.
+const journalSuffix = ".journal"
+
+// journalDir keeps the journals of the atomic writes outside of it, with the
+// written file on a second line, so recoverJournal finds them all. The state
+// directory once started, journals stay next to the written files if unset.
+var journalDir string
+
+// crashPoint is called after each step of writeFileAtomic, tests make it
+// panic to simulate a crash.
+var crashPoint = func(step string) {}
+
+// writeFileAtomic replaces target with data so a crash or a power loss
+// leaves either the previous or the new content, never a partial file. The
+// data is written to a temporary file, synced and journaled before being
+// renamed over target, recoverJournal completes the interrupted writes.
+func writeFileAtomic(target string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(target)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	f, err := createTemp(dir, "."+filepath.Base(target)+".tmp*")
+	if err != nil {
+		return err
+	}
+	if err := writeSynced(f, data, perm); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	crashPoint("write")
+
+	sum := sha256.Sum256(data)
+	journal, entry := journalPath(target)
+	if err := os.MkdirAll(filepath.Dir(journal), 0755); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	entry = hex.EncodeToString(sum[:]) + " " + filepath.Base(f.Name()) + "\n" + entry
+	j, err := os.OpenFile(journal, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err == nil {
+		err = writeSynced(j, []byte(entry), 0644)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		os.Remove(journal)
+		return err
+	}
+	crashPoint("journal")
+
+	if err := os.Rename(f.Name(), target); err != nil {
+		os.Remove(f.Name())
+		os.Remove(journal)
+		return err
+	}
+	crashPoint("rename")
+
+	syncDir(dir)
+	return os.Remove(journal)
+}
+
+// journalPath returns the journal of an atomic write of target, and the
+// line naming target in it when the journal is not next to target.
+func journalPath(target string) (string, string) {
+	if journalDir == "" || filepath.Dir(target) == filepath.Clean(journalDir) {
+		return target + journalSuffix, ""
+	}
+	if abs, err := filepath.Abs(target); err == nil {
+		target = abs
+	}
+	sum := sha256.Sum256([]byte(target))
+	return filepath.Join(journalDir, hex.EncodeToString(sum[:8])+journalSuffix), target + "\n"
+}
+
+// writeSynced writes data to f and flushes it to the disk before closing f.
+func writeSynced(f *os.File, data []byte, perm os.FileMode) error {
+	_, err := f.Write(data)
+	if err == nil {
+		err = f.Chmod(perm)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+	return err
+}
+
+// syncDir flushes the entries of dir, the renames into it survive a power
+// loss once done. Not every platform supports it, hence no error.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
+
+// recoverJournal completes the atomic writes into dir interrupted by a
+// crash: the journaled ones are renamed into place, the temporary files of
+// the others removed. It must not run while another go-dl could be writing.
+func recoverJournal(dir string) error {
+	journals, err := filepath.Glob(filepath.Join(dir, "*"+journalSuffix))
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, journal := range journals {
+		target, err := replayJournal(journal)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("recovering %s: %w", target, err))
+			continue
+		}
+		os.Remove(journal)
+	}
+
+	// Temporary files without a journal were never complete.
+	leftovers, _ := filepath.Glob(filepath.Join(dir, ".*.tmp*"))
+	for _, name := range leftovers {
+		os.Remove(name)
+	}
+
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// replayJournal renames the temporary file recorded in journal to the file
+// it was written for when it holds the journaled content, and returns that
+// file.
+func replayJournal(journal string) (string, error) {
+	target := strings.TrimSuffix(journal, journalSuffix)
+	b, err := os.ReadFile(journal)
+	if err != nil {
+		return target, err
+	}
+
+	// A truncated journal was written by a crashed process before the
+	// rename, target still holds its previous content.
+	lines := strings.Split(string(b), "\n")
+	if len(lines) < 2 || lines[len(lines)-1] != "" {
+		return target, nil
+	}
+	if len(lines) > 2 {
+		target = lines[1]
+	}
+	sum, tmp, ok := strings.Cut(lines[0], " ")
+	if !ok || strings.ContainsAny(tmp, `/\`) {
+		return target, nil
+	}
+	tmp = filepath.Join(filepath.Dir(target), tmp)
+
+	data, err := os.ReadFile(tmp)
+	if os.IsNotExist(err) {
+		// Already renamed.
+		return target, nil
+	}
+	if err != nil {
+		return target, err
+	}
+	got := sha256.Sum256(data)
+	if hex.EncodeToString(got[:]) != sum {
+		return target, os.Remove(tmp)
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		return target, err
+	}
+	syncDir(filepath.Dir(target))
+	return target, nil
+}
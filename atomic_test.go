@@ -0,0 +1,122 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// crashAt makes writeFileAtomic stop at step as if the process died, it
+// returns whether it crashed.
+func crashAt(t *testing.T, step string, write func()) (crashed bool) {
+	t.Helper()
+
+	crashPoint = func(s string) {
+		if s == step {
+			panic(step)
+		}
+	}
+	defer func() {
+		crashPoint = func(string) {}
+		crashed = recover() != nil
+	}()
+	write()
+	return false
+}
+
+func TestWriteFileAtomicCrash(t *testing.T) {
+	for step, want := range map[string]string{
+		"write":   "old",
+		"journal": "new",
+		"rename":  "new",
+		"":        "new",
+	} {
+		dir := t.TempDir()
+		target := filepath.Join(dir, "pipeline.json")
+		if err := os.WriteFile(target, []byte("old"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		var err error
+		crashed := crashAt(t, step, func() { err = writeFileAtomic(target, []byte("new"), 0644) })
+		if crashed != (step != "") || err != nil {
+			t.Fatalf("Expected a crash at %q, got %v (%v)", step, crashed, err)
+		}
+
+		if b, _ := os.ReadFile(target); string(b) != "old" && string(b) != "new" {
+			t.Errorf("Expected the previous or the new content after a crash at %q, got %q", step, b)
+		}
+
+		if err := recoverJournal(dir); err != nil {
+			t.Fatal(err)
+		}
+		if b, _ := os.ReadFile(target); string(b) != want {
+			t.Errorf("Expected %q once recovered from a crash at %q, got %q", want, step, b)
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+			t.Errorf("Expected no leftovers after a crash at %q, got %v", step, entries)
+		}
+	}
+}
+
+func TestRecoverJournalCorrupted(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "run.json")
+	if err := os.WriteFile(target, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A temporary file truncated by a power loss doesn't match the journal.
+	tmp := filepath.Join(dir, ".run.json.tmp123")
+	if err := os.WriteFile(tmp, []byte("ne"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	journal := "7c4d5e2fb4ed23a12b45b3e5c2c9c84a1c3a1c3aa8d8a4d8d3ff2d2cbd7d1b4c .run.json.tmp123\n"
+	if err := os.WriteFile(target+journalSuffix, []byte(journal), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// As is a truncated journal.
+	other := filepath.Join(dir, "download.json")
+	if err := os.WriteFile(other+journalSuffix, []byte("7c4d5e"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := recoverJournal(dir); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(target); string(b) != "old" {
+		t.Errorf("Expected the previous content to be kept, got %q", b)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("Expected the journals and temporary files to be removed, got %v", entries)
+	}
+}
+
+func TestRecoverJournalElsewhere(t *testing.T) {
+	journalDir = t.TempDir()
+	defer func() { journalDir = "" }()
+
+	for _, step := range []string{"journal", "rename"} {
+		dir := t.TempDir()
+		target := filepath.Join(dir, "mirrors.json")
+		if err := os.WriteFile(target, []byte("old"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if !crashAt(t, step, func() { writeFileAtomic(target, []byte("new"), 0644) }) {
+			t.Fatalf("Expected a crash at %q", step)
+		}
+		if err := recoverJournal(journalDir); err != nil {
+			t.Fatal(err)
+		}
+		if b, _ := os.ReadFile(target); string(b) != "new" {
+			t.Errorf("Expected the write interrupted at %q to be completed, got %q", step, b)
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+			t.Errorf("Expected no leftovers after a crash at %q, got %v", step, entries)
+		}
+		if entries, _ := os.ReadDir(journalDir); len(entries) != 0 {
+			t.Errorf("Expected the journal to be removed after a crash at %q, got %v", step, entries)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// automationName identifies the systemd units, launchd agent and scheduled
+// task created by automate.
+const automationName = "go-dl-upgrade"
+
+var schedules = map[string]bool{"hourly": true, "daily": true, "weekly": true}
+
+// systemdUnits returns the service and timer running the upgrade for the
+// user on schedule.
+func systemdUnits(exe, schedule string) (string, string) {
+	service := fmt.Sprintf(`[Unit]
+Description=Upgrade Go to the latest stable release
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+Type=oneshot
+ExecStart=%s latest --quiet
+`, systemdQuote(exe))
+
+	timer := fmt.Sprintf(`[Unit]
+Description=Upgrade Go to the latest stable release %s
+
+[Timer]
+OnCalendar=%s
+Persistent=true
+RandomizedDelaySec=15m
+
+[Install]
+WantedBy=timers.target
+`, schedule, schedule)
+
+	return service, timer
+}
+
+func systemdQuote(s string) string {
+	if !strings.ContainsAny(s, " \t\"\\") {
+		return s
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// launchdPlist returns the agent running the upgrade on schedule, its output
+// is logged to logPath.
+func launchdPlist(exe, schedule, logPath string) string {
+	interval := map[string]string{
+		"hourly": "<key>Minute</key><integer>0</integer>",
+		"daily":  "<key>Hour</key><integer>3</integer><key>Minute</key><integer>0</integer>",
+		"weekly": "<key>Weekday</key><integer>0</integer><key>Hour</key><integer>3</integer><key>Minute</key><integer>0</integer>",
+	}[schedule]
+
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key><string>%s</string>
+	<key>ProgramArguments</key>
+	<array>
+		<string>%s</string>
+		<string>latest</string>
+		<string>--quiet</string>
+	</array>
+	<key>StartCalendarInterval</key>
+	<dict>%s</dict>
+	<key>StandardOutPath</key><string>%s</string>
+	<key>StandardErrorPath</key><string>%s</string>
+</dict>
+</plist>
+`, launchdLabel, xmlEscape(exe), interval, xmlEscape(logPath), xmlEscape(logPath))
+}
+
+const launchdLabel = "io.github.blckfalcon." + automationName
+
+func xmlEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// schtasksArgs returns the arguments creating the scheduled task on Windows.
+func schtasksArgs(exe, schedule string) []string {
+	return []string{"/Create", "/F", "/TN", automationName, "/SC", strings.ToUpper(schedule), "/ST", "03:00", "/TR", fmt.Sprintf(`"%s" latest --quiet`, exe)}
+}
+
+func (c *cli) automate(args []string) error {
+	fs := flag.NewFlagSet("automate", flag.ContinueOnError)
+	schedule := fs.String("schedule", "daily", "how often to upgrade: hourly, daily or weekly")
+	remove := fs.Bool("remove", false, "remove the automation")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-dl automate [--schedule daily] [--remove]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if !schedules[*schedule] {
+		return fmt.Errorf("unknown schedule %q, expected hourly, daily or weekly", *schedule)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	switch runtime.GOOS {
+	case "linux":
+		err = c.automateSystemd(exe, *schedule, *remove)
+	case "darwin":
+		err = c.automateLaunchd(exe, *schedule, *remove)
+	case "windows":
+		if *remove {
+			err = runAutomation("schtasks", "/Delete", "/F", "/TN", automationName)
+		} else {
+			err = runAutomation("schtasks", schtasksArgs(exe, *schedule)...)
+		}
+	default:
+		return fmt.Errorf("automate is not supported on %s", runtime.GOOS)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *remove {
+		fmt.Fprintln(c.stdout, "Removed the automatic upgrades")
+	} else {
+		fmt.Fprintf(c.stdout, "Go will be upgraded %s\n", *schedule)
+	}
+	return nil
+}
+
+func (c *cli) automateSystemd(exe, schedule string, remove bool) error {
+	config, err := os.UserConfigDir()
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(config, "systemd", "user")
+	servicePath := filepath.Join(dir, automationName+".service")
+	timerPath := filepath.Join(dir, automationName+".timer")
+
+	if remove {
+		runAutomation("systemctl", "--user", "disable", "--now", automationName+".timer")
+		err := errors.Join(removeIfExists(servicePath), removeIfExists(timerPath))
+		return errors.Join(err, runAutomation("systemctl", "--user", "daemon-reload"))
+	}
+
+	service, timer := systemdUnits(exe, schedule)
+	if err := writeFile(servicePath, strings.NewReader(service), 0644); err != nil {
+		return err
+	}
+	if err := writeFile(timerPath, strings.NewReader(timer), 0644); err != nil {
+		return err
+	}
+
+	if err := runAutomation("systemctl", "--user", "daemon-reload"); err != nil {
+		return err
+	}
+	return runAutomation("systemctl", "--user", "enable", "--now", automationName+".timer")
+}
+
+func (c *cli) automateLaunchd(exe, schedule string, remove bool) error {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(home, "Library", "LaunchAgents", launchdLabel+".plist")
+
+	if remove {
+		runAutomation("launchctl", "unload", path)
+		return removeIfExists(path)
+	}
+
+	if err := os.MkdirAll(c.paths.State, 0755); err != nil {
+		return err
+	}
+	plist := launchdPlist(exe, schedule, filepath.Join(c.paths.State, automationName+".log"))
+	if err := writeFile(path, strings.NewReader(plist), 0644); err != nil {
+		return err
+	}
+
+	runAutomation("launchctl", "unload", path)
+	return runAutomation("launchctl", "load", path)
+}
+
+func runAutomation(name string, args ...string) error {
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %s failed: %w: %s", name, strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func removeIfExists(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
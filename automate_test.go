@@ -0,0 +1,32 @@
+package main
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestSystemdUnits(t *testing.T) {
+	service, timer := systemdUnits("/home/me/go bin/go-dl", "weekly")
+
+	if !strings.Contains(service, `ExecStart="/home/me/go bin/go-dl" latest --quiet`) {
+		t.Errorf("Expected the service to run the upgrade, got:\n%s", service)
+	}
+	if !strings.Contains(timer, "OnCalendar=weekly\n") || !strings.Contains(timer, "WantedBy=timers.target") {
+		t.Errorf("Expected a weekly timer, got:\n%s", timer)
+	}
+}
+
+func TestLaunchdPlist(t *testing.T) {
+	plist := launchdPlist("/usr/local/bin/go-dl", "daily", "/tmp/a&b.log")
+
+	if err := xml.Unmarshal([]byte(plist), new(struct{})); err != nil {
+		t.Errorf("Expected a valid plist, got %v:\n%s", err, plist)
+	}
+	if !strings.Contains(plist, "<string>latest</string>") || !strings.Contains(plist, "<key>Hour</key><integer>3</integer>") {
+		t.Errorf("Expected a daily upgrade, got:\n%s", plist)
+	}
+	if !strings.Contains(plist, "/tmp/a&amp;b.log") {
+		t.Errorf("Expected the log path to be escaped, got:\n%s", plist)
+	}
+}
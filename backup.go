@@ -0,0 +1,206 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// backupLink is the file of a backup recording the version the managed
+// layout pointed to, instead of a copy of the installation.
+const backupLink = "link"
+
+// backupsDir holds the installation replaced by the last install under
+// prefix, which go-dl rollback restores.
+func backupsDir(prefix string) string {
+	return filepath.Join(prefix, ".go-dl-backups")
+}
+
+// defaultBackupAge is how long a backup is kept without backup_max_age.
+const defaultBackupAge = 7 * 24 * time.Hour
+
+// backupTime is the format of the names of the backups.
+const backupTime = "20060102T150405.000000000Z"
+
+// newBackup creates a timestamped backup directory under prefix.
+func newBackup(prefix string) (string, error) {
+	dir := filepath.Join(backupsDir(prefix), time.Now().UTC().Format(backupTime))
+	return dir, os.MkdirAll(dir, 0755)
+}
+
+// replaceDir replaces dir with staging, moving dir to backup first and
+// moving it back when the swap fails.
+func replaceDir(dir, staging, backup string) error {
+	if err := os.Rename(dir, backup); err != nil {
+		return err
+	}
+	if err := os.Rename(staging, dir); err != nil {
+		if errRestore := os.Rename(backup, dir); errRestore != nil {
+			return fmt.Errorf("%w (previous installation left at %s: %v)", err, backup, errRestore)
+		}
+		return err
+	}
+	return nil
+}
+
+// listBackups returns the backups under prefix, oldest first.
+func listBackups(prefix string) ([]string, error) {
+	entries, err := os.ReadDir(backupsDir(prefix))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []string
+	for _, e := range entries {
+		if e.IsDir() {
+			backups = append(backups, filepath.Join(backupsDir(prefix), e.Name()))
+		}
+	}
+	sort.Strings(backups)
+	return backups, nil
+}
+
+// pruneBackups removes the backups under prefix but keep, only the last
+// install can be rolled back.
+func pruneBackups(prefix, keep string) error {
+	backups, err := listBackups(prefix)
+	if err != nil {
+		return err
+	}
+	for _, b := range backups {
+		if b != keep {
+			if err := os.RemoveAll(b); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// expireBackups removes the backups under prefix older than maxAge and
+// returns the space they used. A zero maxAge keeps them until the next
+// install.
+func expireBackups(prefix string, maxAge time.Duration) (int64, error) {
+	if maxAge <= 0 {
+		return 0, nil
+	}
+	backups, err := listBackups(prefix)
+	if err != nil {
+		return 0, err
+	}
+
+	var reclaimed int64
+	for _, b := range backups {
+		created, err := time.Parse(backupTime, filepath.Base(b))
+		if err != nil || time.Since(created) < maxAge {
+			continue
+		}
+		size, _ := treeSize(b)
+		if err := os.RemoveAll(b); err != nil {
+			return reclaimed, err
+		}
+		reclaimed += size
+	}
+	return reclaimed, nil
+}
+
+// backupLinkTarget records the directory the goroot link points to in a new
+// backup, before the managed layout switches to another version.
+func backupLinkTarget(goroot string) error {
+	target, err := linkTarget(goroot)
+	if err != nil {
+		return err
+	}
+
+	prefix := filepath.Dir(goroot)
+	backup, err := newBackup(prefix)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(backup, backupLink), []byte(target+"\n"), 0644); err != nil {
+		os.RemoveAll(backup)
+		return err
+	}
+	return pruneBackups(prefix, backup)
+}
+
+// rollbackInstallation restores the installation replaced by the last
+// install at goroot and returns its version, once allowed by allow.
+func rollbackInstallation(goroot string, allow func(version string) error) (string, error) {
+	if err := checkForeignLink(goroot); err != nil {
+		return "", err
+	}
+	prefix := filepath.Dir(goroot)
+	backups, err := listBackups(prefix)
+	if err != nil {
+		return "", err
+	}
+	if len(backups) == 0 {
+		return "", fmt.Errorf("no previous installation to restore in %s", backupsDir(prefix))
+	}
+	backup := backups[len(backups)-1]
+
+	if b, err := os.ReadFile(filepath.Join(backup, backupLink)); err == nil {
+		dir := strings.TrimSpace(string(b))
+		version, err := installedVersion(dir)
+		if err != nil {
+			return "", fmt.Errorf("the previous version is no longer installed: %w", err)
+		}
+		if err := allow(version); err != nil {
+			return "", err
+		}
+		if err := linkGoroot(goroot, dir); err != nil {
+			return "", err
+		}
+		return version, os.RemoveAll(backup)
+	}
+
+	previous := filepath.Join(backup, filepath.Base(goroot))
+	version, err := installedVersion(previous)
+	if err != nil {
+		return "", fmt.Errorf("invalid backup %s: %w", backup, err)
+	}
+	if err := allow(version); err != nil {
+		return "", err
+	}
+	if isManaged(goroot) {
+		return "", fmt.Errorf("%s uses the managed layout since the backup, run go-dl use instead", goroot)
+	}
+
+	old := goroot + ".go-dl-old"
+	os.RemoveAll(old)
+	if err := replaceDir(goroot, previous, old); err != nil {
+		return "", err
+	}
+	if err := os.RemoveAll(old); err != nil {
+		return "", err
+	}
+	return version, os.RemoveAll(backup)
+}
+
+// rollback reverts the last installation.
+func (c *cli) rollback(args []string) error {
+	fs := flag.NewFlagSet("rollback", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-dl rollback")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	version, err := rollbackInstallation(c.goroot(), c.allow)
+	if err != nil {
+		return wrapPermission(err)
+	}
+	fmt.Fprintf(c.stdout, "Restored %s\n", version)
+	return nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// allowAll allows rolling back to any version.
+func allowAll(string) error { return nil }
+
+func TestRollback(t *testing.T) {
+	prefix := t.TempDir()
+	goroot := filepath.Join(prefix, "go")
+	install := func(version string) {
+		t.Helper()
+		archive := newTestArchive(t, map[string]string{"go/VERSION": version + "\n"})
+		if err := extractArchive(prefix, bytes.NewReader(archive), processOwner, extractProgress{}, archiveOptions{}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	if _, err := rollbackInstallation(goroot, allowAll); err == nil {
+		t.Errorf("Expected an error without a previous installation")
+	}
+
+	install("go1.21.0")
+	install("go1.22.0")
+	install("go1.22.1")
+	if backups, _ := listBackups(prefix); len(backups) != 1 {
+		t.Errorf("Expected only the last backup to be kept, got %v", backups)
+	}
+
+	denied := errors.New("denied")
+	if _, err := rollbackInstallation(goroot, func(string) error { return denied }); !errors.Is(err, denied) {
+		t.Errorf("Expected the rollback to be denied, got %v", err)
+	}
+	if v, _ := installedVersion(goroot); v != "go1.22.1" {
+		t.Errorf("Expected a denied rollback to keep go1.22.1, got %s", v)
+	}
+
+	version, err := rollbackInstallation(goroot, allowAll)
+	if err != nil || version != "go1.22.0" {
+		t.Fatalf("Expected go1.22.0 to be restored, got %s (%v)", version, err)
+	}
+	if v, err := installedVersion(goroot); err != nil || v != "go1.22.0" {
+		t.Errorf("Expected go1.22.0 to be installed, got %s (%v)", v, err)
+	}
+	if _, err := rollbackInstallation(goroot, allowAll); err == nil {
+		t.Errorf("Expected the backup to be used once")
+	}
+}
+
+func TestRollbackManaged(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the managed layout is not supported on windows")
+	}
+
+	prefix := t.TempDir()
+	goroot := filepath.Join(prefix, "go")
+	if err := os.MkdirAll(goroot, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(goroot, "VERSION"), []byte("go1.21.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrateInstallation(context.Background(), goroot, false); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, version := range []string{"go1.22.1", "go1.22.1"} {
+		archive := newTestArchive(t, map[string]string{"go/VERSION": version + "\n"})
+		if err := extractArchive(prefix, bytes.NewReader(archive), processOwner, extractProgress{}, archiveOptions{}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	version, err := rollbackInstallation(goroot, allowAll)
+	if err != nil || version != "go1.21.0" {
+		t.Fatalf("Expected go1.21.0 to be restored, got %s (%v)", version, err)
+	}
+	if target, err := os.Readlink(goroot); err != nil || target != filepath.Join("go-versions", "go1.21.0") {
+		t.Errorf("Expected the symlink to switch back, got %q (%v)", target, err)
+	}
+	if v, err := installedVersion(filepath.Join(prefix, "go-versions", "go1.22.1")); err != nil || v != "go1.22.1" {
+		t.Errorf("Expected the rolled back version to stay in the managed layout, got %s (%v)", v, err)
+	}
+}
+
+func TestRollbackForeignLink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need privileges on windows")
+	}
+
+	prefix := t.TempDir()
+	goroot := filepath.Join(prefix, "go")
+	if err := os.Symlink(t.TempDir(), goroot); err != nil {
+		t.Fatal(err)
+	}
+	backup, err := newBackup(prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(backup, backupLink), []byte(t.TempDir()+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := rollbackInstallation(goroot, allowAll); !errors.Is(err, ErrForeignLink) {
+		t.Errorf("Expected ErrForeignLink, got %v", err)
+	}
+}
+
+func TestExpireBackups(t *testing.T) {
+	prefix := t.TempDir()
+	old := filepath.Join(backupsDir(prefix), time.Now().Add(-8*24*time.Hour).UTC().Format(backupTime))
+	if err := os.MkdirAll(filepath.Join(old, "go"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(old, "go", "VERSION"), []byte("go1.21.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	recent, err := newBackup(prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if reclaimed, err := expireBackups(prefix, 0); err != nil || reclaimed != 0 {
+		t.Errorf("Expected no expiry without an age, got %d (%v)", reclaimed, err)
+	}
+	if reclaimed, err := expireBackups(prefix, defaultBackupAge); err != nil || reclaimed == 0 {
+		t.Errorf("Expected the old backup to be reclaimed, got %d (%v)", reclaimed, err)
+	}
+	if backups, _ := listBackups(prefix); len(backups) != 1 || backups[0] != recent {
+		t.Errorf("Expected only %s to be kept, got %v", recent, backups)
+	}
+}
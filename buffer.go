@@ -0,0 +1,34 @@
+package main
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+// copyBufferSize is the size of the buffers of the downloads and of the
+// copies of archives, larger than the 32KiB of io.Copy so that copying an
+// archive takes fewer reads and writes.
+const copyBufferSize = 256 << 10
+
+// copyBuffers are reused across the downloads and the copies, most of which
+// would otherwise allocate a buffer of their own.
+var copyBuffers = sync.Pool{New: func() any {
+	buf := make([]byte, copyBufferSize)
+	return &buf
+}}
+
+// copyBuffered is io.Copy with a buffer of copyBuffers. A file copied to
+// another is left to the kernel, with copy_file_range on Linux.
+func copyBuffered(dst io.Writer, src io.Reader) (int64, error) {
+	_, fromFile := src.(*os.File)
+	_, toFile := dst.(*os.File)
+	if !fromFile || !toFile {
+		// os.File would copy to or from anything else with a buffer of its
+		// own, allocated for each copy.
+		dst, src = struct{ io.Writer }{dst}, struct{ io.Reader }{src}
+	}
+	buf := copyBuffers.Get().(*[]byte)
+	defer copyBuffers.Put(buf)
+	return io.CopyBuffer(dst, src, *buf)
+}
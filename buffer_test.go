@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// countingWriter discards what is written to it, counting the writes.
+type countingWriter struct {
+	writes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return len(p), nil
+}
+
+func newRandomFile(tb testing.TB, size int) (string, []byte) {
+	tb.Helper()
+	b := make([]byte, size)
+	rand.New(rand.NewSource(1)).Read(b)
+	path := filepath.Join(tb.TempDir(), "archive")
+	if err := os.WriteFile(path, b, 0644); err != nil {
+		tb.Fatal(err)
+	}
+	return path, b
+}
+
+func TestCopyBuffered(t *testing.T) {
+	path, content := newRandomFile(t, 3*copyBufferSize+17)
+
+	src, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	var buf bytes.Buffer
+	w := &countingWriter{}
+	if n, err := copyBuffered(io.MultiWriter(&buf, w), src); err != nil || n != int64(len(content)) {
+		t.Fatalf("copyBuffered() = %d, %v, want %d", n, err, len(content))
+	}
+	if !bytes.Equal(buf.Bytes(), content) {
+		t.Error("Expected the content to be copied")
+	}
+	if w.writes != 4 {
+		t.Errorf("Expected 4 writes of the pooled buffer, got %d", w.writes)
+	}
+
+	// Between files.
+	src.Seek(0, io.SeekStart)
+	dst, err := os.Create(filepath.Join(t.TempDir(), "copy"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+	if _, err := copyBuffered(dst, src); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(dst.Name()); !bytes.Equal(got, content) {
+		t.Error("Expected the file to be copied")
+	}
+}
+
+func BenchmarkDownload(b *testing.B) {
+	const size = 16 << 20
+	content := make([]byte, size)
+	client := NewTestClient(func(*http.Request) *http.Response {
+		return &http.Response{
+			StatusCode:    http.StatusOK,
+			Body:          io.NopCloser(bytes.NewReader(content)),
+			ContentLength: size,
+		}
+	})
+	repo := &GoRepository{client: client, onProgress: func(float64) {}}
+	w := &countingWriter{}
+	b.SetBytes(size)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if err := repo.Download(context.Background(), File{Filename: "go1.22.1.linux-amd64.tar.gz"}, w); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(w.writes)/float64(b.N), "writes/op")
+}
+
+func BenchmarkVerifyChecksum(b *testing.B) {
+	path, content := newRandomFile(b, 16<<20)
+	sum := sha256.Sum256(content)
+	checksum := File{Sha256: hex.EncodeToString(sum[:])}.Checksum()
+	b.SetBytes(int64(len(content)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if err := verifyChecksum(path, checksum); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
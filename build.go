@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/version"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/blckfalcon/go-dl/versions"
+)
+
+// minimumBootstrap returns the oldest release able to build version from
+// source, as documented in the install instructions of each release.
+func minimumBootstrap(v string) string {
+	var minor int
+	if _, err := fmt.Sscanf(version.Lang(v), "go1.%d", &minor); err != nil {
+		return ""
+	}
+	switch {
+	case minor >= 22:
+		// Every other release requires the latest release from a year before.
+		return fmt.Sprintf("go1.%d.6", minor-2-minor%2)
+	case minor >= 20:
+		return "go1.17.13"
+	}
+	return "go1.4"
+}
+
+// bootstrapCandidates returns the installed toolchains which can build Go,
+// the active installation, the managed layout and the toolchains of go-dl
+// run under toolchains.
+func bootstrapCandidates(prefix, toolchains string) []string {
+	candidates := []string{filepath.Join(prefix, "go")}
+	if entries, err := os.ReadDir(versionsDir(prefix)); err == nil {
+		for _, e := range entries {
+			candidates = append(candidates, filepath.Join(versionsDir(prefix), e.Name()))
+		}
+	}
+	if entries, err := os.ReadDir(toolchains); err == nil {
+		for _, e := range entries {
+			candidates = append(candidates, filepath.Join(toolchains, e.Name(), "go"))
+		}
+	}
+	return candidates
+}
+
+// bootstrapGoroot returns the toolchain building v from source. override,
+// an installed version or a GOROOT, comes first, then GOROOT_BOOTSTRAP, else
+// the newest of the candidates meeting the minimum bootstrap version.
+func bootstrapGoroot(v, override string, candidates []string) (string, error) {
+	minimum := minimumBootstrap(v)
+
+	if override != "" && strings.ContainsAny(override, `/\`) {
+		return override, nil
+	}
+	if override != "" {
+		want := versions.Normalize(override)
+		if minimum != "" && version.Compare(want, minimum) < 0 {
+			return "", fmt.Errorf("building %s from source requires %s or newer, not %s", v, minimum, want)
+		}
+		for _, goroot := range candidates {
+			if installed, err := installedVersion(goroot); err == nil && installed == want {
+				return goroot, nil
+			}
+		}
+		return "", fmt.Errorf("bootstrap toolchain %s is not installed", want)
+	}
+
+	if dir := os.Getenv("GOROOT_BOOTSTRAP"); dir != "" {
+		return dir, nil
+	}
+
+	var best, bestVersion string
+	for _, goroot := range candidates {
+		installed, err := installedVersion(goroot)
+		if err != nil || !version.IsValid(installed) || (minimum != "" && version.Compare(installed, minimum) < 0) {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(goroot, "bin", exeName("go"))); err != nil {
+			continue
+		}
+		if best == "" || version.Compare(installed, bestVersion) > 0 {
+			best, bestVersion = goroot, installed
+		}
+	}
+	if best == "" {
+		return "", fmt.Errorf("building %s from source requires an installed %s or newer, GOROOT_BOOTSTRAP or --bootstrap", v, minimum)
+	}
+	return best, nil
+}
+
+func exeName(name string) string {
+	if runtime.GOOS == "windows" {
+		return name + ".exe"
+	}
+	return name
+}
+
+// buildFromSource extracts the source archive in a staging directory next to
+// the installation and builds it with the bootstrap toolchain, the
+// installation under prefix is only replaced once the build succeeded.
+func buildFromSource(ctx context.Context, prefix, bootstrap string, archive io.ReadSeeker, owner Owner, progress extractProgress, opts archiveOptions) error {
+	if err := execTemp.prepare(); err != nil {
+		return fmt.Errorf("unable to build from source: %w", err)
+	}
+
+	staging := filepath.Join(prefix, ".go-dl-build")
+	os.RemoveAll(staging)
+	defer os.RemoveAll(staging)
+
+	if _, err := archive.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	// The build may need any file of the source, none is left out.
+	opts.filter = archiveFilter{}
+	if err := decompress(staging, archive, progress, opts); err != nil {
+		return err
+	}
+
+	script := "./make.bash"
+	if runtime.GOOS == "windows" {
+		script = "make.bat"
+	}
+
+	cmd := exec.CommandContext(ctx, script)
+	cmd.Dir = filepath.Join(staging, "go", "src")
+	cmd.Env = append(os.Environ(), "GOROOT_BOOTSTRAP="+bootstrap)
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("building from source failed: %w\n%s", err, lastLines(out.String(), 20))
+	}
+
+	if err := chownTree(filepath.Join(staging, "go"), owner); err != nil {
+		return err
+	}
+	return swapInstallation(filepath.Join(prefix, "go"), filepath.Join(staging, "go"))
+}
+
+func lastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
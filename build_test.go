@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestBuildFromSource(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("make.bash is not used on windows")
+	}
+
+	prefix := t.TempDir()
+	bootstrap := t.TempDir()
+
+	archive := newTestArchive(t, map[string]string{
+		"go/VERSION":       "go1.22.1\n",
+		"go/src/make.bash": "#!/bin/sh\ntest -n \"$GOROOT_BOOTSTRAP\" && mkdir -p ../bin && echo built > ../bin/go\n",
+	})
+
+	err := buildFromSource(context.Background(), prefix, bootstrap, bytes.NewReader(archive), processOwner, extractProgress{}, archiveOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(prefix, "go", "bin", "go")); err != nil {
+		t.Errorf("Expected the built toolchain to be installed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(prefix, ".go-dl-build")); !os.IsNotExist(err) {
+		t.Errorf("Expected the staging directory to be removed")
+	}
+}
+
+func TestBuildFromSourceFailure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("make.bash is not used on windows")
+	}
+
+	prefix := t.TempDir()
+
+	goroot := filepath.Join(prefix, "go")
+	if err := os.MkdirAll(goroot, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(goroot, "VERSION"), []byte("go1.21.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	archive := newTestArchive(t, map[string]string{
+		"go/VERSION":       "go1.22.1\n",
+		"go/src/make.bash": "#!/bin/sh\necho broken >&2\nexit 2\n",
+	})
+
+	err := buildFromSource(context.Background(), prefix, t.TempDir(), bytes.NewReader(archive), processOwner, extractProgress{}, archiveOptions{})
+	if err == nil {
+		t.Fatalf("Expected the build to fail")
+	}
+
+	if v, _ := installedVersion(goroot); v != "go1.21.0" {
+		t.Errorf("Expected the previous installation to be kept, got %s", v)
+	}
+}
+
+func TestMinimumBootstrap(t *testing.T) {
+	for v, want := range map[string]string{
+		"go1.19.5":  "go1.4",
+		"go1.21.0":  "go1.17.13",
+		"go1.22.1":  "go1.20.6",
+		"go1.23rc1": "go1.20.6",
+		"go1.24.0":  "go1.22.6",
+		"go1.26.2":  "go1.24.6",
+	} {
+		if got := minimumBootstrap(v); got != want {
+			t.Errorf("Expected %s to be built by %s, got %s", v, want, got)
+		}
+	}
+}
+
+func TestBootstrapGoroot(t *testing.T) {
+	t.Setenv("GOROOT_BOOTSTRAP", "")
+
+	var candidates []string
+	for _, v := range []string{"go1.19.13", "go1.20.14", "go1.21.10"} {
+		goroot := filepath.Join(t.TempDir(), "go")
+		newTestGoBin(t, filepath.Join(goroot, "bin"))
+		if err := os.WriteFile(filepath.Join(goroot, "VERSION"), []byte(v+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		candidates = append(candidates, goroot)
+	}
+
+	if got, err := bootstrapGoroot("go1.22.1", "", candidates); err != nil || got != candidates[2] {
+		t.Errorf("Expected the newest installed version, got %q (%v)", got, err)
+	}
+	if got, err := bootstrapGoroot("go1.22.1", "1.20.14", candidates); err != nil || got != candidates[1] {
+		t.Errorf("Expected --bootstrap to pick go1.20.14, got %q (%v)", got, err)
+	}
+	if _, err := bootstrapGoroot("go1.22.1", "go1.19.13", candidates); err == nil {
+		t.Errorf("Expected an error for a bootstrap toolchain below the minimum")
+	}
+	if _, err := bootstrapGoroot("go1.22.1", "go1.21.1", candidates); err == nil {
+		t.Errorf("Expected an error for a bootstrap toolchain which isn't installed")
+	}
+	if _, err := bootstrapGoroot("go1.24.0", "", candidates); err == nil {
+		t.Errorf("Expected an error when no installed version is recent enough")
+	}
+
+	t.Setenv("GOROOT_BOOTSTRAP", "/opt/go")
+	if got, _ := bootstrapGoroot("go1.24.0", "", candidates); got != "/opt/go" {
+		t.Errorf("Expected GOROOT_BOOTSTRAP to be used, got %q", got)
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// retention limits the archives kept by the disk cache, zero values are
+// unlimited.
+type retention struct {
+	maxSize int64
+	maxAge  time.Duration
+}
+
+func parseRetention(config StorageConfig) (retention, error) {
+	var r retention
+	var err error
+
+	if config.MaxSize != "" {
+		if r.maxSize, err = parseSize(config.MaxSize); err != nil {
+			return r, fmt.Errorf("invalid cache max_size: %w", err)
+		}
+	}
+	if config.MaxAge != "" {
+		if r.maxAge, err = parseAge(config.MaxAge); err != nil {
+			return r, fmt.Errorf("invalid cache max_age: %w", err)
+		}
+	}
+	return r, nil
+}
+
+var sizeUnits = map[string]int64{
+	"": 1, "b": 1,
+	"kb": 1e3, "mb": 1e6, "gb": 1e9, "tb": 1e12,
+	"kib": 1 << 10, "mib": 1 << 20, "gib": 1 << 30, "tib": 1 << 40,
+}
+
+// parseSize parses a number of bytes with an optional decimal (KB, MB, GB,
+// TB) or binary (KiB, MiB, GiB, TiB) unit.
+func parseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i < 0 {
+		i = len(s)
+	}
+
+	n, err := strconv.ParseFloat(s[:i], 64)
+	unit, ok := sizeUnits[strings.ToLower(strings.TrimSpace(s[i:]))]
+	if err != nil || !ok || n < 0 {
+		return 0, fmt.Errorf("%q is not a size such as 500MB or 5GiB", s)
+	}
+	return int64(n * float64(unit)), nil
+}
+
+// byteUnits selects how sizes are shown, with decimal (MB) or binary (MiB)
+// units, and a decimal comma for the locales writing 1,5 MB.
+type byteUnits struct {
+	binary bool
+	comma  bool
+}
+
+var (
+	decimalUnits = byteUnits{}
+	binaryUnits  = byteUnits{binary: true}
+)
+
+// parseByteUnits parses the units configuration: si (the default) or binary.
+func parseByteUnits(s string) (byteUnits, error) {
+	switch strings.ToLower(s) {
+	case "", "si", "decimal":
+		return decimalUnits, nil
+	case "binary", "iec":
+		return binaryUnits, nil
+	}
+	return decimalUnits, fmt.Errorf("unknown units %q, expected si or binary", s)
+}
+
+// commaLanguages are the languages writing numbers with a decimal comma.
+var commaLanguages = []string{
+	"bg", "cs", "da", "de", "el", "es", "et", "fi", "fr", "hr", "hu", "id", "it", "lt", "lv",
+	"nb", "nl", "nn", "no", "pl", "pt", "ro", "ru", "sk", "sl", "sr", "sv", "tr", "uk", "vi",
+}
+
+// systemLocale returns the locale formatting the numbers, from the
+// environment read with getenv.
+func systemLocale(getenv func(string) string) string {
+	for _, name := range []string{"LC_ALL", "LC_NUMERIC", "LANG"} {
+		if locale := getenv(name); locale != "" {
+			return locale
+		}
+	}
+	return ""
+}
+
+// localized returns u with the decimal separator of locale, such as
+// de_DE.UTF-8.
+func (u byteUnits) localized(locale string) byteUnits {
+	language, _, _ := strings.Cut(strings.ToLower(locale), "_")
+	language, _, _ = strings.Cut(language, ".")
+	u.comma = slices.Contains(commaLanguages, language)
+	return u
+}
+
+// size returns n bytes with the largest unit fitting it.
+func (u byteUnits) size(n int64) string {
+	type unit struct {
+		name string
+		size int64
+	}
+	units := []unit{{"TB", 1e12}, {"GB", 1e9}, {"MB", 1e6}, {"kB", 1e3}}
+	if u.binary {
+		units = []unit{{"TiB", 1 << 40}, {"GiB", 1 << 30}, {"MiB", 1 << 20}, {"KiB", 1 << 10}}
+	}
+
+	for _, unit := range units {
+		if n >= unit.size {
+			s := fmt.Sprintf("%.1f %s", float64(n)/float64(unit.size), unit.name)
+			if u.comma {
+				s = strings.Replace(s, ".", ",", 1)
+			}
+			return s
+		}
+	}
+	return fmt.Sprintf("%d B", n)
+}
+
+// speed returns a transfer rate given in bytes per second.
+func (u byteUnits) speed(bytesPerSecond float64) string {
+	return u.size(int64(bytesPerSecond)) + "/s"
+}
+
+// parseAge parses a duration, which can also be given in days such as 90d.
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(strings.TrimSpace(s), "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("%q is not a number of days", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// collector is implemented by the storages enforcing a retention policy.
+type collector interface {
+	collect(now time.Time) ([]evicted, error)
+}
+
+type evicted struct {
+	Key    string
+	Size   int64
+	Reason string
+}
+
+// collect evicts the archives older than the maximum age, then the least
+// recently used ones until the cache fits its maximum size.
+func (d *diskStorage) collect(now time.Time) ([]evicted, error) {
+	if d.retention == (retention{}) {
+		return nil, nil
+	}
+
+	type archive struct {
+		key  string
+		size int64
+		used time.Time
+	}
+	var archives []archive
+	var total int64
+
+	err := filepath.WalkDir(d.dir, func(path string, e fs.DirEntry, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil || !e.Type().IsRegular() || strings.Contains(e.Name(), ".tmp") {
+			return err
+		}
+		info, err := e.Info()
+		if err != nil {
+			return err
+		}
+		key, _ := filepath.Rel(d.dir, path)
+		archives = append(archives, archive{key, info.Size(), info.ModTime()})
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(archives, func(i, j int) bool { return archives[i].used.Before(archives[j].used) })
+
+	var evictions []evicted
+	for _, a := range archives {
+		reason := ""
+		switch {
+		case d.retention.maxAge > 0 && now.Sub(a.used) > d.retention.maxAge:
+			reason = "unused for " + now.Sub(a.used).Round(time.Hour).String()
+		case d.retention.maxSize > 0 && total > d.retention.maxSize:
+			reason = "cache over max_size"
+		default:
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(d.dir, a.key)); err != nil {
+			return evictions, err
+		}
+		total -= a.size
+		evictions = append(evictions, evicted{a.key, a.size, reason})
+	}
+	return evictions, nil
+}
+
+// collectCache enforces the retention policy of the cache after an install.
+func (c *cli) collectCache() {
+	if col, ok := c.storage.(collector); ok {
+		if _, err := col.collect(time.Now()); err != nil {
+			slog.Warn("unable to apply the cache retention policy", "err", err)
+		}
+	}
+}
+
+func (c *cli) cache(args []string) error {
+	fs := flag.NewFlagSet("cache", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-dl cache gc | go-dl cache prefetch <version constraint>...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.Arg(0) == "prefetch" {
+		return c.prefetch(fs.Args()[1:])
+	}
+	if fs.Arg(0) != "gc" {
+		fs.Usage()
+		return fmt.Errorf("unknown cache command %q", fs.Arg(0))
+	}
+
+	col, ok := c.storage.(collector)
+	if !ok {
+		return fmt.Errorf("retention is only enforced for the disk cache, use the lifecycle rules of the bucket instead")
+	}
+
+	evictions, err := col.collect(time.Now())
+	for _, e := range evictions {
+		fmt.Fprintf(c.stdout, "Evicted %s (%s, %s)\n", e.Key, c.units.size(e.Size), e.Reason)
+	}
+	if err != nil {
+		return wrapPermission(err)
+	}
+	if len(evictions) == 0 {
+		fmt.Fprintln(c.stdout, "Nothing to evict")
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSize(t *testing.T) {
+	for s, want := range map[string]int64{"1024": 1024, "5GB": 5e9, "1.5 MiB": 3 << 19, "500mb": 5e8} {
+		if got, err := parseSize(s); err != nil || got != want {
+			t.Errorf("parseSize(%q) want %d, got %d (%v)", s, want, got, err)
+		}
+	}
+	for _, s := range []string{"", "GB", "5 parsecs", "-1GB"} {
+		if _, err := parseSize(s); err == nil {
+			t.Errorf("Expected parseSize(%q) to fail", s)
+		}
+	}
+
+	if got, err := parseAge("90d"); err != nil || got != 90*24*time.Hour {
+		t.Errorf("Expected 90 days, got %v (%v)", got, err)
+	}
+}
+
+func TestByteUnits(t *testing.T) {
+	if got := decimalUnits.size(1536e3); got != "1.5 MB" {
+		t.Errorf("Expected 1.5 MB, got %s", got)
+	}
+	if got := binaryUnits.size(3 << 19); got != "1.5 MiB" {
+		t.Errorf("Expected 1.5 MiB, got %s", got)
+	}
+	if got := binaryUnits.speed(512); got != "512 B/s" {
+		t.Errorf("Expected 512 B/s, got %s", got)
+	}
+
+	if u, err := parseByteUnits("binary"); err != nil || u != binaryUnits {
+		t.Errorf("Expected binary units, got %v (%v)", u, err)
+	}
+	if _, err := parseByteUnits("imperial"); err == nil {
+		t.Errorf("Expected unknown units to fail")
+	}
+
+	for locale, want := range map[string]string{"de_DE.UTF-8": "1,5 MiB", "pt_BR": "1,5 MiB", "en_US.UTF-8": "1.5 MiB", "C": "1.5 MiB", "": "1.5 MiB"} {
+		if got := binaryUnits.localized(locale).size(3 << 19); got != want {
+			t.Errorf("Expected %s in the %q locale, got %s", want, locale, got)
+		}
+	}
+	env := map[string]string{"LANG": "en_US.UTF-8", "LC_NUMERIC": "fr_FR.UTF-8"}
+	if got := systemLocale(func(name string) string { return env[name] }); got != "fr_FR.UTF-8" {
+		t.Errorf("Expected LC_NUMERIC to take precedence over LANG, got %q", got)
+	}
+}
+
+func TestDiskStorageCollect(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	for name, age := range map[string]time.Duration{
+		"go1.19.0.tar.gz": 200 * 24 * time.Hour,
+		"go1.20.0.tar.gz": 30 * 24 * time.Hour,
+		"go1.21.0.tar.gz": 20 * 24 * time.Hour,
+		"go1.22.0.tar.gz": time.Hour,
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(strings.Repeat("x", 100)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d := &diskStorage{dir: dir, retention: retention{maxSize: 250, maxAge: 90 * 24 * time.Hour}}
+	evictions, err := d.collect(now)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var keys []string
+	for _, e := range evictions {
+		keys = append(keys, e.Key)
+	}
+	if want := []string{"go1.19.0.tar.gz", "go1.20.0.tar.gz"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("Expected %v to be evicted, got %v", want, keys)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "go1.21.0.tar.gz")); err != nil {
+		t.Errorf("Expected recent archives to be kept, got %v", err)
+	}
+}
+
+func TestNewStorageRetention(t *testing.T) {
+	if _, err := newStorage(StorageConfig{Type: "s3", Bucket: "b", MaxSize: "5GB"}, t.TempDir(), nil); err == nil {
+		t.Errorf("Expected retention to be rejected for remote caches")
+	}
+	if _, err := newStorage(StorageConfig{MaxAge: "soon"}, t.TempDir(), nil); err == nil {
+		t.Errorf("Expected an invalid max_age to be rejected")
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// checkReport lists the differences found between an installation and the
+// archive it was extracted from.
+type checkReport struct {
+	Checked  int
+	Missing  []string
+	Modified []string
+}
+
+func (r checkReport) Ok() bool {
+	return len(r.Missing) == 0 && len(r.Modified) == 0
+}
+
+// checkInstallation compares every regular file of the archive not left out
+// by filter with its extracted copy under goroot, without writing anything.
+func checkInstallation(goroot string, archive io.Reader, filter archiveFilter) (checkReport, error) {
+	var report checkReport
+
+	gzr, err := gzip.NewReader(archive)
+	if err != nil {
+		return report, err
+	}
+	defer gzr.Close()
+
+	tr := tar.NewReader(gzr)
+	for {
+		header, err := tr.Next()
+
+		switch {
+		case err == io.EOF:
+			return report, nil
+		case err != nil:
+			return report, err
+		}
+
+		if header.Typeflag != tar.TypeReg || filter.Skip(header.Name) {
+			continue
+		}
+
+		name := strings.TrimPrefix(header.Name, "go/")
+		report.Checked++
+
+		want := sha256.New()
+		if _, err := io.Copy(want, tr); err != nil {
+			return report, err
+		}
+
+		got, err := fileSum(filepath.Join(goroot, filepath.FromSlash(name)))
+		if errors.Is(err, os.ErrNotExist) {
+			report.Missing = append(report.Missing, name)
+			continue
+		}
+		if err != nil {
+			return report, err
+		}
+
+		if !bytes.Equal(got, want.Sum(nil)) {
+			report.Modified = append(report.Modified, name)
+		}
+	}
+}
+
+func fileSum(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := copyBuffered(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCheckInstallation(t *testing.T) {
+	archive := newTestArchive(t, map[string]string{
+		"go/VERSION":     "go1.22.1\n",
+		"go/bin/go":      "binary",
+		"go/src/fmt.go":  "package fmt",
+		"go/src/sort.go": "package sort",
+	})
+
+	goroot := filepath.Join(t.TempDir(), "go")
+	if err := Decompress(filepath.Dir(goroot), bytes.NewReader(archive), func(ratio float64) {}); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := checkInstallation(goroot, bytes.NewReader(archive), archiveFilter{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !report.Ok() || report.Checked != 4 {
+		t.Errorf("Expected a clean report of 4 files, got %+v", report)
+	}
+
+	if err := os.WriteFile(filepath.Join(goroot, "bin", "go"), []byte("tampered"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(goroot, "src", "sort.go")); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err = checkInstallation(goroot, bytes.NewReader(archive), archiveFilter{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(report.Modified, []string{"bin/go"}) || !reflect.DeepEqual(report.Missing, []string{"src/sort.go"}) {
+		t.Errorf("Expected bin/go modified and src/sort.go missing, got %+v", report)
+	}
+}
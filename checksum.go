@@ -0,0 +1,110 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// checksumAlgorithms creates the hashes of the supported checksum
+// algorithms, by name. Builds for internal mirrors can register more from a
+// file of their own, along with their place in checksumPreference.
+var checksumAlgorithms = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// checksumPreference lists the algorithms from the strongest, the first one
+// known with a published sum verifies the file.
+var checksumPreference = []string{"sha512", "sha256"}
+
+// A Checksum is the expected hex encoded sum of a file with one of the
+// checksumAlgorithms. The zero Checksum accepts any file, one with an
+// unknown algorithm none.
+type Checksum struct {
+	Algorithm string
+	Sum       string
+}
+
+// Checksum returns the checksum verifying f, the sha256 of the feed unless
+// the manifest publishes a stronger one. A file only published with sums of
+// unknown algorithms gets one of them, which fails the verification.
+func (f File) Checksum() Checksum {
+	for _, algorithm := range checksumPreference {
+		if _, ok := checksumAlgorithms[algorithm]; !ok {
+			continue
+		}
+		if sum := f.Checksums[algorithm]; sum != "" {
+			return Checksum{Algorithm: algorithm, Sum: sum}
+		}
+	}
+	if f.Sha256 != "" {
+		return Checksum{Algorithm: "sha256", Sum: f.Sha256}
+	}
+
+	var unknown []string
+	for algorithm, sum := range f.Checksums {
+		if sum != "" {
+			unknown = append(unknown, algorithm)
+		}
+	}
+	if len(unknown) == 0 {
+		return Checksum{}
+	}
+	slices.Sort(unknown)
+	return Checksum{Algorithm: unknown[0], Sum: f.Checksums[unknown[0]]}
+}
+
+// New returns the hash computing the checksum, sha256 for the zero Checksum.
+func (c Checksum) New() hash.Hash {
+	if h, ok := checksumAlgorithms[c.Algorithm]; ok {
+		return h()
+	}
+	return sha256.New()
+}
+
+func (c Checksum) String() string {
+	return c.Algorithm + " " + c.Sum
+}
+
+// verifyChecksum compares the checksum of the file at path with the
+// expected one.
+func verifyChecksum(path string, sum Checksum) error {
+	if sum.Sum == "" {
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sum.New()
+	if _, err := copyBuffered(h, f); err != nil {
+		return err
+	}
+
+	return compareChecksum(path, sum, h.Sum(nil))
+}
+
+// compareChecksum compares the already computed checksum of the file at path
+// with the expected one.
+func compareChecksum(path string, sum Checksum, computed []byte) error {
+	if sum.Sum == "" {
+		return nil
+	}
+	if _, ok := checksumAlgorithms[sum.Algorithm]; !ok {
+		return fmt.Errorf("unable to verify %s: %s checksums are not supported", filepath.Base(path), sum.Algorithm)
+	}
+
+	if got := hex.EncodeToString(computed); got != sum.Sum {
+		return fmt.Errorf("%w for %s: want %s %s, got %s", ErrChecksumMismatch, filepath.Base(path), sum.Algorithm, sum.Sum, got)
+	}
+	return nil
+}
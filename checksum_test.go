@@ -0,0 +1,52 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileChecksum(t *testing.T) {
+	data := []byte("go1.22.1")
+	path := filepath.Join(t.TempDir(), "go1.22.1.linux-amd64.tar.gz")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	sum256 := sha256.Sum256(data)
+	sum512 := sha512.Sum512(data)
+
+	f := File{Sha256: hex.EncodeToString(sum256[:])}
+	if got := f.Checksum(); got.Algorithm != "sha256" {
+		t.Errorf("Expected the sha256 of the feed, got %v", got)
+	}
+	if err := verifyChecksum(path, f.Checksum()); err != nil {
+		t.Errorf("Expected the sha256 to match, got %v", err)
+	}
+
+	f.Checksums = map[string]string{"sha512": hex.EncodeToString(sum512[:]), "blake3": "unregistered"}
+	if got := f.Checksum(); got.Algorithm != "sha512" {
+		t.Errorf("Expected the strongest known checksum, got %v", got)
+	}
+	if err := verifyChecksum(path, f.Checksum()); err != nil {
+		t.Errorf("Expected the sha512 to match, got %v", err)
+	}
+
+	f.Checksums["sha512"] = f.Sha256
+	if err := verifyChecksum(path, f.Checksum()); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Expected a mismatch of the sha512, got %v", err)
+	}
+
+	// Sums of unknown algorithms alone can't verify the file.
+	unknown := File{Checksums: map[string]string{"blake3": hex.EncodeToString(sum256[:])}}
+	if err := verifyChecksum(path, unknown.Checksum()); err == nil || errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Expected an unsupported checksum, got %v", err)
+	}
+
+	if err := verifyChecksum(path, File{}.Checksum()); err != nil {
+		t.Errorf("Expected files without checksum to be accepted, got %v", err)
+	}
+}
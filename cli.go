@@ -0,0 +1,612 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/blckfalcon/go-dl/tui"
+	"github.com/blckfalcon/go-dl/versions"
+)
+
+type cli struct {
+	ctx       context.Context
+	repo      *GoRepository
+	selection Selection
+	policy    Policy
+	prefix    string
+	owner     Owner
+	scanner   []string
+	paths     Paths
+	storage   Storage
+	deltaURL  string
+	events    *progressEvents
+	hardlinks bool
+	keys      tui.KeyMap
+	hooks     installHooks
+	webhooks  []WebhookConfig
+	// webhookClient posts to the webhooks, without the credentials of the
+	// mirrors.
+	webhookClient *http.Client
+	units         byteUnits
+	config        string
+	mirrors       []string
+	aliases       map[string]string
+	stdin         io.Reader
+	stdout        io.Writer
+	// restart runs go-dl again with args after the global flags of this
+	// run, loading the configuration anew.
+	restart func(args []string, stdin io.Reader) error
+	// nonInteractive answers no to every question instead of reading
+	// stdin, for the unattended runs.
+	nonInteractive bool
+}
+
+var commands = map[string]func(c *cli, args []string) error{
+	"alias":      (*cli).alias,
+	"apply":      (*cli).apply,
+	"automate":   (*cli).automate,
+	"cache":      (*cli).cache,
+	"check":      (*cli).check,
+	"dedupe":     (*cli).dedupe,
+	"delta-gen":  (*cli).deltaGen,
+	"describe":   (*cli).describe,
+	"diff":       (*cli).diff,
+	"direnv":     (*cli).direnv,
+	"doctor":     (*cli).doctor,
+	"exec":       (*cli).execAll,
+	"export-oci": (*cli).exportOCI,
+	"generate":   (*cli).generate,
+	"info":       (*cli).info,
+	"install":    (*cli).install,
+	"latest":     (*cli).latest,
+	"list":       (*cli).list,
+	"migrate":    (*cli).migrate,
+	"mirrors":    (*cli).benchMirrors,
+	"outdated":   (*cli).outdated,
+	"pack":       (*cli).pack,
+	"pin":        (*cli).pin,
+	"plan":       (*cli).plan,
+	"plugin":     (*cli).plugin,
+	"release":    (*cli).release,
+	"resume":     (*cli).resume,
+	"rollback":   (*cli).rollback,
+	"run":        (*cli).runToolchain,
+	"service":    (*cli).service,
+	"state":      (*cli).state,
+	"suggest":    (*cli).suggest,
+	"use":        (*cli).use,
+	"watch":      (*cli).watch,
+}
+
+// readOnlyCommands only read the installations and the state, go-dl skips
+// the cleanup of the leftovers of crashed runs and of the expired backups
+// when running them.
+var readOnlyCommands = map[string]bool{
+	"check":    true,
+	"describe": true,
+	"diff":     true,
+	"doctor":   true,
+	"info":     true,
+	"list":     true,
+	"mirrors":  true,
+	"outdated": true,
+}
+
+func (c *cli) run(args []string) error {
+	cmd, ok := commands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+	return cmd(c, args[1:])
+}
+
+// confirm asks a yes/no question on stdin, anything but yes is a no, and so is
+// every question of the non-interactive runs.
+func (c *cli) confirm(question string) bool {
+	if c.nonInteractive {
+		return false
+	}
+	fmt.Fprintf(c.stdout, "%s [y/N] ", question)
+
+	answer, _ := bufio.NewReader(c.stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+func (c *cli) goroot() string {
+	return filepath.Join(c.prefix, "go")
+}
+
+// parseInterspersed parses args with fs, the flags may also follow the
+// arguments, as in go-dl check go1.22.1 --goroot /opt/go. The arguments are
+// then fs.Args(), everything after "--" included.
+func parseInterspersed(fs *flag.FlagSet, args []string) error {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		if i := len(args) - fs.NArg() - 1; i >= 0 && args[i] == "--" {
+			positional = append(positional, fs.Args()...)
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	return fs.Parse(append([]string{"--"}, positional...))
+}
+
+// query returns the version constraint given as argument, or the one from the
+// nearest .go-version file, aliases replaced.
+func (c *cli) query(fs *flag.FlagSet) (string, error) {
+	if fs.NArg() > 0 {
+		return c.resolveAlias(fs.Arg(0)), nil
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+
+	_, query, err := findGoVersionFile(wd)
+	return c.resolveAlias(query), err
+}
+
+func (c *cli) install(args []string) error {
+	fs := flag.NewFlagSet("install", flag.ContinueOnError)
+	fromSource := fs.Bool("from-source", false, "build from the source tarball without asking when no archive matches the platform")
+	downloadOnly := fs.Bool("download-only", false, "only download and verify the file, including installers, and store it in the cache")
+	output := fs.String("output", ".", "directory receiving the verified file with --download-only")
+	target := fs.String("target", "", "install on another machine, ssh://user@host:/prefix, or in a container, docker://container:/prefix")
+	platform := fs.String("platform", "", "os/arch of the --target, linux with the local architecture by default")
+	force := fs.Bool("force", false, "replace an installation owned by a system package")
+	bootstrap := fs.String("bootstrap", "", "toolchain building from source, an installed version or a GOROOT, the newest suitable installed version by default")
+	wait := fs.Bool("wait", false, "wait for a version not published yet, checking the releases every --wait-interval")
+	waitInterval := fs.Duration("wait-interval", 5*time.Minute, "interval between the checks of --wait")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-dl install [--download-only] [--bootstrap version] [--wait] [version constraint]")
+		fs.PrintDefaults()
+	}
+	if err := parseInterspersed(fs, args); err != nil {
+		return err
+	}
+
+	query, err := c.query(fs)
+	if err != nil {
+		return err
+	}
+	if *waitInterval <= 0 {
+		return fmt.Errorf("invalid --wait-interval %s", *waitInterval)
+	}
+
+	var releases []Release
+	var release Release
+	if *wait {
+		releases, release, err = c.waitRelease(query, *waitInterval)
+	} else if releases, err = c.repo.GetVersions(c.ctx); err == nil {
+		release, err = resolveRelease(releases, query)
+	}
+	if err != nil {
+		return err
+	}
+
+	policy, err := c.policy.resolve(releases)
+	if err != nil {
+		return err
+	}
+	if err := policy.Allow(release.Version); err != nil {
+		return err
+	}
+	warnEndOfLife(releases, release.Version)
+
+	if *target != "" {
+		t, err := parseRemoteTarget(*target)
+		if err != nil {
+			return err
+		}
+		selection := Selection{Os: "linux", Arch: c.selection.Arch}
+		if *platform != "" {
+			var ok bool
+			if selection.Os, selection.Arch, ok = strings.Cut(*platform, "/"); !ok {
+				return fmt.Errorf("invalid platform %q, expected os/arch", *platform)
+			}
+		}
+		dlf, ok := selection.Pick(release.Files)
+		if !ok {
+			return fmt.Errorf("%w for %s on %s/%s", ErrNoMatchingFile, release.Version, selection.Os, selection.Arch)
+		}
+		return c.installRemote(dlf, t)
+	}
+
+	// The same version of another distribution replaces the installation.
+	if v, err := installedVersion(c.goroot()); err == nil && v == release.Version && installedDist(c.goroot()) == c.repo.dist && !*downloadOnly {
+		fmt.Fprintf(c.stdout, "%s is already installed\n", release.Version)
+		return nil
+	}
+
+	dlf, ok := c.selection.Pick(release.Files)
+	if !ok {
+		dlf, ok = c.selection.Source(release.Files)
+		if !ok {
+			return fmt.Errorf("%w for %s on %s/%s", ErrNoMatchingFile, release.Version, c.selection.Os, c.selection.Arch)
+		}
+
+		question := fmt.Sprintf("No %s/%s archive for %s, build it from source?", c.selection.Os, c.selection.Arch, release.Version)
+		if !*fromSource && !c.confirm(question) {
+			return fmt.Errorf("%w for %s on %s/%s", ErrNoMatchingFile, release.Version, c.selection.Os, c.selection.Arch)
+		}
+	}
+
+	if *downloadOnly {
+		return c.downloadOnly(dlf, *output)
+	}
+
+	// From here on the outcome is notified to the webhooks.
+	previous, _ := installedVersion(c.goroot())
+	notify := func(err error) error {
+		return c.notifyInstall(release.Version, previous, err)
+	}
+	if err := checkForeignLink(c.goroot()); err != nil {
+		return notify(err)
+	}
+	if err := checkPackageOwner(c.ctx, c.goroot()); err != nil {
+		if !*force {
+			return notify(err)
+		}
+		slog.Warn("replacing an installation owned by a system package, which may overwrite it again", "err", err)
+	}
+
+	p := newPipeline(c.repo, c.storage, dlf, c.prefix, c.owner, c.paths)
+	p.events = c.events
+	p.scanner = c.scanner
+	p.state.Bootstrap = *bootstrap
+	p.preInstall = c.preInstall(release.Version)
+	if installed, err := installedVersion(c.goroot()); err == nil && c.deltaURL != "" && isExtractable(dlf) && versions.IsNewer(release.Version, installed) && fullInstallation(c.goroot()) && c.repo.dist == "" && installedDist(c.goroot()) == "" {
+		fmt.Fprintf(c.stdout, "Upgrading %s to %s\n", installed, release.Version)
+		p.delta = c.deltaSource(installed, release.Version)
+	} else {
+		fmt.Fprintf(c.stdout, "Installing %s\n", release.Version)
+	}
+	if err := p.run(c.ctx); err != nil {
+		return notify(err)
+	}
+	fmt.Fprintf(c.stdout, "Installed %s in %s\n", release.Version, c.goroot())
+	return c.afterInstall(release.Version, previous, c.prefix, c.stdout)
+}
+
+// downloadOnly downloads and verifies dlf, stores it in the cache and copies
+// it to dir, without installing it.
+func (c *cli) downloadOnly(dlf File, dir string) error {
+	fmt.Fprintf(c.stdout, "Downloading %s\n", dlf.Filename)
+
+	p := newPipeline(c.repo, c.storage, dlf, c.prefix, c.owner, c.paths)
+	p.events = c.events
+	p.scanner = c.scanner
+	target, err := downloadVerified(c.ctx, p, dir)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stdout, "Verified %s (%s)\n", target, dlf.Checksum())
+	return nil
+}
+
+// downloadVerified downloads and verifies the file of p, stores it in the
+// cache and copies it to dir, it returns the path of the copy.
+func downloadVerified(ctx context.Context, p *pipeline, dir string) (string, error) {
+	// Keep the state of an interrupted installation, this one is not resumable.
+	p.statePath = filepath.Join(filepath.Dir(p.statePath), "download.json")
+	defer os.Remove(p.state.Archive)
+	defer p.clear()
+
+	err := p.download(ctx)
+	if err == nil {
+		err = p.verify(ctx)
+	}
+	if err == nil {
+		err = runScanner(ctx, p.scanner, p.state.Archive)
+	}
+	if err != nil {
+		return "", wrapPermission(err)
+	}
+	return copyVerified(p, dir)
+}
+
+// copyVerified copies the verified file of p to dir, it returns the path of
+// the copy.
+func copyVerified(p *pipeline, dir string) (string, error) {
+	f, err := os.Open(p.state.Archive)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	target := filepath.Join(dir, p.state.File.Filename)
+	if err := writeFile(target, f, 0644); err != nil {
+		return "", wrapPermission(err)
+	}
+	return target, nil
+}
+
+// afterInstall runs the steps following every installation of version in
+// prefix, from the command line as from the picker, and notifies their
+// outcome to the webhooks. previous is the version it replaced.
+func (c *cli) afterInstall(version, previous, prefix string, out io.Writer) error {
+	return c.notifyInstall(version, previous, c.installed(version, prefix, out))
+}
+
+// installed applies the retention of the cache to the installation of version
+// in prefix, deduplicates its files when enabled and runs its hooks.
+func (c *cli) installed(version, prefix string, out io.Writer) error {
+	goroot := filepath.Join(prefix, "go")
+	c.collectCache()
+
+	if c.hardlinks && isManaged(goroot) {
+		stats, err := dedupeTree(filepath.Join(versionsDir(prefix), version), storeDir(prefix))
+		if err != nil {
+			slog.Warn("unable to deduplicate the installation", "err", err)
+		} else if stats.Saved > 0 {
+			fmt.Fprintf(out, "Shared %d files with other versions, %s saved\n", stats.Cloned+stats.Linked, c.units.size(stats.Saved))
+		}
+		// A reinstalled version may no longer use all of its previous files.
+		if err := pruneStore(prefix); err != nil {
+			slog.Warn("unable to prune the store of deduplicated files", "err", err)
+		}
+	}
+
+	return runHooks(c.ctx, "post_install", c.hooks.of(version).PostInstall, postInstallEnv(goroot, version), c.owner, out)
+}
+
+// latest installs the newest stable release.
+func (c *cli) latest(args []string) error {
+	fs := flag.NewFlagSet("latest", flag.ContinueOnError)
+	quiet := fs.Bool("quiet", false, "only print errors and answer no to every question, for unattended upgrades")
+	force := fs.Bool("force", false, "replace an installation owned by a system package")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-dl latest [--quiet] [--force]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	releases, err := c.repo.GetVersions(c.ctx)
+	if err != nil {
+		return err
+	}
+
+	latest, ok := latestStable(releases)
+	if !ok {
+		return fmt.Errorf("no stable release available")
+	}
+
+	if *quiet {
+		c.stdout = io.Discard
+		c.nonInteractive = true
+	}
+	if *force {
+		return c.install([]string{"--force", latest.Version})
+	}
+	return c.install([]string{latest.Version})
+}
+
+func (c *cli) outdated(args []string) error {
+	fs := flag.NewFlagSet("outdated", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-dl outdated [version constraint]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	installed, err := installedVersion(c.goroot())
+	if err != nil {
+		installed = "-"
+	}
+
+	releases, err := c.repo.GetVersions(c.ctx)
+	if err != nil {
+		return err
+	}
+	sort.Sort(ByRelease(releases))
+
+	latest, ok := latestStable(releases)
+	if !ok {
+		return fmt.Errorf("no stable release available")
+	}
+
+	wanted := "-"
+	if query, err := c.query(fs); err == nil {
+		r, err := resolveRelease(releases, query)
+		if err != nil {
+			return err
+		}
+		wanted = r.Version
+	}
+
+	w := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Installed\tWanted\tLatest")
+	fmt.Fprintf(w, "%s\t%s\t%s\n", installed, wanted, latest.Version)
+	return w.Flush()
+}
+
+func (c *cli) resume(args []string) error {
+	fs := flag.NewFlagSet("resume", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-dl resume")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	p, err := loadPipeline(c.repo, c.storage, c.paths)
+	if err != nil {
+		return err
+	}
+	if p == nil {
+		fmt.Fprintln(c.stdout, "Nothing to resume")
+		return nil
+	}
+	// The cache and the mirror of the installation are the ones of its
+	// distribution.
+	if p.state.Dist != c.repo.dist {
+		return fmt.Errorf("the installation of %s is of the distribution %s, resume it with --dist %[2]s", p.state.Version, distLabel(p.state.Dist))
+	}
+	p.scanner = c.scanner
+	p.events = c.events
+	p.preInstall = c.preInstall(p.state.Version)
+
+	fmt.Fprintf(c.stdout, "Resuming installation of %s from phase %q\n", p.state.Version, p.state.Phase)
+	if err := p.run(c.ctx); err != nil {
+		return c.notifyInstall(p.state.Version, "", err)
+	}
+	fmt.Fprintf(c.stdout, "Installed %s in %s\n", p.state.Version, filepath.Join(p.state.Prefix, "go"))
+	return c.afterInstall(p.state.Version, "", p.state.Prefix, c.stdout)
+}
+
+func (c *cli) check(args []string) error {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	goroot := fs.String("goroot", c.goroot(), "path of the installation to check")
+	versionOnly := fs.Bool("version-only", false, "only check the installed version, skip file integrity")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-dl check [flags] [version constraint]")
+		fs.PrintDefaults()
+	}
+	if err := parseInterspersed(fs, args); err != nil {
+		return err
+	}
+
+	query, err := c.query(fs)
+	if err != nil {
+		return err
+	}
+
+	installed, err := installedVersion(*goroot)
+	if err != nil {
+		return err
+	}
+
+	ok, err := versions.Matches(installed, query)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("installed version %s does not match %s", installed, query)
+	}
+	fmt.Fprintf(c.stdout, "%s matches %s\n", installed, query)
+
+	if *versionOnly {
+		return nil
+	}
+	// The files are compared with the archive of the distribution installed.
+	if dist := installedDist(*goroot); dist != c.repo.dist {
+		return fmt.Errorf("%s is of the distribution %s, check it with --dist %[2]s", installed, distLabel(dist))
+	}
+
+	releases, err := c.repo.GetVersions(c.ctx)
+	if err != nil {
+		return err
+	}
+
+	release, err := resolveRelease(releases, installed)
+	if err != nil {
+		return err
+	}
+
+	dlf, ok := c.selection.Pick(release.Files)
+	if !ok || !isExtractable(dlf) {
+		return fmt.Errorf("%w for %s on %s/%s", ErrNoMatchingFile, release.Version, c.selection.Os, c.selection.Arch)
+	}
+
+	archive, err := c.openArchive(dlf)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	// Without --minimal, --include or --exclude, the files left out at the
+	// installation are not reported missing.
+	filter := c.repo.archive.filter
+	if provenance, err := readProvenance(*goroot); err == nil && filter.empty() {
+		filter = provenance.Filter
+	}
+	report, err := checkInstallation(*goroot, archive, filter)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range report.Missing {
+		fmt.Fprintf(c.stdout, "missing: %s\n", name)
+	}
+	for _, name := range report.Modified {
+		fmt.Fprintf(c.stdout, "modified: %s\n", name)
+	}
+	if !report.Ok() {
+		return fmt.Errorf("%d of %d files differ from %s", len(report.Missing)+len(report.Modified), report.Checked, dlf.Filename)
+	}
+
+	fmt.Fprintf(c.stdout, "%d files match %s\n", report.Checked, dlf.Filename)
+	return nil
+}
+
+// openArchive returns a verified copy of dlf in a temporary file, from the
+// cache when available or downloaded otherwise. The file is removed when
+// closed.
+func (c *cli) openArchive(dlf File) (*tempFile, error) {
+	dir, err := c.paths.TempDir()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := createTemp(dir, "go-dl-tmp-*.tar.gz")
+	if err != nil {
+		return nil, err
+	}
+	archive := &tempFile{File: f, source: "cache"}
+
+	err = ErrCacheMiss
+	if c.storage != nil {
+		err = c.storage.Get(c.ctx, dlf.Filename, f)
+	}
+	if err != nil {
+		f.Truncate(0)
+		f.Seek(0, io.SeekStart)
+		archive.source, err = c.repo.download(c.ctx, dlf, f)
+	}
+	if err == nil {
+		err = verifyChecksum(f.Name(), dlf.Checksum())
+	}
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		return nil, errors.Join(err, archive.Close())
+	}
+	return archive, nil
+}
+
+type tempFile struct {
+	*os.File
+	// source is where the archive was downloaded from, or "cache".
+	source string
+}
+
+func (f *tempFile) Close() error {
+	return errors.Join(f.File.Close(), os.Remove(f.Name()))
+}
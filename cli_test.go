@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newTestCLI returns a cli serving a feed with go1.22.1, whose linux/amd64
+// archive is archive, along with its output.
+func newTestCLI(t *testing.T, archive []byte) (*cli, *bytes.Buffer) {
+	sum := sha256.Sum256(archive)
+	feed := fmt.Sprintf(`[{"version":"go1.22.1","stable":true,"files":[{"filename":"go1.22.1.linux-amd64.tar.gz","os":"linux","arch":"amd64","version":"go1.22.1","sha256":%q,"kind":"archive"}]},`+
+		`{"version":"go1.21.0","stable":true,"files":[]},{"version":"go1.23rc1","stable":false,"files":[]}]`, hex.EncodeToString(sum[:]))
+
+	client := NewTestClient(func(req *http.Request) *http.Response {
+		body := archive
+		if req.URL.Query().Get("mode") == "json" {
+			body = []byte(feed)
+		}
+		return &http.Response{
+			StatusCode:    http.StatusOK,
+			Body:          io.NopCloser(bytes.NewReader(body)),
+			ContentLength: int64(len(body)),
+		}
+	})
+
+	var out bytes.Buffer
+	return &cli{
+		ctx:       context.Background(),
+		repo:      &GoRepository{client: client, url: "https://go.dev/dl", onProgress: func(float64) {}},
+		selection: Selection{Os: "linux", Arch: "amd64"},
+		owner:     processOwner,
+		paths:     newTestPaths(t),
+		stdout:    &out,
+	}, &out
+}
+
+func TestInstallDownloadOnly(t *testing.T) {
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n"})
+	c, _ := newTestCLI(t, archive)
+	c.prefix = t.TempDir()
+
+	interrupted := []byte(`{"version":"go1.21.0","phase":"downloaded"}`)
+	if err := writeFile(c.paths.PipelineFile(), bytes.NewReader(interrupted), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	output := t.TempDir()
+	if err := c.install([]string{"--download-only", "--output", output, "1.22.1"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	b, err := os.ReadFile(filepath.Join(output, "go1.22.1.linux-amd64.tar.gz"))
+	if err != nil || !bytes.Equal(b, archive) {
+		t.Errorf("Expected the verified archive in %s, got %v", output, err)
+	}
+	if _, err := os.Stat(filepath.Join(c.prefix, "go")); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing to be installed")
+	}
+	if b, _ := os.ReadFile(c.paths.PipelineFile()); !bytes.Equal(b, interrupted) {
+		t.Errorf("Expected the interrupted installation to be kept, got %s", b)
+	}
+}
+
+func TestParseInterspersed(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"--goroot", "/opt/go", "go1.22.1"}, []string{"go1.22.1"}},
+		{[]string{"go1.22.1", "--goroot", "/opt/go"}, []string{"go1.22.1"}},
+		{[]string{"1.21", "--goroot", "/opt/go", "1.22"}, []string{"1.21", "1.22"}},
+		{[]string{"go1.22.1", "--", "--goroot", "x"}, []string{"go1.22.1", "--goroot", "x"}},
+	}
+	for _, tt := range tests {
+		fs := flag.NewFlagSet("check", flag.ContinueOnError)
+		goroot := fs.String("goroot", "", "")
+		if err := parseInterspersed(fs, tt.args); err != nil {
+			t.Fatalf("parseInterspersed(%q): %v", tt.args, err)
+		}
+		if !reflect.DeepEqual(fs.Args(), tt.want) {
+			t.Errorf("parseInterspersed(%q) args = %q, want %q", tt.args, fs.Args(), tt.want)
+		}
+		if tt.want[len(tt.want)-1] != "x" && *goroot != "/opt/go" {
+			t.Errorf("parseInterspersed(%q) goroot = %q, want /opt/go", tt.args, *goroot)
+		}
+	}
+}
+
+func TestConfirmNonInteractive(t *testing.T) {
+	c, out := newTestCLI(t, nil)
+	c.stdin = strings.NewReader("y\n")
+	c.nonInteractive = true
+
+	if c.confirm("Build it from source?") {
+		t.Error("Expected the non-interactive runs to answer no")
+	}
+	if out.Len() != 0 {
+		t.Errorf("Expected no question printed, got %q", out.String())
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/blckfalcon/go-dl/versions"
+)
+
+type Config struct {
+	MinimumVersion string              `json:"minimum_version,omitempty"`
+	Cache          StorageConfig       `json:"cache"`
+	Transport      TransportConfig     `json:"transport"`
+	DeltaURL       string              `json:"delta_url,omitempty"`
+	Mirror         string              `json:"mirror,omitempty"`
+	Mirrors        []string            `json:"mirrors,omitempty"`
+	Aliases        map[string]string   `json:"aliases,omitempty"`
+	SystemOwner    string              `json:"system_owner,omitempty"`
+	Scanner        []string            `json:"scanner,omitempty"`
+	Keys           map[string][]string `json:"keys,omitempty"`
+
+	ProxyAuthCommand []string          `json:"proxy_auth_command,omitempty"`
+	CredentialHelper []string          `json:"credential_helper,omitempty"`
+	Proxies          map[string]string `json:"proxies,omitempty"`
+	Dedupe           bool              `json:"dedupe,omitempty"`
+	StagingDir       string            `json:"staging_dir,omitempty"`
+	Units            string            `json:"units,omitempty"`
+	Metered          string            `json:"metered,omitempty"`
+	Webhooks         []WebhookConfig   `json:"webhooks,omitempty"`
+	BackupMaxAge     string            `json:"backup_max_age,omitempty"`
+
+	// The hooks of every installation, Hooks the ones of the versions
+	// matching a constraint.
+	HookConfig
+	Hooks map[string]HookConfig `json:"hooks,omitempty"`
+
+	// Dist is the distribution installed without --dist, Distributions the
+	// ones besides go.dev.
+	Dist          string                  `json:"dist,omitempty"`
+	Distributions map[string]Distribution `json:"distributions,omitempty"`
+}
+
+// loadConfig reads the configuration at path, a missing file results in the
+// default configuration.
+func loadConfig(path string) (Config, error) {
+	var config Config
+
+	if path == "" {
+		return config, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return config, err
+	}
+
+	if err := json.Unmarshal(b, &config); err != nil {
+		return config, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+
+	if v := config.MinimumVersion; v != "" && !versions.IsValid(v) && !isChannel(v) {
+		return config, fmt.Errorf("invalid config %s: minimum_version %q is not a valid version", path, v)
+	}
+
+	for name, target := range config.Aliases {
+		if err := checkAlias(name, target); err != nil {
+			return config, fmt.Errorf("invalid config %s: %w", path, err)
+		}
+	}
+
+	if _, err := parseByteUnits(config.Units); err != nil {
+		return config, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+
+	if _, err := newMeteredGuard(config.Metered, nil, decimalUnits); err != nil {
+		return config, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+
+	if _, err := config.backupAge(); err != nil {
+		return config, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+
+	for name, d := range config.Distributions {
+		if err := checkDist(name, d); err != nil {
+			return config, fmt.Errorf("invalid config %s: %w", path, err)
+		}
+	}
+	if _, _, err := lookupDist(config.Distributions, config.Dist); err != nil {
+		return config, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+
+	for constraint := range config.Hooks {
+		if _, err := versions.ParseConstraint(constraint); err != nil {
+			return config, fmt.Errorf("invalid config %s: hooks: %w", path, err)
+		}
+	}
+
+	for _, w := range config.Webhooks {
+		if err := w.check(); err != nil {
+			return config, fmt.Errorf("invalid config %s: %w", path, err)
+		}
+	}
+
+	return config, nil
+}
+
+// backupAge returns how long the backups of the replaced installations are
+// kept.
+func (config Config) backupAge() (time.Duration, error) {
+	if config.BackupMaxAge == "" {
+		return defaultBackupAge, nil
+	}
+	age, err := parseAge(config.BackupMaxAge)
+	if err != nil {
+		return 0, fmt.Errorf("invalid backup_max_age: %w", err)
+	}
+	return age, nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"minimum_version": "go1.21.0"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got.MinimumVersion != "go1.21.0" {
+		t.Errorf("Expected minimum version go1.21.0, got %s", got.MinimumVersion)
+	}
+}
+
+func TestLoadConfigMissing(t *testing.T) {
+	got, err := loadConfig(filepath.Join(t.TempDir(), "config.json"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, Config{}) {
+		t.Errorf("Expected default config, got %v", got)
+	}
+}
+
+func TestLoadConfigInvalidMinimumVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"minimum_version": "latest"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := loadConfig(path); err == nil {
+		t.Errorf("Expected an invalid minimum_version to fail")
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// confinedResolve keeps openat2 from resolving a path outside of the
+// directory it starts from, through "..", absolute links or /proc magic
+// links, and from following any link.
+const confinedResolve = unix.RESOLVE_BENEATH | unix.RESOLVE_NO_SYMLINKS | unix.RESOLVE_NO_MAGICLINKS
+
+// confinedDir creates the files of an extraction relative to a descriptor of
+// its destination with openat2, even a malicious archive can't write
+// outside of it.
+type confinedDir struct {
+	root string
+	fd   int
+}
+
+// openConfined opens dst for a confined extraction, it requires Linux 5.6
+// for openat2.
+func openConfined(dst string) (extractDir, error) {
+	fd, err := unix.Open(dst, unix.O_DIRECTORY|unix.O_RDONLY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: dst, Err: err}
+	}
+	d := &confinedDir{root: dst, fd: fd}
+
+	probe, err := d.openDir(".")
+	if errors.Is(err, unix.ENOSYS) {
+		d.Close()
+		return nil, fmt.Errorf("confined extraction needs openat2, available since Linux 5.6: %w", errors.ErrUnsupported)
+	}
+	if err != nil {
+		d.Close()
+		return nil, err
+	}
+	unix.Close(probe)
+	return d, nil
+}
+
+// confinedError describes the failure of op on name, the paths openat2
+// refused to resolve make the archive unsafe.
+func (d *confinedDir) confinedError(op, name string, err error) error {
+	if errors.Is(err, unix.EXDEV) || errors.Is(err, unix.ELOOP) {
+		return fmt.Errorf("%w: %s resolves outside of %s", ErrUnsafeArchive, name, d.root)
+	}
+	return &os.PathError{Op: op, Path: filepath.Join(d.root, name), Err: err}
+}
+
+func (d *confinedDir) openDir(name string) (int, error) {
+	fd, err := unix.Openat2(d.fd, name, &unix.OpenHow{Flags: unix.O_DIRECTORY | unix.O_RDONLY | unix.O_CLOEXEC, Resolve: confinedResolve})
+	if err != nil {
+		return -1, d.confinedError("openat2", name, err)
+	}
+	return fd, nil
+}
+
+// MkdirAll creates the directories of name one at a time, each relative to
+// its confined parent.
+func (d *confinedDir) MkdirAll(name string) error {
+	parent := "."
+	for _, part := range strings.Split(filepath.ToSlash(filepath.Clean(name)), "/") {
+		if part == "." {
+			continue
+		}
+		fd, err := d.openDir(parent)
+		if err != nil {
+			return err
+		}
+		err = unix.Mkdirat(fd, part, 0755)
+		unix.Close(fd)
+		if err != nil && !errors.Is(err, unix.EEXIST) {
+			return d.confinedError("mkdirat", filepath.Join(parent, part), err)
+		}
+		parent = filepath.Join(parent, part)
+	}
+
+	// An existing entry has to be a directory, not a file or a link.
+	fd, err := d.openDir(parent)
+	if err != nil {
+		return err
+	}
+	return unix.Close(fd)
+}
+
+func (d *confinedDir) Create(name string, mode os.FileMode) (*os.File, error) {
+	how := &unix.OpenHow{
+		Flags:   unix.O_CREAT | unix.O_TRUNC | unix.O_RDWR | unix.O_NOFOLLOW | unix.O_CLOEXEC,
+		Mode:    uint64(mode.Perm()),
+		Resolve: confinedResolve,
+	}
+	fd, err := unix.Openat2(d.fd, filepath.Clean(name), how)
+	if err != nil {
+		return nil, d.confinedError("openat2", name, err)
+	}
+	return os.NewFile(uintptr(fd), filepath.Join(d.root, name)), nil
+}
+
+func (d *confinedDir) Close() error {
+	return unix.Close(d.fd)
+}
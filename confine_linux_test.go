@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfinedExtraction(t *testing.T) {
+	dst := t.TempDir()
+	if d, err := openConfined(dst); errors.Is(err, errors.ErrUnsupported) {
+		t.Skip(err)
+	} else if err != nil {
+		t.Fatal(err)
+	} else {
+		d.Close()
+	}
+
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n", "go/bin/go": "binary"})
+	confined := archiveOptions{confine: true}
+	if err := decompress(dst, bytes.NewReader(archive), extractProgress{}, confined); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := installedVersion(filepath.Join(dst, "go")); err != nil || v != "go1.22.1" {
+		t.Errorf("Expected go1.22.1 to be extracted, got %q (%v)", v, err)
+	}
+
+	// A link planted in the destination can't redirect the extraction.
+	outside := t.TempDir()
+	planted := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(planted, "go")); err != nil {
+		t.Fatal(err)
+	}
+	if err := decompress(planted, bytes.NewReader(archive), extractProgress{}, confined); !errors.Is(err, ErrUnsafeArchive) {
+		t.Errorf("Expected the link to be refused, got %v", err)
+	}
+	if entries, _ := os.ReadDir(outside); len(entries) > 0 {
+		t.Errorf("Expected nothing written outside of the destination, got %v", entries)
+	}
+}
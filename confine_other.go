@@ -0,0 +1,13 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+)
+
+func openConfined(dst string) (extractDir, error) {
+	return nil, fmt.Errorf("confined extraction is not supported on %s: %w", runtime.GOOS, errors.ErrUnsupported)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// credentialTransport authenticates the requests with the credentials of a
+// helper speaking the git credential protocol, so the secrets of private
+// mirrors stay out of the configuration. The helper is asked once per host,
+// and told to erase credentials the server rejected.
+type credentialTransport struct {
+	next    http.RoundTripper
+	command []string
+
+	mu    sync.Mutex
+	cache map[string]map[string]string
+}
+
+// newCredentialTransport returns next unchanged without a helper.
+func newCredentialTransport(next http.RoundTripper, command []string) http.RoundTripper {
+	if len(command) == 0 {
+		return next
+	}
+	return &credentialTransport{next: next, command: command, cache: map[string]map[string]string{}}
+}
+
+func (t *credentialTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Authorization") != "" {
+		return t.next.RoundTrip(req)
+	}
+
+	auth, err := t.authorization(req.Context(), req.URL)
+	if err != nil {
+		return nil, err
+	}
+	if auth == "" {
+		return t.next.RoundTrip(req)
+	}
+
+	authenticated := req.Clone(req.Context())
+	authenticated.Header.Set("Authorization", auth)
+	resp, err := t.next.RoundTrip(authenticated)
+	if err == nil && resp.StatusCode == http.StatusUnauthorized {
+		t.reject(req.Context(), req.URL)
+	}
+	return resp, err
+}
+
+// authorization returns the Authorization header of the host of u, empty
+// when the helper has no credentials for it.
+func (t *credentialTransport) authorization(ctx context.Context, u *url.URL) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := u.Scheme + "://" + u.Host
+	fields, ok := t.cache[key]
+	if !ok {
+		var err error
+		if fields, err = t.helper(ctx, "get", credentialRequest(u, nil)); err != nil {
+			return "", err
+		}
+		t.cache[key] = fields
+	}
+	return credentialAuthorization(fields), nil
+}
+
+// reject tells the helper to erase the credentials of the host of u, the
+// next requests are sent without them.
+func (t *credentialTransport) reject(ctx context.Context, u *url.URL) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := u.Scheme + "://" + u.Host
+	if fields := t.cache[key]; len(fields) > 0 {
+		t.helper(ctx, "erase", credentialRequest(u, fields))
+	}
+	t.cache[key] = nil
+}
+
+// helper runs the helper with action as last argument and the attributes
+// of input on its standard input, it returns the attributes it printed.
+func (t *credentialTransport) helper(ctx context.Context, action, input string) (map[string]string, error) {
+	args := append(append([]string{}, t.command[1:]...), action)
+	cmd := exec.CommandContext(ctx, t.command[0], args...)
+	cmd.Stdin = strings.NewReader(input)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("credential helper %s failed: %w: %s", t.command[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return parseCredential(out), nil
+}
+
+// credentialRequest describes the url to the helper as git does, along
+// with the attributes in fields.
+func credentialRequest(u *url.URL, fields map[string]string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "protocol=%s\nhost=%s\npath=%s\n", u.Scheme, u.Host, strings.TrimPrefix(u.Path, "/"))
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s=%s\n", k, fields[k])
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// parseCredential reads the key=value lines printed by a helper, up to the
+// first empty line.
+func parseCredential(out []byte) map[string]string {
+	fields := map[string]string{}
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		if s.Text() == "" {
+			break
+		}
+		if k, v, ok := strings.Cut(s.Text(), "="); ok {
+			fields[k] = v
+		}
+	}
+	return fields
+}
+
+// credentialAuthorization returns the Authorization header of the
+// credentials: authtype and credential for tokens, username and password
+// for basic authentication.
+func credentialAuthorization(fields map[string]string) string {
+	if fields["authtype"] != "" && fields["credential"] != "" {
+		return fields["authtype"] + " " + fields["credential"]
+	}
+	if fields["username"] != "" || fields["password"] != "" {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(fields["username"]+":"+fields["password"]))
+	}
+	return ""
+}
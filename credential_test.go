@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCredentialTransport(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the helper is a shell script")
+	}
+
+	dir := t.TempDir()
+	log := filepath.Join(dir, "log")
+	helper := filepath.Join(dir, "helper")
+	script := `#!/bin/sh
+input=$(cat)
+echo "$1 $(echo "$input" | tr '\n' ' ')" >> ` + log + `
+case "$1 $input" in
+"get protocol=https"*"host=mirror.example.com"*) echo username=ci; echo password=s3cret ;;
+"get protocol=https"*"host=tokens.example.com"*) echo authtype=Bearer; echo credential=t0ken ;;
+esac
+`
+	if err := os.WriteFile(helper, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var auths []string
+	client := &http.Client{Transport: newCredentialTransport(RoundTripFunc(func(req *http.Request) *http.Response {
+		auths = append(auths, req.Header.Get("Authorization"))
+		status := http.StatusOK
+		if req.URL.Path == "/expired" {
+			status = http.StatusUnauthorized
+		}
+		return &http.Response{StatusCode: status, Body: http.NoBody, Request: req}
+	}), []string{helper})}
+
+	for _, u := range []string{
+		"https://mirror.example.com/go1.22.1.linux-amd64.tar.gz",
+		"https://mirror.example.com/go1.22.2.linux-amd64.tar.gz",
+		"https://tokens.example.com/?mode=json",
+		"https://go.dev/dl/?mode=json",
+		"https://mirror.example.com/expired",
+		"https://mirror.example.com/go1.22.1.linux-amd64.tar.gz",
+	} {
+		resp, err := client.Get(u)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	basic := "Basic Y2k6czNjcmV0"
+	if want := []string{basic, basic, "Bearer t0ken", "", basic, ""}; !reflect.DeepEqual(auths, want) {
+		t.Errorf("Expected the authorizations %q, got %q", want, auths)
+	}
+
+	b, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(calls) != 4 || !strings.HasPrefix(calls[0], "get protocol=https host=mirror.example.com path=go1.22.1.linux-amd64.tar.gz") ||
+		!strings.HasPrefix(calls[3], "erase protocol=https host=mirror.example.com path=expired password=s3cret username=ci") {
+		t.Errorf("Expected the helper to be asked once per host then to erase the rejected credentials, got %q", calls)
+	}
+}
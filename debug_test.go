@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestServeDebug(t *testing.T) {
+	addr, err := serveDebug("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	resp, err := http.Get("http://" + addr.String() + "/debug/vars")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var vars map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&vars); err != nil {
+		t.Fatalf("Expected JSON metrics, got %v", err)
+	}
+	if _, ok := vars["goroutines"]; !ok || vars["memstats"] == nil {
+		t.Errorf("Expected the runtime metrics, got %v", vars)
+	}
+
+	resp, err = http.Get("http://" + addr.String() + "/debug/pprof/goroutine?debug=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected the goroutine profile, got %s", resp.Status)
+	}
+
+	resp, err = http.Get("http://" + addr.String() + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain") {
+		t.Errorf("Expected the Prometheus metrics, got %s %s", resp.Status, resp.Header.Get("Content-Type"))
+	}
+
+	for _, addr := range []string{"0.0.0.0:6060", ":6060", "example.com:6060", "6060"} {
+		if _, err := serveDebug(addr); err == nil {
+			t.Errorf("Expected %s to be rejected", addr)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// storeDir holds one copy of each distinct file of the versions installed in
+// the managed layout, named after its sha256, mode and owner, which the
+// versions share.
+func storeDir(prefix string) string {
+	return filepath.Join(versionsDir(prefix), ".store")
+}
+
+// storeIndex lists the store files used by a version, the ones no version
+// uses any more are pruned.
+func storeIndex(store, version string) string {
+	return filepath.Join(store, "index", version+".json")
+}
+
+// storeEntry is the store file used by a file of a version, indexed by its
+// path in the version. Inode identifies the file once it was shared, so the
+// next runs skip it, reflinks being distinct files of the same content.
+type storeEntry struct {
+	Key   string `json:"key"`
+	Inode uint64 `json:"inode,omitempty"`
+}
+
+// readStoreIndex returns the store index at path, an empty one when missing.
+func readStoreIndex(path string) (map[string]storeEntry, error) {
+	index := map[string]storeEntry{}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &index); err != nil {
+		return nil, fmt.Errorf("unable to read the store index %s: %w", path, err)
+	}
+	return index, nil
+}
+
+type dedupeStats struct {
+	Files int
+	// Cloned files are reflinks, with blocks of their own once modified,
+	// Linked ones hardlinks, where reflinks are not supported.
+	Cloned int
+	Linked int
+	Saved  int64
+}
+
+// dedupeTree shares the regular files of dir identical to a file of store,
+// and adds the other ones to store. The files are reflinked where the file
+// system supports it, and hardlinked otherwise. The files shared by a
+// previous run are left as they are, and not counted again.
+func dedupeTree(dir, store string) (dedupeStats, error) {
+	var stats dedupeStats
+	indexPath := storeIndex(store, filepath.Base(dir))
+	previous, err := readStoreIndex(indexPath)
+	if err != nil {
+		return stats, err
+	}
+	index := map[string]storeEntry{}
+
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		stats.Files++
+
+		sum, err := fileSum(path)
+		if err != nil {
+			return err
+		}
+		owner := fileOwner(info)
+		key := hex.EncodeToString(sum)
+		key = filepath.Join(key[:2], fmt.Sprintf("%s-%o-%d-%d", key, info.Mode().Perm(), owner.Uid, owner.Gid))
+		stored := filepath.Join(store, key)
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		entry := storeEntry{Key: key}
+		index[rel] = entry
+
+		existing, err := os.Stat(stored)
+		if errors.Is(err, os.ErrNotExist) {
+			if err := os.MkdirAll(filepath.Dir(stored), 0755); err != nil {
+				return err
+			}
+			// The file stays in place, the store holds a copy of it.
+			entry.Inode = fileInode(info)
+			index[rel] = entry
+			_, err := cloneLike(path, stored, info)
+			return err
+		}
+		if err != nil || os.SameFile(existing, info) {
+			return err
+		}
+		if p, ok := previous[rel]; ok && p.Key == key && p.Inode != 0 && p.Inode == fileInode(info) {
+			index[rel] = p
+			return nil
+		}
+
+		link := path + ".go-dl-link"
+		os.Remove(link)
+		cloned, err := cloneLike(stored, link, info)
+		if err != nil {
+			return err
+		}
+		if err := os.Rename(link, path); err != nil {
+			os.Remove(link)
+			return err
+		}
+		if shared, err := os.Stat(path); err == nil {
+			entry.Inode = fileInode(shared)
+			index[rel] = entry
+		}
+		if cloned {
+			stats.Cloned++
+		} else {
+			stats.Linked++
+		}
+		stats.Saved += info.Size()
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+
+	b, err := json.Marshal(index)
+	if err != nil {
+		return stats, err
+	}
+	if err := os.MkdirAll(filepath.Dir(indexPath), 0755); err != nil {
+		return stats, err
+	}
+	return stats, writeFileAtomic(indexPath, b, 0644)
+}
+
+// reflink creates dst as a reflink of src, the tests replace it where the file
+// system has no reflinks.
+var reflink = cloneFile
+
+// cloneLike creates dst as a reflink of src with the mode, times and owner of
+// the file described by info, or hardlinks it when reflinks are not
+// supported. It reports whether dst is a reflink.
+func cloneLike(src, dst string, info os.FileInfo) (bool, error) {
+	if err := reflink(src, dst); err != nil {
+		return false, os.Link(src, dst)
+	}
+
+	err := os.Chmod(dst, info.Mode().Perm())
+	if err == nil {
+		err = os.Chtimes(dst, info.ModTime(), info.ModTime())
+	}
+	if owner := fileOwner(info); err == nil && owner.isSet() {
+		err = os.Lchown(dst, owner.Uid, owner.Gid)
+	}
+	if err != nil {
+		os.Remove(dst)
+		return false, err
+	}
+	return true, nil
+}
+
+// pruneStore removes the indexes of the versions no longer installed in
+// prefix, and the store files no index lists.
+func pruneStore(prefix string) error {
+	store := storeDir(prefix)
+	indexes, err := os.ReadDir(filepath.Join(store, "index"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	used := make(map[string]bool)
+	for _, e := range indexes {
+		path := filepath.Join(store, "index", e.Name())
+		version := strings.TrimSuffix(e.Name(), ".json")
+		if _, err := os.Stat(filepath.Join(versionsDir(prefix), version)); errors.Is(err, os.ErrNotExist) {
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+			continue
+		}
+
+		index, err := readStoreIndex(path)
+		if err != nil {
+			return err
+		}
+		for _, entry := range index {
+			used[filepath.Join(store, entry.Key)] = true
+		}
+	}
+
+	return filepath.WalkDir(store, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && path == filepath.Join(store, "index") {
+			return filepath.SkipDir
+		}
+		if !d.Type().IsRegular() || used[path] {
+			return nil
+		}
+		return os.Remove(path)
+	})
+}
+
+// removeVersion removes an installed version of the managed layout, along
+// with the store files only it used.
+func removeVersion(prefix, version string) error {
+	if err := os.RemoveAll(filepath.Join(versionsDir(prefix), version)); err != nil {
+		return err
+	}
+	return pruneStore(prefix)
+}
+
+// dedupeVersions deduplicates every version of the managed layout.
+func dedupeVersions(prefix string) (dedupeStats, error) {
+	var total dedupeStats
+
+	entries, err := os.ReadDir(versionsDir(prefix))
+	if err != nil {
+		return total, err
+	}
+
+	store := storeDir(prefix)
+	for _, e := range entries {
+		if !e.IsDir() || e.Name() == filepath.Base(store) {
+			continue
+		}
+
+		stats, err := dedupeTree(filepath.Join(versionsDir(prefix), e.Name()), store)
+		total.Files += stats.Files
+		total.Cloned += stats.Cloned
+		total.Linked += stats.Linked
+		total.Saved += stats.Saved
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, pruneStore(prefix)
+}
+
+func (c *cli) dedupe(args []string) error {
+	fs := flag.NewFlagSet("dedupe", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-dl dedupe")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if !isManaged(c.goroot()) {
+		return fmt.Errorf("%s does not use the managed layout, run go-dl migrate first", c.goroot())
+	}
+
+	stats, err := dedupeVersions(c.prefix)
+	if err != nil {
+		return wrapPermission(err)
+	}
+	fmt.Fprintf(c.stdout, "Shared %d of %d files, %d reflinked and %d hardlinked, %s saved\n", stats.Cloned+stats.Linked, stats.Files, stats.Cloned, stats.Linked, c.units.size(stats.Saved))
+	return nil
+}
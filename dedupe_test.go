@@ -0,0 +1,112 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestDedupeVersions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the managed layout is not supported on windows")
+	}
+
+	prefix := t.TempDir()
+	for version, files := range map[string]map[string]string{
+		"go1.21.0": {"VERSION": "go1.21.0\n", "src/fmt/print.go": "package fmt\n", "bin/go": "#!/bin/sh\n"},
+		"go1.22.1": {"VERSION": "go1.22.1\n", "src/fmt/print.go": "package fmt\n", "bin/go": "#!/bin/sh\n"},
+	} {
+		for name, content := range files {
+			path := filepath.Join(versionsDir(prefix), version, name)
+			mode := os.FileMode(0644)
+			if filepath.Base(filepath.Dir(path)) == "bin" {
+				mode = 0755
+			}
+			if err := writeFile(path, strings.NewReader(content), mode); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	stats, err := dedupeVersions(prefix)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if stats.Files != 6 || stats.Cloned+stats.Linked != 2 {
+		t.Errorf("Expected 2 of 6 files to be shared, got %+v", stats)
+	}
+
+	a, _ := os.Stat(filepath.Join(versionsDir(prefix), "go1.21.0", "src/fmt/print.go"))
+	b, _ := os.Stat(filepath.Join(versionsDir(prefix), "go1.22.1", "src/fmt/print.go"))
+	if linked := os.SameFile(a, b); linked != (stats.Linked > 0) {
+		t.Errorf("Expected only the files not reflinked to be hardlinked, got %v with %+v", linked, stats)
+	}
+	if info, _ := os.Stat(filepath.Join(versionsDir(prefix), "go1.22.1", "bin/go")); info.Mode().Perm() != 0755 {
+		t.Errorf("Expected the mode to be kept, got %v", info.Mode())
+	}
+
+	if err := removeVersion(prefix, "go1.21.0"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var stored []string
+	filepath.WalkDir(storeDir(prefix), func(path string, d os.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() {
+			stored = append(stored, path)
+		}
+		return nil
+	})
+	// The 3 files of go1.22.1 and its index remain.
+	if len(stored) != 4 {
+		t.Errorf("Expected the files of removed versions to be pruned, got %v", stored)
+	}
+	if _, err := os.Stat(storeIndex(storeDir(prefix), "go1.21.0")); !os.IsNotExist(err) {
+		t.Errorf("Expected the index of the removed version to be pruned, got %v", err)
+	}
+}
+
+func TestDedupeVersionsRerun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the managed layout is not supported on windows")
+	}
+
+	// Copies stand for the reflinks, distinct files sharing the content.
+	defer func(clone func(string, string) error) { reflink = clone }(reflink)
+	reflink = func(src, dst string) error {
+		f, err := os.Open(src)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return writeFile(dst, f, 0644)
+	}
+
+	prefix := t.TempDir()
+	// The file is replaced as by a reinstallation, created before the
+	// previous one is removed.
+	write := func(version string) {
+		path := filepath.Join(versionsDir(prefix), version, "src/fmt/print.go")
+		if err := writeFile(path+".new", strings.NewReader("package fmt\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(path+".new", path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("go1.21.0")
+	write("go1.22.1")
+
+	if stats, err := dedupeVersions(prefix); err != nil || stats.Cloned != 1 || stats.Saved == 0 {
+		t.Fatalf("Expected one file to be reflinked, got %+v (%v)", stats, err)
+	}
+	if stats, err := dedupeVersions(prefix); err != nil || stats.Cloned != 0 || stats.Saved != 0 {
+		t.Errorf("Expected the reflinked files to be skipped by the next run, got %+v (%v)", stats, err)
+	}
+
+	// A reinstalled version has files of its own again.
+	write("go1.22.1")
+	if stats, err := dedupeVersions(prefix); err != nil || stats.Cloned != 1 {
+		t.Errorf("Expected the reinstalled file to be reflinked again, got %+v (%v)", stats, err)
+	}
+}
@@ -0,0 +1,475 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// A delta upgrades an installation from one release to the next without
+// downloading the whole archive: it rebuilds the archive of the new release
+// byte for byte, from the files of the installed release and the bytes they
+// lack. The rebuilt archive is then verified against the releases feed and
+// installed as a downloaded one. A delta is a tar.gz holding the
+// "delta.json" manifest followed by "data", the bytes the installation lacks.
+const (
+	deltaManifestName = "delta.json"
+	deltaDataName     = "data"
+)
+
+// deltaSegment is a part of the uncompressed tar of the new release: either
+// Literal bytes of the data of the delta, or the content of an installed
+// File of Size bytes.
+type deltaSegment struct {
+	Literal int64  `json:"literal,omitempty"`
+	File    string `json:"file,omitempty"`
+	Size    int64  `json:"size,omitempty"`
+	Sha256  string `json:"sha256,omitempty"`
+}
+
+// deltaGzip is how the archive of the new release was compressed, the
+// rebuilt tar is compressed the same way.
+type deltaGzip struct {
+	Level   int       `json:"level"`
+	Name    string    `json:"name,omitempty"`
+	Comment string    `json:"comment,omitempty"`
+	Extra   []byte    `json:"extra,omitempty"`
+	ModTime time.Time `json:"mtime"`
+	OS      byte      `json:"os"`
+}
+
+type deltaManifest struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Sha256 and Size are the ones of the archive of the new release.
+	Sha256   string         `json:"sha256"`
+	Size     int64          `json:"size"`
+	Gzip     deltaGzip      `json:"gzip"`
+	Segments []deltaSegment `json:"segments"`
+}
+
+// gzipLevels are the compression levels tried to reproduce an archive.
+var gzipLevels = []int{gzip.BestCompression, gzip.DefaultCompression, gzip.BestSpeed}
+
+// deltaFilename returns the name under which the delta between two releases
+// is published.
+func deltaFilename(from, to string, selection Selection) string {
+	return fmt.Sprintf("%s-%s.%s-%s.delta.tar.gz", from, to, selection.Os, selection.Arch)
+}
+
+// archiveVersion returns the installed version recorded in the VERSION file of
+// a tar entry name, or an empty string when name is not such a file.
+func archiveVersion(name string, r io.Reader) string {
+	if strings.TrimPrefix(name, "go/") != "VERSION" {
+		return ""
+	}
+	// Only the first line matters, a huge VERSION file is not buffered.
+	b, _ := io.ReadAll(io.LimitReader(r, 4096))
+	version, _, _ := strings.Cut(string(b), "\n")
+	return strings.TrimSpace(version)
+}
+
+// scanArchive returns the sha256 of every regular file of a release archive,
+// keyed by their name relative to GOROOT, along with the release version.
+func scanArchive(r io.Reader) (map[string]string, string, error) {
+	sums := map[string]string{}
+	var version string
+
+	gzr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, "", err
+	}
+	defer gzr.Close()
+
+	tr := tar.NewReader(gzr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return sums, version, nil
+		}
+		if err != nil {
+			return nil, "", err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir, tar.TypeXGlobalHeader:
+			continue
+		case tar.TypeReg:
+		default:
+			return nil, "", fmt.Errorf("unsupported entry %s in archive", header.Name)
+		}
+
+		name := strings.TrimPrefix(header.Name, "go/")
+		h := sha256.New()
+		if v := archiveVersion(header.Name, io.TeeReader(tr, h)); v != "" {
+			version = v
+		}
+		io.Copy(h, tr)
+		sums[name] = hex.EncodeToString(h.Sum(nil))
+	}
+}
+
+// deltaRecorder keeps the bytes read from r in data, except while skip is
+// set.
+type deltaRecorder struct {
+	r    io.Reader
+	data io.Writer
+	skip bool
+	// size is the count of bytes kept, pending the ones since the last
+	// segment.
+	size, pending int64
+}
+
+func (d *deltaRecorder) Read(p []byte) (int, error) {
+	n, err := d.r.Read(p)
+	if n > 0 && !d.skip {
+		if _, errWrite := d.data.Write(p[:n]); errWrite != nil {
+			return n, errWrite
+		}
+		d.size += int64(n)
+		d.pending += int64(n)
+	}
+	return n, err
+}
+
+// flush ends the literal segment of the pending bytes, if any.
+func (d *deltaRecorder) flush(manifest *deltaManifest) {
+	if d.pending > 0 {
+		manifest.Segments = append(manifest.Segments, deltaSegment{Literal: d.pending})
+		d.pending = 0
+	}
+}
+
+// generateDelta writes to w the delta rebuilding newArchive from the
+// installation of oldArchive. newArchive is read twice, it must be
+// reproducible by compress/gzip at one of gzipLevels.
+func generateDelta(w io.Writer, oldArchive io.Reader, newArchive io.ReadSeeker) (deltaManifest, error) {
+	var manifest deltaManifest
+
+	old, from, err := scanArchive(oldArchive)
+	if err != nil {
+		return manifest, err
+	}
+	sums, to, err := scanArchive(newArchive)
+	if err != nil {
+		return manifest, err
+	}
+	manifest.From, manifest.To = from, to
+	if _, err := newArchive.Seek(0, io.SeekStart); err != nil {
+		return manifest, err
+	}
+
+	archiveSum := sha256.New()
+	archive := &countingReader{r: io.TeeReader(newArchive, archiveSum)}
+	gzr, err := gzip.NewReader(archive)
+	if err != nil {
+		return manifest, err
+	}
+	defer gzr.Close()
+	gzr.Multistream(false)
+	manifest.Gzip = deltaGzip{Name: gzr.Name, Comment: gzr.Comment, Extra: gzr.Extra, ModTime: gzr.ModTime, OS: gzr.OS}
+
+	// The tar is compressed again at each level as it is read, to find the
+	// one reproducing the archive.
+	compressors := make([]*gzip.Writer, len(gzipLevels))
+	recompressed := make([]hash.Hash, len(gzipLevels))
+	writers := make([]io.Writer, len(gzipLevels))
+	for i, level := range gzipLevels {
+		recompressed[i] = sha256.New()
+		compressors[i], _ = gzip.NewWriterLevel(recompressed[i], level)
+		compressors[i].Header = gzr.Header
+		writers[i] = compressors[i]
+	}
+
+	data := &spool{limit: spoolMemory}
+	defer data.Close()
+	rec := &deltaRecorder{r: io.TeeReader(gzr, io.MultiWriter(writers...)), data: data}
+
+	tr := tar.NewReader(rec)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return manifest, err
+		}
+
+		// The content of the files left unread is kept with the next
+		// header.
+		name := strings.TrimPrefix(header.Name, "go/")
+		if header.Typeflag != tar.TypeReg || header.Size == 0 || old[name] == "" || old[name] != sums[name] {
+			continue
+		}
+		rec.flush(&manifest)
+		rec.skip = true
+		_, err = io.Copy(io.Discard, tr)
+		rec.skip = false
+		if err != nil {
+			return manifest, err
+		}
+		manifest.Segments = append(manifest.Segments, deltaSegment{File: name, Size: header.Size, Sha256: sums[name]})
+	}
+	// The blocks ending the tar.
+	if _, err := io.Copy(io.Discard, rec); err != nil {
+		return manifest, err
+	}
+	rec.flush(&manifest)
+	if _, err := io.Copy(io.Discard, archive); err != nil {
+		return manifest, err
+	}
+	manifest.Sha256, manifest.Size = hex.EncodeToString(archiveSum.Sum(nil)), archive.n
+
+	manifest.Gzip.Level = -2
+	for i, zw := range compressors {
+		if err := zw.Close(); err != nil {
+			return manifest, err
+		}
+		if hex.EncodeToString(recompressed[i].Sum(nil)) == manifest.Sha256 {
+			manifest.Gzip.Level = gzipLevels[i]
+			break
+		}
+	}
+	if manifest.Gzip.Level == -2 {
+		return manifest, errors.New("the new archive is not reproducible by compress/gzip, no delta can rebuild it")
+	}
+
+	gzw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gzw)
+	b, err := json.Marshal(manifest)
+	if err != nil {
+		return manifest, err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: deltaManifestName, Mode: 0644, Size: int64(len(b)), Typeflag: tar.TypeReg}); err != nil {
+		return manifest, err
+	}
+	if _, err := tw.Write(b); err != nil {
+		return manifest, err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: deltaDataName, Mode: 0644, Size: rec.size, Typeflag: tar.TypeReg}); err != nil {
+		return manifest, err
+	}
+	r, err := data.Reader()
+	if err != nil {
+		return manifest, err
+	}
+	if _, err := copyBuffered(tw, r); err != nil {
+		return manifest, err
+	}
+	if err := tw.Close(); err != nil {
+		return manifest, err
+	}
+	return manifest, gzw.Close()
+}
+
+// rebuildArchive writes to w the archive rebuilt by the delta from the
+// installation goroot. The archive is only as trustworthy as the delta, it
+// must be verified against the releases feed.
+func rebuildArchive(goroot string, delta io.Reader, w io.Writer) (deltaManifest, error) {
+	var manifest deltaManifest
+
+	gzr, err := gzip.NewReader(delta)
+	if err != nil {
+		return manifest, err
+	}
+	defer gzr.Close()
+
+	tr := tar.NewReader(gzr)
+	header, err := tr.Next()
+	if err != nil || header.Name != deltaManifestName {
+		return manifest, fmt.Errorf("delta has no manifest")
+	}
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return manifest, fmt.Errorf("invalid delta manifest: %w", err)
+	}
+
+	installed, err := installedVersion(goroot)
+	if err != nil {
+		return manifest, err
+	}
+	if installed != manifest.From {
+		return manifest, fmt.Errorf("delta applies to %s, installed version is %s", manifest.From, installed)
+	}
+
+	if header, err = tr.Next(); err != nil || header.Name != deltaDataName {
+		return manifest, fmt.Errorf("delta has no data")
+	}
+	zw, err := gzip.NewWriterLevel(w, manifest.Gzip.Level)
+	if err != nil {
+		return manifest, fmt.Errorf("invalid delta manifest: %w", err)
+	}
+	zw.Header = gzip.Header{Name: manifest.Gzip.Name, Comment: manifest.Gzip.Comment, Extra: manifest.Gzip.Extra, ModTime: manifest.Gzip.ModTime, OS: manifest.Gzip.OS}
+
+	for _, segment := range manifest.Segments {
+		if segment.File == "" {
+			n, err := copyBuffered(zw, io.LimitReader(tr, segment.Literal))
+			if err == nil && n != segment.Literal {
+				err = io.ErrUnexpectedEOF
+			}
+			if err != nil {
+				return manifest, fmt.Errorf("reading the data of the delta: %w", err)
+			}
+			continue
+		}
+		if err := copyInstalled(zw, goroot, segment); err != nil {
+			return manifest, err
+		}
+	}
+	return manifest, zw.Close()
+}
+
+// copyInstalled writes the installed file of segment to w, the file must
+// not have changed since its installation.
+func copyInstalled(w io.Writer, goroot string, segment deltaSegment) error {
+	if !filepath.IsLocal(segment.File) {
+		return fmt.Errorf("unexpected file %s in delta manifest", segment.File)
+	}
+	f, err := os.Open(filepath.Join(goroot, filepath.FromSlash(segment.File)))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	n, err := copyBuffered(io.MultiWriter(w, h), f)
+	if err != nil {
+		return err
+	}
+	if n != segment.Size || hex.EncodeToString(h.Sum(nil)) != segment.Sha256 {
+		return fmt.Errorf("%w for %s, modified since its installation", ErrChecksumMismatch, segment.File)
+	}
+	return nil
+}
+
+func writeFile(target string, r io.Reader, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := copyBuffered(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (c *cli) deltaGen(args []string) error {
+	fs := flag.NewFlagSet("delta-gen", flag.ContinueOnError)
+	out := fs.String("o", "", "output file (default <from>-<to>.<os>-<arch>.delta.tar.gz)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-dl delta-gen [flags] old.tar.gz new.tar.gz")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("delta-gen requires two archives")
+	}
+
+	oldArchive, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer oldArchive.Close()
+
+	newArchive, err := os.Open(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	defer newArchive.Close()
+
+	dir := filepath.Dir(*out)
+	if *out == "" {
+		dir = "."
+	}
+
+	f, err := createTemp(dir, "go-dl-tmp-*.delta.tar.gz")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	manifest, err := generateDelta(f, oldArchive, newArchive)
+	if err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	name := *out
+	if name == "" {
+		// go1.22.3.linux-amd64.tar.gz holds the platform after the version.
+		platform := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(fs.Arg(1)), manifest.To+"."), ".tar.gz")
+		name = fmt.Sprintf("%s-%s.%s.delta.tar.gz", manifest.From, manifest.To, platform)
+	}
+
+	if err := os.Rename(f.Name(), name); err != nil {
+		return err
+	}
+	info, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.stdout, "Wrote %s (%s to %s, %s of %s)\n", name, manifest.From, manifest.To, c.units.size(info.Size()), c.units.size(manifest.Size))
+	return nil
+}
+
+// fullInstallation reports whether no file of the release was left out of
+// the installation at goroot, the deltas rebuild the archive from all of them.
+func fullInstallation(goroot string) bool {
+	provenance, err := readProvenance(goroot)
+	return err != nil || provenance.Filter.empty()
+}
+
+// deltaSource returns the source of a pipeline rebuilding the archive of
+// to from the installation of from, with the delta published at delta_url.
+func (c *cli) deltaSource(from, to string) func(context.Context, io.Writer) (string, error) {
+	return func(ctx context.Context, w io.Writer) (string, error) {
+		name := deltaFilename(from, to, c.selection)
+		source := c.deltaURL + "/" + name
+
+		dir, err := c.paths.TempDir()
+		if err != nil {
+			return source, err
+		}
+		repo := &GoRepository{url: c.deltaURL, client: c.repo.client, onProgress: c.repo.onProgress, timeouts: c.repo.timeouts}
+		path, err := repo.DownloadTemp(ctx, File{Filename: name}, dir)
+		if err != nil {
+			return source, err
+		}
+		defer os.Remove(path)
+
+		f, err := os.Open(path)
+		if err != nil {
+			return source, err
+		}
+		defer f.Close()
+
+		manifest, err := rebuildArchive(c.goroot(), f, w)
+		if err == nil && manifest.To != to {
+			err = fmt.Errorf("delta upgrades to %s, want %s", manifest.To, to)
+		}
+		return source, err
+	}
+}
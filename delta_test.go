@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestDelta returns the archives of go1.22.2 and go1.22.3 and the delta
+// between both.
+func newTestDelta(t *testing.T) (oldArchive, newArchive []byte, delta []byte) {
+	oldArchive = newTestArchive(t, map[string]string{
+		"go/VERSION":    "go1.22.2\n",
+		"go/bin/go":     "go 1.22.2",
+		"go/src/fmt.go": "package fmt",
+		"go/src/old.go": "package old",
+	})
+	newArchive = newTestArchive(t, map[string]string{
+		"go/VERSION":    "go1.22.3\n",
+		"go/bin/go":     "go 1.22.3",
+		"go/src/fmt.go": "package fmt",
+		"go/src/new.go": "package new",
+	})
+
+	var buf bytes.Buffer
+	manifest, err := generateDelta(&buf, bytes.NewReader(oldArchive), bytes.NewReader(newArchive))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sum := sha256.Sum256(newArchive)
+	if manifest.From != "go1.22.2" || manifest.To != "go1.22.3" || manifest.Sha256 != hex.EncodeToString(sum[:]) || manifest.Size != int64(len(newArchive)) {
+		t.Errorf("Unexpected manifest %+v", manifest)
+	}
+	return oldArchive, newArchive, buf.Bytes()
+}
+
+func TestDeltaRoundTrip(t *testing.T) {
+	oldArchive, newArchive, delta := newTestDelta(t)
+
+	prefix := t.TempDir()
+	if err := Decompress(prefix, bytes.NewReader(oldArchive), func(ratio float64) {}); err != nil {
+		t.Fatal(err)
+	}
+
+	var rebuilt bytes.Buffer
+	manifest, err := rebuildArchive(filepath.Join(prefix, "go"), bytes.NewReader(delta), &rebuilt)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !bytes.Equal(rebuilt.Bytes(), newArchive) {
+		t.Errorf("Expected the delta to rebuild the new archive byte for byte")
+	}
+	var shared int
+	for _, s := range manifest.Segments {
+		if s.File != "" {
+			shared++
+		}
+	}
+	if shared != 1 {
+		t.Errorf("Expected the unchanged go/src/fmt.go to be taken from the installation, got %+v", manifest.Segments)
+	}
+}
+
+func TestRebuildArchiveModifiedInstallation(t *testing.T) {
+	oldArchive, _, delta := newTestDelta(t)
+
+	prefix := t.TempDir()
+	if err := Decompress(prefix, bytes.NewReader(oldArchive), func(ratio float64) {}); err != nil {
+		t.Fatal(err)
+	}
+	goroot := filepath.Join(prefix, "go")
+	if err := os.WriteFile(filepath.Join(goroot, "src", "fmt.go"), []byte("tampered"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := rebuildArchive(goroot, bytes.NewReader(delta), io.Discard); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Expected the delta to be rejected on a modified installation, got %v", err)
+	}
+}
+
+func TestPipelineDelta(t *testing.T) {
+	oldArchive, newArchive, delta := newTestDelta(t)
+	sum := sha256.Sum256(newArchive)
+	dlf := File{Filename: "go1.22.3.linux-amd64.tar.gz", Version: "go1.22.3", Sha256: hex.EncodeToString(sum[:])}
+
+	tests := []struct {
+		name   string
+		delta  []byte
+		source string
+	}{
+		{"rebuilt", delta, "https://deltas.example.com/delta"},
+		// A delta rebuilding another archive fails the checksum of the feed.
+		{"tampered", func() []byte {
+			var buf bytes.Buffer
+			_, err := generateDelta(&buf, bytes.NewReader(oldArchive), bytes.NewReader(newTestArchive(t, map[string]string{"go/VERSION": "go1.22.2\n"})))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return buf.Bytes()
+		}(), ""},
+		{"corrupted", []byte("not a delta"), ""},
+	}
+	for _, tt := range tests {
+		prefix := t.TempDir()
+		if err := Decompress(prefix, bytes.NewReader(oldArchive), func(ratio float64) {}); err != nil {
+			t.Fatal(err)
+		}
+		goroot := filepath.Join(prefix, "go")
+
+		p := newPipeline(newTestArchiveRepo(newArchive), nil, dlf, prefix, processOwner, newTestPaths(t))
+		p.delta = func(ctx context.Context, w io.Writer) (string, error) {
+			_, err := rebuildArchive(goroot, bytes.NewReader(tt.delta), w)
+			return "https://deltas.example.com/delta", err
+		}
+		if err := p.run(context.Background()); err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		if v, err := installedVersion(goroot); err != nil || v != "go1.22.3" {
+			t.Errorf("%s: expected go1.22.3 to be installed, got %s (%v)", tt.name, v, err)
+		}
+		if tt.source != "" && p.state.Source != tt.source {
+			t.Errorf("%s: expected the archive to be rebuilt by the delta, source %q", tt.name, p.state.Source)
+		}
+		if provenance, err := readProvenance(goroot); err != nil || provenance.Version != "go1.22.3" || provenance.Source != p.state.Source {
+			t.Errorf("%s: expected the provenance of go1.22.3, got %+v (%v)", tt.name, provenance, err)
+		}
+		if tt.source == "" && p.state.Source == "https://deltas.example.com/delta" {
+			t.Errorf("%s: expected the full archive to be downloaded", tt.name)
+		}
+	}
+}
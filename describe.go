@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// feedCopy is the copy of the last releases feed fetched, kept in the cache.
+type feedCopy struct {
+	URL      string          `json:"url"`
+	Fetched  time.Time       `json:"fetched"`
+	Releases json.RawMessage `json:"releases"`
+}
+
+// cacheFeed keeps a copy of the feed b in the cache.
+func (g *GoRepository) cacheFeed(b []byte) {
+	if g.feedCache == "" {
+		return
+	}
+	data, err := json.Marshal(feedCopy{URL: g.url, Fetched: g.fetched.UTC(), Releases: b})
+	if err == nil {
+		err = writeFileAtomic(g.feedCache, data, 0644)
+	}
+	if err != nil {
+		slog.Warn("unable to keep a copy of the releases feed", "path", g.feedCache, "err", err)
+	}
+}
+
+// cachedVersions returns the releases of the last feed fetched, and where
+// it was fetched from.
+func (g *GoRepository) cachedVersions() ([]Release, feedCopy, error) {
+	var feed feedCopy
+	if g.feedCache == "" {
+		return nil, feed, fmt.Errorf("no copy of the releases feed: %w", os.ErrNotExist)
+	}
+	b, err := os.ReadFile(g.feedCache)
+	if err != nil {
+		return nil, feed, fmt.Errorf("no copy of the releases feed: %w", err)
+	}
+	if err := json.Unmarshal(b, &feed); err != nil {
+		return nil, feed, fmt.Errorf("invalid copy of the releases feed %s: %w", g.feedCache, err)
+	}
+	releases, err := decodeReleases(feed.Releases)
+	return releases, feed, err
+}
+
+// releaseRecord is the description of a release printed by go-dl describe
+// --json. Its fields are only ever added to, Schema changes otherwise.
+type releaseRecord struct {
+	Schema  int          `json:"schema"`
+	Version string       `json:"version"`
+	Stable  bool         `json:"stable"`
+	Feed    string       `json:"feed"`
+	Fetched string       `json:"fetched,omitempty"`
+	Cached  bool         `json:"cached"`
+	Files   []fileRecord `json:"files"`
+}
+
+// fileRecord is a file of a releaseRecord.
+type fileRecord struct {
+	Filename  string            `json:"filename"`
+	URL       string            `json:"url"`
+	Os        string            `json:"os"`
+	Arch      string            `json:"arch"`
+	Kind      string            `json:"kind"`
+	Size      int               `json:"size"`
+	Sha256    string            `json:"sha256"`
+	Checksums map[string]string `json:"checksums,omitempty"`
+}
+
+const releaseRecordSchema = 1
+
+// describe prints everything the feed publishes about a release.
+func (c *cli) describe(args []string) error {
+	fs := flag.NewFlagSet("describe", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the release record as JSON")
+	offline := fs.Bool("offline", false, "only use the copy of the last releases feed fetched")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-dl describe [--json] [--offline] <version constraint>")
+		fs.PrintDefaults()
+	}
+	if err := parseInterspersed(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("describe expects a single version")
+	}
+
+	record := releaseRecord{Schema: releaseRecordSchema, Feed: c.repo.url}
+	var releases []Release
+	var err error
+	if !*offline {
+		releases, err = c.repo.GetVersions(c.ctx)
+		if !c.repo.fetched.IsZero() {
+			record.Fetched = c.repo.fetched.UTC().Format(time.RFC3339)
+		}
+	}
+	if *offline || err != nil {
+		cached, feed, errCache := c.repo.cachedVersions()
+		if errCache != nil {
+			return errors.Join(err, errCache)
+		}
+		if err != nil {
+			slog.Warn("unable to fetch the releases, describing the last ones fetched", "err", err, "fetched", feed.Fetched)
+		}
+		releases, err = cached, nil
+		record.Feed, record.Fetched, record.Cached = feed.URL, feed.Fetched.Format(time.RFC3339), true
+	}
+
+	release, err := resolveRelease(releases, c.resolveAlias(fs.Arg(0)))
+	if err != nil {
+		return err
+	}
+	record.Version, record.Stable = release.Version, release.Stable
+	record.Files = []fileRecord{}
+	for _, f := range release.Files {
+		record.Files = append(record.Files, fileRecord{
+			Filename:  f.Filename,
+			URL:       record.Feed + "/" + f.Filename,
+			Os:        f.Os,
+			Arch:      f.Arch,
+			Kind:      f.Kind,
+			Size:      f.Size,
+			Sha256:    f.Sha256,
+			Checksums: f.Checksums,
+		})
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(c.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(record)
+	}
+
+	status := "unstable"
+	if record.Stable {
+		status = "stable"
+	}
+	fmt.Fprintf(c.stdout, "%s (%s), from %s", record.Version, status, record.Feed)
+	if record.Cached {
+		fmt.Fprintf(c.stdout, ", copy fetched %s", record.Fetched)
+	}
+	fmt.Fprintln(c.stdout)
+	w := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	for _, f := range release.Files {
+		platform := "-"
+		if f.Os != "" {
+			platform = f.Os + "/" + f.Arch
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", f.Filename, platform, f.Kind, c.units.size(int64(f.Size)), f.Checksum())
+	}
+	return w.Flush()
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDescribe(t *testing.T) {
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n"})
+	c, out := newTestCLI(t, archive)
+	c.repo.feedCache = filepath.Join(t.TempDir(), "releases.json")
+
+	// The flags may follow the version.
+	if err := c.run([]string{"describe", "go1.22.1", "--json"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var record releaseRecord
+	if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+		t.Fatalf("Expected a JSON record, got %v: %s", err, out.String())
+	}
+	if record.Schema != 1 || record.Version != "go1.22.1" || !record.Stable || record.Cached || record.Fetched == "" {
+		t.Errorf("Unexpected record %+v", record)
+	}
+	if len(record.Files) != 1 || record.Files[0].URL != "https://go.dev/dl/go1.22.1.linux-amd64.tar.gz" || len(record.Files[0].Sha256) != 64 {
+		t.Errorf("Expected the archive with its url and checksum, got %+v", record.Files)
+	}
+
+	// Without go.dev, the copy of the last feed is described.
+	c.repo.client = NewTestClient(func(*http.Request) *http.Response {
+		return &http.Response{StatusCode: http.StatusBadGateway, Body: http.NoBody}
+	})
+	for _, args := range [][]string{{"describe", "--json", "1.22"}, {"describe", "--json", "--offline", "1.22"}} {
+		out.Reset()
+		if err := c.run(args); err != nil {
+			t.Fatalf("Unexpected error for %v: %v", args, err)
+		}
+		var cached releaseRecord
+		if err := json.Unmarshal(out.Bytes(), &cached); err != nil {
+			t.Fatal(err)
+		}
+		if !cached.Cached || cached.Feed != "https://go.dev/dl" || !strings.HasPrefix(cached.Fetched, record.Fetched[:10]) || len(cached.Files) != 1 {
+			t.Errorf("Expected the cached record for %v, got %+v", args, cached)
+		}
+	}
+
+	out.Reset()
+	if err := c.run([]string{"describe", "--offline", "1.22"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "go1.22.1.linux-amd64.tar.gz  linux/amd64  archive") {
+		t.Errorf("Expected the files to be listed, got %q", out.String())
+	}
+}
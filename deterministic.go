@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// determinism holds the settings of --deterministic, for the image builds
+// expecting the same layers from the same inputs.
+type determinism struct {
+	enabled bool
+	// epoch is the time of the installed files and of their provenance.
+	epoch time.Time
+	// temps numbers the temporary files in their order of creation.
+	temps atomic.Int64
+}
+
+// reproducible is set from --deterministic, off by default.
+var reproducible determinism
+
+// enableDeterminism turns the deterministic mode on, with the time set by
+// SOURCE_DATE_EPOCH, the Unix epoch when unset.
+func enableDeterminism(getenv func(string) string) error {
+	epoch, err := sourceDateEpoch(getenv)
+	if err != nil {
+		return err
+	}
+	reproducible.enabled, reproducible.epoch = true, epoch
+	reproducible.temps.Store(0)
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{ReplaceAttr: dropTime})))
+	return nil
+}
+
+// dropTime removes the time of the log records, so two runs log the same
+// lines.
+func dropTime(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.TimeKey && len(groups) == 0 {
+		return slog.Attr{}
+	}
+	return a
+}
+
+// now returns the current time, or the epoch in deterministic mode.
+func (d *determinism) now() time.Time {
+	if d.enabled {
+		return d.epoch
+	}
+	return time.Now()
+}
+
+// createTemp is os.CreateTemp, with the random part of the name replaced in
+// deterministic mode by the count of temporary files created so far.
+func createTemp(dir, pattern string) (*os.File, error) {
+	if !reproducible.enabled {
+		return os.CreateTemp(dir, pattern)
+	}
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	prefix, suffix, _ := strings.Cut(pattern, "*")
+	for {
+		name := filepath.Join(dir, prefix+strconv.FormatInt(reproducible.temps.Add(1), 10)+suffix)
+		f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+		if !errors.Is(err, fs.ErrExist) {
+			return f, err
+		}
+	}
+}
+
+// mkdirTemp is os.MkdirTemp, named like createTemp names the files.
+func mkdirTemp(dir, pattern string) (string, error) {
+	if !reproducible.enabled {
+		return os.MkdirTemp(dir, pattern)
+	}
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	prefix, suffix, _ := strings.Cut(pattern, "*")
+	for {
+		name := filepath.Join(dir, prefix+strconv.FormatInt(reproducible.temps.Add(1), 10)+suffix)
+		err := os.Mkdir(name, 0700)
+		if !errors.Is(err, fs.ErrExist) {
+			return name, err
+		}
+	}
+}
+
+// normalizeTimes sets the access and modification times of the files under
+// root, root included, to t. The symbolic links are left as they are.
+func normalizeTimes(root string, t time.Time) error {
+	root, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return err
+	}
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.Type()&fs.ModeSymlink != 0 {
+			return err
+		}
+		return os.Chtimes(path, t, t)
+	})
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// withDeterminism turns the deterministic mode on for the test, with epoch
+// as SOURCE_DATE_EPOCH.
+func withDeterminism(t *testing.T, epoch string) {
+	logger := slog.Default()
+	t.Cleanup(func() {
+		reproducible.enabled, reproducible.epoch = false, time.Time{}
+		slog.SetDefault(logger)
+	})
+	getenv := func(string) string { return epoch }
+	if err := enableDeterminism(getenv); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateTempDeterministic(t *testing.T) {
+	withDeterminism(t, "")
+	dir := t.TempDir()
+
+	var names []string
+	for range 2 {
+		f, err := createTemp(dir, "go-dl-tmp-*.tar.gz")
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+		names = append(names, filepath.Base(f.Name()))
+	}
+	if names[0] != "go-dl-tmp-1.tar.gz" || names[1] != "go-dl-tmp-2.tar.gz" {
+		t.Errorf("Expected numbered temporary files, got %v", names)
+	}
+
+	// A leftover of another run is skipped, not reused.
+	reproducible.temps.Store(0)
+	f, err := createTemp(dir, "go-dl-tmp-*.tar.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if got := filepath.Base(f.Name()); got != "go-dl-tmp-3.tar.gz" {
+		t.Errorf("Expected the existing names skipped, got %s", got)
+	}
+
+	if err := enableDeterminism(func(string) string { return "soon" }); err == nil {
+		t.Errorf("Expected an error for an invalid SOURCE_DATE_EPOCH")
+	}
+}
+
+func TestPipelineDeterministic(t *testing.T) {
+	withDeterminism(t, "1700000000")
+	epoch := time.Unix(1700000000, 0)
+
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n", "go/bin/go": "#!/bin/sh\n"})
+	sum := sha256.Sum256(archive)
+	dlf := File{Filename: "go1.22.1.linux-amd64.tar.gz", Version: "go1.22.1", Sha256: hex.EncodeToString(sum[:])}
+	prefix := t.TempDir()
+
+	repo := newTestArchiveRepo(archive)
+	repo.fetched = time.Now()
+	if err := newPipeline(repo, nil, dlf, prefix, processOwner, newTestPaths(t)).run(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	goroot := filepath.Join(prefix, "go")
+	err := filepath.WalkDir(goroot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !info.ModTime().Equal(epoch) {
+			t.Errorf("Expected %s to have the time of SOURCE_DATE_EPOCH, got %v", path, info.ModTime())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	provenance, err := readProvenance(goroot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if provenance.InstalledAt != "2023-11-14T22:13:20Z" {
+		t.Errorf("Expected the installation time of SOURCE_DATE_EPOCH, got %s", provenance.InstalledAt)
+	}
+	if provenance.Feed != "" || provenance.Source != "" || provenance.Cached {
+		t.Errorf("Expected no source nor feed time in the provenance, got %+v", provenance)
+	}
+	if _, err := os.Stat(filepath.Join(goroot, "bin", "go")); err != nil {
+		t.Errorf("Expected the files extracted: %v", err)
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/blckfalcon/go-dl/tui"
+)
+
+// manifestDiff lists the files added, removed and changed between two
+// release archives, relative to GOROOT.
+type manifestDiff struct {
+	Added   []string
+	Removed []string
+	Changed []string
+}
+
+func diffManifests(from, to map[string]string) manifestDiff {
+	var d manifestDiff
+	for name, sum := range to {
+		switch old, ok := from[name]; {
+		case !ok:
+			d.Added = append(d.Added, name)
+		case old != sum:
+			d.Changed = append(d.Changed, name)
+		}
+	}
+	for name := range from {
+		if _, ok := to[name]; !ok {
+			d.Removed = append(d.Removed, name)
+		}
+	}
+	sort.Strings(d.Added)
+	sort.Strings(d.Removed)
+	sort.Strings(d.Changed)
+	return d
+}
+
+// stdPackages returns the import paths of the standard library packages of a
+// release manifest.
+func stdPackages(manifest map[string]string) map[string]bool {
+	pkgs := map[string]bool{}
+	for name := range manifest {
+		if pkg, ok := stdPackage(name); ok {
+			pkgs[pkg] = true
+		}
+	}
+	return pkgs
+}
+
+func stdPackage(name string) (string, bool) {
+	rel, ok := strings.CutPrefix(name, "src/")
+	if !ok || !strings.HasSuffix(rel, ".go") || strings.HasSuffix(rel, "_test.go") {
+		return "", false
+	}
+	pkg := path.Dir(rel)
+	if pkg == "." || pkg == "cmd" || strings.HasPrefix(pkg, "cmd/") || strings.Contains(pkg, "testdata") || strings.HasPrefix(pkg, "vendor/") {
+		return "", false
+	}
+	return pkg, true
+}
+
+func isTool(name string) bool {
+	return strings.HasPrefix(name, "bin/") || strings.HasPrefix(name, "pkg/tool/")
+}
+
+// formatDiff describes the changes of tooling, standard library packages and
+// files between the releases from and to.
+func formatDiff(fromVersion, toVersion string, from, to map[string]string) string {
+	d := diffManifests(from, to)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s → %s: %d files added, %d removed, %d changed\n", fromVersion, toVersion, len(d.Added), len(d.Removed), len(d.Changed))
+
+	b.WriteString("\nTooling:\n")
+	tools := 0
+	for _, group := range []struct {
+		mark  string
+		names []string
+	}{{"+", d.Added}, {"-", d.Removed}, {"~", d.Changed}} {
+		for _, name := range group.names {
+			if isTool(name) {
+				fmt.Fprintf(&b, "  %s %s\n", group.mark, name)
+				tools++
+			}
+		}
+	}
+	if tools == 0 {
+		b.WriteString("  no changes\n")
+	}
+
+	oldPkgs, newPkgs := stdPackages(from), stdPackages(to)
+	changedPkgs := map[string]int{}
+	for _, name := range append(append(append([]string{}, d.Added...), d.Removed...), d.Changed...) {
+		if pkg, ok := stdPackage(name); ok && oldPkgs[pkg] && newPkgs[pkg] {
+			changedPkgs[pkg]++
+		}
+	}
+
+	b.WriteString("\nStandard library:\n")
+	lines := []string{}
+	for pkg := range newPkgs {
+		if !oldPkgs[pkg] {
+			lines = append(lines, fmt.Sprintf("  + %s", pkg))
+		}
+	}
+	for pkg := range oldPkgs {
+		if !newPkgs[pkg] {
+			lines = append(lines, fmt.Sprintf("  - %s", pkg))
+		}
+	}
+	for pkg, n := range changedPkgs {
+		unit := "files"
+		if n == 1 {
+			unit = "file"
+		}
+		lines = append(lines, fmt.Sprintf("  ~ %s (%d %s changed)", pkg, n, unit))
+	}
+	sort.Slice(lines, func(i, j int) bool { return lines[i][4:] < lines[j][4:] })
+	if len(lines) == 0 {
+		lines = append(lines, "  no changes")
+	}
+	b.WriteString(strings.Join(lines, "\n") + "\n")
+
+	b.WriteString("\nFiles:\n")
+	for _, group := range []struct {
+		mark  string
+		names []string
+	}{{"+", d.Added}, {"-", d.Removed}, {"~", d.Changed}} {
+		for _, name := range group.names {
+			fmt.Fprintf(&b, "  %s %s\n", group.mark, name)
+		}
+	}
+	return b.String()
+}
+
+func (c *cli) diff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	plain := fs.Bool("plain", false, "print the diff instead of opening it in a scrollable pane")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-dl diff [--plain] <version> <version>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return errors.New("diff expects two versions")
+	}
+
+	releases, err := c.repo.GetVersions(c.ctx)
+	if err != nil {
+		return err
+	}
+
+	var manifests [2]map[string]string
+	var names [2]string
+	for i, query := range fs.Args() {
+		release, err := resolveRelease(releases, query)
+		if err != nil {
+			return err
+		}
+		dlf, ok := c.selection.Pick(release.Files)
+		if !ok || !isExtractable(dlf) {
+			return fmt.Errorf("%w for %s on %s/%s", ErrNoMatchingFile, release.Version, c.selection.Os, c.selection.Arch)
+		}
+
+		archive, err := c.openArchive(dlf)
+		if err != nil {
+			return err
+		}
+		manifests[i], _, err = scanArchive(archive)
+		archive.Close()
+		if err != nil {
+			return fmt.Errorf("unable to read %s: %w", dlf.Filename, err)
+		}
+		names[i] = release.Version
+	}
+
+	report := formatDiff(names[0], names[1], manifests[0], manifests[1])
+
+	if f, ok := c.stdout.(*os.File); *plain || !ok || !isTerminal(f) {
+		fmt.Fprint(c.stdout, report)
+		return nil
+	}
+
+	_, err = tea.NewProgram(tui.NewPager(fmt.Sprintf("%s → %s", names[0], names[1]), report, c.keys), tea.WithAltScreen()).Run()
+	return err
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFormatDiff(t *testing.T) {
+	from := map[string]string{
+		"VERSION":                          "a",
+		"bin/go":                           "a",
+		"pkg/tool/linux_amd64/vet":         "a",
+		"src/net/http/server.go":           "a",
+		"src/net/http/server_test.go":      "a",
+		"src/go/types/api.go":              "a",
+		"src/old/pkg/removed.go":           "a",
+		"src/cmd/go/internal/work/exec.go": "a",
+	}
+	to := map[string]string{
+		"VERSION":                          "b",
+		"bin/go":                           "b",
+		"src/net/http/server.go":           "b",
+		"src/net/http/server_test.go":      "b",
+		"src/go/types/api.go":              "a",
+		"src/iter/iter.go":                 "b",
+		"src/cmd/go/internal/work/exec.go": "b",
+	}
+
+	d := diffManifests(from, to)
+	if want := []string{"src/iter/iter.go"}; !reflect.DeepEqual(d.Added, want) {
+		t.Errorf("Expected %v to be added, got %v", want, d.Added)
+	}
+	if want := []string{"pkg/tool/linux_amd64/vet", "src/old/pkg/removed.go"}; !reflect.DeepEqual(d.Removed, want) {
+		t.Errorf("Expected %v to be removed, got %v", want, d.Removed)
+	}
+
+	report := formatDiff("go1.22.0", "go1.23.0", from, to)
+	for _, want := range []string{
+		"go1.22.0 → go1.23.0: 1 files added, 2 removed, 5 changed",
+		"Tooling:\n  - pkg/tool/linux_amd64/vet\n  ~ bin/go\n",
+		"Standard library:\n  + iter\n  ~ net/http (1 file changed)\n  - old/pkg\n",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("Expected the report to contain %q, got:\n%s", want, report)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/blckfalcon/go-dl/versions"
+)
+
+// direnvStdlib defines "use go" for the direnvrc, so an .envrc only needs
+// "use go" or "use go 1.21".
+const direnvStdlib = `use_go() {
+  eval "$(go-dl direnv "$@")"
+}
+`
+
+// localToolchain returns the GOROOT of the newest installed version
+// matching query, without reaching the network.
+func (c *cli) localToolchain(query string) (string, bool) {
+	if goroot, ok := c.toolchain(versions.Normalize(query)); ok {
+		return goroot, true
+	}
+
+	constraint, err := versions.ParseConstraint(query)
+	if err != nil {
+		return "", false
+	}
+
+	v, ok := versions.Latest(c.installedVersions(), constraint)
+	if !ok {
+		return "", false
+	}
+	return c.toolchain(v)
+}
+
+// resolveToolchain returns the GOROOT of the version matching query, an
+// installed one when allowed by the policy, else one installed for it.
+func (c *cli) resolveToolchain(query string) (string, error) {
+	goroot, ok := c.localToolchain(query)
+	if !ok {
+		return c.installToolchain(query)
+	}
+	version, err := installedVersion(goroot)
+	if err != nil {
+		return "", err
+	}
+	if err := c.allow(version); err != nil {
+		return "", err
+	}
+	return goroot, nil
+}
+
+// installedVersions returns the candidate versions of the prefix, its
+// managed layout and the toolchains of go-dl run, c.toolchain confirms they
+// are installed.
+func (c *cli) installedVersions() []string {
+	var installed []string
+	for _, dir := range []string{versionsDir(c.prefix), c.toolchainsDir()} {
+		entries, _ := os.ReadDir(dir)
+		for _, e := range entries {
+			installed = append(installed, e.Name())
+		}
+	}
+	if v, err := installedVersion(c.goroot()); err == nil {
+		installed = append(installed, v)
+	}
+	return installed
+}
+
+// direnv prints the .envrc lines activating the version pinned by the
+// nearest .go-version, or given as argument.
+func (c *cli) direnv(args []string) error {
+	fs := flag.NewFlagSet("direnv", flag.ContinueOnError)
+	stdlib := fs.Bool("stdlib", false, "print the use_go function to add to the direnvrc instead")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-dl direnv [--stdlib] [version constraint]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *stdlib {
+		fmt.Fprint(c.stdout, direnvStdlib)
+		return nil
+	}
+
+	var watch, query string
+	if fs.NArg() > 0 {
+		query = fs.Arg(0)
+	} else {
+		wd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		if watch, query, err = findGoVersionFile(wd); err != nil {
+			return err
+		}
+	}
+
+	query = c.resolveAlias(query)
+	goroot, err := c.resolveToolchain(query)
+	if err != nil {
+		return err
+	}
+
+	if watch != "" {
+		fmt.Fprintf(c.stdout, "watch_file %s\n", shellQuote(watch))
+	}
+	fmt.Fprintf(c.stdout, "export GOROOT=%s\n", shellQuote(goroot))
+	fmt.Fprintln(c.stdout, "export GOTOOLCHAIN=local")
+	fmt.Fprintf(c.stdout, "PATH_add %s\n", shellQuote(filepath.Join(goroot, "bin")))
+	return nil
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDirenv(t *testing.T) {
+	c, out := newTestCLI(t, nil)
+	c.prefix = t.TempDir()
+	for _, v := range []string{"go1.21.3", "go1.21.10", "go1.22.1"} {
+		dir := filepath.Join(versionsDir(c.prefix), v)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "VERSION"), []byte(v+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The feed is not needed when a matching version is installed.
+	c.repo = nil
+	if err := c.direnv([]string{"1.21"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	goroot := filepath.Join(versionsDir(c.prefix), "go1.21.10")
+	want := "export GOROOT=" + shellQuote(goroot) + "\nexport GOTOOLCHAIN=local\nPATH_add " + shellQuote(filepath.Join(goroot, "bin")) + "\n"
+	if out.String() != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, out.String())
+	}
+
+	out.Reset()
+	c.aliases = map[string]string{"legacy": "~1.21.3"}
+	if err := c.direnv([]string{"legacy"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if out.String() != want {
+		t.Errorf("Expected the alias to resolve to go1.21.10, got\n%s", out.String())
+	}
+
+	out.Reset()
+	c.policy = Policy{MinimumVersion: "go1.22.0"}
+	if err := c.direnv([]string{"1.21"}); !errors.Is(err, ErrPolicy) {
+		t.Errorf("Expected ErrPolicy for an installed version older than the minimum, got %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("Expected no environment for a blocked version, got\n%s", out.String())
+	}
+	c.policy = Policy{}
+
+	out.Reset()
+	if err := c.direnv([]string{"--stdlib"}); err != nil || !strings.Contains(out.String(), "use_go()") {
+		t.Errorf("Expected the use_go function, got %q (%v)", out.String(), err)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	if got := shellQuote("/opt/it's go"); got != `'/opt/it'\''s go'` {
+		t.Errorf("Unexpected quoting %s", got)
+	}
+}
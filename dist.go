@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// defaultDist is the name of the distribution of go.dev.
+const defaultDist = "go"
+
+// distName matches the names of the distributions, such as msgo.
+var distName = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// Distribution is a build of Go other than the one of go.dev, such as a fork
+// with FIPS validated cryptography, publishing its releases with a feed like
+// the one of go.dev.
+type Distribution struct {
+	// Feed is the base URL of the releases feed, served at <feed>/?mode=json
+	// as on go.dev, and of their files.
+	Feed string `json:"feed"`
+	// Manifest, when set, is the file listing the releases instead of the
+	// feed, as with --manifest.
+	Manifest string `json:"manifest,omitempty"`
+	// RequireChecksum rejects the files without a published checksum.
+	RequireChecksum bool `json:"require_checksum,omitempty"`
+	// StrictArchive applies --strict-archive to the archives.
+	StrictArchive bool `json:"strict_archive,omitempty"`
+}
+
+// distLabel returns the name of the distribution dist, as given to --dist.
+func distLabel(dist string) string {
+	if dist == "" {
+		return defaultDist
+	}
+	return dist
+}
+
+// installedDist returns the distribution the installation at goroot comes
+// from, empty for go.dev or when it has no provenance.
+func installedDist(goroot string) string {
+	provenance, _ := readProvenance(goroot)
+	return provenance.Dist
+}
+
+// checkDist reports why d can't be the distribution name.
+func checkDist(name string, d Distribution) error {
+	switch {
+	case !distName.MatchString(name):
+		return fmt.Errorf("invalid distribution name %q, expected a lowercase letter followed by lowercase letters, digits or '-'", name)
+	case name == defaultDist:
+		return fmt.Errorf("%q is the distribution of go.dev and can't be redefined", name)
+	}
+	if u, err := url.Parse(d.Feed); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("invalid feed %q of the distribution %s", d.Feed, name)
+	}
+	return nil
+}
+
+// lookupDist returns the distribution name among dists, false for the one of
+// go.dev.
+func lookupDist(dists map[string]Distribution, name string) (Distribution, bool, error) {
+	if name == "" || name == defaultDist {
+		return Distribution{}, false, nil
+	}
+	d, ok := dists[name]
+	if !ok {
+		known := []string{defaultDist}
+		for n := range dists {
+			known = append(known, n)
+		}
+		sort.Strings(known[1:])
+		return d, false, fmt.Errorf("unknown distribution %q, expected one of %s", name, strings.Join(known, ", "))
+	}
+	return d, true, nil
+}
+
+// distStorage returns the cache configuration of the distribution name: its
+// archives are kept apart from the ones of go.dev, which may have the same
+// names.
+func distStorage(name string, config StorageConfig, cacheDir string) StorageConfig {
+	switch config.Type {
+	case "", "disk":
+		if config.Path == "" {
+			config.Path = filepath.Join(cacheDir, "archives-"+name)
+		} else {
+			config.Path = filepath.Join(config.Path, name)
+		}
+	default:
+		config.Prefix += name + "/"
+	}
+	return config
+}
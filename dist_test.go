@@ -0,0 +1,124 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLookupDist(t *testing.T) {
+	dists := map[string]Distribution{
+		"msgo": {Feed: "https://dists.example.com/msgo", RequireChecksum: true},
+		"fips": {Feed: "https://dists.example.com/fips"},
+	}
+
+	for _, name := range []string{"", "go"} {
+		if _, forked, err := lookupDist(dists, name); err != nil || forked {
+			t.Errorf("Expected %q to be go.dev, got %v (%v)", name, forked, err)
+		}
+	}
+	d, forked, err := lookupDist(dists, "msgo")
+	if err != nil || !forked || !reflect.DeepEqual(d, dists["msgo"]) {
+		t.Errorf("Expected the msgo distribution, got %+v %v (%v)", d, forked, err)
+	}
+	_, _, err = lookupDist(dists, "other")
+	if err == nil || !strings.Contains(err.Error(), "expected one of go, fips, msgo") {
+		t.Errorf("Expected the known distributions listed, got %v", err)
+	}
+}
+
+func TestCheckDist(t *testing.T) {
+	for name, d := range map[string]Distribution{
+		"go":   {Feed: "https://go.example.com"},
+		"MsGo": {Feed: "https://dists.example.com"},
+		"msgo": {Feed: "dists.example.com/msgo"},
+		"fips": {},
+	} {
+		if err := checkDist(name, d); err == nil {
+			t.Errorf("Expected the distribution %s %+v to be invalid", name, d)
+		}
+	}
+	if err := checkDist("golang-fips", Distribution{Feed: "https://dists.example.com/fips", StrictArchive: true}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestDistStorage(t *testing.T) {
+	cache := filepath.Join("home", "cache")
+	tests := []struct {
+		config StorageConfig
+		want   StorageConfig
+	}{
+		{StorageConfig{}, StorageConfig{Path: filepath.Join(cache, "archives-msgo")}},
+		{StorageConfig{Type: "disk", Path: "/srv/go"}, StorageConfig{Type: "disk", Path: filepath.Join("/srv/go", "msgo")}},
+		{StorageConfig{Type: "s3", Bucket: "b", Prefix: "toolchains/"}, StorageConfig{Type: "s3", Bucket: "b", Prefix: "toolchains/msgo/"}},
+	}
+	for _, tt := range tests {
+		if got := distStorage("msgo", tt.config, cache); got != tt.want {
+			t.Errorf("Expected %+v, got %+v", tt.want, got)
+		}
+	}
+}
+
+func TestLoadConfigDistributions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	write := func(s string) {
+		if err := os.WriteFile(path, []byte(s), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(`{"dist": "msgo", "distributions": {"msgo": {"feed": "https://dists.example.com/msgo", "require_checksum": true}}}`)
+	config, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.Dist != "msgo" || !config.Distributions["msgo"].RequireChecksum {
+		t.Errorf("Unexpected distributions %+v", config)
+	}
+
+	write(`{"dist": "fips"}`)
+	if _, err := loadConfig(path); err == nil {
+		t.Errorf("Expected an error for an unknown default distribution")
+	}
+	write(`{"distributions": {"go": {"feed": "https://go.example.com"}}}`)
+	if _, err := loadConfig(path); err == nil {
+		t.Errorf("Expected an error when redefining go.dev")
+	}
+}
+
+func TestInstallDist(t *testing.T) {
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n"})
+	c, out := newTestCLI(t, archive)
+	c.prefix = t.TempDir()
+
+	if err := c.run([]string{"install", "1.22.1"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The same version of another distribution is installed over it.
+	out.Reset()
+	c.repo.dist = "fips"
+	if err := c.run([]string{"install", "1.22.1"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Contains(out.String(), "already installed") {
+		t.Errorf("Expected the fips build to replace the go.dev one, got %q", out.String())
+	}
+	if dist := installedDist(c.goroot()); dist != "fips" {
+		t.Errorf("Expected the distribution recorded in the provenance, got %q", dist)
+	}
+
+	out.Reset()
+	if err := c.run([]string{"install", "1.22.1"}); err != nil || !strings.Contains(out.String(), "already installed") {
+		t.Errorf("Expected the fips build to be installed already, got %q (%v)", out.String(), err)
+	}
+
+	// The files are only checked against the archives of the distribution.
+	c.repo.dist = ""
+	if err := c.run([]string{"check", "1.22.1"}); err == nil || !strings.Contains(err.Error(), "--dist fips") {
+		t.Errorf("Expected check to ask for the distribution, got %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// drift is an environment variable which does not point to the active
+// installation, with the command fixing it in the current shell.
+type drift struct {
+	problem string
+	fix     string
+}
+
+// environmentDrift reports where GOROOT and PATH, as read by getenv, disagree
+// with the active installation at goroot.
+func environmentDrift(goroot string, getenv func(string) string) []drift {
+	var drifts []drift
+
+	if env := getenv("GOROOT"); env != "" && !sameGoroot(env, goroot) {
+		drifts = append(drifts, drift{
+			problem: fmt.Sprintf("GOROOT is set to %s instead of %s", env, goroot),
+			fix:     unsetCommand("GOROOT"),
+		})
+	}
+
+	bin := filepath.Join(goroot, "bin")
+	found := lookGo(getenv("PATH"))
+	switch {
+	case found == "":
+		drifts = append(drifts, drift{
+			problem: fmt.Sprintf("go is not found in PATH, %s is missing", bin),
+			fix:     prependPathCommand(bin),
+		})
+	case !samePath(found, filepath.Join(bin, goExecutable())):
+		drifts = append(drifts, drift{
+			problem: fmt.Sprintf("PATH finds %s before %s", found, bin),
+			fix:     prependPathCommand(bin),
+		})
+	}
+	return drifts
+}
+
+// sameGoroot reports whether GOROOT set to env follows goroot. A GOROOT set
+// to one of the managed versions is stale as soon as another one is used,
+// even when that version is active.
+func sameGoroot(env, goroot string) bool {
+	if filepath.Clean(env) == filepath.Clean(goroot) {
+		return true
+	}
+	if rel, err := filepath.Rel(versionsDir(filepath.Dir(goroot)), env); err == nil && !strings.HasPrefix(rel, "..") {
+		return false
+	}
+	return samePath(env, goroot)
+}
+
+// samePath reports whether both paths resolve to the same file.
+func samePath(a, b string) bool {
+	ra, errA := filepath.EvalSymlinks(a)
+	rb, errB := filepath.EvalSymlinks(b)
+	return errA == nil && errB == nil && ra == rb
+}
+
+// lookGo returns the go command found first in path, like the shell would.
+func lookGo(path string) string {
+	for _, dir := range filepath.SplitList(path) {
+		if dir == "" {
+			continue
+		}
+		name := filepath.Join(dir, goExecutable())
+		info, err := os.Stat(name)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if runtime.GOOS != "windows" && info.Mode()&0111 == 0 {
+			continue
+		}
+		return name
+	}
+	return ""
+}
+
+func goExecutable() string {
+	if runtime.GOOS == "windows" {
+		return "go.exe"
+	}
+	return "go"
+}
+
+func unsetCommand(name string) string {
+	if runtime.GOOS == "windows" {
+		return fmt.Sprintf("Remove-Item Env:%s", name)
+	}
+	return "unset " + name
+}
+
+func prependPathCommand(dir string) string {
+	if runtime.GOOS == "windows" {
+		return fmt.Sprintf(`$env:Path = "%s;" + $env:Path`, dir)
+	}
+	return fmt.Sprintf(`export PATH="%s:$PATH"`, dir)
+}
+
+// printDrift explains each drift and how to fix it, and reports whether
+// there was any.
+func (c *cli) printDrift() bool {
+	drifts := environmentDrift(c.goroot(), os.Getenv)
+	for _, d := range drifts {
+		fmt.Fprintf(c.stdout, "%s, run: %s\n", d.problem, d.fix)
+	}
+	if len(drifts) > 0 {
+		fmt.Fprintln(c.stdout, "Remove the other Go settings from your shell profile to make it permanent.")
+	}
+	return len(drifts) > 0
+}
+
+// doctor checks that the environment uses the active installation.
+func (c *cli) doctor(args []string) error {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-dl doctor")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if c.printDrift() {
+		return fmt.Errorf("the environment does not use %s", c.goroot())
+	}
+	fmt.Fprintf(c.stdout, "GOROOT and PATH use %s\n", c.goroot())
+	return nil
+}
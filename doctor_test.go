@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestGoBin(t *testing.T, dir string) {
+	t.Helper()
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, goExecutable()), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEnvironmentDrift(t *testing.T) {
+	prefix := t.TempDir()
+	goroot := filepath.Join(prefix, "go")
+	active := filepath.Join(versionsDir(prefix), "go1.22.1")
+	newTestGoBin(t, filepath.Join(active, "bin"))
+	if err := linkGoroot(goroot, active); err != nil {
+		t.Fatal(err)
+	}
+
+	other := filepath.Join(t.TempDir(), "bin")
+	newTestGoBin(t, other)
+	bin := filepath.Join(goroot, "bin")
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		want []string
+	}{
+		{"clean", map[string]string{"PATH": bin}, nil},
+		{"resolved bin", map[string]string{"PATH": filepath.Join(active, "bin")}, nil},
+		{"other go first", map[string]string{"PATH": other + string(filepath.ListSeparator) + bin}, []string{"PATH finds"}},
+		{"no go", map[string]string{"PATH": t.TempDir()}, []string{"not found in PATH"}},
+		{"stale GOROOT", map[string]string{"PATH": bin, "GOROOT": active}, []string{"GOROOT is set"}},
+		{"GOROOT", map[string]string{"PATH": bin, "GOROOT": goroot}, nil},
+	}
+
+	for _, tt := range tests {
+		drifts := environmentDrift(goroot, func(name string) string { return tt.env[name] })
+		if len(drifts) != len(tt.want) {
+			t.Errorf("%s: expected %d drifts, got %v", tt.name, len(tt.want), drifts)
+			continue
+		}
+		for i, d := range drifts {
+			if !strings.Contains(d.problem, tt.want[i]) || d.fix == "" {
+				t.Errorf("%s: expected %q with a fix, got %+v", tt.name, tt.want[i], d)
+			}
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// DNS record types of the answers used by go-dl.
+const (
+	dnsTypeA    = 1
+	dnsTypeAAAA = 28
+)
+
+// dohResolver resolves host names over HTTPS with the JSON API offered by
+// public resolvers such as https://1.1.1.1/dns-query.
+type dohResolver struct {
+	url    string
+	client *http.Client
+}
+
+func newDoHResolver(rawURL string) (*dohResolver, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("invalid DNS over HTTPS resolver %q, expected an https URL", rawURL)
+	}
+	// The resolver is reached with the system DNS, an IP address in the
+	// URL avoids depending on it.
+	return &dohResolver{url: rawURL, client: &http.Client{Timeout: 10 * time.Second}}, nil
+}
+
+type dohResponse struct {
+	Status int `json:"Status"`
+	Answer []struct {
+		Type int    `json:"type"`
+		Data string `json:"data"`
+	} `json:"Answer"`
+}
+
+// lookup returns the IPv4 then IPv6 addresses of host.
+func (r *dohResolver) lookup(ctx context.Context, host string) ([]string, error) {
+	var addrs []string
+	for _, qtype := range []int{dnsTypeA, dnsTypeAAAA} {
+		u, _ := url.Parse(r.url)
+		q := u.Query()
+		q.Set("name", host)
+		q.Set("type", fmt.Sprint(qtype))
+		u.RawQuery = q.Encode()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/dns-json")
+
+		resp, err := r.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		var answer dohResponse
+		err = json.NewDecoder(resp.Body).Decode(&answer)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || err != nil {
+			return nil, fmt.Errorf("DNS over HTTPS query of %s failed: %s", host, resp.Status)
+		}
+
+		for _, a := range answer.Answer {
+			if a.Type == qtype && net.ParseIP(a.Data) != nil {
+				addrs = append(addrs, a.Data)
+			}
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, &net.DNSError{Err: "no such host", Name: host, Server: r.url, IsNotFound: true}
+	}
+	return addrs, nil
+}
+
+// dialContext wraps dial so the host names the system DNS can't resolve are
+// resolved over HTTPS, or every host name when always is set.
+func (r *dohResolver) dialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error), always bool) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil || net.ParseIP(host) != nil {
+			return dial(ctx, network, addr)
+		}
+
+		if !always {
+			conn, err := dial(ctx, network, addr)
+			var dnsErr *net.DNSError
+			if err == nil || !errors.As(err, &dnsErr) {
+				return conn, err
+			}
+		}
+
+		addrs, err := r.lookup(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		var errs []error
+		for _, ip := range addrs {
+			conn, err := dial(ctx, network, net.JoinHostPort(ip, port))
+			if err == nil {
+				return conn, nil
+			}
+			errs = append(errs, err)
+		}
+		return nil, errors.Join(errs...)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDoHResolver(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "releases")
+	}))
+	defer target.Close()
+	_, port, _ := net.SplitHostPort(target.Listener.Addr().String())
+
+	var queried []string
+	doh := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, qtype := r.URL.Query().Get("name"), r.URL.Query().Get("type")
+		queried = append(queried, name)
+		if r.Header.Get("Accept") != "application/dns-json" {
+			t.Errorf("Expected a JSON query, got %q", r.Header.Get("Accept"))
+		}
+		if qtype == "1" {
+			fmt.Fprintf(w, `{"Status":0,"Answer":[{"name":%q,"type":5,"data":"alias."},{"name":%q,"type":1,"data":"127.0.0.1"}]}`, name, name)
+			return
+		}
+		io.WriteString(w, `{"Status":0}`)
+	}))
+	defer doh.Close()
+
+	r := &dohResolver{url: doh.URL, client: doh.Client()}
+	get := func(host string, always bool) (string, error) {
+		client := &http.Client{Transport: &http.Transport{DialContext: r.dialContext((&net.Dialer{}).DialContext, always)}}
+		resp, err := client.Get("http://" + net.JoinHostPort(host, port))
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		return string(b), err
+	}
+
+	// .invalid names never resolve with the system DNS.
+	if body, err := get("go.dev.invalid", false); err != nil || body != "releases" {
+		t.Errorf("Expected the host to be resolved over HTTPS, got %q (%v)", body, err)
+	}
+	if len(queried) != 2 {
+		t.Errorf("Expected A and AAAA queries, got %v", queried)
+	}
+
+	queried = nil
+	if _, err := get("127.0.0.1", true); err != nil || len(queried) != 0 {
+		t.Errorf("Expected IP addresses to be dialed directly, got %v (%v)", queried, err)
+	}
+	if _, err := get("localhost", true); err != nil || len(queried) == 0 {
+		t.Errorf("Expected every host to be resolved over HTTPS when always is set, got %v (%v)", queried, err)
+	}
+}
+
+func TestDoHResolverInvalid(t *testing.T) {
+	for _, u := range []string{"http://1.1.1.1/dns-query", "1.1.1.1", ":"} {
+		if _, err := newTransport(TransportConfig{DoHURL: u}, false); err == nil {
+			t.Errorf("Expected an error for %q", u)
+		}
+	}
+	if _, err := newTransport(TransportConfig{DoHURL: "https://1.1.1.1/dns-query"}, false); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// isGzip reports whether the file at path starts like a gzip stream.
+func isGzip(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	magic, err := bufio.NewReader(f).Peek(len(gzipMagic))
+	return err == nil && bytes.Equal(magic, gzipMagic)
+}
+
+// warnEncoding warns about the downloads served with a Content-Encoding:
+// their bytes are kept as received, such responses come from proxies
+// compressing files already compressed or mislabeling them.
+func warnEncoding(resp *http.Response, dlFile File, source string) {
+	if enc := resp.Header.Get("Content-Encoding"); enc != "" && enc != "identity" {
+		slog.Warn("the download has a Content-Encoding, a proxy may be compressing it",
+			"file", dlFile.Filename, "content_encoding", enc, "url", source)
+	}
+}
+
+// unwrapGzip replaces the file at path by the content of its gzip stream
+// when that content has the expected checksum, for the downloads compressed
+// once more on their way. It reports whether the file was replaced.
+func unwrapGzip(path string, sum Checksum) (bool, error) {
+	if sum.Sum == "" || !isGzip(path) {
+		return false, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	gzr, err := gzip.NewReader(f)
+	if err != nil {
+		return false, nil
+	}
+	gzr.Multistream(false)
+
+	tmp, err := createTemp(filepath.Dir(path), ".go-dl-unwrap-*")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sum.New()
+	_, err = io.Copy(io.MultiWriter(tmp, h), gzr)
+	if errClose := tmp.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil || compareChecksum(path, sum, h.Sum(nil)) != nil {
+		return false, nil
+	}
+
+	f.Close()
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// recoverEncoding handles the checksum mismatches caused by proxies altering
+// the Content-Encoding of the downloads: a download compressed twice is
+// replaced by its decompressed content, and one decompressed on the way,
+// which can't be verified anymore, fails with an explanation.
+func (p *pipeline) recoverEncoding(mismatch error) error {
+	file := p.state.File
+	unwrapped, err := unwrapGzip(p.state.Archive, file.Checksum())
+	if err != nil {
+		return fmt.Errorf("%w, decompressing it failed: %w", mismatch, err)
+	}
+	if unwrapped {
+		slog.Warn("the download was compressed twice, a proxy gzipped it again, using its decompressed content",
+			"file", file.Filename, "url", p.source)
+		if isExtractable(file) {
+			return checkArchive(p.state.Archive)
+		}
+		return nil
+	}
+	if isExtractable(file) && !isGzip(p.state.Archive) {
+		return fmt.Errorf("%w: %s is not gzip compressed as published, a proxy decompressed it on the way, check the Content-Encoding it serves", mismatch, file.Filename)
+	}
+	return mismatch
+}
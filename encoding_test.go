@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// withBody serves body instead of the archive, with the headers of header.
+func withBody(t *testing.T, c *cli, body []byte, header http.Header) {
+	t.Helper()
+
+	feed := c.repo.client.Transport
+	c.repo.client = NewTestClient(func(req *http.Request) *http.Response {
+		if req.URL.Query().Get("mode") == "json" {
+			resp, _ := feed.RoundTrip(req)
+			return resp
+		}
+		if got := req.Header.Get("Accept-Encoding"); got != "identity" {
+			t.Errorf("Expected the downloads to ask for the identity encoding, got %q", got)
+		}
+		return &http.Response{
+			StatusCode:    http.StatusOK,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader(body)),
+			ContentLength: int64(len(body)),
+		}
+	})
+}
+
+func TestInstallDoubleCompressed(t *testing.T) {
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n"})
+	c, _ := newTestCLI(t, archive)
+	c.prefix = t.TempDir()
+
+	var twice bytes.Buffer
+	gzw := gzip.NewWriter(&twice)
+	gzw.Write(archive)
+	gzw.Close()
+	withBody(t, c, twice.Bytes(), http.Header{"Content-Encoding": {"gzip"}})
+
+	if err := c.run([]string{"install", "go1.22.1"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if v, err := installedVersion(c.goroot()); err != nil || v != "go1.22.1" {
+		t.Errorf("Expected go1.22.1 to be installed, got %q (%v)", v, err)
+	}
+}
+
+func TestInstallDecompressedByProxy(t *testing.T) {
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n"})
+	c, _ := newTestCLI(t, archive)
+	c.prefix = t.TempDir()
+
+	gzr, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tarball, err := io.ReadAll(gzr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	withBody(t, c, tarball, nil)
+
+	err = c.run([]string{"install", "go1.22.1"})
+	if !errors.Is(err, ErrChecksumMismatch) || !strings.Contains(err.Error(), "a proxy decompressed it") {
+		t.Errorf("Expected the decompression to be explained, got %v", err)
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// archiveOptions hardens the extraction of the archives.
+type archiveOptions struct {
+	// strict rejects anything but the regular files and directories of a Go
+	// release, see checkEntry.
+	strict bool
+	// confine creates the files with a file system API unable to resolve
+	// paths outside of the destination, see openConfined.
+	confine bool
+	// filter leaves files of the release out, such as its documentation.
+	filter archiveFilter
+}
+
+// extractDir creates the files of an extraction, by name relative to its
+// destination.
+type extractDir interface {
+	MkdirAll(name string) error
+	Create(name string, mode os.FileMode) (*os.File, error)
+	Close() error
+}
+
+// plainDir creates the files of an extraction under a directory with the
+// os package, the names are checked by checkEntry beforehand.
+type plainDir string
+
+func (d plainDir) MkdirAll(name string) error {
+	target := filepath.Join(string(d), name)
+	if _, err := os.Stat(target); err == nil {
+		return nil
+	}
+	return os.MkdirAll(target, 0755)
+}
+
+func (d plainDir) Create(name string, mode os.FileMode) (*os.File, error) {
+	return os.OpenFile(filepath.Join(string(d), name), os.O_CREATE|os.O_TRUNC|os.O_RDWR, mode)
+}
+
+func (d plainDir) Close() error {
+	return nil
+}
+
+// openExtractDir returns where the extraction to dst creates its files.
+func openExtractDir(dst string, opts archiveOptions) (extractDir, error) {
+	if opts.confine {
+		return openConfined(dst)
+	}
+	return plainDir(dst), nil
+}
+
+// specialModes are the permission bits never carried over to an installation.
+const specialModes = os.ModeSetuid | os.ModeSetgid | os.ModeSticky
+
+// checkEntry returns the permissions extracting header, without the setuid,
+// setgid and sticky bits, and reports whether the entry is extracted at all.
+// Devices, FIFOs and names outside of the destination are rejected, strict
+// also rejects the special bits and any entry but the regular files and
+// directories of a Go release.
+func checkEntry(header *tar.Header, strict bool) (os.FileMode, bool, error) {
+	if !filepath.IsLocal(header.Name) {
+		return 0, false, fmt.Errorf("%w: %s is outside of the archive", ErrUnsafeArchive, header.Name)
+	}
+
+	switch header.Typeflag {
+	case tar.TypeReg, tar.TypeDir:
+	case tar.TypeChar, tar.TypeBlock, tar.TypeFifo:
+		return 0, false, fmt.Errorf("%w: %s is a device or a FIFO", ErrUnsafeArchive, header.Name)
+	case tar.TypeXGlobalHeader:
+		return 0, false, nil
+	default:
+		if strict {
+			return 0, false, fmt.Errorf("%w: %s has the unexpected type %q", ErrUnsafeArchive, header.Name, header.Typeflag)
+		}
+		return 0, false, nil
+	}
+
+	mode := header.FileInfo().Mode()
+	if strict && mode&specialModes != 0 {
+		return 0, false, fmt.Errorf("%w: %s has the mode %v", ErrUnsafeArchive, header.Name, mode)
+	}
+	return mode.Perm(), true, nil
+}
+
+// checkArchive reads the whole tar.gz archive at path, so truncated or
+// corrupted archives fail the verification, with their gzip CRC or an
+// unexpected EOF, before the installation is replaced.
+func checkArchive(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := readArchive(f); err != nil {
+		return fmt.Errorf("%w %s: %w", ErrCorruptArchive, filepath.Base(path), err)
+	}
+	return nil
+}
+
+// readArchive reads the tar.gz archive r up to the end of its gzip stream.
+func readArchive(r io.Reader) error {
+	gzr, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(gzr)
+	for {
+		_, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(io.Discard, tr); err != nil {
+			return err
+		}
+	}
+
+	// The CRC is only checked once the end of the gzip stream is read, past
+	// the end of the tar archive.
+	if _, err := io.Copy(io.Discard, gzr); err != nil {
+		return err
+	}
+	return gzr.Close()
+}
+
+// streamCheck runs checkArchive on an archive as it is written, such as
+// while downloading it.
+type streamCheck struct {
+	name string
+	pw   *io.PipeWriter
+	done chan error
+}
+
+func newStreamCheck(name string) *streamCheck {
+	pr, pw := io.Pipe()
+	c := &streamCheck{name: name, pw: pw, done: make(chan error, 1)}
+	go func() {
+		err := readArchive(pr)
+		// The writes keep succeeding once the archive is known to be corrupt.
+		io.Copy(io.Discard, pr)
+		c.done <- err
+	}()
+	return c
+}
+
+func (c *streamCheck) Write(p []byte) (int, error) {
+	return c.pw.Write(p)
+}
+
+// Close ends the archive and returns the result of the check.
+func (c *streamCheck) Close() error {
+	c.pw.Close()
+	if err := <-c.done; err != nil {
+		return fmt.Errorf("%w %s: %w", ErrCorruptArchive, c.name, err)
+	}
+	return nil
+}
+
+// sanitizeArchive writes to w the tar.gz archive read from r as decompress
+// would extract it, for the installations extracted by another tar: the
+// entries are checked by checkEntry, the files left out by the filter are
+// removed, and the entries keep neither their owner nor their special bits.
+func sanitizeArchive(w io.Writer, r io.Reader, opts archiveOptions) error {
+	gzr, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gzr.Close()
+
+	zw := gzip.NewWriter(w)
+	tw := tar.NewWriter(zw)
+	tr := tar.NewReader(gzr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		mode, ok, err := checkEntry(header, opts.strict)
+		if err != nil {
+			return err
+		}
+		if !ok || opts.filter.Skip(header.Name) {
+			continue
+		}
+
+		clean := &tar.Header{
+			Name:     header.Name,
+			Typeflag: header.Typeflag,
+			Mode:     int64(mode),
+			Size:     header.Size,
+			ModTime:  header.ModTime,
+		}
+		if err := tw.WriteHeader(clean); err != nil {
+			return err
+		}
+		if _, err := copyBuffered(tw, tr); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+// newTestEntries returns a tar.gz archive of the headers, regular files
+// holding their name.
+func newTestEntries(t *testing.T, headers ...*tar.Header) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gzw)
+	for _, header := range headers {
+		if header.Typeflag == tar.TypeReg {
+			header.Size = int64(len(header.Name))
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			tw.Write([]byte(header.Name))
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecompressSanitizesModes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no setuid bits on windows")
+	}
+
+	archive := newTestEntries(t,
+		&tar.Header{Name: "go/", Mode: 01755, Typeflag: tar.TypeDir},
+		&tar.Header{Name: "go/bin/go", Mode: 06755, Typeflag: tar.TypeReg},
+		&tar.Header{Name: "go/link", Linkname: "bin/go", Typeflag: tar.TypeSymlink},
+	)
+
+	dst := t.TempDir()
+	if err := decompress(dst, bytes.NewReader(archive), extractProgress{}, archiveOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filepath.Join(dst, "go/bin/go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode() != 0755 {
+		t.Errorf("Expected the special bits to be stripped, got %v", info.Mode())
+	}
+	if _, err := os.Lstat(filepath.Join(dst, "go/link")); !os.IsNotExist(err) {
+		t.Errorf("Expected the symbolic link to be skipped, got %v", err)
+	}
+
+	if err := decompress(t.TempDir(), bytes.NewReader(archive), extractProgress{}, archiveOptions{strict: true}); !errors.Is(err, ErrUnsafeArchive) {
+		t.Errorf("Expected strict extractions to reject the setuid binary, got %v", err)
+	}
+}
+
+func TestCheckEntry(t *testing.T) {
+	for _, test := range []struct {
+		header         tar.Header
+		strict         bool
+		extracted, bad bool
+	}{
+		{tar.Header{Name: "go/VERSION", Mode: 0644, Typeflag: tar.TypeReg}, true, true, false},
+		{tar.Header{Name: "go/bin/go", Mode: 04755, Typeflag: tar.TypeReg}, false, true, false},
+		{tar.Header{Name: "go/bin/go", Mode: 04755, Typeflag: tar.TypeReg}, true, false, true},
+		{tar.Header{Name: "go/null", Typeflag: tar.TypeChar}, false, false, true},
+		{tar.Header{Name: "go/fifo", Typeflag: tar.TypeFifo}, false, false, true},
+		{tar.Header{Name: "go/link", Linkname: "VERSION", Typeflag: tar.TypeLink}, false, false, false},
+		{tar.Header{Name: "go/link", Linkname: "VERSION", Typeflag: tar.TypeLink}, true, false, true},
+		{tar.Header{Name: "go/../../etc/passwd", Mode: 0644, Typeflag: tar.TypeReg}, false, false, true},
+	} {
+		mode, ok, err := checkEntry(&test.header, test.strict)
+		if ok != test.extracted || (err != nil) != test.bad || (err != nil && !errors.Is(err, ErrUnsafeArchive)) {
+			t.Errorf("Expected %s (strict %v) to be extracted %v with error %v, got %v and %v", test.header.Name, test.strict, test.extracted, test.bad, ok, err)
+		}
+		if ok && mode&^0777 != 0 {
+			t.Errorf("Expected only permissions for %s, got %v", test.header.Name, mode)
+		}
+	}
+}
+
+func TestVerifyArchiveIntegrity(t *testing.T) {
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n"})
+	truncated := archive[:len(archive)-8]
+	sum := sha256.Sum256(truncated)
+	dlf := File{Filename: "go1.22.1.linux-amd64.tar.gz", Version: "go1.22.1", Sha256: hex.EncodeToString(sum[:])}
+
+	p := newPipeline(newTestArchiveRepo(truncated), nil, dlf, t.TempDir(), processOwner, newTestPaths(t))
+	if err := p.download(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := p.verify(context.Background()); !errors.Is(err, ErrCorruptArchive) {
+		t.Errorf("Expected the streamed check to catch the truncated archive, got %v", err)
+	}
+
+	if err := checkArchive(p.state.Archive); !errors.Is(err, ErrCorruptArchive) {
+		t.Errorf("Expected the truncated archive to be corrupt, got %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), dlf.Filename)
+	if err := os.WriteFile(path, archive, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkArchive(path); err != nil {
+		t.Errorf("Expected the archive to be readable, got %v", err)
+	}
+}
+
+func TestSanitizeArchive(t *testing.T) {
+	archive := newTestEntries(t,
+		&tar.Header{Name: "go/", Mode: 01755, Typeflag: tar.TypeDir, Uid: 1234, Gid: 1234, Uname: "builder"},
+		&tar.Header{Name: "go/bin/go", Mode: 06755, Typeflag: tar.TypeReg, Uid: 1234, Gid: 1234, Uname: "builder"},
+		&tar.Header{Name: "go/link", Linkname: "bin/go", Typeflag: tar.TypeSymlink},
+		&tar.Header{Name: "go/doc/go_spec.html", Mode: 0644, Typeflag: tar.TypeReg},
+	)
+	filter, _ := newArchiveFilter(true, "", "")
+
+	var buf bytes.Buffer
+	if err := sanitizeArchive(&buf, bytes.NewReader(archive), archiveOptions{filter: filter}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	gzr, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gzr)
+	var names []string
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, header.Name)
+		if header.Mode&^0777 != 0 || header.Uid != 0 || header.Gid != 0 || header.Uname != "" {
+			t.Errorf("Expected %s without special bits nor owner, got mode %o, owner %d:%d %q", header.Name, header.Mode, header.Uid, header.Gid, header.Uname)
+		}
+		if b, _ := io.ReadAll(tr); header.Typeflag == tar.TypeReg && string(b) != header.Name {
+			t.Errorf("Expected the content of %s to be kept, got %q", header.Name, b)
+		}
+	}
+	if want := []string{"go/", "go/bin/go"}; !slices.Equal(names, want) {
+		t.Errorf("Expected the entries %v, got %v", want, names)
+	}
+
+	outside := newTestEntries(t, &tar.Header{Name: "go/../../etc/passwd", Mode: 0644, Typeflag: tar.TypeReg})
+	if err := sanitizeArchive(io.Discard, bytes.NewReader(outside), archiveOptions{}); !errors.Is(err, ErrUnsafeArchive) {
+		t.Errorf("Expected a name outside of the archive to be rejected, got %v", err)
+	}
+}
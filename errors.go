@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+// Failures wrap one of these errors so both the TUI and callers can branch on
+// their kind with errors.Is.
+var (
+	ErrVersionNotFound  = errors.New("version not found")
+	ErrNoMatchingFile   = errors.New("no matching file")
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	ErrPermission       = errors.New("insufficient permissions")
+	ErrPolicy           = errors.New("blocked by policy")
+	ErrSchema           = errors.New("unexpected feed schema")
+	ErrCacheMiss        = errors.New("not found in cache")
+	ErrScanRejected     = errors.New("rejected by scanner")
+	ErrMetered          = errors.New("metered connection")
+	ErrForeignLink      = errors.New("installation managed by another tool")
+	ErrPackageManaged   = errors.New("installation managed by a system package")
+	ErrUnsafeArchive    = errors.New("unsafe archive entry")
+	ErrCorruptArchive   = errors.New("corrupt archive")
+	ErrStalled          = errors.New("download stalled")
+)
+
+// wrapPermission wraps err with ErrPermission when it was caused by missing
+// file system permissions.
+func wrapPermission(err error) error {
+	if errors.Is(err, fs.ErrPermission) && !errors.Is(err, ErrPermission) {
+		return fmt.Errorf("%w: %w", ErrPermission, err)
+	}
+	return err
+}
+
+// errorHint returns a suggestion on how to recover from err, if any.
+func errorHint(err error) string {
+	switch {
+	case errors.Is(err, ErrPermission):
+		return "run go-dl again with enough privileges to write the installation, e.g. with sudo"
+	case errors.Is(err, ErrChecksumMismatch):
+		return "the download is corrupted or was tampered with, run go-dl resume to download it again"
+	case errors.Is(err, ErrNoMatchingFile):
+		return "no archive is published for this platform, try --installer or another release"
+	case errors.Is(err, ErrVersionNotFound):
+		return "check the version constraint, e.g. 1.22.1, 1.22 or ~1.21.3"
+	case errors.Is(err, ErrMetered):
+		return "run go-dl again with --metered allow to download over this connection"
+	case errors.Is(err, ErrForeignLink):
+		return "upgrade it with the tool managing the link, or remove the link to let go-dl install its own copy"
+	case errors.Is(err, ErrPackageManaged):
+		return "upgrade Go with the package manager, or run go-dl again with --force to replace it anyway"
+	case errors.Is(err, ErrStalled):
+		return "check the network, then run go-dl resume, or raise --stall-timeout on slow connections"
+	case errors.Is(err, ErrCorruptArchive):
+		return "the download is incomplete or damaged, run go-dl resume to download it again"
+	case errors.Is(err, ErrUnsafeArchive):
+		return "the archive doesn't look like a Go release, check the mirror or the cache it came from"
+	case errors.Is(err, ErrSchema):
+		return "the go.dev releases feed changed, please report this issue"
+	}
+	return ""
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"testing"
+)
+
+func TestWrapPermission(t *testing.T) {
+	err := wrapPermission(&os.PathError{Op: "unlinkat", Path: "/usr/local/go", Err: fs.ErrPermission})
+	if !errors.Is(err, ErrPermission) || !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("Expected ErrPermission wrapping the original error, got %v", err)
+	}
+
+	if err := wrapPermission(wrapPermission(fs.ErrPermission)); err.Error() != "insufficient permissions: permission denied" {
+		t.Errorf("Expected ErrPermission to be wrapped only once, got %v", err)
+	}
+
+	if err := wrapPermission(fs.ErrNotExist); errors.Is(err, ErrPermission) {
+		t.Errorf("Expected other errors to be left untouched, got %v", err)
+	}
+}
+
+func TestErrorHint(t *testing.T) {
+	for _, err := range []error{ErrPermission, ErrChecksumMismatch, ErrNoMatchingFile, ErrVersionNotFound, ErrSchema, ErrMetered, ErrForeignLink, ErrPackageManaged, ErrUnsafeArchive, ErrCorruptArchive, ErrStalled} {
+		if hint := errorHint(fmt.Errorf("wrapped: %w", err)); hint == "" {
+			t.Errorf("Expected a hint for %v", err)
+		}
+	}
+
+	if hint := errorHint(errors.New("unknown")); hint != "" {
+		t.Errorf("Expected no hint for an unknown error, got %s", hint)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// progressEvent is a line of JSON written with --progress-fd.
+type progressEvent struct {
+	Event      string   `json:"event"`
+	Step       string   `json:"step,omitempty"`
+	Version    string   `json:"version,omitempty"`
+	Ratio      *float64 `json:"ratio,omitempty"`
+	Bytes      *int64   `json:"bytes,omitempty"`
+	Total      *int64   `json:"total,omitempty"`
+	Files      *int     `json:"files,omitempty"`
+	TotalFiles *int     `json:"total_files,omitempty"`
+	Attempt    int      `json:"attempt,omitempty"`
+	Max        int      `json:"max,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// progressEvents reports the progress of go-dl to wrapper programs. The
+// methods of a nil *progressEvents do nothing.
+type progressEvents struct {
+	mu   sync.Mutex
+	enc  *json.Encoder
+	step string
+	last float64
+
+	// bytes and total are the bytes transferred by the current step, when
+	// it downloads or extracts.
+	bytes int64
+	total int64
+	// files and allFiles are the files extracted, when it extracts.
+	files    int
+	allFiles int
+}
+
+func newProgressEvents(w io.Writer) *progressEvents {
+	return &progressEvents{enc: json.NewEncoder(w), last: -1}
+}
+
+// openProgressFd returns the events written to the file descriptor fd.
+func openProgressFd(fd int) (*progressEvents, error) {
+	f := os.NewFile(uintptr(fd), "progress-fd")
+	if f == nil {
+		return nil, fmt.Errorf("invalid progress file descriptor %d", fd)
+	}
+	if _, err := f.Stat(); err != nil {
+		return nil, fmt.Errorf("invalid progress file descriptor %d: %w", fd, err)
+	}
+	return newProgressEvents(f), nil
+}
+
+// Step reports the start of a step of the installation of version:
+// downloading, verifying or extracting.
+func (e *progressEvents) Step(step, version string) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.step = step
+	e.last = -1
+	e.bytes, e.total = 0, 0
+	e.files, e.allFiles = 0, 0
+	e.enc.Encode(progressEvent{Event: "step", Step: step, Version: version})
+}
+
+// Progress reports the ratio done of the current step, with a resolution of
+// a percent.
+func (e *progressEvents) Progress(ratio float64) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if ratio < 1 && ratio-e.last < 0.01 {
+		return
+	}
+	e.last = ratio
+	event := progressEvent{Event: "progress", Step: e.step, Ratio: &ratio}
+	if e.total > 0 {
+		bytes, total := e.bytes, e.total
+		event.Bytes, event.Total = &bytes, &total
+	}
+	if e.allFiles > 0 {
+		files, allFiles := e.files, e.allFiles
+		event.Files, event.TotalFiles = &files, &allFiles
+	}
+	e.enc.Encode(event)
+}
+
+// Transfer records the bytes transferred by the current step, reported with
+// its next progress.
+func (e *progressEvents) Transfer(done, total int64) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.bytes, e.total = done, total
+}
+
+// Files records the files extracted by the current step, reported with its
+// next progress.
+func (e *progressEvents) Files(done, total int) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.files, e.allFiles = done, total
+}
+
+// Reconnect reports that the download stalled and is resumed, for the
+// attempt-th time out of max.
+func (e *progressEvents) Reconnect(attempt, max int) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.enc.Encode(progressEvent{Event: "reconnect", Step: e.step, Attempt: attempt, Max: max})
+}
+
+// Done reports the end of the command, successful when err is nil.
+func (e *progressEvents) Done(err error) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err != nil {
+		e.enc.Encode(progressEvent{Event: "error", Error: err.Error()})
+		return
+	}
+	e.enc.Encode(progressEvent{Event: "done"})
+}
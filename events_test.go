@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestProgressEvents(t *testing.T) {
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n"})
+	dlf := File{Filename: "go1.22.1.linux-amd64.tar.gz", Version: "go1.22.1"}
+
+	var out bytes.Buffer
+	events := newProgressEvents(&out)
+
+	repo := newTestArchiveRepo(archive)
+	repo.onProgress = events.Progress
+	repo.onTransfer = events.Transfer
+	repo.onFiles = events.Files
+	p := newPipeline(repo, nil, dlf, t.TempDir(), processOwner, newTestPaths(t))
+	p.events = events
+	events.Done(p.run(context.Background()))
+	events.Done(errors.New("boom"))
+
+	var steps []string
+	var last progressEvent
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var e progressEvent
+		if err := dec.Decode(&e); err != nil {
+			t.Fatalf("Expected JSON lines, got %v", err)
+		}
+		if e.Event == "step" {
+			steps = append(steps, e.Step)
+		}
+		if e.Event == "progress" && (e.Ratio == nil || *e.Ratio < 0 || *e.Ratio > 1 || e.Step == "") {
+			t.Errorf("Expected progress events with a ratio and a step, got %+v", e)
+		}
+		if e.Event == "progress" && e.Step == "downloading" && (e.Bytes == nil || e.Total == nil || *e.Total != int64(len(archive))) {
+			t.Errorf("Expected download progress events with byte counts, got %+v", e)
+		}
+		if e.Event == "progress" && e.Step == "extracting" && (e.Bytes == nil || e.Files == nil || e.TotalFiles == nil || *e.TotalFiles != 1) {
+			t.Errorf("Expected extraction progress events with byte and file counts, got %+v", e)
+		}
+		if e.Event == "progress" && e.Step == "verifying" && (e.Bytes != nil || e.Files != nil) {
+			t.Errorf("Expected no counts while verifying, got %+v", e)
+		}
+		if e.Event == "error" && e.Error != "boom" {
+			t.Errorf("Expected the error to be reported, got %+v", e)
+		}
+		last = e
+	}
+
+	if want := []string{"downloading", "verifying", "extracting"}; !reflect.DeepEqual(steps, want) {
+		t.Errorf("Expected steps %v, got %v", want, steps)
+	}
+	if last.Event != "error" {
+		t.Errorf("Expected the error event last, got %+v", last)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/blckfalcon/go-dl/versions"
+)
+
+// execResult is the outcome of a command run with one of the installed
+// versions.
+type execResult struct {
+	version string
+	err     error
+}
+
+func (r execResult) status() string {
+	var exitErr *exec.ExitError
+	switch {
+	case r.err == nil:
+		return "ok"
+	case errors.As(r.err, &exitErr):
+		return fmt.Sprintf("exit status %d", exitErr.ExitCode())
+	}
+	return r.err.Error()
+}
+
+// execAll runs a command once per installed version, newest first, with the
+// environment of each, and summarizes their exit statuses.
+func (c *cli) execAll(args []string) error {
+	fs := flag.NewFlagSet("exec", flag.ContinueOnError)
+	all := fs.Bool("all", false, "run the command with every installed version")
+	match := fs.String("match", "", "run the command with the installed versions matching this constraint")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-dl exec --all|--match constraint [--] <command> [arguments]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 || *all == (*match != "") {
+		fs.Usage()
+		return errors.New("exec expects either --all or --match, and a command")
+	}
+
+	constraint := versions.Constraint{}
+	if *match != "" {
+		var err error
+		if constraint, err = versions.ParseConstraint(c.resolveAlias(*match)); err != nil {
+			return err
+		}
+	}
+
+	goroots := map[string]string{}
+	for _, v := range c.installedVersions() {
+		if goroot, ok := c.toolchain(v); ok && (*all || constraint.Check(v)) {
+			goroots[v] = goroot
+		}
+	}
+	if len(goroots) == 0 {
+		return fmt.Errorf("no installed version to run %s with: %w", fs.Arg(0), ErrVersionNotFound)
+	}
+
+	installed := make([]string, 0, len(goroots))
+	for v := range goroots {
+		installed = append(installed, v)
+	}
+	sort.Slice(installed, func(i, j int) bool { return versions.IsNewer(installed[i], installed[j]) })
+
+	var results []execResult
+	for _, v := range installed {
+		fmt.Fprintf(c.stdout, "==> %s: %s\n", v, strings.Join(fs.Args(), " "))
+
+		cmd := c.toolchainCommand(goroots[v], fs.Args())
+		cmd.Stdout = c.stdout
+		cmd.Stderr = c.stdout
+		results = append(results, execResult{version: v, err: cmd.Run()})
+
+		if c.ctx.Err() != nil {
+			return c.ctx.Err()
+		}
+	}
+
+	fmt.Fprintln(c.stdout)
+	w := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	failed := 0
+	for _, r := range results {
+		if r.err != nil {
+			failed++
+		}
+		fmt.Fprintf(w, "%s\t%s\n", r.version, r.status())
+	}
+	w.Flush()
+
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "%d of %d versions failed\n", failed, len(results))
+		return commandExit{1}
+	}
+	return nil
+}
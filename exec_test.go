@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestExecAll(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test toolchains are shell scripts")
+	}
+
+	c, out := newTestCLI(t, nil)
+	c.prefix = t.TempDir()
+
+	// go1.20.14 fails, as a library dropping its support would.
+	for version, goroot := range map[string]string{
+		"go1.22.1":  filepath.Join(c.prefix, "go"),
+		"go1.21.8":  filepath.Join(versionsDir(c.prefix), "go1.21.8"),
+		"go1.20.14": filepath.Join(c.toolchainsDir(), "go1.20.14", "go"),
+	} {
+		if err := os.MkdirAll(filepath.Join(goroot, "bin"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(goroot, "VERSION"), []byte(version+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		script := "#!/bin/sh\necho \"vet with $(cat \"$GOROOT/VERSION\")\"\n"
+		if version == "go1.20.14" {
+			script += "exit 2\n"
+		}
+		if err := os.WriteFile(filepath.Join(goroot, "bin", "go"), []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := c.execAll([]string{"--all", "--", "go", "vet", "./..."})
+	var exit commandExit
+	if !errors.As(err, &exit) || exit.code != 1 {
+		t.Errorf("Expected a failure exit status, got %v", err)
+	}
+
+	got := out.String()
+	for _, want := range []string{
+		"==> go1.22.1: go vet ./...\nvet with go1.22.1\n==> go1.21.8: go vet ./...\nvet with go1.21.8\n==> go1.20.14",
+		"go1.22.1   ok\ngo1.21.8   ok\ngo1.20.14  exit status 2\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q in the output, got %q", want, got)
+		}
+	}
+
+	out.Reset()
+	if err := c.execAll([]string{"--match", ">=1.21", "go", "vet"}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if strings.Contains(out.String(), "go1.20.14") {
+		t.Errorf("Expected only the matching versions to run, got %q", out.String())
+	}
+
+	out.Reset()
+	c.aliases = map[string]string{"supported": ">=1.21"}
+	if err := c.execAll([]string{"--match", "supported", "go", "vet"}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if strings.Contains(out.String(), "go1.20.14") || !strings.Contains(out.String(), "go1.21.8") {
+		t.Errorf("Expected the alias to match the versions of its constraint, got %q", out.String())
+	}
+
+	if err := c.execAll([]string{"go", "vet"}); err == nil {
+		t.Errorf("Expected an error without --all or --match")
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// faultEnv enables the fault injection flags, which are hidden otherwise.
+const faultEnv = "GO_DL_FAULT_INJECTION"
+
+var errInjectedFault = errors.New("injected network failure")
+
+// faultTransport drops the connection of the first downloads after a number
+// of bytes, deterministically, to exercise the resume, retry and cleanup
+// paths. The releases feed is never faulted.
+type faultTransport struct {
+	next  http.RoundTripper
+	after int64
+	times int
+
+	mu     sync.Mutex
+	faults int
+}
+
+func (t *faultTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || req.URL.Query().Get("mode") == "json" || resp.StatusCode/100 != 2 {
+		return resp, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.faults >= t.times {
+		return resp, nil
+	}
+	t.faults++
+	resp.Body = &faultBody{ReadCloser: resp.Body, left: t.after}
+	return resp, nil
+}
+
+// faultBody fails once left bytes were read.
+type faultBody struct {
+	io.ReadCloser
+	left int64
+}
+
+func (b *faultBody) Read(p []byte) (int, error) {
+	if b.left <= 0 {
+		return 0, errInjectedFault
+	}
+	if int64(len(p)) > b.left {
+		p = p[:b.left]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.left -= int64(n)
+	return n, err
+}
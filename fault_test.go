@@ -0,0 +1,30 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFaultTransport(t *testing.T) {
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n"})
+	c, _ := newTestCLI(t, archive)
+	c.prefix = t.TempDir()
+	c.repo.client.Transport = &faultTransport{next: c.repo.client.Transport, after: 64, times: 1}
+
+	err := c.run([]string{"install", "1.22.1"})
+	if !errors.Is(err, errInjectedFault) {
+		t.Fatalf("Expected the injected failure, got %v", err)
+	}
+	if _, err := os.Stat(c.paths.PipelineFile()); err != nil {
+		t.Fatalf("Expected the interrupted installation to be recorded, got %v", err)
+	}
+
+	if err := c.run([]string{"resume"}); err != nil {
+		t.Fatalf("Expected the installation to resume once the network is back, got %v", err)
+	}
+	if v, err := installedVersion(filepath.Join(c.prefix, "go")); err != nil || v != "go1.22.1" {
+		t.Errorf("Expected go1.22.1 to be installed, got %q (%v)", v, err)
+	}
+}
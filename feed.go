@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// SchemaError reports where the releases feed does not match the expected
+// schema, along with the offending JSON.
+type SchemaError struct {
+	Path    string
+	Reason  string
+	Snippet string
+}
+
+func (e *SchemaError) Error() string {
+	msg := fmt.Sprintf("%v: %s", ErrSchema, e.Reason)
+	if e.Path != "" {
+		msg = fmt.Sprintf("%v: %s: %s", ErrSchema, e.Path, e.Reason)
+	}
+	if e.Snippet != "" {
+		msg += fmt.Sprintf(" near `%s`", e.Snippet)
+	}
+	return msg
+}
+
+func (e *SchemaError) Unwrap() error { return ErrSchema }
+
+const snippetLength = 80
+
+// snippet returns the JSON around offset, shortened to stay readable in an
+// error message.
+func snippet(raw []byte, offset int64) string {
+	start := max(0, int(offset)-snippetLength/2)
+	end := min(len(raw), start+snippetLength)
+
+	s := string(raw[start:end])
+	if start > 0 {
+		s = "…" + s
+	}
+	if end < len(raw) {
+		s += "…"
+	}
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// decodeReleases parses the releases feed. Unknown fields are ignored and
+// optional ones may be missing, only the fields go-dl can't work without are
+// required.
+func decodeReleases(b []byte) ([]Release, error) {
+	var raws []json.RawMessage
+
+	if err := json.Unmarshal(b, &raws); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return nil, &SchemaError{Reason: syntaxErr.Error(), Snippet: snippet(b, syntaxErr.Offset)}
+		}
+		return nil, &SchemaError{Reason: "expected an array of releases", Snippet: snippet(b, 0)}
+	}
+
+	releases := make([]Release, 0, len(raws))
+	for i, raw := range raws {
+		var r Release
+		path := fmt.Sprintf("releases[%d]", i)
+
+		if err := json.Unmarshal(raw, &r); err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) {
+				return nil, &SchemaError{
+					Path:    path + "." + typeErr.Field,
+					Reason:  fmt.Sprintf("expected %s, got %s", typeErr.Type, typeErr.Value),
+					Snippet: snippet(raw, typeErr.Offset),
+				}
+			}
+			return nil, &SchemaError{Path: path, Reason: err.Error(), Snippet: snippet(raw, 0)}
+		}
+
+		if r.Version == "" {
+			return nil, &SchemaError{Path: path + ".version", Reason: "missing required field", Snippet: snippet(raw, 0)}
+		}
+		for j, f := range r.Files {
+			if f.Filename == "" {
+				var files struct{ Files []json.RawMessage }
+				json.Unmarshal(raw, &files)
+
+				return nil, &SchemaError{
+					Path:    fmt.Sprintf("%s.files[%d].filename", path, j),
+					Reason:  "missing required field",
+					Snippet: snippet(files.Files[j], 0),
+				}
+			}
+		}
+
+		releases = append(releases, r)
+	}
+
+	return releases, nil
+}
+
+// feedAnomaly is an inconsistency of the releases feed, which go-dl works
+// around but which can reveal a proxy altering the feed.
+type feedAnomaly struct {
+	kind     string
+	version  string
+	filename string
+}
+
+// findFeedAnomalies returns the duplicate versions and files of releases,
+// and the files with a missing or malformed sha256.
+func findFeedAnomalies(releases []Release) []feedAnomaly {
+	var anomalies []feedAnomaly
+	seen := map[string]bool{}
+	files := map[string]bool{}
+
+	for _, r := range releases {
+		if seen[r.Version] {
+			anomalies = append(anomalies, feedAnomaly{kind: "duplicate version", version: r.Version})
+		}
+		seen[r.Version] = true
+
+		for _, f := range r.Files {
+			if files[f.Filename] {
+				anomalies = append(anomalies, feedAnomaly{kind: "duplicate file", version: r.Version, filename: f.Filename})
+			}
+			files[f.Filename] = true
+
+			if b, err := hex.DecodeString(f.Sha256); f.Sha256 == "" {
+				anomalies = append(anomalies, feedAnomaly{kind: "missing sha256", version: r.Version, filename: f.Filename})
+			} else if err != nil || len(b) != 32 {
+				anomalies = append(anomalies, feedAnomaly{kind: "malformed sha256", version: r.Version, filename: f.Filename})
+			}
+		}
+	}
+	return anomalies
+}
+
+// warnFeedAnomalies logs the anomalies of the feed at url.
+func warnFeedAnomalies(url string, releases []Release) {
+	for _, a := range findFeedAnomalies(releases) {
+		attrs := []any{"anomaly", a.kind, "feed", url, "version", a.version}
+		if a.filename != "" {
+			attrs = append(attrs, "file", a.filename)
+		}
+		slog.Warn("the releases feed is inconsistent, a proxy may be altering it", attrs...)
+	}
+}
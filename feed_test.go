@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeReleasesTolerant(t *testing.T) {
+	feed := `[{"version":"go1.22.1","stable":true,"released":"2024-03-05","files":[{"filename":"go1.22.1.src.tar.gz","kind":"source","signature":"..."}]},{"version":"go1.23rc1"}]`
+
+	got, err := decodeReleases([]byte(feed))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(got) != 2 || got[0].Files[0].Filename != "go1.22.1.src.tar.gz" || got[1].Files != nil {
+		t.Errorf("Unexpected releases %+v", got)
+	}
+}
+
+func TestDecodeReleasesSchemaError(t *testing.T) {
+	tests := []struct {
+		name string
+		feed string
+		want string
+	}{
+		{"not an array", `{"releases":[]}`, "expected an array of releases"},
+		{"syntax", `[{"version":"go1.22.1",}]`, "invalid character"},
+		{"type", `[{"version":"go1.22.1","files":[{"filename":"a.tar.gz","size":"big"}]}]`, `size: expected int, got string near`},
+		{"missing version", `[{"stable":true}]`, "releases[0].version: missing required field"},
+		{"missing filename", `[{"version":"go1.22.1","files":[{"filename":"a.tar.gz"},{"os":"linux"}]}]`, "releases[0].files[1].filename: missing required field near `{\"os\":\"linux\"}`"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeReleases([]byte(tt.feed))
+
+			var schemaErr *SchemaError
+			if !errors.As(err, &schemaErr) || !errors.Is(err, ErrSchema) {
+				t.Fatalf("Expected a SchemaError, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error to contain %q, got %q", tt.want, err.Error())
+			}
+		})
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	raw := []byte(strings.Repeat("a", 100) + "\n  OFFENDING  \n" + strings.Repeat("b", 100))
+
+	got := snippet(raw, 103)
+	if !strings.HasPrefix(got, "…") || !strings.HasSuffix(got, "…") || !strings.Contains(got, " OFFENDING ") {
+		t.Errorf("Unexpected snippet %q", got)
+	}
+}
+
+func TestFindFeedAnomalies(t *testing.T) {
+	sha := strings.Repeat("ab", 32)
+	releases := []Release{
+		{Version: "go1.22.1", Files: []File{
+			{Filename: "go1.22.1.linux-amd64.tar.gz", Sha256: sha},
+			{Filename: "go1.22.1.darwin-arm64.tar.gz"},
+			{Filename: "go1.22.1.linux-arm64.tar.gz", Checksums: map[string]string{"sha512": sha + sha}},
+		}},
+		{Version: "go1.22.1", Files: []File{
+			{Filename: "go1.22.1.linux-amd64.tar.gz", Sha256: "not-hex"},
+		}},
+		{Version: "go1.21.8", Files: []File{
+			{Filename: "go1.21.8.linux-amd64.tar.gz", Sha256: sha[:32]},
+		}},
+	}
+
+	want := []feedAnomaly{
+		{kind: "missing sha256", version: "go1.22.1", filename: "go1.22.1.darwin-arm64.tar.gz"},
+		{kind: "missing sha256", version: "go1.22.1", filename: "go1.22.1.linux-arm64.tar.gz"},
+		{kind: "duplicate version", version: "go1.22.1"},
+		{kind: "duplicate file", version: "go1.22.1", filename: "go1.22.1.linux-amd64.tar.gz"},
+		{kind: "malformed sha256", version: "go1.22.1", filename: "go1.22.1.linux-amd64.tar.gz"},
+		{kind: "malformed sha256", version: "go1.21.8", filename: "go1.21.8.linux-amd64.tar.gz"},
+	}
+	if got := findFeedAnomalies(releases); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected anomalies %+v, got %+v", want, got)
+	}
+}
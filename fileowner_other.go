@@ -0,0 +1,16 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// fileOwner is unknown on this platform, the files keep the owner of the
+// process.
+func fileOwner(info os.FileInfo) Owner {
+	return processOwner
+}
+
+// fileInode is unknown on this platform.
+func fileInode(info os.FileInfo) uint64 {
+	return 0
+}
@@ -0,0 +1,25 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileOwner returns the owner of the file described by info.
+func fileOwner(info os.FileInfo) Owner {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return Owner{Uid: int(st.Uid), Gid: int(st.Gid)}
+	}
+	return processOwner
+}
+
+// fileInode returns the inode of the file described by info, or 0 when it is
+// unknown.
+func fileInode(info os.FileInfo) uint64 {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Ino)
+	}
+	return 0
+}
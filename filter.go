@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// minimalExcludes is the optional payload of a release left out by
+// --minimal: the API files, the documentation, the misc files and the tests
+// of the toolchain, none of which is needed to build programs.
+var minimalExcludes = []string{"/api", "/doc", "/misc", "/test", "testdata"}
+
+// minimalIncludes are kept by --minimal all the same, misc/wasm holds the
+// support files GOOS=js needs to run its programs.
+var minimalIncludes = []string{"/misc/wasm"}
+
+// archiveFilter selects the files of a release extracted, by their path
+// relative to the go directory.
+//
+// A pattern starting with / matches from the go directory, others match at
+// any depth, and a pattern matching a directory matches everything below
+// it. The patterns are shell patterns, as with path.Match. The files matched
+// by Exclude are skipped, unless they are matched by Include. The filter is
+// recorded in the pipeline state and in the provenance, so resuming or
+// checking an installation leaves out the same files.
+type archiveFilter struct {
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// newArchiveFilter returns the filter of the comma-separated include and
+// exclude patterns, minimal also excludes minimalExcludes.
+func newArchiveFilter(minimal bool, include, exclude string) (archiveFilter, error) {
+	var filter archiveFilter
+	if minimal {
+		filter.Include = append(filter.Include, minimalIncludes...)
+		filter.Exclude = append(filter.Exclude, minimalExcludes...)
+	}
+
+	for _, list := range []struct {
+		patterns string
+		into     *[]string
+	}{{include, &filter.Include}, {exclude, &filter.Exclude}} {
+		for _, pattern := range strings.Split(list.patterns, ",") {
+			pattern = strings.TrimSpace(pattern)
+			if pattern == "" {
+				continue
+			}
+			if _, err := path.Match(strings.TrimPrefix(pattern, "/"), ""); err != nil {
+				return filter, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+			}
+			*list.into = append(*list.into, pattern)
+		}
+	}
+	return filter, nil
+}
+
+// empty reports whether the filter leaves no file out.
+func (f archiveFilter) empty() bool {
+	return len(f.Exclude) == 0
+}
+
+// Skip reports whether the archive entry name, such as go/doc/go_spec.html,
+// is left out.
+func (f archiveFilter) Skip(name string) bool {
+	if f.empty() {
+		return false
+	}
+
+	// The names are relative to the go directory of the archive.
+	_, rel, ok := strings.Cut(strings.TrimSuffix(name, "/"), "/")
+	if !ok || rel == "" {
+		return false
+	}
+	return matchAny(f.Exclude, rel) && !matchAny(f.Include, rel)
+}
+
+// matchAny reports whether a pattern matches rel or one of its parent
+// directories.
+func matchAny(patterns []string, rel string) bool {
+	parts := strings.Split(rel, "/")
+	for _, pattern := range patterns {
+		anchored := strings.HasPrefix(pattern, "/")
+		pattern = strings.TrimPrefix(pattern, "/")
+		depth := strings.Count(pattern, "/") + 1
+
+		for start := 0; start+depth <= len(parts); start++ {
+			if ok, _ := path.Match(pattern, strings.Join(parts[start:start+depth], "/")); ok {
+				return true
+			}
+			if anchored {
+				break
+			}
+		}
+	}
+	return false
+}
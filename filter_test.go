@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestArchiveFilter(t *testing.T) {
+	filter, err := newArchiveFilter(true, "/misc/wasm", "*.bat")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for name, skip := range map[string]bool{
+		"go/":                              false,
+		"go/VERSION":                       false,
+		"go/api/go1.22.txt":                true,
+		"go/doc/":                          true,
+		"go/doc/go_spec.html":              true,
+		"go/misc/cgo/gmp/gmp.go":           true,
+		"go/misc/wasm/wasm_exec.js":        false,
+		"go/test/fixedbugs/bug000.go":      true,
+		"go/src/net/http/testdata/foo.txt": true,
+		"go/src/cmd/api/main_test.go":      false,
+		"go/src/make.bat":                  true,
+		"go/src/net/http/server.go":        false,
+	} {
+		if got := filter.Skip(name); got != skip {
+			t.Errorf("Expected Skip(%q) to be %v, got %v", name, skip, got)
+		}
+	}
+
+	if (archiveFilter{}).Skip("go/doc/go_spec.html") {
+		t.Errorf("Expected the empty filter to extract everything")
+	}
+	if _, err := newArchiveFilter(false, "", "[doc"); err == nil {
+		t.Errorf("Expected invalid patterns to be rejected")
+	}
+}
+
+func TestDecompressMinimal(t *testing.T) {
+	archive := newTestArchive(t, map[string]string{
+		"go/VERSION":                    "go1.22.1\n",
+		"go/doc/go_spec.html":           "spec",
+		"go/misc/cgo/gmp/gmp.go":        "package main",
+		"go/misc/wasm/wasm_exec.js":     "wasm",
+		"go/src/fmt/testdata/input.txt": "input",
+		"go/src/fmt/print.go":           "package fmt",
+	})
+	filter, _ := newArchiveFilter(true, "", "")
+
+	dst := t.TempDir()
+	if err := decompress(dst, bytes.NewReader(archive), extractProgress{}, archiveOptions{filter: filter}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for name, extracted := range map[string]bool{
+		"go/VERSION":                    true,
+		"go/src/fmt/print.go":           true,
+		"go/misc/wasm/wasm_exec.js":     true,
+		"go/doc/go_spec.html":           false,
+		"go/misc/cgo/gmp/gmp.go":        false,
+		"go/src/fmt/testdata/input.txt": false,
+	} {
+		if _, err := os.Stat(filepath.Join(dst, name)); (err == nil) != extracted {
+			t.Errorf("Expected %s extracted: %v, got %v", name, extracted, err)
+		}
+	}
+
+	goroot := filepath.Join(dst, "go")
+	if report, err := checkInstallation(goroot, bytes.NewReader(archive), filter); err != nil || !report.Ok() || report.Checked != 3 {
+		t.Errorf("Expected the files left out not to be reported missing, got %+v (%v)", report, err)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// reportDumpSize is the number of bytes dumped from each end of the archive.
+const reportDumpSize = 1024
+
+// writeMismatchReport writes the details of a checksum mismatch of archive in
+// dir, to tell a truncated download from altered content. source is where
+// the archive came from, it returns the path of the report.
+func writeMismatchReport(dir string, dlf File, archive, source string) (string, error) {
+	f, err := os.Open(archive)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	sum := dlf.Checksum()
+	h := sum.New()
+	size, err := copyBuffered(h, f)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "file:            %s\n", dlf.Filename)
+	fmt.Fprintf(&b, "expected %s: %s\n", sum.Algorithm, sum.Sum)
+	fmt.Fprintf(&b, "actual %s:   %s\n", sum.Algorithm, hex.EncodeToString(h.Sum(nil)))
+	fmt.Fprintf(&b, "expected size:   %d\n", dlf.Size)
+	fmt.Fprintf(&b, "actual size:     %d\n", size)
+	fmt.Fprintf(&b, "source:          %s\n", source)
+	fmt.Fprintf(&b, "proxy:           %s\n", reportProxy(source))
+	fmt.Fprintf(&b, "diagnosis:       %s\n", diagnoseMismatch(int64(dlf.Size), size))
+
+	head, tail, err := dumpEnds(f, size)
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(&b, "\nfirst %d bytes:\n%s", len(head), hex.Dump(head))
+	fmt.Fprintf(&b, "\nlast %d bytes:\n%s", len(tail), hex.Dump(tail))
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("mismatch-%s-%s.txt", dlf.Filename, time.Now().UTC().Format("20060102T150405Z")))
+	return path, os.WriteFile(path, []byte(b.String()), 0644)
+}
+
+func diagnoseMismatch(expected, actual int64) string {
+	switch {
+	case expected == 0:
+		return "the expected size is unknown"
+	case actual < expected:
+		return fmt.Sprintf("truncated, %d bytes missing", expected-actual)
+	case actual > expected:
+		return fmt.Sprintf("%d extra bytes, the content was altered (error page, captive portal or tampering)", actual-expected)
+	}
+	return "the size matches but the content differs, the archive was corrupted or tampered with"
+}
+
+// reportProxy returns the proxy used to download from source.
+func reportProxy(source string) string {
+	req, err := http.NewRequest(http.MethodGet, source, nil)
+	if err != nil || req.URL.Host == "" {
+		return "-"
+	}
+
+	proxy, err := proxyFromEnvironment(os.Getenv)(req)
+	if err != nil {
+		return err.Error()
+	}
+	if proxy == nil {
+		return "none"
+	}
+	return proxy.Redacted()
+}
+
+func dumpEnds(f io.ReaderAt, size int64) ([]byte, []byte, error) {
+	head := make([]byte, min(size, reportDumpSize))
+	if _, err := f.ReadAt(head, 0); err != nil && err != io.EOF {
+		return nil, nil, err
+	}
+
+	tail := make([]byte, min(size, reportDumpSize))
+	if _, err := f.ReadAt(tail, size-int64(len(tail))); err != nil && err != io.EOF {
+		return nil, nil, err
+	}
+	return head, tail, nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteMismatchReport(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "go1.22.1.linux-amd64.tar.gz")
+	if err := os.WriteFile(archive, bytes.Repeat([]byte{0x1f}, 3000), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dlf := File{Filename: "go1.22.1.linux-amd64.tar.gz", Sha256: "deadbeef", Size: 4000}
+	path, err := writeMismatchReport(dir, dlf, archive, "https://dl.google.com/go/go1.22.1.linux-amd64.tar.gz")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := string(b)
+
+	for _, want := range []string{
+		"expected sha256: deadbeef",
+		"actual size:     3000",
+		"source:          https://dl.google.com/go/go1.22.1.linux-amd64.tar.gz",
+		"truncated, 1000 bytes missing",
+		"first 1024 bytes:\n00000000  1f 1f",
+		"last 1024 bytes:",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("Expected the report to contain %q, got:\n%s", want, report)
+		}
+	}
+}
+
+func TestDiagnoseMismatch(t *testing.T) {
+	for _, tc := range []struct {
+		expected, actual int64
+		want             string
+	}{
+		{0, 10, "unknown"},
+		{10, 5, "truncated"},
+		{10, 15, "extra bytes"},
+		{10, 10, "size matches"},
+	} {
+		if got := diagnoseMismatch(tc.expected, tc.actual); !strings.Contains(got, tc.want) {
+			t.Errorf("Expected diagnosis of %d/%d to mention %q, got %q", tc.actual, tc.expected, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !(linux || darwin || freebsd)
+
+package main
+
+import "errors"
+
+func diskSpace(path string) (int64, uint64, error) {
+	return 0, 0, errors.ErrUnsupported
+}
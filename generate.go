@@ -0,0 +1,266 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// unameArch lists the names uname -m gives the architectures of the
+// archives, besides the GOARCH and TARGETARCH ones.
+var unameArch = map[string][]string{
+	"amd64":   {"x86_64"},
+	"arm64":   {"aarch64"},
+	"386":     {"i386", "i686"},
+	"armv6l":  {"arm", "armv7l"},
+	"loong64": {"loongarch64"},
+}
+
+// nixArch are the names of the architectures of the archives in the Nix
+// systems, such as x86_64-linux.
+var nixArch = map[string]string{
+	"amd64":   "x86_64",
+	"arm64":   "aarch64",
+	"386":     "i686",
+	"armv6l":  "armv6l",
+	"ppc64le": "powerpc64le",
+	"riscv64": "riscv64",
+	"s390x":   "s390x",
+	"loong64": "loongarch64",
+}
+
+// provisionFiles returns the archives of release for the operating systems
+// oses, restricted to platforms when given, sorted by architecture. They
+// must all have a checksum with the same algorithm, which is returned.
+func provisionFiles(release Release, oses []string, platforms []string) ([]File, string, error) {
+	var files []File
+	algorithm := ""
+	for _, f := range release.Files {
+		if !isExtractable(f) || !slices.Contains(oses, f.Os) {
+			continue
+		}
+		if len(platforms) > 0 && !slices.Contains(platforms, f.Os+"/"+f.Arch) {
+			continue
+		}
+		sum := f.Checksum()
+		if sum.Sum == "" {
+			return nil, "", fmt.Errorf("%s has no published checksum to pin", f.Filename)
+		}
+		if algorithm != "" && sum.Algorithm != algorithm {
+			return nil, "", fmt.Errorf("the archives of %s are published with both %s and %s checksums", release.Version, algorithm, sum.Algorithm)
+		}
+		algorithm = sum.Algorithm
+		files = append(files, f)
+	}
+	if len(files) == 0 {
+		target := oses
+		if len(platforms) > 0 {
+			target = platforms
+		}
+		return nil, "", fmt.Errorf("%w for %s on %s", ErrNoMatchingFile, release.Version, strings.Join(target, ", "))
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Os+files[i].Arch < files[j].Os+files[j].Arch })
+	return files, algorithm, nil
+}
+
+// installLines returns the shell lines downloading, verifying and extracting
+// the archive of the machine among files to /usr/local/go. arch is the
+// expression of the architecture.
+func installLines(version, url, arch, algorithm string, files []File) []string {
+	lines := []string{fmt.Sprintf(`arch="%s"`, arch), `case "$arch" in`}
+	for _, f := range files {
+		patterns := append([]string{f.Arch}, unameArch[f.Arch]...)
+		lines = append(lines, fmt.Sprintf("    %s) file=%s; sum=%s ;;", strings.Join(patterns, "|"), f.Filename, f.Checksum().Sum))
+	}
+	return append(lines,
+		fmt.Sprintf(`    *) echo "%s has no archive for $arch" >&2; exit 1 ;;`, version),
+		`esac`,
+		fmt.Sprintf(`curl -fsSL -o /tmp/go.tar.gz "%s/$file"`, url),
+		fmt.Sprintf(`echo "$sum  /tmp/go.tar.gz" | %ssum -c -`, algorithm),
+		`rm -rf /usr/local/go`,
+		`tar -C /usr/local -xzf /tmp/go.tar.gz`,
+		`rm /tmp/go.tar.gz`,
+	)
+}
+
+// dockerfileSnippet returns the Dockerfile instructions installing release
+// for the target architecture of the build.
+func dockerfileSnippet(release Release, url, algorithm string, files []File) string {
+	lines := installLines(release.Version, url, "${TARGETARCH:-$(uname -m)}", algorithm, files)
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Go %s, pinned by go-dl to the checksums of the releases feed.\n", release.Version)
+	b.WriteString("ARG TARGETARCH\nRUN set -eux; \\\n")
+	for i, l := range lines {
+		sep := "; \\"
+		switch {
+		case i == len(lines)-1:
+			sep = ""
+		case strings.HasSuffix(l, ";;") || strings.HasSuffix(l, " in"):
+			sep = " \\"
+		}
+		fmt.Fprintf(&b, "    %s%s\n", l, sep)
+	}
+	b.WriteString("ENV PATH=/usr/local/go/bin:$PATH\n")
+	return b.String()
+}
+
+// devcontainerFeature returns the devcontainer-feature.json and install.sh
+// of a feature installing release.
+func devcontainerFeature(release Release, url, algorithm string, files []File) (string, string, error) {
+	var platforms []string
+	for _, f := range files {
+		platforms = append(platforms, f.Os+"/"+f.Arch)
+	}
+	feature := map[string]any{
+		"id":          "go",
+		"version":     strings.TrimPrefix(release.Version, "go"),
+		"name":        "Go " + release.Version,
+		"description": fmt.Sprintf("Installs Go %s for %s, pinned by go-dl to the checksums of the releases feed", release.Version, strings.Join(platforms, ", ")),
+		"containerEnv": map[string]string{
+			"GOROOT": "/usr/local/go",
+			"PATH":   "/usr/local/go/bin:${PATH}",
+		},
+	}
+	b, err := json.MarshalIndent(feature, "", "  ")
+	if err != nil {
+		return "", "", err
+	}
+
+	script := fmt.Sprintf("#!/bin/sh\n# Installs Go %s, pinned by go-dl to the checksums of the releases feed.\nset -eu\n", release.Version)
+	script += strings.Join(installLines(release.Version, url, "$(uname -m)", algorithm, files), "\n") + "\n"
+	return string(b) + "\n", script, nil
+}
+
+// nixExpression returns a Nix derivation of the archives of release, for
+// the systems of files.
+func nixExpression(release Release, url, algorithm string, files []File) (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Go %s, pinned by go-dl to the checksums of the releases feed.\n", release.Version)
+	b.WriteString("{ lib, stdenv, fetchurl }:\n\nlet\n  sources = {\n")
+	for _, f := range files {
+		arch, ok := nixArch[f.Arch]
+		if !ok {
+			continue
+		}
+		sum, err := hex.DecodeString(f.Checksum().Sum)
+		if err != nil {
+			return "", fmt.Errorf("invalid checksum of %s: %w", f.Filename, err)
+		}
+		fmt.Fprintf(&b, "    %s-%s = fetchurl {\n      url = \"%s/%s\";\n      hash = \"%s-%s\";\n    };\n",
+			arch, f.Os, url, f.Filename, algorithm, base64.StdEncoding.EncodeToString(sum))
+	}
+	fmt.Fprintf(&b, `  };
+in
+stdenv.mkDerivation {
+  pname = "go-bin";
+  version = "%s";
+  src = sources.${stdenv.hostPlatform.system} or (throw "go %s has no archive for ${stdenv.hostPlatform.system}");
+  dontStrip = true;
+  installPhase = ''
+    mkdir -p $out/share/go $out/bin
+    cp -r . $out/share/go
+    ln -s $out/share/go/bin/* $out/bin/
+  '';
+  meta.platforms = lib.attrNames sources;
+}
+`, strings.TrimPrefix(release.Version, "go"), release.Version)
+	return b.String(), nil
+}
+
+// generate prints the provisioning snippets installing a release, pinned to
+// the checksums of the feed.
+func (c *cli) generate(args []string) error {
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	platforms := fs.String("platforms", "", "comma-separated os/arch of the archives, e.g. linux/amd64,linux/arm64 (default all)")
+	output := fs.String("output", "", "file, or directory for devcontainer-feature, receiving the snippet instead of the standard output")
+	baseURL := fs.String("url", defaultMirror, "base URL the snippets download the archives from, go.dev rather than the mirror of go-dl by default")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-dl generate devcontainer-feature|dockerfile-snippet|nix [--platforms list] [--output path] [--url base] [version constraint]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// The flags may also follow the kind of snippet.
+	kind := fs.Arg(0)
+	if err := fs.Parse(fs.Args()[min(1, fs.NArg()):]); err != nil {
+		return err
+	}
+	oses := map[string][]string{
+		"devcontainer-feature": {"linux"},
+		"dockerfile-snippet":   {"linux"},
+		"nix":                  {"linux", "darwin"},
+	}[kind]
+	if oses == nil {
+		fs.Usage()
+		return fmt.Errorf("unknown snippet %q", kind)
+	}
+
+	query, err := c.query(fs)
+	if err != nil {
+		return err
+	}
+	releases, err := c.repo.GetVersions(c.ctx)
+	if err != nil {
+		return err
+	}
+	release, err := resolveRelease(releases, query)
+	if err != nil {
+		return err
+	}
+	var only []string
+	if *platforms != "" {
+		only = strings.Split(*platforms, ",")
+	}
+	files, algorithm, err := provisionFiles(release, oses, only)
+	if err != nil {
+		return err
+	}
+
+	// The snippets run without go-dl, where its mirror, which may be
+	// private, is not necessarily reachable.
+	url := strings.TrimSuffix(*baseURL, "/")
+	var snippet string
+	switch kind {
+	case "dockerfile-snippet":
+		snippet = dockerfileSnippet(release, url, algorithm, files)
+	case "nix":
+		if snippet, err = nixExpression(release, url, algorithm, files); err != nil {
+			return err
+		}
+	case "devcontainer-feature":
+		feature, script, err := devcontainerFeature(release, url, algorithm, files)
+		if err != nil {
+			return err
+		}
+		if *output == "" {
+			fmt.Fprintf(c.stdout, "# devcontainer-feature.json\n%s\n# install.sh\n%s", feature, script)
+			return nil
+		}
+		if err := writeFile(filepath.Join(*output, "devcontainer-feature.json"), strings.NewReader(feature), 0644); err != nil {
+			return err
+		}
+		if err := writeFile(filepath.Join(*output, "install.sh"), strings.NewReader(script), 0755); err != nil {
+			return err
+		}
+		fmt.Fprintf(c.stdout, "Wrote the feature installing %s to %s\n", release.Version, *output)
+		return nil
+	}
+
+	if *output == "" {
+		_, err = fmt.Fprint(c.stdout, snippet)
+		return err
+	}
+	if err := writeFile(*output, strings.NewReader(snippet), 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stdout, "Wrote the snippet installing %s to %s\n", release.Version, *output)
+	return nil
+}
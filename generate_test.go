@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var provisionRelease = Release{Version: "go1.22.1", Files: []File{
+	{Filename: "go1.22.1.linux-arm64.tar.gz", Os: "linux", Arch: "arm64", Sha256: strings.Repeat("ab", 32), Kind: "archive"},
+	{Filename: "go1.22.1.linux-amd64.tar.gz", Os: "linux", Arch: "amd64", Sha256: strings.Repeat("cd", 32), Kind: "archive"},
+	{Filename: "go1.22.1.darwin-arm64.tar.gz", Os: "darwin", Arch: "arm64", Sha256: strings.Repeat("ef", 32), Kind: "archive"},
+	{Filename: "go1.22.1.darwin-arm64.pkg", Os: "darwin", Arch: "arm64", Sha256: strings.Repeat("01", 32), Kind: "installer"},
+}}
+
+func TestProvisionFiles(t *testing.T) {
+	files, algorithm, err := provisionFiles(provisionRelease, []string{"linux"}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if algorithm != "sha256" || len(files) != 2 || files[0].Arch != "amd64" || files[1].Arch != "arm64" {
+		t.Errorf("Expected the linux archives by architecture, got %s %v", algorithm, files)
+	}
+
+	if _, _, err := provisionFiles(provisionRelease, []string{"linux"}, []string{"linux/s390x"}); !errors.Is(err, ErrNoMatchingFile) {
+		t.Errorf("Expected no archive for linux/s390x, got %v", err)
+	}
+}
+
+func TestDockerfileSnippet(t *testing.T) {
+	files, algorithm, _ := provisionFiles(provisionRelease, []string{"linux"}, nil)
+	snippet := dockerfileSnippet(provisionRelease, "https://go.dev/dl", algorithm, files)
+
+	for _, want := range []string{
+		"amd64|x86_64) file=go1.22.1.linux-amd64.tar.gz; sum=" + strings.Repeat("cd", 32) + " ;; \\\n",
+		"arm64|aarch64) file=go1.22.1.linux-arm64.tar.gz",
+		`curl -fsSL -o /tmp/go.tar.gz "https://go.dev/dl/$file"; \`,
+		"sha256sum -c -",
+		"ENV PATH=/usr/local/go/bin:$PATH\n",
+	} {
+		if !strings.Contains(snippet, want) {
+			t.Errorf("Expected %q in the snippet, got:\n%s", want, snippet)
+		}
+	}
+}
+
+func TestNixExpression(t *testing.T) {
+	files, algorithm, _ := provisionFiles(provisionRelease, []string{"linux", "darwin"}, nil)
+	nix, err := nixExpression(provisionRelease, "https://go.dev/dl", algorithm, files)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, want := range []string{"aarch64-darwin = fetchurl", "x86_64-linux = fetchurl", `hash = "sha256-q6urq6urq6urq6urq6urq6urq6urq6urq6urq6urq6s=";`, `version = "1.22.1";`} {
+		if !strings.Contains(nix, want) {
+			t.Errorf("Expected %q in the expression, got:\n%s", want, nix)
+		}
+	}
+}
+
+func TestGenerateDevcontainerFeature(t *testing.T) {
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n"})
+	c, _ := newTestCLI(t, archive)
+	dir := t.TempDir()
+
+	if err := c.run([]string{"generate", "devcontainer-feature", "--output", dir, "1.22"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, "devcontainer-feature.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var feature struct{ ID, Version string }
+	if err := json.Unmarshal(b, &feature); err != nil || feature.ID != "go" || feature.Version != "1.22.1" {
+		t.Errorf("Expected the feature of go 1.22.1, got %+v (%v)", feature, err)
+	}
+
+	script := filepath.Join(dir, "install.sh")
+	if _, err := exec.LookPath("sh"); err == nil {
+		if out, err := exec.Command("sh", "-n", script).CombinedOutput(); err != nil {
+			t.Errorf("Expected a valid script, got %v: %s", err, out)
+		}
+	}
+}
+
+func TestGenerateURL(t *testing.T) {
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n"})
+	c, out := newTestCLI(t, archive)
+	c.repo.url = "https://mirror.corp/go"
+	output := filepath.Join(t.TempDir(), "Dockerfile.go")
+
+	// The private mirror of go-dl is not embedded in the snippets.
+	if err := c.run([]string{"generate", "dockerfile-snippet", "--output", output, "1.22"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	b, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"https://go.dev/dl/$file"`) || strings.Contains(string(b), "mirror.corp") {
+		t.Errorf("Expected the snippet to download from go.dev, got:\n%s", b)
+	}
+
+	out.Reset()
+	if err := c.run([]string{"generate", "dockerfile-snippet", "--url", "https://cdn.example.com/go/", "1.22"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), `"https://cdn.example.com/go/$file"`) {
+		t.Errorf("Expected the snippet to download from --url, got:\n%s", out)
+	}
+}
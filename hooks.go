@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/blckfalcon/go-dl/versions"
+)
+
+// HookConfig holds the commands run through the shell before extracting a
+// version, pre_install, and once it is installed, post_install.
+type HookConfig struct {
+	PreInstall  []string `json:"pre_install,omitempty"`
+	PostInstall []string `json:"post_install,omitempty"`
+}
+
+// installHooks are the hooks of every installation, and those of the
+// versions matching a constraint.
+type installHooks struct {
+	global   HookConfig
+	versions map[string]HookConfig
+}
+
+// of returns the hooks of version, the ones of every installation first,
+// then those of the constraints it matches, sorted.
+func (h installHooks) of(version string) HookConfig {
+	hooks := HookConfig{
+		PreInstall:  slices.Clone(h.global.PreInstall),
+		PostInstall: slices.Clone(h.global.PostInstall),
+	}
+	constraints := make([]string, 0, len(h.versions))
+	for constraint := range h.versions {
+		constraints = append(constraints, constraint)
+	}
+	slices.Sort(constraints)
+	for _, constraint := range constraints {
+		if ok, err := versions.Matches(version, constraint); err != nil || !ok {
+			continue
+		}
+		hooks.PreInstall = append(hooks.PreInstall, h.versions[constraint].PreInstall...)
+		hooks.PostInstall = append(hooks.PostInstall, h.versions[constraint].PostInstall...)
+	}
+	return hooks
+}
+
+// preInstall returns a function running the pre_install hooks of version
+// with the environment of go-dl, GO_DL_VERSION set to the version.
+func (c *cli) preInstall(version string) func(ctx context.Context) error {
+	hooks := c.hooks.of(version).PreInstall
+	return func(ctx context.Context) error {
+		env := append(os.Environ(), "GO_DL_VERSION="+version)
+		return runHooks(ctx, "pre_install", hooks, env, c.owner, c.stdout)
+	}
+}
+
+// runHooks runs the command lines of a hook stage in order, with env, as
+// owner when go-dl runs as root, and stops at the first failure.
+func runHooks(ctx context.Context, stage string, hooks []string, env []string, owner Owner, w io.Writer) error {
+	if len(hooks) > 0 {
+		if err := execTemp.prepare(); err != nil {
+			return fmt.Errorf("unable to run the %s hooks: %w", stage, err)
+		}
+		// env may predate the setup of the temporary directory.
+		for _, name := range []string{"TMPDIR", "GOTMPDIR"} {
+			if dir := os.Getenv(name); dir != "" {
+				env = append(env, name+"="+dir)
+			}
+		}
+	}
+	for _, line := range hooks {
+		var cmd *exec.Cmd
+		if runtime.GOOS == "windows" {
+			cmd = exec.CommandContext(ctx, "cmd", "/C", line)
+		} else {
+			cmd = exec.CommandContext(ctx, "sh", "-c", line)
+		}
+		cmd.Env = env
+		cmd.Stdout = w
+		cmd.Stderr = w
+		runAs(cmd, owner)
+
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s hook %q failed: %w", stage, line, err)
+		}
+	}
+	return nil
+}
+
+// postInstallEnv returns the environment of the post_install hooks of
+// version: the one of the installation at goroot, GO_DL_VERSION set to the
+// version.
+func postInstallEnv(goroot, version string) []string {
+	return append(toolchainEnv(os.Environ(), goroot), "GO_DL_VERSION="+version)
+}
+
+// toolchainEnv returns environ set up to use the Go installation at goroot:
+// its go command comes first in PATH and it does not switch toolchains.
+func toolchainEnv(environ []string, goroot string) []string {
+	env := make([]string, 0, len(environ)+3)
+	path := ""
+	for _, kv := range environ {
+		name, value, _ := strings.Cut(kv, "=")
+		switch strings.ToUpper(name) {
+		case "PATH":
+			path = value
+		case "GOROOT", "GOTOOLCHAIN":
+		default:
+			env = append(env, kv)
+		}
+	}
+
+	bin := filepath.Join(goroot, "bin")
+	if path != "" {
+		bin += string(filepath.ListSeparator) + path
+	}
+	return append(env, "PATH="+bin, "GOROOT="+goroot, "GOTOOLCHAIN=local")
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package main
+
+import "os/exec"
+
+// runAs runs cmd as the process owner, owners are not supported on this
+// platform.
+func runAs(cmd *exec.Cmd, owner Owner) {}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestToolchainEnv(t *testing.T) {
+	goroot := filepath.Join("opt", "go")
+	sep := string(filepath.ListSeparator)
+
+	got := toolchainEnv([]string{"HOME=/home/gopher", "GOROOT=/old", "PATH=/usr/bin", "GOTOOLCHAIN=auto"}, goroot)
+	want := []string{
+		"HOME=/home/gopher",
+		"PATH=" + filepath.Join(goroot, "bin") + sep + "/usr/bin",
+		"GOROOT=" + goroot,
+		"GOTOOLCHAIN=local",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestRunHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks run through sh")
+	}
+
+	goroot := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(goroot, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	script := "#!/bin/sh\necho \"go $1 from $GOROOT\"\n"
+	if err := os.WriteFile(filepath.Join(goroot, "bin", "go"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	err := runHooks(context.Background(), "post_install", []string{"go install", "false", "go never"}, postInstallEnv(goroot, "go1.22.1"), processOwner, &out)
+	if err == nil || !strings.Contains(err.Error(), `post_install hook "false"`) {
+		t.Errorf("Expected the false hook to fail, got %v", err)
+	}
+	if got, want := out.String(), "go install from "+goroot+"\n"; got != want {
+		t.Errorf("Expected output %q, got %q", want, got)
+	}
+}
+
+func TestInstallHooksOf(t *testing.T) {
+	hooks := installHooks{
+		global: HookConfig{PreInstall: []string{"pre"}, PostInstall: []string{"post"}},
+		versions: map[string]HookConfig{
+			"1.22":   {PostInstall: []string{"post 1.22"}},
+			"<1.22":  {PreInstall: []string{"pre 1.21"}},
+			">=1.21": {PostInstall: []string{"post 1.21+"}},
+		},
+	}
+
+	got := hooks.of("go1.22.1")
+	want := HookConfig{PreInstall: []string{"pre"}, PostInstall: []string{"post", "post 1.22", "post 1.21+"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+	if got := hooks.global.PostInstall; len(got) != 1 {
+		t.Errorf("Expected the global hooks to be left alone, got %v", got)
+	}
+}
+
+func TestPreInstallHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks run through sh")
+	}
+
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n"})
+	c, out := newTestCLI(t, archive)
+	c.prefix = t.TempDir()
+	c.hooks = installHooks{versions: map[string]HookConfig{"1.22": {PreInstall: []string{"echo before $GO_DL_VERSION", "false"}}}}
+
+	if err := c.install([]string{"1.22.1"}); err == nil || !strings.Contains(err.Error(), `pre_install hook "false"`) {
+		t.Fatalf("Expected the failing pre_install hook to abort the installation, got %v", err)
+	}
+	if !strings.Contains(out.String(), "before go1.22.1\n") {
+		t.Errorf("Expected the hook to run with GO_DL_VERSION, got %q", out.String())
+	}
+	if _, err := os.Stat(c.goroot()); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing to be extracted, got %v", err)
+	}
+}
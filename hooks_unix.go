@@ -0,0 +1,25 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// runAs runs cmd as owner when go-dl runs as root, as the files of the
+// installations are given to it.
+func runAs(cmd *exec.Cmd, owner Owner) {
+	if os.Geteuid() != 0 || !owner.isSet() {
+		return
+	}
+	uid, gid := owner.Uid, owner.Gid
+	if uid < 0 {
+		uid = os.Geteuid()
+	}
+	if gid < 0 {
+		gid = os.Getegid()
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{Credential: &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid)}}
+}
@@ -0,0 +1,468 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const defaultPrefix = "/usr/local"
+
+type Phase string
+
+const (
+	PhaseDownloading Phase = "downloading"
+	PhaseDownloaded  Phase = "downloaded"
+	PhaseVerified    Phase = "verified"
+	PhaseExtracted   Phase = "extracted"
+)
+
+// pipelineState is persisted after each phase of an installation so an
+// interrupted install can be resumed where it stopped.
+type pipelineState struct {
+	Version string `json:"version"`
+	File    File   `json:"file"`
+	Archive string `json:"archive"`
+	Prefix  string `json:"prefix"`
+	Owner   Owner  `json:"owner"`
+	Phase   Phase  `json:"phase"`
+	// Bootstrap overrides the toolchain building the source archives.
+	Bootstrap string `json:"bootstrap,omitempty"`
+	// Source is where the archive was downloaded from, and Feed when the
+	// releases feed was fetched, recorded in the provenance.
+	Source string `json:"source,omitempty"`
+	Feed   string `json:"feed,omitempty"`
+	// Filter leaves files of the release out of the installation.
+	Filter archiveFilter `json:"filter"`
+	// Dist is the distribution of the release, empty for go.dev.
+	Dist string `json:"dist,omitempty"`
+}
+
+type pipeline struct {
+	repo      *GoRepository
+	storage   Storage
+	scanner   []string
+	statePath string
+	state     pipelineState
+	cached    bool
+	// evicted is set once the cached archive failed its verification, it is
+	// downloaded from the origin instead.
+	evicted bool
+	// delta, when set, rebuilds the archive from the installed release
+	// rather than downloading it, rebuilt is set once it did.
+	delta   func(ctx context.Context, w io.Writer) (string, error)
+	rebuilt bool
+	// preInstall, when set, runs before the extraction, which its failure
+	// aborts.
+	preInstall func(ctx context.Context) error
+	// toolchains holds the toolchains of go-dl run, which can bootstrap
+	// source builds.
+	toolchains string
+	events     *progressEvents
+
+	// sum is the checksum computed while downloading the archive, it is only
+	// trusted by the process that downloaded it.
+	sum []byte
+	// integrity is the result of the check of the archive structure run
+	// along with sum.
+	integrity error
+	// source is where the archive was downloaded from, for the reports of
+	// checksum mismatches.
+	source string
+}
+
+func isExtractable(f File) bool {
+	return strings.HasSuffix(f.Filename, ".tar.gz")
+}
+
+// feedFetched returns when the releases feed of repo was fetched, as
+// recorded in the provenance.
+func feedFetched(repo *GoRepository) string {
+	if repo.fetched.IsZero() {
+		return ""
+	}
+	return repo.fetched.UTC().Format(time.RFC3339)
+}
+
+func newPipeline(repo *GoRepository, storage Storage, dlf File, prefix string, owner Owner, paths Paths) *pipeline {
+	return &pipeline{
+		repo:       repo,
+		storage:    storage,
+		statePath:  paths.PipelineFile(),
+		toolchains: filepath.Join(paths.Cache, "toolchains"),
+		state: pipelineState{
+			Version: dlf.Version,
+			File:    dlf,
+			Archive: filepath.Join(paths.Cache, "tmp", "go-dl-tmp-"+dlf.Filename),
+			Prefix:  prefix,
+			Owner:   owner,
+			Feed:    feedFetched(repo),
+			Filter:  repo.archive.filter,
+			Dist:    repo.dist,
+		},
+	}
+}
+
+// loadPipeline returns the interrupted installation recorded in the state
+// directory, or nil when there is nothing to resume.
+func loadPipeline(repo *GoRepository, storage Storage, paths Paths) (*pipeline, error) {
+	p := &pipeline{repo: repo, storage: storage, statePath: paths.PipelineFile(), toolchains: filepath.Join(paths.Cache, "toolchains")}
+	p.state.Owner = processOwner
+
+	b, err := os.ReadFile(p.statePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(b, &p.state); err != nil {
+		return nil, fmt.Errorf("invalid pipeline state %s: %w", p.statePath, err)
+	}
+	return p, nil
+}
+
+func (p *pipeline) save(phase Phase) error {
+	p.state.Phase = phase
+
+	b, err := json.Marshal(p.state)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(p.statePath, b, 0644)
+}
+
+func (p *pipeline) expect(phase Phase) error {
+	if p.state.Phase != phase {
+		return fmt.Errorf("unexpected installation phase %q, want %q", p.state.Phase, phase)
+	}
+	return nil
+}
+
+func (p *pipeline) download(ctx context.Context) error {
+	p.events.Step("downloading", p.state.Version)
+	if err := p.save(PhaseDownloading); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p.state.Archive), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(p.state.Archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if p.fromStorage(ctx, f) {
+		p.source = "cache"
+		p.state.Source = p.source
+		return p.save(PhaseDownloaded)
+	}
+	// Reserved once the cache missed, emptying f released the space.
+	if err := preallocate(f, int64(p.state.File.Size)); err != nil {
+		return err
+	}
+
+	if p.delta != nil {
+		err := p.receive(f, func(w io.Writer) (string, error) { return p.delta(ctx, w) })
+		if err == nil {
+			p.rebuilt = true
+			return p.save(PhaseDownloaded)
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		slog.Warn("delta upgrade failed, downloading the full archive", "err", err)
+		p.delta = nil
+		f.Truncate(0)
+		f.Seek(0, io.SeekStart)
+		if err := preallocate(f, int64(p.state.File.Size)); err != nil {
+			return err
+		}
+	}
+
+	err = p.receive(f, func(w io.Writer) (string, error) { return p.repo.download(ctx, p.state.File, w) })
+	if err != nil {
+		return err
+	}
+	return p.save(PhaseDownloaded)
+}
+
+// receive writes the archive from source to f, computing its checksum and
+// checking its structure meanwhile.
+func (p *pipeline) receive(f *os.File, source func(io.Writer) (string, error)) error {
+	h := p.state.File.Checksum().New()
+	w := io.MultiWriter(f, h)
+	var check *streamCheck
+	if isExtractable(p.state.File) {
+		check = newStreamCheck(p.state.File.Filename)
+		w = io.MultiWriter(f, h, check)
+	}
+	var err error
+	p.source, err = source(w)
+	if check != nil {
+		p.integrity = check.Close()
+	}
+	if err != nil {
+		return err
+	}
+	p.sum = h.Sum(nil)
+	p.state.Source = p.source
+	return nil
+}
+
+// fromStorage fills f with the archive kept in storage, it reports false and
+// leaves f empty when the archive has to be downloaded.
+func (p *pipeline) fromStorage(ctx context.Context, f *os.File) bool {
+	if p.storage == nil || p.evicted {
+		return false
+	}
+
+	err := p.storage.Get(ctx, p.state.File.Filename, f)
+	metrics.cache(err == nil)
+	if err == nil {
+		p.cached = true
+		return true
+	}
+	if !errors.Is(err, ErrCacheMiss) {
+		slog.Warn("unable to read archive from cache", "file", p.state.File.Filename, "err", err)
+	}
+
+	f.Truncate(0)
+	f.Seek(0, io.SeekStart)
+	return false
+}
+
+// toStorage keeps a verified archive in storage for later installs.
+func (p *pipeline) toStorage(ctx context.Context) {
+	if p.storage == nil || p.cached {
+		return
+	}
+
+	err := func() error {
+		f, err := os.Open(p.state.Archive)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		info, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		return p.storage.Put(ctx, p.state.File.Filename, f, info.Size())
+	}()
+	if err != nil {
+		slog.Warn("unable to store archive in cache", "file", p.state.File.Filename, "err", err)
+	}
+}
+
+func (p *pipeline) verify(ctx context.Context) error {
+	if err := p.expect(PhaseDownloaded); err != nil {
+		return err
+	}
+	p.events.Step("verifying", p.state.Version)
+
+	var err error
+	if p.repo.requireChecksum && p.state.File.Checksum().Sum == "" {
+		err = fmt.Errorf("%s has no published checksum, required by the system policy or the distribution: %w", p.state.File.Filename, ErrPolicy)
+	} else if p.sum != nil {
+		err = compareChecksum(p.state.Archive, p.state.File.Checksum(), p.sum)
+		if err == nil && isExtractable(p.state.File) {
+			err = p.integrity
+		}
+	} else {
+		err = verifyChecksum(p.state.Archive, p.state.File.Checksum())
+		if err == nil && isExtractable(p.state.File) {
+			err = checkArchive(p.state.Archive)
+		}
+	}
+	if err == nil && isZip(p.state.File) {
+		err = checkZip(p.state.Archive)
+	}
+	if err != nil && p.rebuilt {
+		slog.Warn("the archive rebuilt by the delta failed its verification, downloading the full archive", "file", p.state.File.Filename, "err", err)
+		p.rebuilt, p.delta = false, nil
+		if err := p.download(ctx); err != nil {
+			return err
+		}
+		return p.verify(ctx)
+	}
+	if err != nil && p.cached {
+		// A corrupted or poisoned entry would fail every later install.
+		slog.Warn("the cached archive failed its verification, downloading it again", "file", p.state.File.Filename, "err", err)
+		if errDelete := p.storage.Delete(ctx, p.state.File.Filename); errDelete != nil {
+			slog.Warn("unable to evict the archive from the cache", "file", p.state.File.Filename, "err", errDelete)
+		}
+		p.cached, p.evicted = false, true
+		if err := p.download(ctx); err != nil {
+			return err
+		}
+		return p.verify(ctx)
+	}
+	if errors.Is(err, ErrChecksumMismatch) {
+		err = p.recoverEncoding(err)
+	}
+	if errors.Is(err, ErrChecksumMismatch) {
+		source := p.source
+		if source == "" {
+			source = "unknown, downloaded by a previous run"
+		}
+		if report, errReport := writeMismatchReport(filepath.Dir(p.statePath), p.state.File, p.state.Archive, source); errReport == nil {
+			err = fmt.Errorf("%w, details in %s", err, report)
+		} else {
+			slog.Warn("unable to write the checksum mismatch report", "err", errReport)
+		}
+	}
+	if err != nil {
+		p.save(PhaseDownloading)
+		return err
+	}
+
+	p.toStorage(ctx)
+	return p.save(PhaseVerified)
+}
+
+func (p *pipeline) extract(ctx context.Context) error {
+	if err := p.expect(PhaseVerified); err != nil {
+		return err
+	}
+	p.events.Step("extracting", p.state.Version)
+
+	if err := runScanner(ctx, p.scanner, p.state.Archive); err != nil {
+		return err
+	}
+
+	if !isExtractable(p.state.File) && !isZip(p.state.File) {
+		p.clear()
+		return fmt.Errorf("extraction of %s is not supported, file kept at %s", p.state.File.Filename, p.state.Archive)
+	}
+	if p.preInstall != nil {
+		if err := p.preInstall(ctx); err != nil {
+			return err
+		}
+	}
+
+	if ok, err := canExec(p.state.Prefix); err == nil && !ok {
+		slog.Warn("the installation is on a filesystem mounted noexec, go will not be able to run", "prefix", p.state.Prefix)
+	}
+
+	f, err := os.Open(p.state.Archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	timeout := p.repo.timeouts.extract
+	ctx, cancel := withPhaseTimeout(ctx, timeout)
+	defer cancel()
+	archive := contextReader{ctx, f}
+
+	// A resumed installation leaves out the files of its recorded filter.
+	opts := p.repo.archive
+	opts.filter = p.state.Filter
+	if p.state.File.Kind == KindSource {
+		var bootstrap string
+		if bootstrap, err = bootstrapGoroot(p.state.Version, p.state.Bootstrap, bootstrapCandidates(p.state.Prefix, p.toolchains)); err != nil {
+			return err
+		}
+		err = buildFromSource(ctx, p.state.Prefix, bootstrap, archive, p.state.Owner, p.repo.extractProgress(), opts)
+	} else {
+		err = extractArchive(p.state.Prefix, archive, p.state.Owner, p.repo.extractProgress(), opts)
+	}
+	if err != nil {
+		return phaseError(ctx, "extraction", timeout, err)
+	}
+	if err := writeProvenance(filepath.Join(p.state.Prefix, "go"), p.state, p.state.Archive, p.state.Owner); err != nil {
+		slog.Warn("unable to record the provenance of the installation", "err", err)
+	}
+	if reproducible.enabled {
+		// The files otherwise have the time of the extraction.
+		if err := normalizeTimes(filepath.Join(p.state.Prefix, "go"), reproducible.epoch); err != nil {
+			return err
+		}
+	}
+
+	if err := p.save(PhaseExtracted); err != nil {
+		return err
+	}
+	os.Remove(p.state.Archive)
+	return p.clear()
+}
+
+func (p *pipeline) clear() error {
+	err := os.Remove(p.statePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// run executes the remaining phases of the installation.
+func (p *pipeline) run(ctx context.Context) error {
+	for {
+		var err error
+
+		switch p.state.Phase {
+		case "", PhaseDownloading:
+			err = p.download(ctx)
+		case PhaseDownloaded:
+			err = p.verify(ctx)
+		case PhaseVerified:
+			err = p.extract(ctx)
+		case PhaseExtracted:
+			return p.clear()
+		default:
+			return fmt.Errorf("unknown installation phase %q", p.state.Phase)
+		}
+
+		if err != nil {
+			return wrapPermission(err)
+		}
+	}
+}
+
+// extractArchive replaces the Go installation under prefix with the content
+// of archive, a tar.gz or a zip archive, owned by owner.
+func extractArchive(prefix string, archive io.ReadSeeker, owner Owner, progress extractProgress, opts archiveOptions) error {
+	_, err := archive.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	staging := filepath.Join(prefix, ".go-dl-extract")
+	os.RemoveAll(staging)
+	defer os.RemoveAll(staging)
+
+	if err := os.MkdirAll(staging, 0755); err != nil {
+		return err
+	}
+
+	zipped, err := isZipArchive(archive)
+	if err != nil {
+		return err
+	}
+	if zipped {
+		err = decompressZip(staging, archive, progress, opts)
+	} else {
+		err = decompress(staging, archive, progress, opts)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := chownTree(filepath.Join(staging, "go"), owner); err != nil {
+		return err
+	}
+	return swapInstallation(filepath.Join(prefix, "go"), filepath.Join(staging, "go"))
+}
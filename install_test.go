@@ -0,0 +1,185 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func newTestArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gzw)
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	dirs := map[string]bool{}
+	for _, name := range names {
+		var parents []string
+		for dir := path.Dir(name); dir != "." && !dirs[dir]; dir = path.Dir(dir) {
+			dirs[dir] = true
+			parents = append([]string{dir}, parents...)
+		}
+		for _, dir := range parents {
+			header := &tar.Header{Name: dir + "/", Mode: 0755, Typeflag: tar.TypeDir}
+			if err := tw.WriteHeader(header); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// Binaries and scripts are executable, as in the release archives.
+		mode := int64(0644)
+		if strings.Contains(name, "/bin/") || strings.HasSuffix(name, ".bash") {
+			mode = 0755
+		}
+
+		content := files[name]
+		header := &tar.Header{Name: name, Size: int64(len(content)), Mode: mode, Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tw.Close()
+	gzw.Close()
+	return buf.Bytes()
+}
+
+func newTestArchiveRepo(archive []byte) *GoRepository {
+	client := NewTestClient(func(*http.Request) *http.Response {
+		return &http.Response{
+			StatusCode:    http.StatusOK,
+			Body:          io.NopCloser(bytes.NewReader(archive)),
+			ContentLength: int64(len(archive)),
+		}
+	})
+	return &GoRepository{client: client, onProgress: func(ratio float64) {}}
+}
+
+func TestPipelineRun(t *testing.T) {
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n"})
+	sum := sha256.Sum256(archive)
+	dlf := File{Filename: "go1.22.1.linux-amd64.tar.gz", Version: "go1.22.1", Sha256: hex.EncodeToString(sum[:])}
+
+	prefix := t.TempDir()
+	paths := newTestPaths(t)
+
+	err := newPipeline(newTestArchiveRepo(archive), nil, dlf, prefix, processOwner, paths).run(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if v, err := installedVersion(filepath.Join(prefix, "go")); err != nil || v != "go1.22.1" {
+		t.Errorf("Expected go1.22.1 to be installed, got %s (%v)", v, err)
+	}
+
+	if _, err := os.Stat(paths.PipelineFile()); !os.IsNotExist(err) {
+		t.Errorf("Expected the pipeline state to be removed after completion")
+	}
+}
+
+func TestPipelineResume(t *testing.T) {
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n", "go/doc/go_spec.html": "spec"})
+	dlf := File{Filename: "go1.22.1.linux-amd64.tar.gz", Version: "go1.22.1"}
+
+	prefix := t.TempDir()
+	paths := newTestPaths(t)
+
+	repo := &GoRepository{onProgress: func(ratio float64) {}}
+	repo.archive.filter, _ = newArchiveFilter(true, "", "")
+	p := newPipeline(repo, nil, dlf, prefix, processOwner, paths)
+	if err := os.MkdirAll(filepath.Dir(p.state.Archive), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p.state.Archive, archive, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.save(PhaseVerified); err != nil {
+		t.Fatal(err)
+	}
+
+	// The resumed installation is run without --minimal.
+	resumed, err := loadPipeline(&GoRepository{onProgress: func(ratio float64) {}}, nil, paths)
+	if err != nil || resumed == nil {
+		t.Fatalf("Expected a pipeline to resume, got %v", err)
+	}
+	if resumed.state.Phase != PhaseVerified {
+		t.Errorf("Expected to resume from phase %q, got %q", PhaseVerified, resumed.state.Phase)
+	}
+
+	// The repository has no client, any download attempt would fail.
+	if err := resumed.run(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if v, err := installedVersion(filepath.Join(prefix, "go")); err != nil || v != "go1.22.1" {
+		t.Errorf("Expected go1.22.1 to be installed, got %s (%v)", v, err)
+	}
+	if _, err := os.Stat(filepath.Join(prefix, "go", "doc")); !os.IsNotExist(err) {
+		t.Errorf("Expected the recorded filter to leave go/doc out, got %v", err)
+	}
+}
+
+func TestPipelineChecksumMismatch(t *testing.T) {
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n"})
+	dlf := File{Filename: "go1.22.1.linux-amd64.tar.gz", Version: "go1.22.1", Sha256: "deadbeef"}
+
+	prefix := t.TempDir()
+	p := newPipeline(newTestArchiveRepo(archive), nil, dlf, prefix, processOwner, newTestPaths(t))
+
+	if err := p.run(context.Background()); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("Expected ErrChecksumMismatch, got %v", err)
+	}
+	if p.state.Phase != PhaseDownloading {
+		t.Errorf("Expected the pipeline to restart from the download, got phase %q", p.state.Phase)
+	}
+	if reports, _ := filepath.Glob(filepath.Join(filepath.Dir(p.statePath), "mismatch-*.txt")); len(reports) != 1 {
+		t.Errorf("Expected a mismatch report, got %v", reports)
+	}
+	if _, err := os.Stat(filepath.Join(prefix, "go")); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing to be extracted")
+	}
+}
+
+func TestPipelineDownloadHashes(t *testing.T) {
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n"})
+	sum := sha256.Sum256(archive)
+	dlf := File{Filename: "go1.22.1.linux-amd64.tar.gz", Version: "go1.22.1", Sha256: hex.EncodeToString(sum[:])}
+
+	p := newPipeline(newTestArchiveRepo(archive), nil, dlf, t.TempDir(), processOwner, newTestPaths(t))
+	if err := p.download(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !bytes.Equal(p.sum, sum[:]) {
+		t.Errorf("Expected the download to be hashed, got %x", p.sum)
+	}
+
+	// The streamed sum is trusted, the archive is not read again.
+	if err := os.Remove(p.state.Archive); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.verify(context.Background()); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/blckfalcon/go-dl/versions"
+)
+
+// In the managed layout each version is installed in its own directory under
+// versionsDir and prefix/go is a symlink to the active one.
+func versionsDir(prefix string) string {
+	return filepath.Join(prefix, "go-versions")
+}
+
+// isLink reports whether goroot is a symlink, or a junction on windows.
+func isLink(goroot string) bool {
+	fi, err := os.Lstat(goroot)
+	return err == nil && fi.Mode()&(os.ModeSymlink|os.ModeIrregular) != 0
+}
+
+// linkTarget returns the directory the goroot link points to.
+func linkTarget(goroot string) (string, error) {
+	target, err := os.Readlink(goroot)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(goroot), target)
+	}
+	return filepath.Clean(target), nil
+}
+
+// isManaged reports whether goroot is a link into the managed layout.
+func isManaged(goroot string) bool {
+	if !isLink(goroot) {
+		return false
+	}
+	target, err := linkTarget(goroot)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(versionsDir(filepath.Dir(goroot)), target)
+	return err == nil && rel != "." && !strings.HasPrefix(rel, "..")
+}
+
+// checkForeignLink returns ErrForeignLink when goroot links outside the
+// managed layout, to an installation of another tool which go-dl must
+// neither replace nor switch away from.
+func checkForeignLink(goroot string) error {
+	if !isLink(goroot) || isManaged(goroot) {
+		return nil
+	}
+	target, err := linkTarget(goroot)
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: %s links to %s", ErrForeignLink, goroot, target)
+}
+
+// swapInstallation replaces goroot with staging, restoring the previous
+// installation when the swap fails. The previous installation is kept as a
+// backup until the next one, for go-dl rollback. A goroot using the managed
+// layout keeps it, one linking elsewhere is left alone.
+func swapInstallation(goroot, staging string) error {
+	if err := checkForeignLink(goroot); err != nil {
+		return err
+	}
+	if isManaged(goroot) {
+		return swapVersion(goroot, staging)
+	}
+	if _, err := os.Lstat(goroot); errors.Is(err, os.ErrNotExist) {
+		return os.Rename(staging, goroot)
+	}
+
+	prefix := filepath.Dir(goroot)
+	backup, err := newBackup(prefix)
+	if err != nil {
+		return err
+	}
+	if err := replaceDir(goroot, staging, filepath.Join(backup, filepath.Base(goroot))); err != nil {
+		os.RemoveAll(backup)
+		return err
+	}
+	return pruneBackups(prefix, backup)
+}
+
+// swapVersion moves staging to the directory of its version and points the
+// goroot symlink to it, recording the previous version for go-dl rollback.
+func swapVersion(goroot, staging string) error {
+	version, err := installedVersion(staging)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(versionsDir(filepath.Dir(goroot)), version)
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return err
+	}
+	if _, err := os.Lstat(dir); errors.Is(err, os.ErrNotExist) {
+		if err := os.Rename(staging, dir); err != nil {
+			return err
+		}
+	} else {
+		// A reinstalled version is not kept, its directory is replaced.
+		old := dir + ".go-dl-old"
+		os.RemoveAll(old)
+		if err := replaceDir(dir, staging, old); err != nil {
+			return err
+		}
+		defer os.RemoveAll(old)
+	}
+
+	previous, err := filepath.EvalSymlinks(goroot)
+	if current, errCurrent := filepath.EvalSymlinks(dir); err == nil && errCurrent == nil && previous != current {
+		if err := backupLinkTarget(goroot); err != nil {
+			return err
+		}
+	}
+	return linkGoroot(goroot, dir)
+}
+
+// migrateInstallation moves a plain installation at goroot into the managed
+// layout, it returns the directory of the installed version. One owned by a
+// system package is only moved with force.
+func migrateInstallation(ctx context.Context, goroot string, force bool) (string, error) {
+	fi, err := os.Lstat(goroot)
+	if err != nil {
+		return "", err
+	}
+	if err := checkForeignLink(goroot); err != nil {
+		return "", err
+	}
+	if err := checkPackageOwner(ctx, goroot); err != nil {
+		if !force {
+			return "", err
+		}
+		slog.Warn("moving an installation owned by a system package, which may reinstall it", "err", err)
+	}
+	if isManaged(goroot) {
+		return "", fmt.Errorf("%s already uses the managed layout", goroot)
+	}
+	if !fi.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", goroot)
+	}
+
+	version, err := installedVersion(goroot)
+	if err != nil {
+		return "", fmt.Errorf("unable to read the version of %s: %w", goroot, err)
+	}
+
+	dir := filepath.Join(versionsDir(filepath.Dir(goroot)), version)
+	if _, err := os.Lstat(dir); !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("%s already exists", dir)
+	}
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return "", err
+	}
+
+	if err := os.Rename(goroot, dir); err != nil {
+		return "", err
+	}
+	if err := linkGoroot(goroot, dir); err != nil {
+		if errRestore := os.Rename(dir, goroot); errRestore != nil {
+			return "", fmt.Errorf("%w (installation left at %s: %v)", err, dir, errRestore)
+		}
+		return "", err
+	}
+	return dir, nil
+}
+
+// installation returns the goroot of an installed version, from the managed
+// layout or the active installation.
+func (c *cli) installation(version string) (string, error) {
+	dir := filepath.Join(versionsDir(c.prefix), version)
+	if v, err := installedVersion(dir); err == nil && v == version {
+		return dir, nil
+	}
+	if v, err := installedVersion(c.goroot()); err == nil && v == version {
+		return c.goroot(), nil
+	}
+	return "", fmt.Errorf("%s is not installed in %s", version, c.prefix)
+}
+
+func (c *cli) migrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	force := fs.Bool("force", false, "move an installation owned by a system package")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-dl migrate [--force]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return errors.New("migrate expects no arguments")
+	}
+
+	dir, err := migrateInstallation(c.ctx, c.goroot(), *force)
+	if err != nil {
+		return wrapPermission(err)
+	}
+
+	fmt.Fprintf(c.stdout, "Moved %s to %s\n", c.goroot(), dir)
+	return nil
+}
+
+// managedVersion returns the version of the managed layout query names,
+// the newest one matching it when query is a constraint.
+func (c *cli) managedVersion(query string) string {
+	version := versions.Normalize(query)
+	if _, err := installedVersion(filepath.Join(versionsDir(c.prefix), version)); err == nil {
+		return version
+	}
+	constraint, err := versions.ParseConstraint(query)
+	if err != nil {
+		return version
+	}
+
+	var installed []string
+	entries, _ := os.ReadDir(versionsDir(c.prefix))
+	for _, e := range entries {
+		installed = append(installed, e.Name())
+	}
+	if latest, ok := versions.Latest(installed, constraint); ok {
+		return latest
+	}
+	return version
+}
+
+// addGoPath adds the bin directory of the goroot to the PATH of the user,
+// the tests replace it so they leave the PATH of the user alone.
+var addGoPath = addToUserPath
+
+// use activates an installed version of the managed layout.
+func (c *cli) use(args []string) error {
+	fs := flag.NewFlagSet("use", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-dl use <version>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("use expects a single version")
+	}
+	version := c.managedVersion(c.resolveAlias(fs.Arg(0)))
+
+	goroot := c.goroot()
+	if err := checkForeignLink(goroot); err != nil {
+		return err
+	}
+	if _, err := os.Lstat(goroot); err == nil && !isManaged(goroot) {
+		return fmt.Errorf("%s does not use the managed layout, run go-dl migrate first", goroot)
+	}
+
+	dir := filepath.Join(versionsDir(c.prefix), version)
+	if v, err := installedVersion(dir); err != nil || v != version {
+		return fmt.Errorf("%s is not installed in %s: %w", version, versionsDir(c.prefix), ErrVersionNotFound)
+	}
+	if err := c.allow(version); err != nil {
+		return err
+	}
+
+	if err := linkGoroot(goroot, dir); err != nil {
+		return wrapPermission(err)
+	}
+	if err := addGoPath(filepath.Join(goroot, "bin")); err != nil {
+		slog.Warn("unable to add go to the PATH", "err", err)
+	}
+
+	fmt.Fprintf(c.stdout, "Using %s\n", version)
+	c.printDrift()
+	return nil
+}
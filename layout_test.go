@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestMigrateInstallation(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the managed layout is not supported on windows")
+	}
+
+	prefix := t.TempDir()
+	goroot := filepath.Join(prefix, "go")
+	if err := os.MkdirAll(goroot, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(goroot, "VERSION"), []byte("go1.21.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := migrateInstallation(context.Background(), goroot, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if want := filepath.Join(prefix, "go-versions", "go1.21.0"); dir != want {
+		t.Errorf("Expected the installation to move to %s, got %s", want, dir)
+	}
+	if target, err := os.Readlink(goroot); err != nil || target != filepath.Join("go-versions", "go1.21.0") {
+		t.Errorf("Expected a relative symlink to the version, got %q (%v)", target, err)
+	}
+	if v, err := installedVersion(goroot); err != nil || v != "go1.21.0" {
+		t.Errorf("Expected go1.21.0 to stay active, got %s (%v)", v, err)
+	}
+
+	if _, err := migrateInstallation(context.Background(), goroot, false); err == nil {
+		t.Errorf("Expected a managed installation to be rejected")
+	}
+
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n"})
+	if err := extractArchive(prefix, bytes.NewReader(archive), processOwner, extractProgress{}, archiveOptions{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if target, err := os.Readlink(goroot); err != nil || target != filepath.Join("go-versions", "go1.22.1") {
+		t.Errorf("Expected the symlink to switch to the new version, got %q (%v)", target, err)
+	}
+	if v, err := installedVersion(dir); err != nil || v != "go1.21.0" {
+		t.Errorf("Expected the previous version to be kept, got %s (%v)", v, err)
+	}
+
+	c := &cli{prefix: prefix, stdout: io.Discard}
+	if err := c.use([]string{"1.21.0"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if v, err := installedVersion(goroot); err != nil || v != "go1.21.0" {
+		t.Errorf("Expected go1.21.0 to be active again, got %s (%v)", v, err)
+	}
+	if err := c.use([]string{"1.20.0"}); !errors.Is(err, ErrVersionNotFound) {
+		t.Errorf("Expected ErrVersionNotFound for a missing version, got %v", err)
+	}
+
+	// An alias of a constraint uses the newest matching version.
+	c.aliases = map[string]string{"current": "1.22"}
+	if err := c.use([]string{"current"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if v, err := installedVersion(goroot); err != nil || v != "go1.22.1" {
+		t.Errorf("Expected the alias to switch to go1.22.1, got %s (%v)", v, err)
+	}
+	if err := c.use([]string{"1.21.0"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	c.policy = Policy{MinimumVersion: "go1.22.0"}
+	if err := c.use([]string{"1.21.0"}); !errors.Is(err, ErrPolicy) {
+		t.Errorf("Expected ErrPolicy for a version older than the minimum, got %v", err)
+	}
+	c.policy.Override = true
+	if err := c.use([]string{"1.21.0"}); err != nil {
+		t.Errorf("Expected --override-policy to allow the version, got %v", err)
+	}
+}
+
+func TestForeignLink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the managed layout is not supported on windows")
+	}
+
+	prefix := t.TempDir()
+	foreign := filepath.Join(t.TempDir(), "Cellar", "go", "1.21.0")
+	if err := os.MkdirAll(foreign, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(foreign, "VERSION"), []byte("go1.21.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	goroot := filepath.Join(prefix, "go")
+	if err := os.Symlink(foreign, goroot); err != nil {
+		t.Fatal(err)
+	}
+
+	if isManaged(goroot) {
+		t.Errorf("Expected a link outside of the managed layout not to be managed")
+	}
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n"})
+	if err := extractArchive(prefix, bytes.NewReader(archive), processOwner, extractProgress{}, archiveOptions{}); !errors.Is(err, ErrForeignLink) {
+		t.Errorf("Expected ErrForeignLink, got %v", err)
+	}
+	if _, err := migrateInstallation(context.Background(), goroot, false); !errors.Is(err, ErrForeignLink) {
+		t.Errorf("Expected migrate to refuse the link, got %v", err)
+	}
+	if target, err := os.Readlink(goroot); err != nil || target != foreign {
+		t.Errorf("Expected the link to be left alone, got %q (%v)", target, err)
+	}
+	if v, err := installedVersion(foreign); err != nil || v != "go1.21.0" {
+		t.Errorf("Expected the linked installation to be left alone, got %s (%v)", v, err)
+	}
+	if _, err := os.Stat(versionsDir(prefix)); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing to be installed in the managed layout")
+	}
+}
+
+func TestUseZipInstallation(t *testing.T) {
+	archive := newTestZip(t, map[string]string{"go/VERSION": "go1.22.1\n", "go/bin/go.exe": "binary"}, nil)
+	c := newTestZipCLI(t, archive)
+	c.prefix = t.TempDir()
+
+	var paths []string
+	addGoPath = func(dir string) error {
+		paths = append(paths, dir)
+		return nil
+	}
+	t.Cleanup(func() { addGoPath = addToUserPath })
+
+	goroot := c.goroot()
+	if err := os.MkdirAll(goroot, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(goroot, "VERSION"), []byte("go1.21.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrateInstallation(context.Background(), goroot, false); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := c.install([]string{"1.22.1"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if v, err := installedVersion(goroot); err != nil || v != "go1.22.1" {
+		t.Errorf("Expected the zip archive to be installed in the managed layout and activated, got %s (%v)", v, err)
+	}
+
+	if err := c.use([]string{"1.21.0"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if v, err := installedVersion(goroot); err != nil || v != "go1.21.0" {
+		t.Errorf("Expected go1.21.0 to be active, got %s (%v)", v, err)
+	}
+	if err := c.use([]string{"1.22.1"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if b, err := os.ReadFile(filepath.Join(goroot, "bin", "go.exe")); err != nil || string(b) != "binary" {
+		t.Errorf("Expected the binary of go1.22.1 to be active, got %q (%v)", b, err)
+	}
+	if want := filepath.Join(goroot, "bin"); len(paths) != 2 || paths[0] != want || paths[1] != want {
+		t.Errorf("Expected %s to be added to the PATH by each use, got %v", want, paths)
+	}
+}
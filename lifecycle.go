@@ -0,0 +1,115 @@
+package main
+
+import (
+	"go/version"
+	"log/slog"
+	"slices"
+)
+
+// majorRelease returns the major release of v, go1.22 for go1.22.1.
+func majorRelease(v string) string {
+	lang := version.Lang(v)
+	if lang == "go1" {
+		return "go1.0"
+	}
+	return lang
+}
+
+// majorReleases returns the major releases with a stable release in the
+// feed, newest first.
+func majorReleases(releases []Release) []string {
+	var majors []string
+	for _, r := range releases {
+		if !r.Stable {
+			continue
+		}
+		major := majorRelease(r.Version)
+		if !slices.Contains(majors, major) {
+			majors = append(majors, major)
+		}
+	}
+	slices.SortFunc(majors, func(a, b string) int { return version.Compare(b, a) })
+	return majors
+}
+
+// supportedReleases returns the major releases supported by the Go release
+// policy: each one is supported until there are two newer major releases.
+func supportedReleases(releases []Release) map[string]bool {
+	newest := majorReleases(releases)
+	supported := map[string]bool{}
+	for _, major := range newest[:min(2, len(newest))] {
+		supported[major] = true
+	}
+	return supported
+}
+
+// isSupported reports whether v belongs to a supported major release,
+// unstable releases of an upcoming major release are not end of life.
+func isSupported(supported map[string]bool, v string) bool {
+	major := majorRelease(v)
+	if supported[major] {
+		return true
+	}
+	for s := range supported {
+		if version.Compare(major, s) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// endOfLife returns the major release of the feed which ended the support
+// of major, the second newer one.
+func endOfLife(releases []Release, major string) (string, bool) {
+	majors := majorReleases(releases)
+	i := slices.IndexFunc(majors, func(m string) bool { return version.Compare(m, major) <= 0 })
+	if i < 0 {
+		i = len(majors)
+	}
+	if i < 2 {
+		return "", false
+	}
+	return majors[i-2], true
+}
+
+// releaseLabels returns the channels of each release along with its end of
+// life, by version.
+func releaseLabels(releases []Release) map[string][]string {
+	labels := releaseChannels(releases)
+	supported := supportedReleases(releases)
+	for _, r := range releases {
+		if isSupported(supported, r.Version) {
+			continue
+		}
+		if since, ok := endOfLife(releases, majorRelease(r.Version)); ok {
+			labels[r.Version] = append(labels[r.Version], "eol since "+since)
+			continue
+		}
+		labels[r.Version] = append(labels[r.Version], "eol")
+	}
+	return labels
+}
+
+// supportedOnly returns the releases of the supported major releases.
+func supportedOnly(releases []Release) []Release {
+	supported := supportedReleases(releases)
+	var filtered []Release
+	for _, r := range releases {
+		if isSupported(supported, r.Version) {
+			filtered = append(filtered, r)
+		}
+	}
+	return filtered
+}
+
+// warnEndOfLife warns when installing a release no longer supported.
+func warnEndOfLife(releases []Release, v string) {
+	if isSupported(supportedReleases(releases), v) {
+		return
+	}
+	if since, ok := endOfLife(releases, majorRelease(v)); ok {
+		slog.Warn("installing a release without security fixes", "version", v, "eol", since)
+		return
+	}
+	slog.Warn("installing a release without security fixes", "version", v)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReleaseLabels(t *testing.T) {
+	releases := []Release{
+		{Version: "go1.24rc1"},
+		{Version: "go1.23.0", Stable: true},
+		{Version: "go1.22.1", Stable: true},
+		{Version: "go1.21.0", Stable: true},
+		{Version: "go1.20", Stable: true},
+		{Version: "go1.9.7", Stable: true},
+	}
+
+	labels := releaseLabels(releases)
+	for v, want := range map[string][]string{
+		"go1.24rc1": {"unstable"},
+		"go1.23.0":  {"stable"},
+		"go1.22.1":  {"oldstable"},
+		"go1.21.0":  {"eol since go1.23"},
+		"go1.20":    {"eol since go1.22"},
+		"go1.9.7":   {"eol since go1.21"},
+	} {
+		if !reflect.DeepEqual(labels[v], want) {
+			t.Errorf("Expected %s labels %v, got %v", v, want, labels[v])
+		}
+	}
+
+	var got []string
+	for _, r := range supportedOnly(releases) {
+		got = append(got, r.Version)
+	}
+	if want := []string{"go1.24rc1", "go1.23.0", "go1.22.1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected the supported releases %v, got %v", want, got)
+	}
+}
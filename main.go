@@ -2,40 +2,86 @@ package main
 
 import (
 	"archive/tar"
+	"bufio"
 	"compress/gzip"
 	"context"
-	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"go/version"
 	"io"
+	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"time"
 
-	"github.com/charmbracelet/bubbles/list"
-	"github.com/charmbracelet/bubbles/progress"
 	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/blckfalcon/go-dl/tui"
 )
 
 type GoRepository struct {
 	url        string
 	client     *http.Client
+	includeAll bool
 	onProgress func(float64)
+	// onTransfer, when set, receives the bytes downloaded, or read from the
+	// archive while extracting it, before each progress.
+	onTransfer func(done, total int64)
+	// onFiles, when set, receives the files extracted before each progress.
+	onFiles func(done, total int)
+
+	// gate pauses the downloads when set.
+	gate *pauseGate
+	// metered, when set, holds the downloads back on metered connections.
+	metered *meteredGuard
+	// fetched is when the releases feed was last fetched.
+	fetched time.Time
+	// manifest, when set, replaces the releases feed of mirrors serving
+	// only the archives.
+	manifest []Release
+	// archive hardens the extractions of the archives.
+	archive archiveOptions
+	// timeouts bounds the feed fetch, the downloads and the extractions.
+	timeouts phaseTimeouts
+	// reconnects is the number of times a stalled download is resumed
+	// before failing, onReconnect, when set, is called before each attempt.
+	reconnects  int
+	onReconnect func(attempt, max int)
+	// requireChecksum rejects the files without a published checksum.
+	requireChecksum bool
+	// feedCache, when set, is the file keeping a copy of the last feed
+	// fetched.
+	feedCache string
+	// dist is the distribution publishing the releases, empty for go.dev.
+	dist string
 }
 
 func (g *GoRepository) GetVersions(ctx context.Context) ([]Release, error) {
 	var results []Release
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.url+"/?mode=json", nil)
+	if g.manifest != nil {
+		return g.manifest, nil
+	}
+
+	query := "/?mode=json"
+	if g.includeAll {
+		query += "&include=all"
+	}
+
+	ctx, cancel := withPhaseTimeout(ctx, g.timeouts.feed)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.url+query, nil)
 	if err != nil {
 		return results, err
 	}
 
 	resp, err := g.client.Do(req)
 	if err != nil {
-		return results, err
+		return results, phaseError(ctx, "fetching the releases", g.timeouts.feed, err)
 	}
 	defer resp.Body.Close()
 
@@ -43,53 +89,207 @@ func (g *GoRepository) GetVersions(ctx context.Context) ([]Release, error) {
 		return results, fmt.Errorf("not valid response status")
 	}
 
-	err = json.NewDecoder(resp.Body).Decode(&results)
+	b, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return results, err
+		return results, phaseError(ctx, "fetching the releases", g.timeouts.feed, err)
 	}
 
+	if results, err = decodeReleases(b); err != nil {
+		return results, err
+	}
+	warnFeedAnomalies(req.URL.String(), results)
+	g.fetched = time.Now()
+	g.cacheFeed(b)
 	return results, nil
 }
 
-func (g *GoRepository) Download(ctx context.Context, dlFile File, outFile *os.File) error {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.url+"/"+dlFile.Filename, nil)
+// extractProgress reports the progress of the extractions to the callbacks
+// of the repository.
+func (g *GoRepository) extractProgress() extractProgress {
+	return extractProgress{ratio: g.onProgress, bytes: g.onTransfer, files: g.onFiles}
+}
+
+// Download streams dlFile to w, which can be a file as well as a buffer, a
+// hash or a pipe.
+func (g *GoRepository) Download(ctx context.Context, dlFile File, w io.Writer) error {
+	_, err := g.download(ctx, dlFile, w)
+	return err
+}
+
+// DownloadTemp downloads dlFile to a new temporary file in dir, the default
+// directory for temporary files when empty, and returns its path. The file
+// is removed when the download fails and by the caller otherwise.
+func (g *GoRepository) DownloadTemp(ctx context.Context, dlFile File, dir string) (string, error) {
+	f, err := createTemp(dir, "go-dl-tmp-*-"+filepath.Base(dlFile.Filename))
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	resp, err := g.client.Do(req)
+	err = preallocate(f, int64(dlFile.Size))
+	if err == nil {
+		_, err = g.download(ctx, dlFile, f)
+	}
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
 	if err != nil {
-		return err
+		os.Remove(f.Name())
+		return "", err
 	}
-	defer resp.Body.Close()
+	return f.Name(), nil
+}
+
+// download writes dlFile to w and returns the url it was downloaded from,
+// after redirects.
+func (g *GoRepository) download(ctx context.Context, dlFile File, w io.Writer) (string, error) {
+	source, err := g.fetch(ctx, dlFile, w)
+	metrics.download(err)
+	return source, err
+}
+
+func (g *GoRepository) fetch(ctx context.Context, dlFile File, w io.Writer) (string, error) {
+	resp, err := g.get(ctx, dlFile, 0)
+	if err != nil {
+		return "", err
+	}
+	defer func() { resp.Body.Close() }()
 
-	downloaded := 0
+	source := g.url + "/" + dlFile.Filename
+	if resp.Request != nil {
+		source = resp.Request.URL.String()
+	}
+	warnEncoding(resp, dlFile, source)
 	total := int(resp.ContentLength)
 	if total == 0 {
-		return errors.New("unable to calculate progress: ContentLength is 0")
+		return source, errors.New("unable to calculate progress: ContentLength is 0")
+	}
+	if dlFile.Size > 0 && total > 0 && total != dlFile.Size {
+		slog.Warn("the size of the download does not match the releases feed, a proxy may be altering it",
+			"file", dlFile.Filename, "size", dlFile.Size, "content_length", total, "url", source)
 	}
-	buf := make([]byte, 32*1024)
+	if err := g.metered.check(ctx, dlFile, int64(total)); err != nil {
+		return source, err
+	}
+	buf := copyBuffers.Get().(*[]byte)
+	defer copyBuffers.Put(buf)
+	body := &downloadBody{ctx: ctx, gate: g.gate, watch: &stallWatch{timeout: g.timeouts.stall}, body: resp.Body}
+	out := &progressWriter{w: w, report: func(downloaded int64) {
+		if g.onTransfer != nil {
+			g.onTransfer(downloaded, int64(total))
+		}
+		g.onProgress(float64(downloaded) / float64(total))
+	}}
+	reconnects := 0
 
 	for {
-		nr, errRead := resp.Body.Read(buf)
-		if nr > 0 {
-			nw, errWrite := outFile.Write(buf[0:nr])
-
-			downloaded += nw
-			g.onProgress(float64(downloaded) / float64(total))
-
-			if errWrite != nil {
-				return errWrite
+		_, err := io.CopyBuffer(out, body, *buf)
+		switch {
+		case err == nil:
+			return source, nil
+		case out.err != nil:
+			return source, out.err
+		case body.paused && !errors.Is(err, ErrStalled):
+			// The connection was dropped while paused, continue where the
+			// download stopped.
+			resp.Body.Close()
+			if resp, err = g.get(ctx, dlFile, int(out.written)); err != nil {
+				return source, err
 			}
-		}
-		if errRead != nil {
-			if errRead != io.EOF {
-				return errRead
+		case errors.Is(err, ErrStalled) && reconnects < g.reconnects:
+			reconnects++
+			slog.Warn("download stalled, reconnecting", "file", dlFile.Filename, "attempt", reconnects, "max", g.reconnects)
+			if g.onReconnect != nil {
+				g.onReconnect(reconnects, g.reconnects)
 			}
-			break
+			resp.Body.Close()
+			errRead := err
+			if resp, err = g.get(ctx, dlFile, int(out.written)); err != nil {
+				return source, fmt.Errorf("%w, reconnecting failed: %w", errRead, err)
+			}
+		default:
+			return source, err
 		}
+		body.body = resp.Body
+	}
+}
+
+// downloadBody is the body of a download for io.CopyBuffer: its reads wait
+// while the downloads are paused and fail once they stall.
+type downloadBody struct {
+	ctx   context.Context
+	gate  *pauseGate
+	watch *stallWatch
+	body  io.ReadCloser
+	// paused reports whether the last read waited for the downloads to
+	// resume, the connection may have been dropped meanwhile.
+	paused bool
+}
+
+func (b *downloadBody) Read(p []byte) (int, error) {
+	paused, err := b.gate.wait(b.ctx)
+	b.paused = paused && err == nil
+	if err != nil {
+		return 0, err
+	}
+	return b.watch.read(b.body, p)
+}
+
+// progressWriter counts the bytes written to w and reports each write.
+type progressWriter struct {
+	w       io.Writer
+	written int64
+	report  func(written int64)
+	// err is the error of the writes, which are never retried.
+	err error
+}
+
+func (pw *progressWriter) Write(p []byte) (int, error) {
+	n, err := pw.w.Write(p)
+	if err == nil && n < len(p) {
+		err = io.ErrShortWrite
+	}
+	pw.written += int64(n)
+	metrics.bytes.Add(int64(n))
+	pw.report(pw.written)
+	pw.err = err
+	return n, err
+}
+
+// get requests dlFile from offset, which must then be honored by the server.
+func (g *GoRepository) get(ctx context.Context, dlFile File, offset int) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.url+"/"+dlFile.Filename, nil)
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	// The files are verified as published, the transport must not
+	// decompress them.
+	req.Header.Set("Accept-Encoding", "identity")
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := g.downloadClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if offset > 0 && (resp.StatusCode != http.StatusPartialContent || !strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", offset))) {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unable to resume the download of %s: %s", dlFile.Filename, resp.Status)
+	}
+	return resp, nil
+}
+
+// downloadClient returns the client of the downloads. When stalls are
+// detected, the downloads are only bounded by them and not by the timeout of
+// the client, which would cut large files on slow connections.
+func (g *GoRepository) downloadClient() *http.Client {
+	if g.timeouts.stall <= 0 || g.client.Timeout == 0 {
+		return g.client
+	}
+	client := *g.client
+	client.Timeout = 0
+	return &client
 }
 
 type File struct {
@@ -100,6 +300,10 @@ type File struct {
 	Sha256   string `json:"sha256"`
 	Size     int    `json:"size"`
 	Kind     string `json:"kind"`
+
+	// Checksums holds the sums of other algorithms published by a
+	// manifest, by algorithm.
+	Checksums map[string]string `json:"checksums,omitempty"`
 }
 type Files []File
 
@@ -133,11 +337,72 @@ func (a ByRelease) Less(i, j int) bool {
 	return version.Compare(a[i].Version, a[j].Version) > 0
 }
 
+// Decompress extracts the tar.gz archive r to dst, onProgress receives the
+// ratio of the compressed archive read so far.
 func Decompress(dst string, r io.ReadSeeker, onProgress func(float64)) error {
+	return decompress(dst, r, extractProgress{ratio: onProgress}, archiveOptions{})
+}
+
+// extractProgress receives the progress of an extraction, in bytes of the
+// compressed archive read, which stays smooth through large files, and in
+// files extracted. Callbacks left nil are skipped.
+type extractProgress struct {
+	ratio func(float64)
+	bytes func(done, total int64)
+	files func(done, total int)
+}
+
+func (p extractProgress) report(read, size int64, files, totalFiles int) {
+	if p.bytes != nil {
+		p.bytes(read, size)
+	}
+	if p.files != nil {
+		p.files(files, totalFiles)
+	}
+	if p.ratio != nil && size > 0 {
+		p.ratio(min(float64(read)/float64(size), 1))
+	}
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// progressReader calls report after each read.
+type progressReader struct {
+	r      io.Reader
+	report func()
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.report()
+	return n, err
+}
+
+// decompress extracts the tar.gz archive r to dst, the entries are checked by
+// checkEntry.
+func decompress(dst string, r io.ReadSeeker, progress extractProgress, opts archiveOptions) error {
 	if err := os.MkdirAll(dst, 0755); err != nil {
 		return err
 	}
 
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
 	gzr, err := gzip.NewReader(r)
 	if err != nil {
 		return err
@@ -146,13 +411,22 @@ func Decompress(dst string, r io.ReadSeeker, onProgress func(float64)) error {
 
 	tr := tar.NewReader(gzr)
 
+	dir, err := openExtractDir(dst, opts)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+
 	totalFiles := 0
 	for {
 		header, err := tr.Next()
 		if err == io.EOF {
 			break
 		}
-		if header.Typeflag == tar.TypeReg {
+		if err != nil {
+			return err
+		}
+		if header.Typeflag == tar.TypeReg && !opts.filter.Skip(header.Name) {
 			totalFiles++
 		}
 	}
@@ -162,90 +436,505 @@ func Decompress(dst string, r io.ReadSeeker, onProgress func(float64)) error {
 		return err
 	}
 
-	err = gzr.Reset(r)
+	// The position in the compressed stream measures the progress, the few
+	// bytes buffered by gzip ahead of it don't matter.
+	compressed := &countingReader{r: r}
+	err = gzr.Reset(compressed)
 	if err != nil {
 		return err
 	}
 	tr = tar.NewReader(gzr)
 
 	countFiles := 0
+	var reported int64
+	report := func() {
+		// Reported every thousandth of the archive at most.
+		if compressed.n-reported >= size/1000 {
+			reported = compressed.n
+			progress.report(compressed.n, size, countFiles, totalFiles)
+		}
+	}
+
 	for {
 		header, err := tr.Next()
 
 		switch {
 		case err == io.EOF:
+			progress.report(size, size, countFiles, totalFiles)
 			return nil
 		case err != nil:
 			return err
 		}
 
-		target := filepath.Join(dst, header.Name)
+		// Long names from PAX or GNU records are resolved by the tar reader.
+		// Extended attributes, such as quarantine flags in archives created
+		// on macOS, are not carried over to the installation.
+		mode, ok, err := checkEntry(header, opts.strict)
+		if err != nil {
+			return err
+		}
+		if !ok || opts.filter.Skip(header.Name) {
+			continue
+		}
 
 		switch header.Typeflag {
 		case tar.TypeDir:
-			if _, err := os.Stat(target); err != nil {
-				if err := os.MkdirAll(target, 0755); err != nil {
-					return err
-				}
+			if err := dir.MkdirAll(header.Name); err != nil {
+				return err
 			}
 		case tar.TypeReg:
-			f, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_RDWR, os.FileMode(header.Mode))
+			if err := dir.MkdirAll(filepath.Dir(header.Name)); err != nil {
+				return err
+			}
+			f, err := dir.Create(header.Name, mode)
 			if err != nil {
 				return err
 			}
-			if _, err := io.Copy(f, tr); err != nil {
+			if _, err := copyBuffered(f, &progressReader{r: tr, report: report}); err != nil {
 				return err
 			}
 			countFiles++
 			f.Close()
 		}
 
-		onProgress(float64(countFiles) / float64(totalFiles))
+		report()
 	}
 }
 
+// isFlagSet reports whether the flag name was given on the command line.
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) { set = set || f.Name == name })
+	return set
+}
+
 func main() {
 	var err error
-	ctx := context.Background()
-	client := &http.Client{Timeout: time.Duration(30) * time.Second}
+
+	installer := flag.Bool("installer", false, "download the platform installer (msi/pkg) instead of the archive")
+	paths := defaultPaths()
+
+	configPath := flag.String("config", paths.ConfigFile(), "path of the configuration file")
+	overridePolicy := flag.Bool("override-policy", false, "install versions blocked by the configured policy")
+	installPrefix := flag.String("prefix", defaultPrefix, "directory the go installation is created in")
+	system := flag.Bool("system", false, "install for every user, files are owned by the configured system_owner")
+	progressFd := flag.Int("progress-fd", -1, "write JSON progress events to this file descriptor")
+	metered := flag.String("metered", "", "on metered connections, prompt, deny or allow the downloads (default from the config, else prompt)")
+	http1 := flag.Bool("http1", false, "use HTTP/1.1, for networks breaking HTTP/2 downloads")
+	mirror := flag.String("mirror", defaultMirror, "base URL of the releases and of their files (default from the config, else go.dev)")
+	manifest := flag.String("manifest", "", "releases of a mirror without the JSON feed, a copy of the feed or sha256sum lines")
+	dist := flag.String("dist", "", "distribution of Go, go for go.dev or one of the distributions of the config (default from the config, else go)")
+	supported := flag.Bool("supported-only", false, "only offer the releases still supported in the interactive picker")
+	minimal := flag.Bool("minimal", false, "leave the API files, documentation, misc files and tests out of the installation")
+	includeFiles := flag.String("include", "", "comma-separated patterns of files extracted even when excluded, e.g. /misc/wasm")
+	excludeFiles := flag.String("exclude", "", "comma-separated patterns of files left out of the installation, e.g. /doc,testdata")
+	confine := flag.Bool("confine-extract", false, "on Linux, create the extracted files with openat2 so they can't resolve outside of the installation")
+	strictArchive := flag.Bool("strict-archive", false, "reject archives with anything but regular files and directories, or with setuid, setgid or sticky bits")
+	timeout := flag.Duration("timeout", 0, "deadline of the whole operation, e.g. 10m (default none)")
+	feedTimeout := flag.Duration("feed-timeout", 30*time.Second, "deadline of the fetch of the releases feed, 0 for none")
+	stallTimeout := flag.Duration("stall-timeout", 30*time.Second, "fail the downloads receiving no data for this long, 0 to never")
+	reconnects := flag.Int("reconnects", 3, "resume a stalled download this many times before failing")
+	extractTimeout := flag.Duration("extract-timeout", 0, "deadline of the extraction or of the build from source (default none)")
+	mock := flag.Bool("mock", false, "use a built-in fake repository and install into a temporary sandbox")
+	mockDelay := flag.Duration("mock-delay", 5*time.Second, "duration of each download with --mock")
+	mockFailures := flag.String("mock-failures", "", "failure probabilities with --mock, e.g. feed=0.2,download=0.3,checksum=0.1")
+	deterministic := flag.Bool("deterministic", os.Getenv("SOURCE_DATE_EPOCH") != "", "stable temporary names, file times from SOURCE_DATE_EPOCH and logs without times, for reproducible image layers (default when SOURCE_DATE_EPOCH is set)")
+	var failAfter *int64
+	var failTimes *int
+	if os.Getenv(faultEnv) == "1" {
+		failAfter = flag.Int64("fail-after", -1, "drop the downloads after this many bytes, to test failures")
+		failTimes = flag.Int("fail-times", 1, "number of downloads dropped by --fail-after")
+	}
+	flag.Parse()
+
+	if *deterministic {
+		if err := enableDeterminism(os.Getenv); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+	}
+
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Println("Error loading config:", err)
+		os.Exit(1)
+	}
+	// loadConfig rejected unknown units already.
+	units, _ := parseByteUnits(config.Units)
+	units = units.localized(systemLocale(os.Getenv))
+	systemPolicy, err := loadSystemPolicy(systemPolicyFile())
+	if err != nil {
+		fmt.Println("Error loading the system policy:", err)
+		os.Exit(1)
+	}
+	policy := newPolicy(config, systemPolicy, *overridePolicy)
+	if len(systemPolicy.Scanner) > 0 {
+		config.Scanner = systemPolicy.Scanner
+	}
+
+	filter, err := newArchiveFilter(*minimal, *includeFiles, *excludeFiles)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+
+	owner, err := resolveOwner(config, *system)
+	if err != nil {
+		fmt.Println("Error resolving owner:", err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := withPhaseTimeout(context.Background(), *timeout)
+	defer cancel()
+	transport, err := newTransport(config.Transport, *http1)
+	if err != nil {
+		fmt.Println("Error configuring the transport:", err)
+		os.Exit(1)
+	}
+	if transport.Proxy, err = withProxyOverrides(proxyFromEnvironment(os.Getenv), config.Proxies); err != nil {
+		fmt.Println("Error configuring the proxies:", err)
+		os.Exit(1)
+	}
+	client := &http.Client{
+		Timeout:   time.Duration(30) * time.Second,
+		Transport: newCredentialTransport(newProxyAuthTransport(transport, config.ProxyAuthCommand), config.CredentialHelper),
+	}
+	// The credential helper is never asked for the hosts of the webhooks.
+	webhookClient := &http.Client{Timeout: webhookTimeout, Transport: newProxyAuthTransport(transport, config.ProxyAuthCommand)}
+	if config.Mirror != "" && !isFlagSet("mirror") {
+		*mirror = config.Mirror
+	}
+	if config.Dist != "" && !isFlagSet("dist") {
+		*dist = config.Dist
+	}
+	distribution, forked, err := lookupDist(config.Distributions, *dist)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+	if forked {
+		if isFlagSet("mirror") {
+			fmt.Println("Error: --mirror can't be combined with the distribution", *dist, "which has a feed of its own")
+			os.Exit(1)
+		}
+		*mirror = distribution.Feed
+		if *manifest == "" {
+			*manifest = distribution.Manifest
+		}
+		config.Cache = distStorage(*dist, config.Cache, paths.Cache)
+	}
+	if *mirror, err = systemPolicy.mirror(*mirror, isFlagSet("mirror") || config.Mirror != "" || forked); err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+	// The deltas and the manifests are sources of archives and of checksums
+	// too, the policy locking the mirrors applies to them.
+	deltaURL := config.DeltaURL
+	if deltaURL != "" {
+		if deltaURL, err = systemPolicy.mirror(deltaURL, true); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+	}
+	if *manifest != "" {
+		if err := systemPolicy.manifest(*manifest); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+	}
 	repo := &GoRepository{
-		client: client,
-		url:    "https://go.dev/dl",
+		client:  client,
+		url:     strings.TrimSuffix(*mirror, "/"),
+		archive: archiveOptions{strict: *strictArchive || systemPolicy.StrictArchive || distribution.StrictArchive, confine: *confine || systemPolicy.ConfineExtract, filter: filter},
+		timeouts: phaseTimeouts{
+			feed:    *feedTimeout,
+			stall:   *stallTimeout,
+			extract: *extractTimeout,
+		},
+		reconnects: *reconnects,
+
+		requireChecksum: systemPolicy.RequireChecksum || distribution.RequireChecksum,
+		feedCache:       filepath.Join(paths.Cache, "releases.json"),
+	}
+	if forked {
+		repo.feedCache = filepath.Join(paths.Cache, "releases-"+*dist+".json")
+		repo.dist = *dist
+	}
+	if *manifest != "" {
+		if repo.manifest, err = loadManifest(*manifest); err != nil {
+			fmt.Println("Error loading manifest:", err)
+			os.Exit(1)
+		}
+	}
+	selection := Selection{Os: runtime.GOOS, Arch: runtime.GOARCH, Installer: *installer}
+	prefix := *installPrefix
+
+	if *mock {
+		failures, err := parseMockFailures(*mockFailures)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		mt, err := newMockTransport(selection, *mockDelay, failures)
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		// Nothing leaves the sandbox, whatever the configuration says.
+		sandbox := filepath.Join(os.TempDir(), "go-dl-mock")
+		client.Transport = mt
+		client.Timeout = 0
+		webhookClient.Transport = mt
+		prefix = sandbox
+		paths = Paths{Config: paths.Config, Cache: filepath.Join(sandbox, "cache"), State: filepath.Join(sandbox, "state")}
+		repo.feedCache = filepath.Join(paths.Cache, "releases.json")
+		config.Cache = StorageConfig{}
+		owner = processOwner
+		fmt.Fprintln(os.Stderr, "Mock mode: nothing is downloaded from go.dev, installing into", sandbox)
+	}
+
+	if !*mock && !*system && !isFlagSet("prefix") {
+		readOnly := prefix
+		var fallback bool
+		if prefix, fallback = writablePrefix(prefix); fallback {
+			fmt.Fprintf(os.Stderr, "%s is read-only, using %s instead, add %s to your PATH\n", readOnly, prefix, filepath.Join(prefix, "go", "bin"))
+		}
+	}
+
+	if failAfter != nil && *failAfter >= 0 {
+		client.Transport = &faultTransport{next: client.Transport, after: *failAfter, times: *failTimes}
 	}
 
+	storage, err := newStorage(config.Cache, paths.Cache, client)
+	if err != nil {
+		fmt.Println("Error configuring cache:", err)
+		os.Exit(1)
+	}
+
+	execTemp = &execTempDir{staging: config.StagingDir, fallback: filepath.Join(paths.Cache, "tmp")}
+
+	// The journals of every atomic write are kept with the state, which is
+	// recovered on startup.
+	journalDir = paths.State
+
+	lockPath := filepath.Join(paths.State, "go-dl.lock")
+	if flag.NArg() > 0 && readOnlyCommands[flag.Arg(0)] {
+		// The read-only commands neither create the state nor clean it up,
+		// the backups of the installations included.
+		if processLock, err = shareProcessLock(lockPath); err != nil {
+			slog.Warn("could not lock the state directory", "err", err)
+		}
+	} else {
+		// Leftovers of crashed runs are only recovered or removed while no
+		// other go-dl runs, whose files could still be in use.
+		lock, alone, err := acquireProcessLock(lockPath)
+		if err != nil {
+			slog.Warn("could not lock the state directory", "err", err)
+		}
+		if alone {
+			if err := recoverJournal(paths.State); err != nil {
+				slog.Warn("could not recover the state of an interrupted run", "err", err)
+			}
+			dirs := []string{os.TempDir(), filepath.Join(paths.Cache, "tmp")}
+			if config.StagingDir != "" {
+				dirs = append(dirs, config.StagingDir)
+			}
+			count, reclaimed := removeStaleTemp(dirs, resumableArchives(paths.State), time.Now().Add(-staleTempAge))
+			if count > 0 {
+				fmt.Fprintf(os.Stderr, "Removed %d stale temporary files, %s reclaimed\n", count, units.size(reclaimed))
+			}
+			backupAge, _ := config.backupAge()
+			if reclaimed, err := expireBackups(prefix, backupAge); err != nil {
+				slog.Warn("could not remove the expired backups", "err", err)
+			} else if reclaimed > 0 {
+				fmt.Fprintf(os.Stderr, "Removed the expired backups of the replaced installations, %s reclaimed\n", units.size(reclaimed))
+			}
+			if err := lockShared(lock); err != nil {
+				slog.Warn("could not share the state directory lock", "err", err)
+			}
+		}
+		processLock = lock
+	}
+
+	if !*mock {
+		if *metered == "" {
+			*metered = config.Metered
+		}
+		if repo.metered, err = newMeteredGuard(*metered, nil, units); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+	}
+
+	keys, err := tui.NewKeyMap(config.Keys)
+	if err != nil {
+		fmt.Println("Error loading key bindings:", err)
+		os.Exit(1)
+	}
+
+	// The steps following the installations are the same for the command
+	// line and the picker.
+	c := &cli{
+		ctx:           ctx,
+		repo:          repo,
+		selection:     selection,
+		policy:        policy,
+		prefix:        prefix,
+		owner:         owner,
+		scanner:       config.Scanner,
+		paths:         paths,
+		storage:       storage,
+		deltaURL:      deltaURL,
+		hardlinks:     config.Dedupe,
+		keys:          keys,
+		hooks:         installHooks{global: config.HookConfig, versions: config.Hooks},
+		webhooks:      config.Webhooks,
+		webhookClient: webhookClient,
+		units:         units,
+		config:        *configPath,
+		mirrors:       config.Mirrors,
+		aliases:       config.Aliases,
+		stdin:         os.Stdin,
+		stdout:        os.Stdout,
+		restart: func(args []string, stdin io.Reader) error {
+			globals := os.Args[1 : len(os.Args)-flag.NArg()]
+			return restart(ctx, append(globals[:len(globals):len(globals)], args...), stdin)
+		},
+	}
+
+	if flag.NArg() > 0 {
+		var events *progressEvents
+		if *progressFd >= 0 {
+			if events, err = openProgressFd(*progressFd); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		}
+
+		repo.includeAll = true
+		repo.onProgress = events.Progress
+		repo.onTransfer = events.Transfer
+		repo.onFiles = events.Files
+		repo.onReconnect = events.Reconnect
+		c.events = events
+		if repo.metered != nil {
+			repo.metered.confirm = c.confirm
+		}
+		err := phaseError(ctx, "go-dl", *timeout, c.run(flag.Args()))
+		events.Done(err)
+		var exit commandExit
+		if errors.As(err, &exit) {
+			os.Exit(exit.code)
+		}
+		if err != nil {
+			fmt.Println("Error:", err)
+			if hint := errorHint(err); hint != "" {
+				fmt.Println("Hint:", hint)
+			}
+			os.Exit(1)
+		}
+		return
+	}
+
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
 	versions, err := repo.GetVersions(ctx)
 	if err != nil {
 		fmt.Println("Error downloading go versions list:", err)
 	}
 
-	items := []list.Item{}
-	for _, v := range versions {
-		items = append(items, item(v.Version))
+	if policy, err = policy.resolve(versions); err != nil {
+		fmt.Println("Error applying policy:", err)
+		os.Exit(1)
+	}
+	labels := releaseLabels(versions)
+	if *supported {
+		versions = supportedOnly(versions)
 	}
 
-	const listHeight = 14
-	const defaultWidth = 20
+	var orders []tui.Order
+	for _, mode := range sortModes {
+		sorted := append([]Release(nil), versions...)
+		sortReleases(sorted, mode)
 
-	l := list.New(items, itemDelegate{}, defaultWidth, listHeight)
-	l.Title = "What version of Go do you to download?"
-	l.SetShowStatusBar(false)
-	l.SetFilteringEnabled(false)
-	l.Styles.Title = titleStyle
-	l.Styles.PaginationStyle = paginationStyle
-	l.Styles.HelpStyle = helpStyle
+		order := tui.Order{Name: mode}
+		for _, v := range sorted {
+			order.Versions = append(order.Versions, v.Version)
+		}
+		orders = append(orders, order)
+	}
 
-	p := progress.New(progress.WithGradient("#000000", "#FFFFFF"))
+	picker := &pickerInstaller{
+		repo:      repo,
+		versions:  versions,
+		selection: selection,
+		policy:    policy,
+		prefix:    prefix,
+		paths:     paths,
+		storage:   storage,
+		owner:     owner,
+		scanner:   config.Scanner,
+		hooks:     installHooks{global: config.HookConfig, versions: config.Hooks},
+		cli:       c,
+	}
+	prompt := &promptPicker{
+		in:        bufio.NewReader(os.Stdin),
+		out:       os.Stdout,
+		installer: picker,
+		versions:  orders[0].Versions,
+		labels:    labels,
+		files:     pickerFiles(versions, selection),
+		platform:  selection.Os + "/" + selection.Arch,
+		size:      units.size,
+	}
+	if !canRunTUI(os.Stdin, os.Stdout, os.Getenv("TERM")) {
+		runPrompt(ctx, prompt)
+		return
+	}
 
-	m := model{ctx: ctx, list: l, progress: p, repo: repo, versions: versions}
+	m := tui.New(ctx, tui.Options{
+		Orders:     orders,
+		Labels:     labels,
+		Files:      pickerFiles(versions, selection),
+		Installer:  picker,
+		Keys:       keys,
+		Platform:   selection.Os + "/" + selection.Arch,
+		Size:       units.size,
+		Hint:       errorHint,
+		Standalone: true,
+	})
 
 	app := tea.NewProgram(m)
 
-	repo.onProgress = func(ratio float64) {
-		app.Send(progressMsg(ratio))
+	picker.progress = func(msg tui.ProgressMsg) {
+		app.Send(msg)
+	}
+	repo.onReconnect = func(attempt, max int) {
+		app.Send(tui.ReconnectingMsg{Attempt: attempt, Max: max})
 	}
+	repo.gate = &pauseGate{}
 
-	if _, err := app.Run(); err != nil {
+	final, err := app.Run()
+	if err != nil {
+		// The terminal could not be set up: nothing was chosen yet, so the
+		// prompt takes over.
+		if m, ok := final.(tui.Model); !ok || m.State() == tui.Choosing {
+			runPrompt(ctx, prompt)
+			return
+		}
 		fmt.Println("Error running program:", err)
 		os.Exit(1)
 	}
 }
+
+// runPrompt runs the numbered prompt picker, exiting on failure.
+func runPrompt(ctx context.Context, p *promptPicker) {
+	if err := p.run(ctx); err != nil {
+		fmt.Println("Error:", err)
+		if hint := errorHint(err); hint != "" {
+			fmt.Println("Hint:", hint)
+		}
+		os.Exit(1)
+	}
+}
@@ -2,12 +2,15 @@ package main
 
 import (
 	"archive/tar"
+	"bytes"
 	"compress/gzip"
 	"context"
 	"errors"
 	"io"
+	"math/rand"
 	"net/http"
 	"os"
+	"path/filepath"
 	"reflect"
 	"sort"
 	"strings"
@@ -256,3 +259,135 @@ func TestDecompress(t *testing.T) {
 		t.Fatalf("could not decompress")
 	}
 }
+
+// newLongNameArchive returns an archive in format whose file name needs long
+// name records, as found in older release tarballs, with extended attributes
+// and a global header for PAX.
+func newLongNameArchive(t *testing.T, format tar.Format, name string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gzw)
+
+	headers := []*tar.Header{
+		{Name: "go/", Mode: 0755, Typeflag: tar.TypeDir, Format: format},
+		{Name: "go/VERSION", Size: 9, Mode: 0644, Typeflag: tar.TypeReg, Format: format},
+		{Name: name, Size: 7, Mode: 0644, Typeflag: tar.TypeReg, Format: format},
+	}
+	if format == tar.FormatPAX {
+		headers = append([]*tar.Header{{Typeflag: tar.TypeXGlobalHeader, PAXRecords: map[string]string{"comment": "release"}}}, headers...)
+		headers[len(headers)-1].PAXRecords = map[string]string{"SCHILY.xattr.user.origin": "go.dev"}
+	}
+
+	for _, h := range headers {
+		if err := tw.WriteHeader(h); err != nil {
+			t.Fatal(err)
+		}
+		switch h.Name {
+		case "go/VERSION":
+			tw.Write([]byte("go1.22.1\n"))
+		case name:
+			tw.Write([]byte("content"))
+		}
+	}
+	tw.Close()
+	gzw.Close()
+	return buf.Bytes()
+}
+
+func TestDecompressLongNames(t *testing.T) {
+	name := "go/" + strings.Repeat("long-directory-name/", 6) + strings.Repeat("f", 120) + ".go"
+
+	for _, format := range []tar.Format{tar.FormatPAX, tar.FormatGNU} {
+		archive := newLongNameArchive(t, format, name)
+
+		dst := t.TempDir()
+		if err := Decompress(dst, bytes.NewReader(archive), func(float64) {}); err != nil {
+			t.Fatalf("%v: unexpected error: %v", format, err)
+		}
+		b, err := os.ReadFile(filepath.Join(dst, filepath.FromSlash(name)))
+		if err != nil || string(b) != "content" {
+			t.Errorf("%v: expected the long named file to be extracted, got %q (%v)", format, b, err)
+		}
+
+		sums, version, err := scanArchive(bytes.NewReader(archive))
+		if err != nil || version != "go1.22.1" || len(sums) != 2 {
+			t.Errorf("%v: expected both files and the version to be scanned, got %v %s (%v)", format, sums, version, err)
+		}
+	}
+}
+
+func TestDecompressProgress(t *testing.T) {
+	// A large incompressible file keeps the file count still for most of the
+	// extraction.
+	large := make([]byte, 4<<20)
+	rand.New(rand.NewSource(1)).Read(large)
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n", "go/pkg/tool/compile": string(large)})
+
+	var ratios []float64
+	var files []int
+	progress := extractProgress{
+		ratio: func(r float64) { ratios = append(ratios, r) },
+		files: func(done, total int) {
+			if total != 2 {
+				t.Errorf("Expected 2 files in total, got %d", total)
+			}
+			files = append(files, done)
+		},
+	}
+	if err := decompress(t.TempDir(), bytes.NewReader(archive), progress, archiveOptions{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(ratios) < 100 || ratios[len(ratios)-1] != 1 || !sort.Float64sAreSorted(ratios) {
+		t.Errorf("Expected smooth progress up to 1, got %d reports ending with %v", len(ratios), ratios[len(ratios)-1])
+	}
+	if len(files) != len(ratios) || files[len(files)-1] != 2 {
+		t.Errorf("Expected the file counts with each progress, got %v", files)
+	}
+}
+
+func TestDownloadWriter(t *testing.T) {
+	fileContent := "The quick brown fox jumps over the lazy dog"
+
+	client := NewTestClient(func(*http.Request) *http.Response {
+		return &http.Response{
+			StatusCode:    http.StatusOK,
+			Body:          io.NopCloser(strings.NewReader(fileContent)),
+			ContentLength: int64(len(fileContent)),
+		}
+	})
+	repo := &GoRepository{client: client, onProgress: func(ratio float64) {}}
+
+	var buf strings.Builder
+	if err := repo.Download(context.Background(), File{}, &buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if buf.String() != fileContent {
+		t.Errorf("Expected '%s', got '%s'", fileContent, buf.String())
+	}
+
+	path, err := repo.DownloadTemp(context.Background(), File{Filename: "go1.22.1.linux-amd64.tar.gz"}, t.TempDir())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got, err := os.ReadFile(path); err != nil || string(got) != fileContent {
+		t.Errorf("Expected the temporary file to hold the download, got '%s' (%v)", got, err)
+	}
+}
+
+func TestDownloadTempCleanup(t *testing.T) {
+	client := NewTestClient(func(*http.Request) *http.Response {
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(""))}
+	})
+	repo := &GoRepository{client: client, onProgress: func(ratio float64) {}}
+
+	dir := t.TempDir()
+	if _, err := repo.DownloadTemp(context.Background(), File{Filename: "go1.22.1.linux-amd64.tar.gz"}, dir); err == nil {
+		t.Fatal("Expected the download to fail")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Expected the temporary file to be removed, got %v", entries)
+	}
+}
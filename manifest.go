@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// releaseFilename matches the names of the files published on go.dev, such
+// as go1.22.1.linux-amd64.tar.gz or go1.22.1.src.tar.gz.
+var releaseFilename = regexp.MustCompile(`^(go\d+(?:\.\d+)*(?:(?:rc|beta)\d+)?)\.(?:src|([a-z0-9]+)-([a-z0-9]+))\.(tar\.gz|zip|msi|pkg)$`)
+
+// loadManifest reads the releases of a mirror serving the archives without
+// the JSON feed. The manifest is either a copy of the feed or lines of
+// "<sum>  <filename>", as printed by sha256sum or sha512sum. The sums of
+// other algorithms are prefixed with their name, as in "blake3:<sum>".
+func loadManifest(path string) ([]Release, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	releases, err := parseManifest(b)
+	if err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+	return releases, nil
+}
+
+func parseManifest(b []byte) ([]Release, error) {
+	if trimmed := bytes.TrimSpace(b); len(trimmed) > 0 && trimmed[0] == '[' {
+		return decodeReleases(trimmed)
+	}
+
+	byVersion := map[string]*Release{}
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected a checksum and a filename", n)
+		}
+		algorithm, sum, ok := parseManifestSum(fields[0])
+		if !ok {
+			return nil, fmt.Errorf("line %d: %q is not a checksum of a known algorithm", n, fields[0])
+		}
+		f, ok := parseReleaseFilename(strings.TrimPrefix(fields[1], "*"))
+		if !ok {
+			return nil, fmt.Errorf("line %d: %q is not the name of a Go release file", n, fields[1])
+		}
+
+		r, ok := byVersion[f.Version]
+		if !ok {
+			stable := !strings.Contains(f.Version, "rc") && !strings.Contains(f.Version, "beta")
+			r = &Release{Version: f.Version, Stable: stable}
+			byVersion[f.Version] = r
+		}
+		i := slices.IndexFunc(r.Files, func(file File) bool { return file.Filename == f.Filename })
+		if i < 0 {
+			r.Files = append(r.Files, f)
+			i = len(r.Files) - 1
+		}
+		if algorithm == "sha256" {
+			r.Files[i].Sha256 = sum
+			continue
+		}
+		if r.Files[i].Checksums == nil {
+			r.Files[i].Checksums = map[string]string{}
+		}
+		r.Files[i].Checksums[algorithm] = sum
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	releases := make([]Release, 0, len(byVersion))
+	for _, r := range byVersion {
+		releases = append(releases, *r)
+	}
+	sort.Sort(ByRelease(releases))
+	return releases, nil
+}
+
+// parseManifestSum returns the algorithm and the sum of a manifest line,
+// bare sums are sha256 or sha512 depending on their length.
+func parseManifestSum(field string) (string, string, bool) {
+	algorithm, sum, ok := strings.Cut(strings.ToLower(field), ":")
+	if !ok {
+		sum = algorithm
+		switch len(sum) {
+		case sha256.Size * 2:
+			algorithm = "sha256"
+		case sha512.Size * 2:
+			algorithm = "sha512"
+		}
+	}
+
+	h, ok := checksumAlgorithms[algorithm]
+	if !ok {
+		return "", "", false
+	}
+	if b, err := hex.DecodeString(sum); err != nil || len(b) != h().Size() {
+		return "", "", false
+	}
+	return algorithm, sum, true
+}
+
+// parseReleaseFilename describes the release file called name.
+func parseReleaseFilename(name string) (File, bool) {
+	m := releaseFilename.FindStringSubmatch(name)
+	if m == nil {
+		return File{}, false
+	}
+
+	f := File{Filename: name, Version: m[1], Os: m[2], Arch: m[3], Kind: KindArchive}
+	switch {
+	case f.Os == "":
+		f.Kind = KindSource
+	case m[4] == "msi" || m[4] == "pkg":
+		f.Kind = KindInstaller
+	}
+	return f, true
+}
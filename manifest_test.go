@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseManifest(t *testing.T) {
+	sum := strings.Repeat("ab", 32)
+	manifest := "# mirrored from go.dev\n" +
+		sum + "  go1.22.1.linux-amd64.tar.gz\n" +
+		sum + " *go1.22.1.src.tar.gz\n" +
+		"\n" +
+		sum + "  go1.23rc1.darwin-arm64.pkg\n" +
+		sum + "  go1.21.10.windows-amd64.zip\n"
+
+	releases, err := parseManifest([]byte(manifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Release{
+		{Version: "go1.23rc1", Files: Files{
+			{Filename: "go1.23rc1.darwin-arm64.pkg", Os: "darwin", Arch: "arm64", Version: "go1.23rc1", Sha256: sum, Kind: KindInstaller},
+		}},
+		{Version: "go1.22.1", Stable: true, Files: Files{
+			{Filename: "go1.22.1.linux-amd64.tar.gz", Os: "linux", Arch: "amd64", Version: "go1.22.1", Sha256: sum, Kind: KindArchive},
+			{Filename: "go1.22.1.src.tar.gz", Version: "go1.22.1", Sha256: sum, Kind: KindSource},
+		}},
+		{Version: "go1.21.10", Stable: true, Files: Files{
+			{Filename: "go1.21.10.windows-amd64.zip", Os: "windows", Arch: "amd64", Version: "go1.21.10", Sha256: sum, Kind: KindArchive},
+		}},
+	}
+	if !reflect.DeepEqual(releases, want) {
+		t.Errorf("Expected %+v, got %+v", want, releases)
+	}
+
+	sha512 := strings.Repeat("cd", 64)
+	releases, err = parseManifest([]byte(sum + "  go1.22.1.linux-amd64.tar.gz\n" + sha512 + "  go1.22.1.linux-amd64.tar.gz\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := releases[0].Files; len(f) != 1 || f[0].Sha256 != sum || f[0].Checksums["sha512"] != sha512 {
+		t.Errorf("Expected the checksums of a file to be merged, got %+v", f)
+	}
+
+	for _, invalid := range []string{
+		"go1.22.1.linux-amd64.tar.gz\n",
+		"md5:" + sum + "  go1.22.1.linux-amd64.tar.gz\n",
+		sum + "  README.md\n",
+		"abc  go1.22.1.linux-amd64.tar.gz\n",
+	} {
+		if _, err := parseManifest([]byte(invalid)); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestManifestFeed(t *testing.T) {
+	feed := `[{"version": "go1.22.1", "stable": true, "files": [{"filename": "go1.22.1.linux-amd64.tar.gz", "os": "linux", "arch": "amd64", "version": "go1.22.1", "sha256": "abc", "kind": "archive"}]}]`
+	path := filepath.Join(t.TempDir(), "feed.json")
+	if err := os.WriteFile(path, []byte(feed), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	releases, err := loadManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The feed is never requested from the mirror.
+	repo := &GoRepository{url: "http://mirror.invalid", manifest: releases}
+	got, err := repo.GetVersions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Version != "go1.22.1" || got[0].Files[0].Sha256 != "abc" {
+		t.Errorf("Expected the releases of the manifest, got %+v", got)
+	}
+}
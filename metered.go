@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// The policies applied when a large download starts on a metered connection.
+const (
+	MeteredPrompt = "prompt"
+	MeteredDeny   = "deny"
+	MeteredAllow  = "allow"
+)
+
+// meteredThreshold is the size from which downloads are checked, every Go
+// archive is above it.
+const meteredThreshold = 32 << 20
+
+// meteredGuard applies the metered policy before the downloads, the
+// connection is only probed and the user only asked once per process.
+type meteredGuard struct {
+	policy string
+	// detect reports whether the connection is metered, confirm asks the
+	// user, nil when it can't be asked.
+	detect  func(ctx context.Context) (bool, error)
+	confirm func(question string) bool
+	units   byteUnits
+
+	mu      sync.Mutex
+	checked bool
+	err     error
+	// detected caches the result of detect.
+	detected *bool
+}
+
+func newMeteredGuard(policy string, confirm func(string) bool, units byteUnits) (*meteredGuard, error) {
+	switch policy {
+	case "":
+		policy = MeteredPrompt
+	case MeteredPrompt, MeteredDeny, MeteredAllow:
+	default:
+		return nil, fmt.Errorf("unknown metered policy %q, expected prompt, deny or allow", policy)
+	}
+	return &meteredGuard{policy: policy, detect: detectMetered, confirm: confirm, units: units}, nil
+}
+
+// check returns ErrMetered when the download of dlf, size bytes long, must
+// not start.
+func (m *meteredGuard) check(ctx context.Context, dlf File, size int64) error {
+	if m == nil || m.policy == MeteredAllow || size < meteredThreshold {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.checked {
+		return m.err
+	}
+	m.checked = true
+
+	if !m.isMetered(ctx) {
+		return nil
+	}
+
+	question := fmt.Sprintf("The connection is metered, download %s (%s) anyway?", dlf.Filename, m.units.size(size))
+	if m.policy == MeteredPrompt && m.confirm != nil && m.confirm(question) {
+		return nil
+	}
+	m.err = fmt.Errorf("%w: not downloading %s", ErrMetered, dlf.Filename)
+	return m.err
+}
+
+// isMetered probes the connection once, m.mu being held.
+func (m *meteredGuard) isMetered(ctx context.Context) bool {
+	if m.detected == nil {
+		metered, err := m.detect(ctx)
+		metered = err == nil && metered
+		m.detected = &metered
+	}
+	return *m.detected
+}
+
+// pending reports whether the download of size bytes needs the consent of
+// the user, the connection being metered under the prompt policy. The
+// pickers ask for it along with the installation, then allow the download.
+func (m *meteredGuard) pending(ctx context.Context, size int64) bool {
+	if m == nil || m.policy != MeteredPrompt || size < meteredThreshold {
+		return false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.isMetered(ctx)
+}
+
+// allow lets the downloads of the process start, the user consented.
+func (m *meteredGuard) allow() {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.checked, m.err = true, nil
+}
+
+// parseNetworkManagerMetered parses the Metered property of NetworkManager,
+// as printed by busctl, the guessed values count.
+func parseNetworkManagerMetered(out string) bool {
+	// NM_METERED_YES is 1 and NM_METERED_GUESS_YES is 3.
+	fields := strings.Fields(out)
+	return len(fields) == 2 && fields[0] == "u" && (fields[1] == "1" || fields[1] == "3")
+}
+
+// parseNetworkCostType parses the NetworkCostType of the Windows connection
+// cost API, only unrestricted connections are free.
+func parseNetworkCostType(out string) bool {
+	switch strings.TrimSpace(out) {
+	case "Fixed", "Variable":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,20 @@
+//go:build linux
+
+package main
+
+import (
+	"context"
+	"os/exec"
+)
+
+// detectMetered asks NetworkManager whether the primary connection is
+// metered, systems without it are assumed not to be.
+func detectMetered(ctx context.Context) (bool, error) {
+	out, err := exec.CommandContext(ctx, "busctl", "--system", "get-property",
+		"org.freedesktop.NetworkManager", "/org/freedesktop/NetworkManager",
+		"org.freedesktop.NetworkManager", "Metered").Output()
+	if err != nil {
+		return false, err
+	}
+	return parseNetworkManagerMetered(string(out)), nil
+}
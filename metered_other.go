@@ -0,0 +1,10 @@
+//go:build !linux && !windows
+
+package main
+
+import "context"
+
+// detectMetered can't tell metered connections on this platform.
+func detectMetered(ctx context.Context) (bool, error) {
+	return false, nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMeteredGuard(t *testing.T) {
+	dlf := File{Filename: "go1.22.1.linux-amd64.tar.gz"}
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		policy  string
+		metered bool
+		answer  bool
+		err     error
+	}{
+		{policy: MeteredPrompt, metered: false},
+		{policy: MeteredPrompt, metered: true, answer: true},
+		{policy: MeteredPrompt, metered: true, answer: false, err: ErrMetered},
+		{policy: MeteredDeny, metered: true, answer: true, err: ErrMetered},
+		{policy: MeteredAllow, metered: true},
+	} {
+		asked := 0
+		m, err := newMeteredGuard(tc.policy, func(string) bool { asked++; return tc.answer }, decimalUnits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m.detect = func(context.Context) (bool, error) { return tc.metered, nil }
+
+		for i := 0; i < 2; i++ {
+			if err := m.check(ctx, dlf, 100<<20); !errors.Is(err, tc.err) {
+				t.Errorf("Expected %v with %s on a metered=%v connection, got %v", tc.err, tc.policy, tc.metered, err)
+			}
+		}
+		if asked > 1 {
+			t.Errorf("Expected the user to be asked once, got %d", asked)
+		}
+	}
+
+	m, _ := newMeteredGuard(MeteredDeny, nil, decimalUnits)
+	m.detect = func(context.Context) (bool, error) { return true, nil }
+	if err := m.check(ctx, File{Filename: "go1.22.1.windows-amd64.zip.sha256"}, 64); err != nil {
+		t.Errorf("Expected small downloads to go through, got %v", err)
+	}
+
+	if _, err := newMeteredGuard("sometimes", nil, decimalUnits); err == nil {
+		t.Errorf("Expected an error for an unknown policy")
+	}
+
+	var unset *meteredGuard
+	if err := unset.check(ctx, dlf, 100<<20); err != nil {
+		t.Errorf("Expected no check without a guard, got %v", err)
+	}
+	if unset.pending(ctx, 100<<20) {
+		t.Errorf("Expected no consent needed without a guard")
+	}
+}
+
+func TestMeteredGuardPending(t *testing.T) {
+	dlf := File{Filename: "go1.22.1.linux-amd64.tar.gz"}
+	ctx := context.Background()
+
+	probes := 0
+	m, _ := newMeteredGuard(MeteredPrompt, nil, decimalUnits)
+	m.detect = func(context.Context) (bool, error) { probes++; return true, nil }
+	if !m.pending(ctx, 100<<20) || m.pending(ctx, 64) {
+		t.Errorf("Expected only large downloads to need consent")
+	}
+	if err := m.check(ctx, dlf, 100<<20); !errors.Is(err, ErrMetered) {
+		t.Errorf("Expected ErrMetered without anyone to ask, got %v", err)
+	}
+	m.allow()
+	if err := m.check(ctx, dlf, 100<<20); err != nil {
+		t.Errorf("Expected the download to go through once allowed, got %v", err)
+	}
+	if probes != 1 {
+		t.Errorf("Expected the connection to be probed once, got %d", probes)
+	}
+
+	m, _ = newMeteredGuard(MeteredDeny, nil, decimalUnits)
+	m.detect = func(context.Context) (bool, error) { return true, nil }
+	if m.pending(ctx, 100<<20) {
+		t.Errorf("Expected the deny policy not to ask")
+	}
+}
+
+func TestParseMetered(t *testing.T) {
+	for out, want := range map[string]bool{"u 1\n": true, "u 3\n": true, "u 2\n": false, "u 4\n": false, "u 0\n": false, "": false} {
+		if got := parseNetworkManagerMetered(out); got != want {
+			t.Errorf("Expected %q to be metered=%v", out, want)
+		}
+	}
+	for out, want := range map[string]bool{"Fixed\r\n": true, "Variable\r\n": true, "Unrestricted\r\n": false, "Unknown\r\n": false} {
+		if got := parseNetworkCostType(out); got != want {
+			t.Errorf("Expected %q to be metered=%v", out, want)
+		}
+	}
+}
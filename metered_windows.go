@@ -0,0 +1,20 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"os/exec"
+)
+
+const networkCostScript = `[Windows.Networking.Connectivity.NetworkInformation, Windows.Networking.Connectivity, ContentType = WindowsRuntime]::GetInternetConnectionProfile().GetConnectionCost().NetworkCostType`
+
+// detectMetered reads the cost of the internet connection from the Windows
+// connection cost API.
+func detectMetered(ctx context.Context) (bool, error) {
+	out, err := exec.CommandContext(ctx, "powershell", "-NoProfile", "-NonInteractive", "-Command", networkCostScript).Output()
+	if err != nil {
+		return false, err
+	}
+	return parseNetworkCostType(string(out)), nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+)
+
+// downloadMetrics counts the downloads and the checks of go-dl watch,
+// served in the Prometheus text format under /metrics on its debug endpoint.
+type downloadMetrics struct {
+	downloads   atomic.Int64
+	bytes       atomic.Int64
+	failures    atomic.Int64
+	cacheHits   atomic.Int64
+	cacheMisses atomic.Int64
+
+	checks        atomic.Int64
+	checkFailures atomic.Int64
+	notifications atomic.Int64
+}
+
+var metrics downloadMetrics
+
+// download counts a finished download, a canceled one is not a failure of
+// the upstream.
+func (m *downloadMetrics) download(err error) {
+	switch {
+	case err == nil:
+		m.downloads.Add(1)
+	case !errors.Is(err, context.Canceled):
+		m.failures.Add(1)
+	}
+}
+
+func (m *downloadMetrics) cache(hit bool) {
+	if hit {
+		m.cacheHits.Add(1)
+	} else {
+		m.cacheMisses.Add(1)
+	}
+}
+
+// check counts a check of the releases by go-dl watch.
+func (m *downloadMetrics) check(err error) {
+	m.checks.Add(1)
+	if err != nil {
+		m.checkFailures.Add(1)
+	}
+}
+
+func (m *downloadMetrics) writeTo(w io.Writer) {
+	for _, c := range []struct {
+		name, help string
+		value      *atomic.Int64
+	}{
+		{"go_dl_downloads_total", "Files downloaded from the upstream.", &m.downloads},
+		{"go_dl_download_bytes_total", "Bytes downloaded from the upstream.", &m.bytes},
+		{"go_dl_upstream_failures_total", "Downloads from the upstream which failed.", &m.failures},
+		{"go_dl_cache_hits_total", "Archives read from the cache.", &m.cacheHits},
+		{"go_dl_cache_misses_total", "Archives missing from the cache.", &m.cacheMisses},
+		{"go_dl_watch_checks_total", "Checks of the releases.", &m.checks},
+		{"go_dl_watch_check_failures_total", "Checks of the releases which failed.", &m.checkFailures},
+		{"go_dl_watch_notifications_total", "Releases and announcements notified of.", &m.notifications},
+	} {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", c.name, c.help, c.name, c.name, c.value.Load())
+	}
+}
+
+func (m *downloadMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	m.writeTo(w)
+}
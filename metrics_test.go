@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDownloadMetrics(t *testing.T) {
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n"})
+	dlf := File{Filename: "go1.22.1.linux-amd64.tar.gz", Version: "go1.22.1"}
+
+	downloads, downloaded, hits, misses := metrics.downloads.Load(), metrics.bytes.Load(), metrics.cacheHits.Load(), metrics.cacheMisses.Load()
+
+	storage := &diskStorage{dir: filepath.Join(t.TempDir(), "archives")}
+	for range 2 {
+		p := newPipeline(newTestArchiveRepo(archive), storage, dlf, t.TempDir(), processOwner, newTestPaths(t))
+		if err := p.run(context.Background()); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	if got := metrics.downloads.Load() - downloads; got != 1 {
+		t.Errorf("Expected 1 download, got %d", got)
+	}
+	if got := metrics.bytes.Load() - downloaded; got != int64(len(archive)) {
+		t.Errorf("Expected %d bytes downloaded, got %d", len(archive), got)
+	}
+	if metrics.cacheHits.Load()-hits != 1 || metrics.cacheMisses.Load()-misses != 1 {
+		t.Errorf("Expected a cache miss then a hit")
+	}
+
+	failures := metrics.failures.Load()
+	failing := &GoRepository{client: NewTestClient(func(*http.Request) *http.Response {
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(&failingReader{strings.NewReader("go"), errors.New("connection reset")}), ContentLength: 100}
+	}), onProgress: func(float64) {}}
+	if err := failing.Download(context.Background(), dlf, io.Discard); err == nil {
+		t.Fatal("Expected the download to fail")
+	}
+	if got := metrics.failures.Load() - failures; got != 1 {
+		t.Errorf("Expected 1 upstream failure, got %d", got)
+	}
+
+	var out bytes.Buffer
+	metrics.writeTo(&out)
+	if !strings.Contains(out.String(), "# TYPE go_dl_downloads_total counter\ngo_dl_downloads_total ") {
+		t.Errorf("Expected counters in the Prometheus text format, got %q", out.String())
+	}
+}
+
+func TestWatchMetrics(t *testing.T) {
+	c, out := newTestCLI(t, nil)
+	checks, failures := metrics.checks.Load(), metrics.checkFailures.Load()
+
+	if err := c.run([]string{"watch", "--once"}); err != nil {
+		t.Fatalf("Unexpected error: %v\n%s", err, out)
+	}
+	if metrics.checks.Load()-checks != 1 || metrics.checkFailures.Load() != failures {
+		t.Errorf("Expected a successful check to be counted")
+	}
+
+	var text bytes.Buffer
+	metrics.writeTo(&text)
+	if !strings.Contains(text.String(), "\ngo_dl_watch_checks_total ") {
+		t.Errorf("Expected the checks to be exported, got %q", text.String())
+	}
+}
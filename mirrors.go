@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const defaultMirror = "https://go.dev/dl"
+
+// mirrorBench is the measure of a mirror: the time to the first byte of a
+// ranged download and the throughput of the rest.
+type mirrorBench struct {
+	mirror     string
+	latency    time.Duration
+	throughput float64
+	err        error
+}
+
+// benchMirror downloads the first n bytes of filename from mirror.
+func benchMirror(ctx context.Context, client *http.Client, mirror, filename string, n int64) mirrorBench {
+	b := mirrorBench{mirror: mirror}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, mirror+"/"+filename, nil)
+	if err != nil {
+		b.err = err
+		return b
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=0-%d", n-1))
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		b.err = err
+		return b
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		b.err = fmt.Errorf("unexpected status %s", resp.Status)
+		return b
+	}
+
+	// A mirror ignoring the range sends the whole file, only n bytes count.
+	buf := make([]byte, 1)
+	if _, err := io.ReadFull(resp.Body, buf); err != nil {
+		b.err = err
+		return b
+	}
+	b.latency = time.Since(start)
+
+	read, err := io.Copy(io.Discard, io.LimitReader(resp.Body, n-1))
+	if err != nil {
+		b.err = err
+		return b
+	}
+	if elapsed := time.Since(start) - b.latency; elapsed > 0 {
+		b.throughput = float64(read+1) / elapsed.Seconds()
+	}
+	return b
+}
+
+// rankMirrors sorts the benches by decreasing throughput, failed mirrors
+// last.
+func rankMirrors(benches []mirrorBench) {
+	sort.SliceStable(benches, func(i, j int) bool {
+		if (benches[i].err == nil) != (benches[j].err == nil) {
+			return benches[i].err == nil
+		}
+		return benches[i].throughput > benches[j].throughput
+	})
+}
+
+// setConfigValue sets key in the configuration file at path, keeping the
+// other settings.
+func setConfigValue(path, key string, value any) error {
+	settings := map[string]json.RawMessage{}
+	b, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if len(b) > 0 {
+		if err := json.Unmarshal(b, &settings); err != nil {
+			return fmt.Errorf("invalid config %s: %w", path, err)
+		}
+	}
+
+	if settings[key], err = json.Marshal(value); err != nil {
+		return err
+	}
+	if b, err = json.MarshalIndent(settings, "", "  "); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(b, '\n'), 0644)
+}
+
+// benchMirrors measures go.dev, the current mirror and the configured ones.
+func (c *cli) benchMirrors(args []string) error {
+	fs := flag.NewFlagSet("mirrors", flag.ContinueOnError)
+	size := fs.Int64("bytes", 4<<20, "bytes downloaded from each mirror")
+	save := fs.Bool("save", false, "make the fastest mirror the default in the configuration")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-dl mirrors bench [--bytes n] [--save]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// The flags may also follow the command.
+	command := fs.Arg(0)
+	if err := fs.Parse(fs.Args()[min(1, fs.NArg()):]); err != nil {
+		return err
+	}
+	if command != "bench" {
+		fs.Usage()
+		return fmt.Errorf("unknown mirrors command %q", command)
+	}
+	if *size < 1 {
+		return errors.New("--bytes must be positive")
+	}
+
+	releases, err := c.repo.GetVersions(c.ctx)
+	if err != nil {
+		return err
+	}
+	latest, ok := latestStable(releases)
+	if !ok {
+		return fmt.Errorf("no stable release to download: %w", ErrVersionNotFound)
+	}
+	dlf, ok := c.selection.Pick(latest.Files)
+	if !ok {
+		return fmt.Errorf("%w for %s on %s/%s", ErrNoMatchingFile, latest.Version, c.selection.Os, c.selection.Arch)
+	}
+
+	// The system policy can restrict the mirrors to its own.
+	mirrors := []string{defaultMirror}
+	if len(c.policy.Mirrors) > 0 {
+		mirrors = nil
+	}
+	for _, m := range slices.Concat([]string{c.repo.url}, c.mirrors, c.policy.Mirrors) {
+		m = strings.TrimSuffix(m, "/")
+		if m != "" && !slices.Contains(mirrors, m) && c.policy.AllowMirror(m) {
+			mirrors = append(mirrors, m)
+		}
+	}
+
+	var benches []mirrorBench
+	for _, m := range mirrors {
+		benches = append(benches, benchMirror(c.ctx, c.repo.client, m, dlf.Filename, *size))
+		if c.ctx.Err() != nil {
+			return c.ctx.Err()
+		}
+	}
+	rankMirrors(benches)
+
+	fmt.Fprintf(c.stdout, "Downloaded %s of %s from each mirror\n", c.units.size(*size), dlf.Filename)
+	w := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MIRROR\tLATENCY\tTHROUGHPUT")
+	for _, b := range benches {
+		if b.err != nil {
+			fmt.Fprintf(w, "%s\t-\t%v\n", b.mirror, b.err)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", b.mirror, b.latency.Round(time.Millisecond), c.units.speed(b.throughput))
+	}
+	w.Flush()
+
+	if !*save {
+		return nil
+	}
+	if benches[0].err != nil {
+		return errors.New("no mirror could be reached")
+	}
+	if err := setConfigValue(c.config, "mirror", benches[0].mirror); err != nil {
+		return wrapPermission(err)
+	}
+	fmt.Fprintf(c.stdout, "Using %s by default\n", benches[0].mirror)
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBenchMirror(t *testing.T) {
+	content := bytes.Repeat([]byte("go"), 64<<10)
+	var ranges []string
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		http.ServeContent(w, r, "go1.22.1.linux-amd64.tar.gz", time.Time{}, bytes.NewReader(content))
+	}))
+	defer fast.Close()
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		w.Write(content[:1024])
+		w.(http.Flusher).Flush()
+		time.Sleep(50 * time.Millisecond)
+		w.Write(content[1024:])
+	}))
+	defer slow.Close()
+	missing := httptest.NewServer(http.NotFoundHandler())
+	defer missing.Close()
+
+	var benches []mirrorBench
+	for _, m := range []string{missing.URL, slow.URL, fast.URL} {
+		benches = append(benches, benchMirror(context.Background(), http.DefaultClient, m, "go1.22.1.linux-amd64.tar.gz", 32<<10))
+	}
+	rankMirrors(benches)
+
+	if benches[0].mirror != fast.URL || benches[1].mirror != slow.URL || benches[2].err == nil {
+		t.Errorf("Expected the fast, slow then missing mirrors, got %+v", benches)
+	}
+	if len(ranges) != 1 || ranges[0] != "bytes=0-32767" {
+		t.Errorf("Expected a ranged download, got %q", ranges)
+	}
+	if benches[1].latency < 50*time.Millisecond || benches[1].throughput <= 0 {
+		t.Errorf("Expected the latency and throughput of the slow mirror, got %+v", benches[1])
+	}
+}
+
+func TestSetConfigValue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"units": "binary", "mirror": "https://old.example.com"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := setConfigValue(path, "mirror", "https://fast.example.com/golang"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	config, err := loadConfig(path)
+	if err != nil || config.Mirror != "https://fast.example.com/golang" || config.Units != "binary" {
+		t.Errorf("Expected the mirror to change and the units to be kept, got %+v (%v)", config, err)
+	}
+}
+
+func TestMirrorsBenchSave(t *testing.T) {
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n"})
+	c, out := newTestCLI(t, archive)
+	c.units = decimalUnits
+	c.config = filepath.Join(t.TempDir(), "config.json")
+	c.mirrors = []string{"https://mirror.example.com/golang/"}
+
+	if err := c.run([]string{"mirrors", "bench", "--bytes", "1024", "--save"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, mirror := range []string{"https://go.dev/dl ", "https://mirror.example.com/golang "} {
+		if !strings.Contains(out.String(), mirror) {
+			t.Errorf("Expected %s to be measured, got %q", mirror, out.String())
+		}
+	}
+	if config, err := loadConfig(c.config); err != nil || config.Mirror == "" {
+		t.Errorf("Expected the fastest mirror to be saved, got %+v (%v)", config, err)
+	}
+}
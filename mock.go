@@ -0,0 +1,248 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// mockReleases are the versions served by the mock repository.
+var mockReleases = []struct {
+	version string
+	stable  bool
+}{
+	{"go1.23rc1", false},
+	{"go1.22.1", true},
+	{"go1.22.0", true},
+	{"go1.21.10", true},
+}
+
+// mockFailures are the probabilities, between 0 and 1, of the faults
+// injected by the mock repository: feed fails the releases request, download
+// drops the connection halfway and checksum corrupts the archive.
+type mockFailures map[string]float64
+
+func parseMockFailures(s string) (mockFailures, error) {
+	failures := mockFailures{}
+	for _, part := range strings.Split(s, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+
+		name, value, _ := strings.Cut(part, "=")
+		name = strings.TrimSpace(name)
+		if name != "feed" && name != "download" && name != "checksum" {
+			return nil, fmt.Errorf("unknown mock failure %q, expected feed, download or checksum", name)
+		}
+		p, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || p < 0 || p > 1 {
+			return nil, fmt.Errorf("mock failure %s expects a probability between 0 and 1", name)
+		}
+		failures[name] = p
+	}
+	return failures, nil
+}
+
+// mockTransport serves a fake go.dev with synthetic releases for a platform,
+// whose downloads take delay.
+type mockTransport struct {
+	feed     []byte
+	archives map[string][]byte
+	delay    time.Duration
+	failures mockFailures
+	// mu guards rand, the requests can run concurrently.
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+func newMockTransport(selection Selection, delay time.Duration, failures mockFailures) (*mockTransport, error) {
+	t := &mockTransport{
+		archives: map[string][]byte{},
+		delay:    delay,
+		failures: failures,
+		rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+
+	var releases []Release
+	for _, r := range mockReleases {
+		archive, err := mockArchive(r.version)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(archive)
+
+		name := fmt.Sprintf("%s.%s-%s%s", r.version, selection.Os, selection.Arch, selection.Extension())
+		t.archives[name] = archive
+		releases = append(releases, Release{
+			Version: r.version,
+			Stable:  r.stable,
+			Files: Files{{
+				Filename: name, Os: selection.Os, Arch: selection.Arch, Version: r.version,
+				Sha256: hex.EncodeToString(sum[:]), Size: len(archive), Kind: selection.Kind(),
+			}},
+		})
+	}
+
+	feed, err := json.Marshal(releases)
+	if err != nil {
+		return nil, err
+	}
+	t.feed = feed
+	return t, nil
+}
+
+// mockArchive returns a release archive installing a go command which only
+// prints its version, padded with random data so downloads take some time.
+func mockArchive(version string) ([]byte, error) {
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gzw)
+
+	padding := make([]byte, 1<<20)
+	rand.New(rand.NewSource(int64(len(version)))).Read(padding)
+
+	for _, entry := range []struct {
+		name    string
+		mode    int64
+		content []byte
+	}{
+		{"go/", 0755, nil},
+		{"go/bin/", 0755, nil},
+		{"go/pkg/", 0755, nil},
+		{"go/VERSION", 0644, []byte(version + "\n")},
+		{"go/bin/go", 0755, []byte(fmt.Sprintf("#!/bin/sh\necho \"go version %s mock\"\n", version))},
+		{"go/pkg/mock.bin", 0644, padding},
+	} {
+		header := &tar.Header{Name: entry.name, Mode: entry.mode, Size: int64(len(entry.content)), Typeflag: tar.TypeReg}
+		if strings.HasSuffix(entry.name, "/") {
+			header.Typeflag = tar.TypeDir
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(entry.content); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gzw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *mockTransport) fails(name string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.rand.Float64() < t.failures[name]
+}
+
+func (t *mockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// The webhooks are accepted, and never posted.
+	if req.Method == http.MethodPost {
+		return mockResponse(req, http.StatusOK, nil), nil
+	}
+	if req.URL.Query().Get("mode") == "json" {
+		if t.fails("feed") {
+			return mockResponse(req, http.StatusServiceUnavailable, []byte("mock feed failure")), nil
+		}
+		return mockResponse(req, http.StatusOK, t.feed), nil
+	}
+
+	archive, ok := t.archives[path.Base(req.URL.Path)]
+	if !ok {
+		return mockResponse(req, http.StatusNotFound, []byte("not found")), nil
+	}
+	if t.fails("checksum") {
+		archive = append([]byte{}, archive...)
+		archive[len(archive)/2] ^= 0xff
+	}
+
+	offset := 0
+	status := http.StatusOK
+	if r := req.Header.Get("Range"); r != "" {
+		if _, err := fmt.Sscanf(r, "bytes=%d-", &offset); err != nil || offset >= len(archive) {
+			return mockResponse(req, http.StatusRequestedRangeNotSatisfiable, nil), nil
+		}
+		status = http.StatusPartialContent
+	}
+
+	resp := mockResponse(req, status, archive[offset:])
+	if status == http.StatusPartialContent {
+		resp.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, len(archive)-1, len(archive)))
+	}
+
+	var failAt int64 = -1
+	if t.fails("download") {
+		failAt = int64(len(archive)-offset) / 2
+	}
+	resp.Body = io.NopCloser(&slowReader{
+		ctx:    req.Context(),
+		r:      bytes.NewReader(archive[offset:]),
+		delay:  t.delay,
+		total:  len(archive),
+		failAt: failAt,
+	})
+	return resp, nil
+}
+
+func mockResponse(req *http.Request, status int, body []byte) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Header:        http.Header{},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// slowReader spreads the reads of a total bytes long body over delay, and
+// fails once failAt bytes were read when positive.
+type slowReader struct {
+	ctx    context.Context
+	r      io.Reader
+	delay  time.Duration
+	total  int
+	failAt int64
+	read   int64
+}
+
+func (s *slowReader) Read(p []byte) (int, error) {
+	if s.failAt >= 0 && s.read >= s.failAt {
+		return 0, errors.New("mock connection reset")
+	}
+
+	const chunk = 32 * 1024
+	if len(p) > chunk {
+		p = p[:chunk]
+	}
+	if s.delay > 0 && s.total > 0 {
+		select {
+		case <-time.After(s.delay * time.Duration(len(p)) / time.Duration(s.total)):
+		case <-s.ctx.Done():
+			return 0, s.ctx.Err()
+		}
+	}
+
+	n, err := s.r.Read(p)
+	s.read += int64(n)
+	return n, err
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestParseMockFailures(t *testing.T) {
+	got, err := parseMockFailures("feed=0.2, download=1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if want := (mockFailures{"feed": 0.2, "download": 1}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	for _, s := range []string{"disk=0.1", "feed=2", "feed"} {
+		if _, err := parseMockFailures(s); err == nil {
+			t.Errorf("Expected an error for %q", s)
+		}
+	}
+}
+
+func newMockRepository(t *testing.T, failures mockFailures) *GoRepository {
+	t.Helper()
+
+	mt, err := newMockTransport(Selection{Os: "linux", Arch: "amd64"}, 0, failures)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &GoRepository{url: "https://go.dev/dl", client: &http.Client{Transport: mt}, onProgress: func(float64) {}}
+}
+
+func TestMockInstall(t *testing.T) {
+	repo := newMockRepository(t, nil)
+	releases, err := repo.GetVersions(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	release, err := resolveRelease(releases, "stable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dlf, ok := Selection{Os: "linux", Arch: "amd64"}.Pick(release.Files)
+	if !ok {
+		t.Fatalf("Expected an archive for linux/amd64 in %v", release.Files)
+	}
+
+	prefix := t.TempDir()
+	if err := newPipeline(repo, nil, dlf, prefix, processOwner, newTestPaths(t)).run(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if v, err := installedVersion(filepath.Join(prefix, "go")); err != nil || v != "go1.22.1" {
+		t.Errorf("Expected go1.22.1 to be installed, got %s (%v)", v, err)
+	}
+}
+
+func TestMockFailures(t *testing.T) {
+	repo := newMockRepository(t, mockFailures{"feed": 1})
+	if _, err := repo.GetVersions(context.Background()); err == nil {
+		t.Errorf("Expected the feed to fail")
+	}
+
+	repo = newMockRepository(t, nil)
+	releases, err := repo.GetVersions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dlf := releases[0].Files[0]
+
+	repo.client.Transport.(*mockTransport).failures = mockFailures{"checksum": 1}
+	p := newPipeline(repo, nil, dlf, t.TempDir(), processOwner, newTestPaths(t))
+	if err := p.run(context.Background()); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Expected ErrChecksumMismatch, got %v", err)
+	}
+
+	repo.client.Transport.(*mockTransport).failures = mockFailures{"download": 1}
+	if err := repo.Download(context.Background(), dlf, io.Discard); err == nil {
+		t.Errorf("Expected the download to fail")
+	}
+}
+
+func TestMockWebhooks(t *testing.T) {
+	repo := newMockRepository(t, nil)
+	if err := postWebhook(context.Background(), repo.client, "https://hooks.example.com/all", installEvent{Event: "install"}); err != nil {
+		t.Errorf("Expected the webhook to be accepted by the mock, got %v", err)
+	}
+}
+
+func TestMockConcurrentDownloads(t *testing.T) {
+	repo := newMockRepository(t, mockFailures{"download": 0.5})
+	releases, err := repo.GetVersions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for _, r := range releases {
+		wg.Add(1)
+		go func(dlf File) {
+			defer wg.Done()
+			repo.download(context.Background(), dlf, io.Discard)
+		}(r.Files[0])
+	}
+	wg.Wait()
+}
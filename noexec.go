@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"runtime"
+	"sync"
+)
+
+// canExec reports whether programs can be run from dir, which is not the
+// case on the filesystems mounted noexec, as /tmp often is on hardened hosts.
+func canExec(dir string) (bool, error) {
+	if runtime.GOOS == "windows" {
+		return true, nil
+	}
+
+	f, err := createTemp(dir, ".go-dl-exec-*")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString("#!/bin/sh\nexit 0\n")
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0755)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	err = exec.Command(f.Name()).Run()
+	if errors.Is(err, os.ErrPermission) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// useExecTempDir points the temporary directory of go-dl and of the commands
+// it runs, builds from source and hooks, to a directory whose
+// programs can be run: staging when configured, the system one unless it is
+// mounted noexec, or fallback.
+func useExecTempDir(staging, fallback string) error {
+	dir := staging
+	if dir == "" {
+		if ok, err := canExec(os.TempDir()); ok || err != nil {
+			return nil
+		}
+		dir = fallback
+		slog.Warn("the temporary directory is mounted noexec, using another one", "dir", dir)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if ok, err := canExec(dir); err == nil && !ok {
+		return fmt.Errorf("programs cannot be run from the staging directory %s, it is mounted noexec", dir)
+	}
+
+	if err := os.Setenv("TMPDIR", dir); err != nil {
+		return err
+	}
+	return os.Setenv("GOTMPDIR", dir)
+}
+
+// execTempDir holds the directories of useExecTempDir, whose probes exec a
+// program, so it only runs once go-dl is about to build from source or run
+// hooks.
+type execTempDir struct {
+	staging, fallback string
+
+	once sync.Once
+	err  error
+}
+
+// execTemp is the temporary directory of the programs run by go-dl, none
+// until configured at startup.
+var execTemp = &execTempDir{}
+
+// prepare runs useExecTempDir the first time it's called, and returns its
+// error.
+func (d *execTempDir) prepare() error {
+	d.once.Do(func() {
+		if d.staging != "" || d.fallback != "" {
+			d.err = useExecTempDir(d.staging, d.fallback)
+		}
+	})
+	return d.err
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestCanExec(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("there is no noexec on windows")
+	}
+
+	ok, err := canExec(t.TempDir())
+	if err != nil || !ok {
+		t.Errorf("Expected programs to run from the test directory, got %v (%v)", ok, err)
+	}
+
+	if _, err := canExec(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Errorf("Expected an error for a missing directory")
+	}
+}
+
+func TestUseExecTempDir(t *testing.T) {
+	t.Setenv("TMPDIR", os.Getenv("TMPDIR"))
+	t.Setenv("GOTMPDIR", os.Getenv("GOTMPDIR"))
+
+	staging := filepath.Join(t.TempDir(), "staging")
+	if err := useExecTempDir(staging, ""); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if os.Getenv("TMPDIR") != staging || os.Getenv("GOTMPDIR") != staging {
+		t.Errorf("Expected the temporary directories to be %s, got %s and %s", staging, os.Getenv("TMPDIR"), os.Getenv("GOTMPDIR"))
+	}
+	if _, err := os.Stat(staging); err != nil {
+		t.Errorf("Expected the staging directory to be created: %v", err)
+	}
+}
+
+func TestExecTempDirPrepare(t *testing.T) {
+	t.Setenv("TMPDIR", os.Getenv("TMPDIR"))
+	t.Setenv("GOTMPDIR", os.Getenv("GOTMPDIR"))
+
+	staging := filepath.Join(t.TempDir(), "staging")
+	d := &execTempDir{staging: staging}
+	if _, err := os.Stat(staging); !os.IsNotExist(err) {
+		t.Fatalf("Expected nothing to be set up before prepare, got %v", err)
+	}
+	if err := d.prepare(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if os.Getenv("TMPDIR") != staging {
+		t.Errorf("Expected the temporary directory to be %s, got %s", staging, os.Getenv("TMPDIR"))
+	}
+
+	os.RemoveAll(staging)
+	if err := d.prepare(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := os.Stat(staging); !os.IsNotExist(err) {
+		t.Errorf("Expected the setup to run only once, got %v", err)
+	}
+}
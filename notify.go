@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+)
+
+// postWebhook posts payload as JSON to the webhook target.
+func postWebhook(ctx context.Context, client *http.Client, target string, payload any) error {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		// The error of the client repeats the whole URL.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("webhook %s: %w", redactURL(target), err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s: %s", redactURL(target), resp.Status)
+	}
+	return nil
+}
+
+// redactURL returns rawURL without its path and query, which often hold the
+// secret of webhooks.
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return "(invalid url)"
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// desktopCommand returns the command showing a desktop notification on goos.
+func desktopCommand(goos, title, message string) (string, []string) {
+	switch goos {
+	case "darwin":
+		quote := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace
+		return "osascript", []string{"-e", fmt.Sprintf(`display notification "%s" with title "%s"`, quote(message), quote(title))}
+	case "windows":
+		quote := func(s string) string { return "'" + strings.ReplaceAll(s, "'", "''") + "'" }
+		script := "Add-Type -AssemblyName System.Windows.Forms; $n = New-Object System.Windows.Forms.NotifyIcon; " +
+			"$n.Icon = [System.Drawing.SystemIcons]::Information; $n.Visible = $true; " +
+			fmt.Sprintf("$n.ShowBalloonTip(10000, %s, %s, 'Info'); Start-Sleep 10; $n.Dispose()", quote(title), quote(message))
+		return "powershell", []string{"-NoProfile", "-Command", script}
+	default:
+		return "notify-send", []string{"--app-name=go-dl", title, message}
+	}
+}
+
+// notifyDesktop shows a desktop notification.
+func notifyDesktop(title, message string) error {
+	name, args := desktopCommand(runtime.GOOS, title, message)
+	return runAutomation(name, args...)
+}
+
+// WebhookConfig is a webhook notified of the installations, and of the
+// releases go-dl watch notifies of.
+type WebhookConfig struct {
+	URL string `json:"url"`
+	// Format is json for the generic installEvent and watchEvent, or slack
+	// for the messages of Slack incoming webhooks.
+	Format string `json:"format,omitempty"`
+	// Events are the outcomes and watch events notified, success and
+	// failure by default.
+	Events []string `json:"events,omitempty"`
+}
+
+// The outcomes of an installation, and the events of go-dl watch.
+const (
+	installSuccess    = "success"
+	installFailure    = "failure"
+	watchRelease      = "release"
+	watchAnnouncement = "announcement"
+)
+
+// wants reports whether w is notified of event.
+func (w WebhookConfig) wants(event string) bool {
+	if len(w.Events) == 0 {
+		return event == installSuccess || event == installFailure
+	}
+	return slices.Contains(w.Events, event)
+}
+
+// webhookTimeout bounds each webhook call, a webhook down doesn't hold the
+// installation back.
+const webhookTimeout = 10 * time.Second
+
+// check validates the webhook configuration.
+func (w WebhookConfig) check() error {
+	if u, err := url.Parse(w.URL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("invalid webhook url %q", redactURL(w.URL))
+	}
+	if w.Format != "" && w.Format != "json" && w.Format != "slack" {
+		return fmt.Errorf("unknown webhook format %q, expected json or slack", w.Format)
+	}
+	for _, e := range w.Events {
+		if e != installSuccess && e != installFailure && e != watchRelease && e != watchAnnouncement {
+			return fmt.Errorf("unknown webhook event %q, expected success, failure, release or announcement", e)
+		}
+	}
+	return nil
+}
+
+// installEvent is the JSON posted to the webhooks once an installation
+// succeeded or failed.
+type installEvent struct {
+	Event    string `json:"event"`
+	Status   string `json:"status"`
+	Version  string `json:"version"`
+	Previous string `json:"previous,omitempty"`
+	Host     string `json:"host"`
+	Platform string `json:"platform"`
+	Goroot   string `json:"goroot"`
+	GoDL     string `json:"go_dl"`
+	Error    string `json:"error,omitempty"`
+}
+
+// payload returns the body posted to w for event.
+func (w WebhookConfig) payload(event installEvent) any {
+	if w.Format != "slack" {
+		return event
+	}
+	version := strings.TrimPrefix(event.Version, "go")
+	text := fmt.Sprintf(":white_check_mark: Go %s installed on %s (%s) in %s", version, event.Host, event.Platform, event.Goroot)
+	if event.Previous != "" && event.Status == installSuccess {
+		text += ", replacing " + strings.TrimPrefix(event.Previous, "go")
+	}
+	if event.Status == installFailure {
+		text = fmt.Sprintf(":x: Installing Go %s failed on %s (%s): %s", version, event.Host, event.Platform, event.Error)
+	}
+	return map[string]string{"text": text}
+}
+
+// watchPayload returns the body posted to w for the watch event.
+func (w WebhookConfig) watchPayload(event watchEvent) any {
+	if w.Format != "slack" {
+		return event
+	}
+	text := ":mega: " + event.Title
+	if event.URL != "" {
+		text += " " + event.URL
+	}
+	return map[string]string{"text": text}
+}
+
+// notifyInstall reports the outcome err of the installation of version to
+// the webhooks, replacing previous, then returns err. The failed deliveries
+// are only logged.
+func (c *cli) notifyInstall(version, previous string, err error) error {
+	if len(c.webhooks) == 0 {
+		return err
+	}
+	host, _ := os.Hostname()
+	event := installEvent{
+		Event:    "install",
+		Status:   installSuccess,
+		Version:  version,
+		Previous: previous,
+		Host:     host,
+		Platform: c.selection.Os + "/" + c.selection.Arch,
+		Goroot:   c.goroot(),
+		GoDL:     goDLVersion(),
+	}
+	if err != nil {
+		event.Status, event.Error = installFailure, err.Error()
+	}
+
+	// The failures are notified even once the command was interrupted.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(c.ctx), webhookTimeout)
+	defer cancel()
+	for _, w := range c.webhooks {
+		if !w.wants(event.Status) {
+			continue
+		}
+		if errPost := postWebhook(ctx, c.webhookClient, w.URL, w.payload(event)); errPost != nil {
+			slog.Warn("unable to notify the webhook of the installation", "err", errPost)
+		}
+	}
+	return err
+}
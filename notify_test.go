@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDesktopCommand(t *testing.T) {
+	name, args := desktopCommand("darwin", "go-dl", `Go "1.22.2" is released`)
+	if want := []string{"-e", `display notification "Go \"1.22.2\" is released" with title "go-dl"`}; name != "osascript" || !reflect.DeepEqual(args, want) {
+		t.Errorf("Expected %v, got %s %v", want, name, args)
+	}
+	if name, args := desktopCommand("linux", "go-dl", "Go 1.22.2 is released"); name != "notify-send" || args[len(args)-1] != "Go 1.22.2 is released" {
+		t.Errorf("Unexpected command %s %v", name, args)
+	}
+	if _, args := desktopCommand("windows", "go-dl", "it's out"); !strings.Contains(args[len(args)-1], "'it''s out'") {
+		t.Errorf("Expected the message quoted for PowerShell, got %v", args)
+	}
+}
+
+func TestPostWebhookRedactsURL(t *testing.T) {
+	client := NewTestClient(func(*http.Request) *http.Response {
+		return &http.Response{StatusCode: http.StatusForbidden, Status: "403 Forbidden", Body: io.NopCloser(bytes.NewReader(nil))}
+	})
+	err := postWebhook(context.Background(), client, "https://hooks.example.com/services/secret", watchEvent{Event: "release"})
+	if err == nil || strings.Contains(err.Error(), "secret") || !strings.Contains(err.Error(), "hooks.example.com") {
+		t.Errorf("Expected the failure without the secret of the URL, got %v", err)
+	}
+}
+
+func TestNotifyInstall(t *testing.T) {
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n"})
+	c, _ := newTestCLI(t, archive)
+	c.prefix = t.TempDir()
+	c.webhooks = []WebhookConfig{
+		{URL: "https://hooks.example.com/all"},
+		{URL: "https://hooks.example.com/slack", Format: "slack", Events: []string{installFailure}},
+	}
+
+	posted := map[string][]map[string]string{}
+	c.webhookClient = NewTestClient(func(req *http.Request) *http.Response {
+		var body map[string]string
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			t.Errorf("Invalid webhook body: %v", err)
+		}
+		posted[req.URL.Path] = append(posted[req.URL.Path], body)
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(nil))}
+	})
+
+	if err := c.install([]string{"1.22.1"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := posted["/all"]; len(got) != 1 || got[0]["status"] != installSuccess || got[0]["version"] != "go1.22.1" || got[0]["platform"] != "linux/amd64" {
+		t.Errorf("Expected the success posted as JSON, got %v", got)
+	}
+	if len(posted["/slack"]) != 0 {
+		t.Errorf("Expected the successes left out of the failure webhook, got %v", posted["/slack"])
+	}
+
+	err := c.notifyInstall("go1.22.2", "go1.22.1", errors.New("checksum mismatch"))
+	if err == nil || err.Error() != "checksum mismatch" {
+		t.Errorf("Expected the error of the installation returned, got %v", err)
+	}
+	if got := posted["/all"]; len(got) != 2 || got[1]["status"] != installFailure || got[1]["error"] != "checksum mismatch" || got[1]["previous"] != "go1.22.1" {
+		t.Errorf("Expected the failure posted as JSON, got %v", got)
+	}
+	if got := posted["/slack"]; len(got) != 1 || !strings.HasPrefix(got[0]["text"], ":x: Installing Go 1.22.2 failed on ") || !strings.HasSuffix(got[0]["text"], ": checksum mismatch") {
+		t.Errorf("Expected the failure posted as a Slack message, got %v", got)
+	}
+}
+
+func TestWebhookConfigCheck(t *testing.T) {
+	for _, w := range []WebhookConfig{
+		{URL: "hooks.example.com"},
+		{URL: "ftp://hooks.example.com"},
+		{URL: "https://hooks.example.com", Format: "teams"},
+		{URL: "https://hooks.example.com", Events: []string{"started"}},
+	} {
+		if err := w.check(); err == nil {
+			t.Errorf("Expected %+v to be invalid", w)
+		}
+	}
+	if err := (WebhookConfig{URL: "https://hooks.example.com/x", Format: "slack", Events: []string{installFailure}}).check(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
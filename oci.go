@@ -0,0 +1,279 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/blckfalcon/go-dl/versions"
+)
+
+const (
+	ociManifestType = "application/vnd.oci.image.manifest.v1+json"
+	ociConfigType   = "application/vnd.oci.image.config.v1+json"
+	ociLayerType    = "application/vnd.oci.image.layer.v1.tar+gzip"
+
+	// ociGoroot is where the toolchain is installed in the image.
+	ociGoroot = "usr/local/go"
+)
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type ociImageConfig struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	Config       struct {
+		Env []string `json:"Env"`
+	} `json:"config"`
+	RootFS struct {
+		Type    string   `json:"type"`
+		DiffIDs []string `json:"diff_ids"`
+	} `json:"rootfs"`
+}
+
+type ociManifest struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	MediaType     string          `json:"mediaType"`
+	Config        ociDescriptor   `json:"config"`
+	Layers        []ociDescriptor `json:"layers"`
+}
+
+type ociIndex struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	MediaType     string          `json:"mediaType"`
+	Manifests     []ociDescriptor `json:"manifests"`
+}
+
+// exportOCI writes an OCI image layout, as a tar archive, with a single layer
+// holding goroot at /usr/local/go.
+func exportOCI(w io.Writer, goroot, tag string, selection Selection, tmpDir string) error {
+	goroot, err := filepath.EvalSymlinks(goroot)
+	if err != nil {
+		return err
+	}
+
+	layer, err := createTemp(tmpDir, "go-dl-layer-*.tar.gz")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(layer.Name())
+	defer layer.Close()
+
+	diffID := sha256.New()
+	digest := sha256.New()
+	if err := writeLayer(io.MultiWriter(layer, digest), diffID, goroot); err != nil {
+		return err
+	}
+	size, err := layer.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := layer.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	var config ociImageConfig
+	config.Architecture = selection.Arch
+	config.OS = selection.Os
+	config.Config.Env = []string{"PATH=/usr/local/go/bin:/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin", "GOROOT=/" + ociGoroot}
+	config.RootFS.Type = "layers"
+	config.RootFS.DiffIDs = []string{"sha256:" + hex.EncodeToString(diffID.Sum(nil))}
+	configBlob, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	manifest := ociManifest{
+		SchemaVersion: 2,
+		MediaType:     ociManifestType,
+		Config:        blobDescriptor(ociConfigType, configBlob),
+		Layers:        []ociDescriptor{{MediaType: ociLayerType, Digest: "sha256:" + hex.EncodeToString(digest.Sum(nil)), Size: size}},
+	}
+	manifestBlob, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+
+	manifestDescriptor := blobDescriptor(ociManifestType, manifestBlob)
+	if tag != "" {
+		manifestDescriptor.Annotations = map[string]string{"org.opencontainers.image.ref.name": tag}
+	}
+	index, err := json.Marshal(ociIndex{
+		SchemaVersion: 2,
+		MediaType:     "application/vnd.oci.image.index.v1+json",
+		Manifests:     []ociDescriptor{manifestDescriptor},
+	})
+	if err != nil {
+		return err
+	}
+
+	layout := []byte(`{"imageLayoutVersion":"1.0.0"}`)
+	tw := tar.NewWriter(w)
+	for _, entry := range []struct {
+		name string
+		size int64
+		r    io.Reader
+	}{
+		{"oci-layout", int64(len(layout)), bytes.NewReader(layout)},
+		{"index.json", int64(len(index)), bytes.NewReader(index)},
+		{blobPath(manifestDescriptor.Digest), int64(len(manifestBlob)), bytes.NewReader(manifestBlob)},
+		{blobPath(manifest.Config.Digest), int64(len(configBlob)), bytes.NewReader(configBlob)},
+		{blobPath(manifest.Layers[0].Digest), size, layer},
+	} {
+		if err := tw.WriteHeader(&tar.Header{Name: entry.name, Mode: 0644, Size: entry.size, Typeflag: tar.TypeReg}); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, entry.r); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// writeLayer writes the gzip compressed layer to w, diffID receives the
+// uncompressed tar stream.
+func writeLayer(w io.Writer, diffID hash.Hash, goroot string) error {
+	gzw := gzip.NewWriter(w)
+	tw := tar.NewWriter(io.MultiWriter(gzw, diffID))
+
+	for _, dir := range []string{"usr/", "usr/local/"} {
+		if err := tw.WriteHeader(&tar.Header{Name: dir, Mode: 0755, Typeflag: tar.TypeDir}); err != nil {
+			return err
+		}
+	}
+
+	err := filepath.WalkDir(goroot, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(goroot, p)
+		if err != nil {
+			return err
+		}
+		if rel == provenanceFile {
+			// Recorded by go-dl, not part of the release.
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(p); err != nil {
+				return err
+			}
+		}
+
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = path.Join(ociGoroot, filepath.ToSlash(rel))
+		if d.IsDir() {
+			header.Name += "/"
+		}
+		header.Uid, header.Gid, header.Uname, header.Gname = 0, 0, "", ""
+
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gzw.Close()
+}
+
+func blobDescriptor(mediaType string, blob []byte) ociDescriptor {
+	sum := sha256.Sum256(blob)
+	return ociDescriptor{MediaType: mediaType, Digest: "sha256:" + hex.EncodeToString(sum[:]), Size: int64(len(blob))}
+}
+
+func blobPath(digest string) string {
+	return path.Join("blobs", "sha256", digest[len("sha256:"):])
+}
+
+func (c *cli) exportOCI(args []string) error {
+	fs := flag.NewFlagSet("export-oci", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-dl export-oci <version> [--tag name] [--output file]")
+		fs.PrintDefaults()
+	}
+	tag := fs.String("tag", "", "reference name of the image, such as registry/go:1.22.1")
+	output := fs.String("output", "", "path of the image layout archive, <version>.oci.tar by default")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// The flags may also follow the version.
+	version := versions.Normalize(fs.Arg(0))
+	if err := fs.Parse(fs.Args()[min(1, fs.NArg()):]); err != nil {
+		return err
+	}
+	if version == "" || fs.NArg() > 0 {
+		fs.Usage()
+		return errors.New("export-oci expects a single version")
+	}
+
+	goroot, err := c.installation(version)
+	if err != nil {
+		return err
+	}
+
+	dir, err := c.paths.TempDir()
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		*output = version + ".oci.tar"
+	}
+	f, err := os.Create(*output)
+	if err != nil {
+		return wrapPermission(err)
+	}
+
+	err = exportOCI(f, goroot, *tag, c.selection, dir)
+	if err = errors.Join(err, f.Close()); err != nil {
+		os.Remove(*output)
+		return err
+	}
+
+	fmt.Fprintf(c.stdout, "Exported %s to %s\n", version, *output)
+	return nil
+}
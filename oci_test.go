@@ -0,0 +1,86 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExportOCI(t *testing.T) {
+	goroot := filepath.Join(t.TempDir(), "go")
+	if err := os.MkdirAll(filepath.Join(goroot, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(goroot, "VERSION"), []byte("go1.22.1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := exportOCI(&out, goroot, "registry/go:1.22.1", Selection{Os: "linux", Arch: "amd64"}, t.TempDir()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	blobs := map[string][]byte{}
+	tr := tar.NewReader(&out)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(tr)
+		blobs[h.Name] = b
+	}
+
+	var index ociIndex
+	if err := json.Unmarshal(blobs["index.json"], &index); err != nil || len(index.Manifests) != 1 {
+		t.Fatalf("Expected an index with one manifest, got %s (%v)", blobs["index.json"], err)
+	}
+	if ref := index.Manifests[0].Annotations["org.opencontainers.image.ref.name"]; ref != "registry/go:1.22.1" {
+		t.Errorf("Expected the manifest to be tagged, got %q", ref)
+	}
+
+	var manifest ociManifest
+	if err := json.Unmarshal(blobs[blobPath(index.Manifests[0].Digest)], &manifest); err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range append([]ociDescriptor{index.Manifests[0], manifest.Config}, manifest.Layers...) {
+		sum := sha256.Sum256(blobs[blobPath(d.Digest)])
+		if d.Digest != "sha256:"+hex.EncodeToString(sum[:]) || d.Size != int64(len(blobs[blobPath(d.Digest)])) {
+			t.Errorf("Expected blob %s to match its descriptor", d.Digest)
+		}
+	}
+
+	gzr, err := gzip.NewReader(bytes.NewReader(blobs[blobPath(manifest.Layers[0].Digest)]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	layer := map[string]string{}
+	tr = tar.NewReader(gzr)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(tr)
+		layer[h.Name] = string(b)
+	}
+	if layer["usr/local/go/VERSION"] != "go1.22.1\n" {
+		t.Errorf("Expected the toolchain in /usr/local/go, got %v", layer)
+	}
+	if _, ok := layer["usr/local/go/bin/"]; !ok {
+		t.Errorf("Expected directories in the layer, got %v", layer)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+const defaultSystemOwner = "root:root"
+
+// Owner is the user and group extracted files are given. Ids encoded in the
+// archives are never used, a negative id keeps the one of the current process.
+type Owner struct {
+	Uid int `json:"uid"`
+	Gid int `json:"gid"`
+}
+
+var processOwner = Owner{Uid: -1, Gid: -1}
+
+func (o Owner) isSet() bool {
+	return o.Uid >= 0 || o.Gid >= 0
+}
+
+// invokingOwner returns the user who invoked go-dl, which differs from the
+// process owner when running through sudo.
+func invokingOwner() Owner {
+	uid, errUid := strconv.Atoi(os.Getenv("SUDO_UID"))
+	gid, errGid := strconv.Atoi(os.Getenv("SUDO_GID"))
+	if errUid != nil || errGid != nil || os.Geteuid() != 0 {
+		return processOwner
+	}
+	return Owner{Uid: uid, Gid: gid}
+}
+
+// parseOwner parses an owner written as "user:group", names and numeric ids
+// are both accepted.
+func parseOwner(s string) (Owner, error) {
+	u, g, ok := strings.Cut(s, ":")
+	if !ok || u == "" || g == "" {
+		return processOwner, fmt.Errorf("invalid owner %q, want user:group", s)
+	}
+
+	uid, err := strconv.Atoi(u)
+	if err != nil {
+		usr, err := user.Lookup(u)
+		if err != nil {
+			return processOwner, err
+		}
+		uid, _ = strconv.Atoi(usr.Uid)
+	}
+
+	gid, err := strconv.Atoi(g)
+	if err != nil {
+		grp, err := user.LookupGroup(g)
+		if err != nil {
+			return processOwner, err
+		}
+		gid, _ = strconv.Atoi(grp.Gid)
+	}
+
+	return Owner{Uid: uid, Gid: gid}, nil
+}
+
+// resolveOwner returns the owner of installed files, the configured system
+// owner for system installs or the invoking user otherwise.
+func resolveOwner(config Config, system bool) (Owner, error) {
+	if runtime.GOOS == "windows" {
+		return processOwner, nil
+	}
+	if !system {
+		return invokingOwner(), nil
+	}
+
+	owner := config.SystemOwner
+	if owner == "" {
+		owner = defaultSystemOwner
+	}
+	return parseOwner(owner)
+}
+
+// chownTree gives every file under root to owner.
+func chownTree(root string, owner Owner) error {
+	if !owner.isSet() {
+		return nil
+	}
+
+	return filepath.WalkDir(root, func(path string, _ fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Lchown(path, owner.Uid, owner.Gid)
+	})
+}
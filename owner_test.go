@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestParseOwner(t *testing.T) {
+	got, err := parseOwner("1000:100")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got != (Owner{Uid: 1000, Gid: 100}) {
+		t.Errorf("Expected 1000:100, got %v", got)
+	}
+
+	for _, s := range []string{"", "1000", ":100", "1000:"} {
+		if _, err := parseOwner(s); err == nil {
+			t.Errorf("parseOwner(%q) expected an error", s)
+		}
+	}
+}
+
+func TestChownTreeProcessOwner(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(dir+"/file", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := chownTree(dir, processOwner); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestChownTreeCurrentUser(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(dir+"/file", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	owner := Owner{Uid: os.Getuid(), Gid: os.Getgid()}
+	if owner.Uid < 0 {
+		t.Skip("ids are not supported on this platform")
+	}
+
+	if err := chownTree(dir, owner); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
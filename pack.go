@@ -0,0 +1,171 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/blckfalcon/go-dl/versions"
+)
+
+// packTime is the modification time of every packed entry, unless
+// SOURCE_DATE_EPOCH says otherwise.
+var packTime = time.Unix(0, 0).UTC()
+
+// writePack writes goroot to w as a release archive, with the files under
+// go/ like the ones published on go.dev. The archive only depends on the
+// content of goroot: entries are sorted, owners, times and modes normalized.
+func writePack(w io.Writer, goroot string, modTime time.Time) error {
+	goroot, err := filepath.EvalSymlinks(goroot)
+	if err != nil {
+		return err
+	}
+
+	gzw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gzw)
+
+	// WalkDir visits the entries in lexical order.
+	err = filepath.WalkDir(goroot, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(goroot, p)
+		if err != nil {
+			return err
+		}
+		if rel == provenanceFile {
+			// Recorded by go-dl, not part of the release.
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		header := &tar.Header{
+			Name:    path.Join("go", filepath.ToSlash(rel)),
+			ModTime: modTime,
+			Mode:    0644,
+			Format:  tar.FormatPAX,
+		}
+		switch {
+		case d.IsDir():
+			header.Typeflag = tar.TypeDir
+			header.Name += "/"
+			header.Mode = 0755
+		case info.Mode()&os.ModeSymlink != 0:
+			header.Typeflag = tar.TypeSymlink
+			header.Mode = 0777
+			if header.Linkname, err = os.Readlink(p); err != nil {
+				return err
+			}
+		case info.Mode().IsRegular():
+			header.Typeflag = tar.TypeReg
+			header.Size = info.Size()
+			if info.Mode()&0111 != 0 {
+				header.Mode = 0755
+			}
+		default:
+			return fmt.Errorf("can't pack %s: not a regular file", p)
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			return nil
+		}
+
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gzw.Close()
+}
+
+// sourceDateEpoch returns the time set by SOURCE_DATE_EPOCH, packTime when
+// unset.
+func sourceDateEpoch(getenv func(string) string) (time.Time, error) {
+	s := getenv("SOURCE_DATE_EPOCH")
+	if s == "" {
+		return packTime, nil
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH %q", s)
+	}
+	return time.Unix(n, 0).UTC(), nil
+}
+
+func (c *cli) pack(args []string) error {
+	fs := flag.NewFlagSet("pack", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-dl pack <version> [--out file]")
+		fs.PrintDefaults()
+	}
+	out := fs.String("out", "", "path of the archive, named like the go.dev archive of the platform by default")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// The flags may also follow the version.
+	version := versions.Normalize(fs.Arg(0))
+	if err := fs.Parse(fs.Args()[min(1, fs.NArg()):]); err != nil {
+		return err
+	}
+	if version == "" || fs.NArg() > 0 {
+		fs.Usage()
+		return errors.New("pack expects a single version")
+	}
+
+	goroot, err := c.installation(version)
+	if err != nil {
+		return err
+	}
+	modTime, err := sourceDateEpoch(os.Getenv)
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		*out = fmt.Sprintf("%s.%s-%s.tar.gz", version, c.selection.Os, c.selection.Arch)
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return wrapPermission(err)
+	}
+
+	sum := sha256.New()
+	err = writePack(io.MultiWriter(f, sum), goroot, modTime)
+	if err = errors.Join(err, f.Close()); err != nil {
+		os.Remove(*out)
+		return err
+	}
+
+	fmt.Fprintf(c.stdout, "Packed %s to %s\n", version, *out)
+	fmt.Fprintf(c.stdout, "%s  %s\n", hex.EncodeToString(sum.Sum(nil)), filepath.Base(*out))
+	return nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPack(t *testing.T) {
+	goroot := filepath.Join(t.TempDir(), "go")
+	for name, content := range map[string]string{"VERSION": "go1.22.1\n", "bin/go": "#!/bin/sh\n", "src/fmt/print.go": "package fmt\n"} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(goroot, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(goroot, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.Chmod(filepath.Join(goroot, "bin/go"), 0700)
+
+	var first, second bytes.Buffer
+	if err := writePack(&first, goroot, packTime); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(filepath.Join(goroot, "VERSION"), later, later)
+	os.Chmod(filepath.Join(goroot, "src/fmt/print.go"), 0600)
+	if err := writePack(&second, goroot, packTime); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Errorf("Expected the archive to only depend on the content")
+	}
+
+	gzr, err := gzip.NewReader(bytes.NewReader(first.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	tr := tar.NewReader(gzr)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, h.Name)
+		if !h.ModTime.Equal(packTime) || h.Uid != 0 || h.Uname != "" {
+			t.Errorf("Expected normalized headers, got %+v", h)
+		}
+		if h.Name == "go/bin/go" && h.Mode != 0755 {
+			t.Errorf("Expected executables to be 0755, got %o", h.Mode)
+		}
+	}
+	want := []string{"go/", "go/VERSION", "go/bin/", "go/bin/go", "go/src/", "go/src/fmt/", "go/src/fmt/print.go"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("Expected sorted entries %v, got %v", want, names)
+	}
+
+	dst := t.TempDir()
+	if err := Decompress(dst, bytes.NewReader(first.Bytes()), func(float64) {}); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := installedVersion(filepath.Join(dst, "go")); err != nil || v != "go1.22.1" {
+		t.Errorf("Expected the archive to install go1.22.1, got %q (%v)", v, err)
+	}
+}
+
+func TestPackCommand(t *testing.T) {
+	c, out := newTestCLI(t, newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n"}))
+	c.prefix = t.TempDir()
+	if err := c.run([]string{"install", "1.22.1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	target := filepath.Join(t.TempDir(), "go1.22.1-repacked.tar.gz")
+	if err := c.run([]string{"pack", "go1.22.1", "--out", target}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(target); err != nil {
+		t.Errorf("Expected the archive to be written, got %v", err)
+	}
+	if !strings.Contains(out.String(), "  go1.22.1-repacked.tar.gz\n") {
+		t.Errorf("Expected the checksum of the archive, got %q", out.String())
+	}
+
+	if err := c.run([]string{"pack", "go1.21.0"}); err == nil {
+		t.Errorf("Expected an error for a version which isn't installed")
+	}
+}
+
+func TestSourceDateEpoch(t *testing.T) {
+	env := map[string]string{"SOURCE_DATE_EPOCH": "1700000000"}
+	if got, err := sourceDateEpoch(func(k string) string { return env[k] }); err != nil || got.Unix() != 1700000000 {
+		t.Errorf("Expected SOURCE_DATE_EPOCH to be used, got %v (%v)", got, err)
+	}
+	if got, _ := sourceDateEpoch(func(string) string { return "" }); !got.Equal(packTime) {
+		t.Errorf("Expected the default time, got %v", got)
+	}
+	env["SOURCE_DATE_EPOCH"] = "yesterday"
+	if _, err := sourceDateEpoch(func(k string) string { return env[k] }); err == nil {
+		t.Errorf("Expected an error for an invalid SOURCE_DATE_EPOCH")
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// Paths holds the directories go-dl keeps its files in. They follow the XDG
+// base directories on Unix systems and the platform conventions on macOS and
+// Windows, each of them can be overridden with GO_DL_CONFIG_DIR,
+// GO_DL_CACHE_DIR and GO_DL_STATE_DIR respectively.
+type Paths struct {
+	Config string
+	Cache  string
+	State  string
+}
+
+func defaultPaths() Paths {
+	return Paths{
+		Config: dirOverride("GO_DL_CONFIG_DIR", userConfigDir),
+		Cache:  dirOverride("GO_DL_CACHE_DIR", userCacheDir),
+		State:  dirOverride("GO_DL_STATE_DIR", userStateDir),
+	}
+}
+
+func dirOverride(env string, fallback func() string) string {
+	if dir := os.Getenv(env); dir != "" {
+		return dir
+	}
+	return filepath.Join(fallback(), "go-dl")
+}
+
+// userConfigDir is $XDG_CONFIG_HOME, ~/Library/Application Support or
+// %AppData%.
+func userConfigDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return os.TempDir()
+	}
+	return dir
+}
+
+// userCacheDir is $XDG_CACHE_HOME, ~/Library/Caches or %LocalAppData%.
+func userCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return os.TempDir()
+	}
+	return dir
+}
+
+// userStateDir is $XDG_STATE_HOME, defaulting to ~/.local/state, on Unix
+// systems. macOS and Windows have no such directory, the application data
+// directory is used instead.
+func userStateDir() string {
+	switch runtime.GOOS {
+	case "windows":
+		if dir := os.Getenv("LocalAppData"); dir != "" {
+			return dir
+		}
+	case "darwin", "ios":
+		return userConfigDir()
+	default:
+		if dir := os.Getenv("XDG_STATE_HOME"); filepath.IsAbs(dir) {
+			return dir
+		}
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, ".local", "state")
+		}
+	}
+	return os.TempDir()
+}
+
+// userDataDir is $XDG_DATA_HOME, defaulting to ~/.local/share, on Unix
+// systems, and the application data directory on macOS and Windows.
+func userDataDir() string {
+	switch runtime.GOOS {
+	case "windows", "darwin", "ios":
+		return userStateDir()
+	default:
+		if dir := os.Getenv("XDG_DATA_HOME"); filepath.IsAbs(dir) {
+			return dir
+		}
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, ".local", "share")
+		}
+	}
+	return os.TempDir()
+}
+
+func (p Paths) ConfigFile() string {
+	return filepath.Join(p.Config, "config.json")
+}
+
+func (p Paths) PipelineFile() string {
+	return filepath.Join(p.State, "pipeline.json")
+}
+
+// TempDir returns the directory holding temporary downloads, creating it when
+// needed.
+func (p Paths) TempDir() (string, error) {
+	dir := filepath.Join(p.Cache, "tmp")
+	return dir, os.MkdirAll(dir, 0755)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func newTestPaths(t *testing.T) Paths {
+	t.Helper()
+	return Paths{Config: t.TempDir(), Cache: t.TempDir(), State: t.TempDir()}
+}
+
+func TestDefaultPathsOverride(t *testing.T) {
+	t.Setenv("GO_DL_CONFIG_DIR", "/etc/go-dl")
+	t.Setenv("GO_DL_CACHE_DIR", "/var/cache/go-dl")
+	t.Setenv("GO_DL_STATE_DIR", "/var/lib/go-dl")
+
+	want := Paths{Config: "/etc/go-dl", Cache: "/var/cache/go-dl", State: "/var/lib/go-dl"}
+	if got := defaultPaths(); got != want {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestDefaultPathsXDG(t *testing.T) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		t.Skip("XDG base directories are only used on Unix systems")
+	}
+
+	t.Setenv("XDG_CONFIG_HOME", "/xdg/config")
+	t.Setenv("XDG_CACHE_HOME", "/xdg/cache")
+	t.Setenv("XDG_STATE_HOME", "/xdg/state")
+
+	want := Paths{
+		Config: filepath.Join("/xdg/config", "go-dl"),
+		Cache:  filepath.Join("/xdg/cache", "go-dl"),
+		State:  filepath.Join("/xdg/state", "go-dl"),
+	}
+	if got := defaultPaths(); got != want {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
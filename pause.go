@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// pauseGate suspends downloads between two reads while paused. The zero value
+// is running.
+type pauseGate struct {
+	mu     sync.Mutex
+	resume chan struct{}
+}
+
+func (g *pauseGate) Pause() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.resume == nil {
+		g.resume = make(chan struct{})
+	}
+}
+
+func (g *pauseGate) Resume() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.resume != nil {
+		close(g.resume)
+		g.resume = nil
+	}
+}
+
+// Toggle pauses a running gate or resumes a paused one, it reports whether
+// the gate is now paused.
+func (g *pauseGate) Toggle() bool {
+	if g.Paused() {
+		g.Resume()
+		return false
+	}
+	g.Pause()
+	return true
+}
+
+func (g *pauseGate) Paused() bool {
+	if g == nil {
+		return false
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.resume != nil
+}
+
+// wait blocks until the gate is resumed or ctx is done, it reports whether it
+// had to wait. A nil gate never waits.
+func (g *pauseGate) wait(ctx context.Context) (bool, error) {
+	if g == nil {
+		return false, nil
+	}
+
+	g.mu.Lock()
+	resume := g.resume
+	g.mu.Unlock()
+	if resume == nil {
+		return false, nil
+	}
+
+	select {
+	case <-resume:
+		return true, nil
+	case <-ctx.Done():
+		return true, ctx.Err()
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// failingReader returns err once r is exhausted.
+type failingReader struct {
+	r   io.Reader
+	err error
+}
+
+func (f *failingReader) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	if err == io.EOF {
+		return n, f.err
+	}
+	return n, err
+}
+
+func TestDownloadResumesAfterPause(t *testing.T) {
+	content := bytes.Repeat([]byte("go"), 40*1024)
+	first := 32 * 1024
+
+	var ranges []string
+	client := NewTestClient(func(req *http.Request) *http.Response {
+		ranges = append(ranges, req.Header.Get("Range"))
+		if len(ranges) == 1 {
+			return &http.Response{
+				StatusCode:    http.StatusOK,
+				Body:          io.NopCloser(&failingReader{bytes.NewReader(content[:first]), errors.New("connection reset")}),
+				ContentLength: int64(len(content)),
+			}
+		}
+		return &http.Response{
+			StatusCode:    http.StatusPartialContent,
+			Header:        http.Header{"Content-Range": {fmt.Sprintf("bytes %d-%d/%d", first, len(content)-1, len(content))}},
+			Body:          io.NopCloser(bytes.NewReader(content[first:])),
+			ContentLength: int64(len(content) - first),
+		}
+	})
+
+	gate := &pauseGate{}
+	repo := &GoRepository{client: client, gate: gate}
+	repo.onProgress = func(ratio float64) {
+		if len(ranges) == 1 && !gate.Paused() {
+			gate.Pause()
+			time.AfterFunc(10*time.Millisecond, gate.Resume)
+		}
+	}
+
+	var out bytes.Buffer
+	if _, err := repo.download(context.Background(), File{Filename: "go1.22.1.linux-amd64.tar.gz"}, &out); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !bytes.Equal(out.Bytes(), content) {
+		t.Errorf("Expected the download to be complete, got %d bytes", out.Len())
+	}
+	if want := fmt.Sprintf("bytes=%d-", first); len(ranges) != 2 || ranges[1] != want {
+		t.Errorf("Expected a ranged request for %s, got %q", want, ranges)
+	}
+}
+
+func TestPauseGateContext(t *testing.T) {
+	gate := &pauseGate{}
+	if !gate.Toggle() {
+		t.Fatalf("Expected the gate to be paused")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := gate.wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a paused download to be canceled, got %v", err)
+	}
+}
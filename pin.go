@@ -0,0 +1,172 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/blckfalcon/go-dl/tui"
+	"github.com/blckfalcon/go-dl/versions"
+)
+
+// pin writes the chosen version to the .go-version of the project, and
+// optionally to the toolchain directive of its go.mod.
+func (c *cli) pin(args []string) error {
+	fs := flag.NewFlagSet("pin", flag.ContinueOnError)
+	toolchain := fs.Bool("toolchain", false, "also set the toolchain directive of the go.mod")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-dl pin [--toolchain] [version constraint]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	releases, err := c.repo.GetVersions(c.ctx)
+	if err != nil {
+		return err
+	}
+
+	// The nearest .go-version is updated, a new one goes to the current
+	// directory.
+	path, current, err := findGoVersionFile(wd)
+	if err != nil {
+		path = filepath.Join(wd, goVersionFile)
+	}
+
+	var version string
+	if fs.NArg() > 0 {
+		release, err := resolveRelease(releases, strings.Join(fs.Args(), " "))
+		if err != nil {
+			return err
+		}
+		version = release.Version
+	} else if version, err = c.choosePin(releases, current); err != nil || version == "" {
+		return err
+	}
+
+	// The go.mod is set first, it can refuse the version.
+	if *toolchain {
+		gomod, err := findGoMod(wd)
+		if err != nil {
+			return err
+		}
+		if err := pinToolchain(gomod, version); err != nil {
+			return wrapPermission(err)
+		}
+		fmt.Fprintf(c.stdout, "Set toolchain %s in %s\n", version, gomod)
+	}
+
+	if err := pinVersion(path, version); err != nil {
+		return wrapPermission(err)
+	}
+	fmt.Fprintf(c.stdout, "Pinned %s in %s\n", version, path)
+	return nil
+}
+
+// choosePin asks for the version to pin in the picker, starting from the
+// current one, and returns an empty version when the user quit.
+func (c *cli) choosePin(releases []Release, current string) (string, error) {
+	if f, ok := c.stdout.(*os.File); !ok || !isTerminal(f) {
+		return "", errors.New("a version constraint is required when not running in a terminal")
+	}
+
+	var list []string
+	for _, r := range releases {
+		list = append(list, r.Version)
+	}
+
+	chooser := tui.NewChooser("What version of Go do you want to pin?", "pin", list, releaseLabels(releases), c.keys)
+	if current != "" {
+		if r, err := resolveRelease(releases, current); err == nil {
+			chooser = chooser.Select(r.Version)
+		}
+	}
+
+	m, err := tea.NewProgram(chooser).Run()
+	if err != nil {
+		return "", err
+	}
+	return m.(tui.Chooser).Choice(), nil
+}
+
+// pinVersion writes version to the .go-version at path, without the go
+// prefix, as read by the other version managers.
+func pinVersion(path, version string) error {
+	return writeFileAtomic(path, []byte(strings.TrimPrefix(version, "go")+"\n"), 0644)
+}
+
+// findGoMod returns the path of the go.mod of the module holding dir.
+func findGoMod(dir string) (string, error) {
+	for {
+		path := filepath.Join(dir, "go.mod")
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", errors.New("no go.mod file found")
+		}
+		dir = parent
+	}
+}
+
+// pinToolchain sets the toolchain directive of the go.mod at path to
+// version, replacing the existing one or adding it after the go directive.
+// The toolchain cannot be older than the go directive.
+func pinToolchain(path, version string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	lines := strings.SplitAfter(string(b), "\n")
+	directive := "toolchain " + version + "\n"
+	goLine, set := -1, false
+	for i, line := range lines {
+		code, _, _ := strings.Cut(line, "//")
+		fields := strings.Fields(code)
+		if len(fields) != 2 {
+			continue
+		}
+		switch fields[0] {
+		case "go":
+			if versions.IsNewer(versions.Normalize(fields[1]), version) {
+				return fmt.Errorf("%s requires go %s, newer than %s", path, fields[1], version)
+			}
+			goLine = i
+		case "toolchain":
+			lines[i] = directive
+			set = true
+		}
+	}
+
+	if !set {
+		if goLine < 0 {
+			return fmt.Errorf("%s has no go directive", path)
+		}
+		if !strings.HasSuffix(lines[goLine], "\n") {
+			lines[goLine] += "\n"
+		}
+		lines = append(lines[:goLine+1], append([]string{"\n", directive}, lines[goLine+1:]...)...)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, []byte(strings.Join(lines, "")), info.Mode().Perm())
+}
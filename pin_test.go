@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPinVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), goVersionFile)
+	if err := pinVersion(path, "go1.22.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, query, err := findGoVersionFile(filepath.Dir(path))
+	if err != nil || query != "1.22.1" {
+		t.Errorf("Expected 1.22.1 to be pinned, got %q (%v)", query, err)
+	}
+}
+
+func TestPinToolchain(t *testing.T) {
+	for _, tc := range []struct {
+		gomod, want string
+	}{
+		{
+			gomod: "module example.com/m\n\ngo 1.21\n\nrequire golang.org/x/mod v0.14.0\n",
+			want:  "module example.com/m\n\ngo 1.21\n\ntoolchain go1.22.1\n\nrequire golang.org/x/mod v0.14.0\n",
+		},
+		{
+			gomod: "module example.com/m\n\ngo 1.21.0\n\ntoolchain go1.21.5 // pinned\n",
+			want:  "module example.com/m\n\ngo 1.21.0\n\ntoolchain go1.22.1\n",
+		},
+		{
+			gomod: "module example.com/m\n\ngo 1.22",
+			want:  "module example.com/m\n\ngo 1.22\n\ntoolchain go1.22.1\n",
+		},
+	} {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "go.mod")
+		if err := os.WriteFile(path, []byte(tc.gomod), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.MkdirAll(filepath.Join(dir, "cmd", "tool"), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		found, err := findGoMod(filepath.Join(dir, "cmd", "tool"))
+		if err != nil || found != path {
+			t.Fatalf("Expected to find %s, got %s (%v)", path, found, err)
+		}
+		if err := pinToolchain(found, "go1.22.1"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if b, _ := os.ReadFile(path); string(b) != tc.want {
+			t.Errorf("Expected go.mod\n%s\ngot\n%s", tc.want, b)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "go.mod")
+	if err := os.WriteFile(path, []byte("module example.com/m\n\ngo 1.23.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := pinToolchain(path, "go1.22.1"); err == nil {
+		t.Errorf("Expected an error pinning a toolchain older than the go directive")
+	}
+}
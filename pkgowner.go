@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// packageQueries print the package owning a file, tried in order among the
+// package managers found in PATH.
+var packageQueries = [][]string{
+	{"dpkg-query", "-S"},
+	{"rpm", "-qf", "--queryformat", "%{NAME}\n"},
+}
+
+// packageOwner returns the system package owning the installation at
+// goroot, empty when there is none or no package manager to ask.
+func packageOwner(ctx context.Context, goroot string) (string, error) {
+	path := filepath.Join(goroot, "VERSION")
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+
+	for _, query := range packageQueries {
+		if _, err := exec.LookPath(query[0]); err != nil {
+			continue
+		}
+
+		out, err := exec.CommandContext(ctx, query[0], append(query[1:], path)...).Output()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			// The file is not owned by a package of this manager.
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if owner := parsePackageOwner(string(out)); owner != "" {
+			return owner, nil
+		}
+	}
+	return "", nil
+}
+
+// parsePackageOwner returns the package of the first line printed by a
+// query, "package: path" with dpkg and the package name with rpm.
+func parsePackageOwner(out string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(out), "\n")
+	if pkg, _, ok := strings.Cut(line, ": "); ok {
+		return strings.TrimSpace(pkg)
+	}
+	return strings.TrimSpace(line)
+}
+
+// checkPackageOwner returns ErrPackageManaged when goroot belongs to a
+// system package, which would later overwrite go-dl's installation.
+func checkPackageOwner(ctx context.Context, goroot string) error {
+	owner, err := packageOwner(ctx, goroot)
+	if err != nil || owner == "" {
+		return err
+	}
+	return fmt.Errorf("%w: %s belongs to the %s package", ErrPackageManaged, goroot, owner)
+}
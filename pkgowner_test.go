@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestParsePackageOwner(t *testing.T) {
+	for out, want := range map[string]string{
+		"golang-1.22-go: /usr/lib/go-1.22/VERSION\n":   "golang-1.22-go",
+		"golang-go, golang-src: /usr/lib/go/VERSION\n": "golang-go, golang-src",
+		"golang-bin\n": "golang-bin",
+		"":             "",
+	} {
+		if got := parsePackageOwner(out); got != want {
+			t.Errorf("Expected %q for %q, got %q", want, out, got)
+		}
+	}
+}
+
+func TestInstallPackageManaged(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the package manager is a shell script")
+	}
+
+	bin := t.TempDir()
+	script := "#!/bin/sh\ncase \"$2\" in */owned/go/VERSION) echo \"golang-1.21-go: $2\" ;; *) echo \"dpkg-query: no path found matching pattern $2\" >&2; exit 1 ;; esac\n"
+	if err := os.WriteFile(filepath.Join(bin, "dpkg-query"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+
+	c, _ := newTestCLI(t, newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n"}))
+	c.prefix = filepath.Join(t.TempDir(), "owned")
+	if err := os.MkdirAll(c.goroot(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(c.goroot(), "VERSION"), []byte("go1.21.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.run([]string{"install", "1.22.1"}); !errors.Is(err, ErrPackageManaged) {
+		t.Fatalf("Expected ErrPackageManaged, got %v", err)
+	}
+	if v, _ := installedVersion(c.goroot()); v != "go1.21.0" {
+		t.Errorf("Expected the packaged installation to be left alone, got %s", v)
+	}
+
+	if err := c.run([]string{"migrate"}); !errors.Is(err, ErrPackageManaged) {
+		t.Fatalf("Expected migrate to refuse the packaged installation, got %v", err)
+	}
+
+	if err := c.run([]string{"install", "--force", "1.22.1"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if v, _ := installedVersion(c.goroot()); v != "go1.22.1" {
+		t.Errorf("Expected --force to replace the installation, got %s", v)
+	}
+
+	if err := os.WriteFile(filepath.Join(c.goroot(), "VERSION"), []byte("go1.21.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.run([]string{"latest"}); !errors.Is(err, ErrPackageManaged) {
+		t.Fatalf("Expected ErrPackageManaged, got %v", err)
+	}
+	if err := c.run([]string{"latest", "--force"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if v, _ := installedVersion(c.goroot()); v != "go1.22.1" {
+		t.Errorf("Expected latest --force to replace the installation, got %s", v)
+	}
+
+	if owner, err := packageOwner(c.ctx, filepath.Join(t.TempDir(), "go")); err != nil || owner != "" {
+		t.Errorf("Expected no owner for another installation, got %q (%v)", owner, err)
+	}
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Plan is the desired state of the toolchains of a machine, which go-dl
+// apply converges to.
+type Plan struct {
+	// Versions are the constraints of the versions installed side by side in
+	// the managed layout, each resolved to its newest release.
+	Versions []string `json:"versions"`
+	// Active is the constraint of the active version, the newest of Versions
+	// by default.
+	Active string `json:"active,omitempty"`
+	// Toolchains are installed apart from the active one, for go-dl run.
+	Toolchains []string `json:"toolchains,omitempty"`
+	// Prune removes the versions and toolchains the plan doesn't list.
+	Prune bool `json:"prune,omitempty"`
+	// Platform is the os/arch of the machines the plan is for, it is
+	// applied on no other.
+	Platform string `json:"platform,omitempty"`
+	// Prefix is the directory of the installation, the one of go-dl by
+	// default.
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// planAction is a change applying a plan makes to the machine.
+type planAction struct {
+	Action  string `json:"action"`
+	Version string `json:"version,omitempty"`
+}
+
+// The actions of a plan.
+const (
+	actionMigrate         = "migrate"
+	actionInstall         = "install"
+	actionToolchain       = "toolchain"
+	actionActivate        = "activate"
+	actionRemove          = "remove"
+	actionRemoveToolchain = "remove-toolchain"
+)
+
+func (a planAction) String() string {
+	switch a.Action {
+	case actionMigrate:
+		return "~ migrate to the managed layout"
+	case actionInstall:
+		return "+ install " + a.Version
+	case actionToolchain:
+		return "+ install toolchain " + a.Version
+	case actionActivate:
+		return "~ activate " + a.Version
+	case actionRemove:
+		return "- remove " + a.Version
+	default:
+		return "- remove toolchain " + a.Version
+	}
+}
+
+// loadPlan reads the plan at path.
+func loadPlan(path string) (Plan, error) {
+	var plan Plan
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return plan, err
+	}
+	if err := json.Unmarshal(b, &plan); err != nil {
+		return plan, fmt.Errorf("invalid plan %s: %w", path, err)
+	}
+	if len(plan.Versions) == 0 {
+		return plan, fmt.Errorf("invalid plan %s: no versions", path)
+	}
+	if plan.Platform != "" {
+		if _, _, ok := strings.Cut(plan.Platform, "/"); !ok {
+			return plan, fmt.Errorf("invalid plan %s: platform %q, expected os/arch", path, plan.Platform)
+		}
+	}
+	return plan, nil
+}
+
+// target points c at the prefix of plan. The archives of another platform
+// than the one of the machine wouldn't run, a plan for another platform is
+// rejected.
+func (c *cli) target(plan Plan) error {
+	if platform := c.selection.Os + "/" + c.selection.Arch; plan.Platform != "" && plan.Platform != platform {
+		return fmt.Errorf("the plan is for %s, not for this %s machine", plan.Platform, platform)
+	}
+	if plan.Prefix != "" {
+		c.prefix = plan.Prefix
+	}
+	return nil
+}
+
+// planActions returns the changes converging the machine to plan.
+func (c *cli) planActions(plan Plan) ([]planAction, error) {
+	releases, err := c.repo.GetVersions(c.ctx)
+	if err != nil {
+		return nil, err
+	}
+	resolve := func(queries []string) ([]string, error) {
+		var resolved []string
+		for _, q := range queries {
+			r, err := resolveRelease(releases, c.resolveAlias(q))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", q, err)
+			}
+			if !slices.Contains(resolved, r.Version) {
+				resolved = append(resolved, r.Version)
+			}
+		}
+		sortVersions(resolved)
+		return resolved, nil
+	}
+
+	wanted, err := resolve(plan.Versions)
+	if err != nil {
+		return nil, err
+	}
+	toolchains, err := resolve(plan.Toolchains)
+	if err != nil {
+		return nil, err
+	}
+	active := wanted[len(wanted)-1]
+	if plan.Active != "" {
+		resolved, err := resolve([]string{plan.Active})
+		if err != nil {
+			return nil, err
+		}
+		if active = resolved[0]; !slices.Contains(wanted, active) {
+			return nil, fmt.Errorf("the active version %s is not one of the versions of the plan", active)
+		}
+	}
+
+	s, err := c.snapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	// More than one version needs the managed layout, migrated to before the
+	// installs replace the active version, or once the first is installed.
+	managed := s.Managed || len(wanted) == 1
+	var actions []planAction
+	if !managed && s.Active != "" {
+		actions = append(actions, planAction{Action: actionMigrate})
+		managed = true
+	}
+	// The versions installed last are activated, the active one comes last.
+	installs := 0
+	for _, v := range append(slices.DeleteFunc(slices.Clone(wanted), func(v string) bool { return v == active }), active) {
+		if slices.Contains(s.Versions, v) {
+			continue
+		}
+		actions = append(actions, planAction{Action: actionInstall, Version: v})
+		if installs++; !managed {
+			actions = append(actions, planAction{Action: actionMigrate})
+			managed = true
+		}
+	}
+	for _, v := range toolchains {
+		if _, ok := c.toolchain(v); !ok && !slices.Contains(wanted, v) {
+			actions = append(actions, planAction{Action: actionToolchain, Version: v})
+		}
+	}
+	if slices.Contains(s.Versions, active) && (s.Active != active || installs > 0) {
+		actions = append(actions, planAction{Action: actionActivate, Version: active})
+	}
+	if !plan.Prune {
+		return actions, nil
+	}
+	for _, v := range s.Versions {
+		// A plain installation is replaced by the install instead.
+		if !slices.Contains(wanted, v) && (s.Managed || len(wanted) > 1) {
+			actions = append(actions, planAction{Action: actionRemove, Version: v})
+		}
+	}
+	for _, v := range s.Toolchains {
+		if !slices.Contains(toolchains, v) && !slices.Contains(wanted, v) {
+			actions = append(actions, planAction{Action: actionRemoveToolchain, Version: v})
+		}
+	}
+	return actions, nil
+}
+
+// applyAction makes the change a to the machine.
+func (c *cli) applyAction(a planAction) error {
+	switch a.Action {
+	case actionMigrate:
+		_, err := migrateInstallation(c.ctx, c.goroot(), false)
+		return wrapPermission(err)
+	case actionInstall:
+		return c.install([]string{a.Version})
+	case actionToolchain:
+		_, err := c.installToolchain(a.Version)
+		return err
+	case actionActivate:
+		return c.use([]string{a.Version})
+	case actionRemove:
+		if v, err := installedVersion(c.goroot()); err == nil && v == a.Version {
+			return fmt.Errorf("%s is still active", a.Version)
+		}
+		return wrapPermission(removeVersion(c.prefix, a.Version))
+	case actionRemoveToolchain:
+		return wrapPermission(os.RemoveAll(filepath.Join(c.toolchainsDir(), a.Version)))
+	}
+	return fmt.Errorf("unknown plan action %q", a.Action)
+}
+
+// printPlan prints the changes of a plan, as JSON with asJSON.
+func (c *cli) printPlan(actions []planAction, asJSON bool) error {
+	if asJSON {
+		if actions == nil {
+			actions = []planAction{}
+		}
+		enc := json.NewEncoder(c.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(actions)
+	}
+	if len(actions) == 0 {
+		fmt.Fprintln(c.stdout, "No changes, the machine matches the plan")
+		return nil
+	}
+	for _, a := range actions {
+		fmt.Fprintln(c.stdout, a)
+	}
+	return nil
+}
+
+// plan prints the changes go-dl apply would make for a plan file.
+func (c *cli) plan(args []string) error {
+	fs := flag.NewFlagSet("plan", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the changes as JSON")
+	detailed := fs.Bool("detailed-exitcode", false, "exit with status 2 when there are changes")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-dl plan [--json] [--detailed-exitcode] <plan.json>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("plan expects a plan file")
+	}
+
+	plan, err := loadPlan(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if err := c.target(plan); err != nil {
+		return err
+	}
+	actions, err := c.planActions(plan)
+	if err != nil {
+		return err
+	}
+	if err := c.printPlan(actions, *asJSON); err != nil {
+		return err
+	}
+	if *detailed && len(actions) > 0 {
+		return commandExit{code: 2}
+	}
+	return nil
+}
+
+// apply converges the machine to a plan file.
+func (c *cli) apply(args []string) error {
+	fs := flag.NewFlagSet("apply", flag.ContinueOnError)
+	yes := fs.Bool("yes", false, "apply the changes without asking")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-dl apply [--yes] <plan.json>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("apply expects a plan file")
+	}
+
+	plan, err := loadPlan(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if err := c.target(plan); err != nil {
+		return err
+	}
+	actions, err := c.planActions(plan)
+	if err != nil {
+		return err
+	}
+	if err := c.printPlan(actions, false); err != nil || len(actions) == 0 {
+		return err
+	}
+	if !*yes && !c.confirm("Apply these changes?") {
+		return nil
+	}
+
+	for i, a := range actions {
+		if err := c.applyAction(a); err != nil {
+			return fmt.Errorf("%s: %w (%d of %d changes applied)", strings.TrimLeft(a.String(), "+-~ "), err, i, len(actions))
+		}
+	}
+	fmt.Fprintf(c.stdout, "Applied %d changes\n", len(actions))
+	return nil
+}
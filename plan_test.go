@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestPlanApply(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the managed layout is not supported on windows")
+	}
+
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n"})
+	c, out := newTestCLI(t, archive)
+	c.prefix = t.TempDir()
+	for _, goroot := range []string{c.goroot(), filepath.Join(c.toolchainsDir(), "go1.20.14", "go")} {
+		if err := os.MkdirAll(goroot, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(c.goroot(), "VERSION"), []byte("go1.21.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(c.toolchainsDir(), "go1.20.14", "go", "VERSION"), []byte("go1.20.14\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrateInstallation(context.Background(), c.goroot(), false); err != nil {
+		t.Fatal(err)
+	}
+
+	plan := filepath.Join(t.TempDir(), "plan.json")
+	if err := os.WriteFile(plan, []byte(`{"versions": ["1.22"], "prune": true}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	actions, err := c.planActions(Plan{Versions: []string{"1.22"}, Prune: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := []planAction{{actionInstall, "go1.22.1"}, {actionRemove, "go1.21.0"}, {actionRemoveToolchain, "go1.20.14"}}
+	if !reflect.DeepEqual(actions, want) {
+		t.Errorf("Expected the actions %v, got %v", want, actions)
+	}
+
+	var exit commandExit
+	if err := c.run([]string{"plan", "--detailed-exitcode", plan}); !errors.As(err, &exit) || exit.code != 2 {
+		t.Errorf("Expected the changes to exit with status 2, got %v", err)
+	}
+	if !strings.Contains(out.String(), "+ install go1.22.1\n- remove go1.21.0\n- remove toolchain go1.20.14\n") {
+		t.Errorf("Expected the changes to be printed, got %q", out.String())
+	}
+
+	if err := c.run([]string{"apply", "--yes", plan}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if v, err := installedVersion(c.goroot()); err != nil || v != "go1.22.1" {
+		t.Errorf("Expected go1.22.1 to be active, got %q (%v)", v, err)
+	}
+	for _, dir := range []string{filepath.Join(versionsDir(c.prefix), "go1.21.0"), filepath.Join(c.toolchainsDir(), "go1.20.14")} {
+		if _, err := os.Stat(dir); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Expected %s to be pruned, got %v", dir, err)
+		}
+	}
+
+	out.Reset()
+	if err := c.run([]string{"plan", "--detailed-exitcode", plan}); err != nil {
+		t.Errorf("Unexpected error once applied: %v", err)
+	}
+	if !strings.Contains(out.String(), "No changes") {
+		t.Errorf("Expected no changes once applied, got %q", out.String())
+	}
+
+	// The archives of another platform are not installed on this machine.
+	if err := os.WriteFile(plan, []byte(`{"versions": ["1.22"], "platform": "plan9/386"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.run([]string{"apply", "--yes", plan}); err == nil || !strings.Contains(err.Error(), "plan9/386") {
+		t.Errorf("Expected the plan of another platform to be rejected, got %v", err)
+	}
+}
+
+func TestPlanActions(t *testing.T) {
+	c, _ := newTestCLI(t, nil)
+	c.prefix = t.TempDir()
+
+	// A new machine installs the versions side by side, the active one last,
+	// and needs no toolchain for the installed versions.
+	actions, err := c.planActions(Plan{Versions: []string{"1.22", "1.21"}, Active: "1.22", Toolchains: []string{"1.21"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := []planAction{{actionInstall, "go1.21.0"}, {actionMigrate, ""}, {actionInstall, "go1.22.1"}}
+	if !reflect.DeepEqual(actions, want) {
+		t.Errorf("Expected the actions %v, got %v", want, actions)
+	}
+
+	if _, err := c.planActions(Plan{Versions: []string{"1.22"}, Active: "1.21"}); err == nil {
+		t.Errorf("Expected an active version outside of the plan to be rejected")
+	}
+}
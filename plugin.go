@@ -0,0 +1,203 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// plugin implements the scripts of an asdf or mise plugin, the versions are
+// given without their go prefix and the paths through the ASDF_* variables.
+func (c *cli) plugin(args []string) error {
+	fs := flag.NewFlagSet("plugin", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-dl plugin list-all|latest-stable|download|install")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	switch fs.Arg(0) {
+	case "list-all":
+		return c.pluginListAll()
+	case "latest-stable":
+		return c.pluginLatestStable()
+	case "download":
+		return c.pluginDownload()
+	case "install":
+		return c.pluginInstall()
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown plugin script %q", fs.Arg(0))
+	}
+}
+
+// pluginListAll prints every release on a single line, oldest first.
+func (c *cli) pluginListAll() error {
+	releases, err := c.repo.GetVersions(c.ctx)
+	if err != nil {
+		return err
+	}
+	sort.Sort(sort.Reverse(ByRelease(releases)))
+
+	names := make([]string, len(releases))
+	for i, r := range releases {
+		names[i] = strings.TrimPrefix(r.Version, "go")
+	}
+	fmt.Fprintln(c.stdout, strings.Join(names, " "))
+	return nil
+}
+
+func (c *cli) pluginLatestStable() error {
+	releases, err := c.repo.GetVersions(c.ctx)
+	if err != nil {
+		return err
+	}
+
+	latest, ok := latestStable(releases)
+	if !ok {
+		return fmt.Errorf("no stable release available")
+	}
+	fmt.Fprintln(c.stdout, strings.TrimPrefix(latest.Version, "go"))
+	return nil
+}
+
+// pluginDownload stores the verified archive in ASDF_DOWNLOAD_PATH.
+func (c *cli) pluginDownload() error {
+	dir, err := pluginEnv("ASDF_DOWNLOAD_PATH")
+	if err != nil {
+		return err
+	}
+
+	dlf, err := c.pluginFile()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.pluginArchive(dlf, dir)
+	return wrapPermission(err)
+}
+
+// pluginInstall extracts the archive from ASDF_DOWNLOAD_PATH, downloading it
+// when missing, to ASDF_INSTALL_PATH/go.
+func (c *cli) pluginInstall() error {
+	prefix, err := pluginEnv("ASDF_INSTALL_PATH")
+	if err != nil {
+		return err
+	}
+
+	dlf, err := c.pluginFile()
+	if err != nil {
+		return err
+	}
+	if !isExtractable(dlf) {
+		return fmt.Errorf("extraction of %s is not supported", dlf.Filename)
+	}
+
+	dir := os.Getenv("ASDF_DOWNLOAD_PATH")
+	if dir == "" {
+		tmp, err := c.paths.TempDir()
+		if err != nil {
+			return err
+		}
+		if dir, err = mkdirTemp(tmp, "go-dl-plugin-*"); err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+	}
+
+	path, err := c.pluginArchive(dlf, dir)
+	if err != nil {
+		return wrapPermission(err)
+	}
+
+	if err := runScanner(c.ctx, c.scanner, path); err != nil {
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return wrapPermission(extractArchive(prefix, f, c.owner, c.repo.extractProgress(), c.repo.archive))
+}
+
+// pluginFile returns the archive of ASDF_INSTALL_VERSION for the platform.
+func (c *cli) pluginFile() (File, error) {
+	if kind := os.Getenv("ASDF_INSTALL_TYPE"); kind != "" && kind != "version" {
+		return File{}, fmt.Errorf("unsupported install type %q, only versions can be installed", kind)
+	}
+
+	version, err := pluginEnv("ASDF_INSTALL_VERSION")
+	if err != nil {
+		return File{}, err
+	}
+
+	releases, err := c.repo.GetVersions(c.ctx)
+	if err != nil {
+		return File{}, err
+	}
+
+	release, err := resolveRelease(releases, "="+version)
+	if err != nil {
+		return File{}, err
+	}
+
+	policy, err := c.policy.resolve(releases)
+	if err != nil {
+		return File{}, err
+	}
+	if err := policy.Allow(release.Version); err != nil {
+		return File{}, err
+	}
+
+	dlf, ok := c.selection.Pick(release.Files)
+	if !ok {
+		return File{}, fmt.Errorf("%w for %s on %s/%s", ErrNoMatchingFile, release.Version, c.selection.Os, c.selection.Arch)
+	}
+	return dlf, nil
+}
+
+// pluginArchive returns the path of the verified archive in dir, fetching it
+// when missing or corrupted.
+func (c *cli) pluginArchive(dlf File, dir string) (string, error) {
+	path := filepath.Join(dir, dlf.Filename)
+	if _, err := os.Stat(path); err == nil {
+		err := verifyChecksum(path, dlf.Checksum())
+		if err == nil {
+			return path, nil
+		}
+		if !errors.Is(err, ErrChecksumMismatch) {
+			return "", err
+		}
+	}
+
+	archive, err := c.openArchive(dlf)
+	if err != nil {
+		return "", err
+	}
+	defer archive.Close()
+
+	if err := runScanner(c.ctx, c.scanner, archive.Name()); err != nil {
+		return "", err
+	}
+	if err := writeFile(path+".part", archive, 0644); err != nil {
+		return "", err
+	}
+	return path, os.Rename(path+".part", path)
+}
+
+func pluginEnv(name string) (string, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return "", fmt.Errorf("%s is not set, go-dl plugin is meant to be run by asdf or mise", name)
+	}
+	return v, nil
+}
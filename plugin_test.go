@@ -0,0 +1,59 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+)
+
+func TestPluginListAll(t *testing.T) {
+	c, out := newTestCLI(t, nil)
+
+	if err := c.plugin([]string{"list-all"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got, want := out.String(), "1.21.0 1.22.1 1.23rc1\n"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	out.Reset()
+	if err := c.plugin([]string{"latest-stable"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got, want := out.String(), "1.22.1\n"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestPluginInstall(t *testing.T) {
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n"})
+	sum := sha256.Sum256(archive)
+	c, _ := newTestCLI(t, archive)
+
+	download := t.TempDir()
+	install := t.TempDir()
+	t.Setenv("ASDF_INSTALL_TYPE", "version")
+	t.Setenv("ASDF_INSTALL_VERSION", "1.22.1")
+	t.Setenv("ASDF_DOWNLOAD_PATH", download)
+	t.Setenv("ASDF_INSTALL_PATH", install)
+
+	if err := c.plugin([]string{"download"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := verifyChecksum(filepath.Join(download, "go1.22.1.linux-amd64.tar.gz"), Checksum{Algorithm: "sha256", Sum: hex.EncodeToString(sum[:])}); err != nil {
+		t.Errorf("Expected the verified archive in the download path, got %v", err)
+	}
+
+	if err := c.plugin([]string{"install"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if v, err := installedVersion(filepath.Join(install, "go")); err != nil || v != "go1.22.1" {
+		t.Errorf("Expected go1.22.1 to be installed, got %s (%v)", v, err)
+	}
+
+	t.Setenv("ASDF_INSTALL_TYPE", "ref")
+	if err := c.plugin([]string{"install"}); err == nil {
+		t.Errorf("Expected refs to be rejected")
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/blckfalcon/go-dl/versions"
+)
+
+// Policy holds the organization rules a release must comply with before it
+// can be installed.
+type Policy struct {
+	MinimumVersion string
+	Override       bool
+	// Locked is set when the minimum version comes from the system policy,
+	// Mirrors are the only mirrors it allows.
+	Locked  bool
+	Mirrors []string
+}
+
+// newPolicy returns the policy of config, the system policy taking
+// precedence. override only bypasses the minimum version of the system
+// policy when it allows it.
+func newPolicy(config Config, system SystemPolicy, override bool) Policy {
+	policy := Policy{MinimumVersion: config.MinimumVersion, Override: override, Mirrors: system.Mirrors}
+	if system.MinimumVersion != "" {
+		policy.MinimumVersion = system.MinimumVersion
+		policy.Override = override && system.AllowOverride
+		policy.Locked = true
+	}
+	return policy
+}
+
+// resolve returns the policy with a minimum version given as a channel
+// replaced by its current release.
+func (p Policy) resolve(releases []Release) (Policy, error) {
+	if !isChannel(p.MinimumVersion) {
+		return p, nil
+	}
+
+	r, err := channelRelease(releases, p.MinimumVersion)
+	if err != nil {
+		return p, fmt.Errorf("unable to resolve minimum_version: %w", err)
+	}
+	p.MinimumVersion = r.Version
+	return p, nil
+}
+
+// AllowMirror reports whether the releases can be fetched from the mirror url.
+func (p Policy) AllowMirror(url string) bool {
+	return len(p.Mirrors) == 0 || slices.Contains(p.Mirrors, strings.TrimSuffix(url, "/"))
+}
+
+// Allow returns an error wrapping ErrPolicy when version is not allowed.
+func (p Policy) Allow(version string) error {
+	if p.Override || p.MinimumVersion == "" {
+		return nil
+	}
+
+	if versions.Compare(version, p.MinimumVersion) < 0 && p.Locked {
+		return fmt.Errorf("%s is older than the minimum version %s of the system policy: %w", version, versions.Normalize(p.MinimumVersion), ErrPolicy)
+	}
+	if versions.Compare(version, p.MinimumVersion) < 0 {
+		return fmt.Errorf("%s is older than the minimum version %s (use --override-policy to bypass): %w", version, versions.Normalize(p.MinimumVersion), ErrPolicy)
+	}
+	return nil
+}
+
+// allow checks version against the policy of c, the releases are only
+// fetched when its minimum version is a channel.
+func (c *cli) allow(version string) error {
+	policy := c.policy
+	if isChannel(policy.MinimumVersion) {
+		releases, err := c.repo.GetVersions(c.ctx)
+		if err != nil {
+			return err
+		}
+		if policy, err = policy.resolve(releases); err != nil {
+			return err
+		}
+	}
+	return policy.Allow(version)
+}
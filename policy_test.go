@@ -0,0 +1,22 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPolicyAllow(t *testing.T) {
+	p := Policy{MinimumVersion: "go1.21.0"}
+
+	if err := p.Allow("go1.21.0"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := p.Allow("go1.20.14"); !errors.Is(err, ErrPolicy) {
+		t.Errorf("Expected ErrPolicy, got %v", err)
+	}
+
+	p.Override = true
+	if err := p.Allow("go1.20.14"); err != nil {
+		t.Errorf("Expected override to allow the version, got %v", err)
+	}
+}
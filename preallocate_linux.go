@@ -0,0 +1,41 @@
+//go:build linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// fallocKeepSize is FALLOC_FL_KEEP_SIZE, which the syscall package lacks.
+const fallocKeepSize = 0x01
+
+// preallocate reserves size bytes of disk space for f, so a full disk fails
+// the download from its start and the file is written in contiguous blocks.
+// The size of f is left as is, an interrupted download is no longer than
+// what was written. The file systems without fallocate are left to allocate
+// as f is written.
+func preallocate(f *os.File, size int64) error {
+	if size <= 0 {
+		return nil
+	}
+	conn, err := f.SyscallConn()
+	if err != nil {
+		return nil
+	}
+	var errAlloc error
+	conn.Control(func(fd uintptr) {
+		for {
+			errAlloc = syscall.Fallocate(int(fd), fallocKeepSize, 0, size)
+			if errAlloc != syscall.EINTR {
+				return
+			}
+		}
+	})
+	if errors.Is(errAlloc, syscall.ENOSPC) {
+		return fmt.Errorf("reserving %d bytes for %s: %w", size, f.Name(), errAlloc)
+	}
+	return nil
+}
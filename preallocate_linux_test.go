@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestPreallocate(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "archive"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	const size = 4 << 20
+	if err := preallocate(f, size); err != nil {
+		t.Fatal(err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != 0 {
+		t.Errorf("Expected the size to be kept, got %d", info.Size())
+	}
+	if blocks := info.Sys().(*syscall.Stat_t).Blocks; blocks*512 < size {
+		t.Skipf("The file system did not reserve the space, %d blocks", blocks)
+	}
+}
+
+func TestPipelinePreallocateAfterCacheMiss(t *testing.T) {
+	const size = 4 << 20
+	probe, err := os.Create(filepath.Join(t.TempDir(), "probe"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer probe.Close()
+	if err := preallocate(probe, size); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := probe.Stat(); err != nil || info.Sys().(*syscall.Stat_t).Blocks*512 < size {
+		t.Skipf("The file system does not reserve the space (%v)", err)
+	}
+
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n"})
+	dlf := File{Filename: "go1.22.1.linux-amd64.tar.gz", Version: "go1.22.1", Size: size}
+	storage := &diskStorage{dir: t.TempDir()}
+	p := newPipeline(newTestArchiveRepo(archive), storage, dlf, t.TempDir(), processOwner, newTestPaths(t))
+
+	// The space is still reserved once the download starts.
+	var blocks int64
+	p.repo.client = NewTestClient(func(*http.Request) *http.Response {
+		if info, err := os.Stat(p.state.Archive); err == nil {
+			blocks = info.Sys().(*syscall.Stat_t).Blocks
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(archive)), ContentLength: int64(len(archive))}
+	})
+	if err := p.download(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if blocks*512 < size {
+		t.Errorf("Expected %d bytes reserved after the cache miss, got %d blocks", size, blocks)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package main
+
+import "os"
+
+// preallocate is not supported on this platform, the file is allocated as
+// it is written.
+func preallocate(f *os.File, size int64) error {
+	return nil
+}
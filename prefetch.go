@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/blckfalcon/go-dl/tui"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// prefetcher downloads archives one after the other and verifies them with
+// a bounded pool of workers while the next ones download.
+type prefetcher struct {
+	jobs int
+	// output, when set, receives a copy of the verified files.
+	output string
+	mu     sync.Mutex
+	out    io.Writer
+	// task, when set, adds the progress of the download of each file, such
+	// as tui.Aggregator.Task.
+	task func(name string, weight float64) func(float64)
+}
+
+// status prints the status of a file, the workers print concurrently.
+func (pf *prefetcher) status(format string, args ...any) {
+	pf.mu.Lock()
+	defer pf.mu.Unlock()
+	fmt.Fprintf(pf.out, format+"\n", args...)
+}
+
+// run downloads then verifies each pipeline, it returns the failures of all
+// of them once every verification is done.
+func (pf *prefetcher) run(ctx context.Context, pipelines []*pipeline) error {
+	var wg sync.WaitGroup
+	workers := make(chan struct{}, max(pf.jobs, 1))
+	errs := make([]error, len(pipelines))
+
+	progress := make([]func(float64), len(pipelines))
+	for i, p := range pipelines {
+		progress[i] = func(float64) {}
+		if pf.task != nil {
+			progress[i] = pf.task(p.state.File.Filename, float64(p.state.File.Size))
+			// Each download reports its own progress.
+			repo := *p.repo
+			repo.onProgress = progress[i]
+			p.repo = &repo
+		}
+	}
+
+	for i, p := range pipelines {
+		name := p.state.File.Filename
+		if err := p.download(ctx); err != nil {
+			errs[i] = fmt.Errorf("%s: %w", name, wrapPermission(err))
+			pf.status("✗ %s: %v", name, err)
+			p.clear()
+			os.Remove(p.state.Archive)
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		// Archives read from the cache report no progress.
+		progress[i](1)
+		pf.status("  %s downloaded, verifying", name)
+
+		if reproducible.enabled {
+			// Verified in turn, so the files are reported in order.
+			errs[i] = pf.verify(ctx, p)
+			continue
+		}
+		workers <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-workers }()
+			errs[i] = pf.verify(ctx, p)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// verify verifies the file downloaded by p and reports its status, the
+// download is removed afterwards.
+func (pf *prefetcher) verify(ctx context.Context, p *pipeline) error {
+	defer os.Remove(p.state.Archive)
+	defer p.clear()
+
+	name := p.state.File.Filename
+	err := p.verify(ctx)
+	if err == nil {
+		err = runScanner(ctx, p.scanner, p.state.Archive)
+	}
+	target := ""
+	if err == nil && pf.output != "" {
+		target, err = copyVerified(p, pf.output)
+	}
+	if err != nil {
+		pf.status("✗ %s: %v", name, err)
+		return fmt.Errorf("%s: %w", name, wrapPermission(err))
+	}
+	if target != "" {
+		pf.status("✓ %s verified (%s), saved to %s", name, p.state.File.Checksum(), target)
+	} else {
+		pf.status("✓ %s verified (%s)", name, p.state.File.Checksum())
+	}
+	return nil
+}
+
+// newPrefetchPipeline returns the pipeline prefetching dlf to the storage,
+// with a state of its own so pipelines can run concurrently.
+func (c *cli) newPrefetchPipeline(dlf File) *pipeline {
+	p := newPipeline(c.repo, c.storage, dlf, c.prefix, c.owner, c.paths)
+	p.statePath = filepath.Join(c.paths.State, "prefetch", dlf.Filename+".json")
+	p.scanner = c.scanner
+	return p
+}
+
+// prefetch downloads the archives of the releases matching the constraints
+// to the cache, for later offline installations.
+func (c *cli) prefetch(args []string) error {
+	fs := flag.NewFlagSet("cache prefetch", flag.ContinueOnError)
+	jobs := fs.Int("jobs", runtime.NumCPU(), "number of archives verified at once, while the next ones download")
+	platforms := fs.String("platforms", "", "comma-separated os/arch of the archives, the one of the machine by default")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-dl cache prefetch [--jobs n] [--platforms list] <version constraint>...")
+		fs.PrintDefaults()
+	}
+	if err := parseInterspersed(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("prefetch expects at least one version")
+	}
+	if c.storage == nil {
+		return errors.New("prefetching needs a cache, none is configured")
+	}
+
+	selections := []Selection{c.selection}
+	if *platforms != "" {
+		selections = nil
+		for _, platform := range strings.Split(*platforms, ",") {
+			goos, goarch, ok := strings.Cut(strings.TrimSpace(platform), "/")
+			if !ok {
+				return fmt.Errorf("invalid platform %q, expected os/arch", platform)
+			}
+			selections = append(selections, Selection{Os: goos, Arch: goarch, Installer: c.selection.Installer})
+		}
+	}
+
+	releases, err := c.repo.GetVersions(c.ctx)
+	if err != nil {
+		return err
+	}
+	policy, err := c.policy.resolve(releases)
+	if err != nil {
+		return err
+	}
+
+	var pipelines []*pipeline
+	seen := map[string]bool{}
+	for _, query := range fs.Args() {
+		release, err := resolveRelease(releases, c.resolveAlias(query))
+		if err != nil {
+			return fmt.Errorf("%s: %w", query, err)
+		}
+		if err := policy.Allow(release.Version); err != nil {
+			return err
+		}
+		for _, selection := range selections {
+			dlf, ok := selection.Pick(release.Files)
+			if !ok {
+				return fmt.Errorf("%w for %s on %s/%s", ErrNoMatchingFile, release.Version, selection.Os, selection.Arch)
+			}
+			if !seen[dlf.Filename] {
+				seen[dlf.Filename] = true
+				pipelines = append(pipelines, c.newPrefetchPipeline(dlf))
+			}
+		}
+	}
+
+	pf := &prefetcher{jobs: *jobs, out: c.stdout}
+	run := pf.run
+	if f, ok := c.stdout.(*os.File); ok && isTerminal(f) && !reproducible.enabled {
+		run = func(ctx context.Context, pipelines []*pipeline) error {
+			return c.prefetchView(ctx, f, pf, pipelines)
+		}
+	}
+	if err := run(c.ctx, pipelines); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stdout, "Prefetched %d archives to the cache\n", len(pipelines))
+	return nil
+}
+
+// prefetchView runs pf showing the progress of each download and the overall
+// one in the terminal f, the statuses of the files are printed above it.
+func (c *cli) prefetchView(ctx context.Context, f *os.File, pf *prefetcher, pipelines []*pipeline) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	view := tea.NewProgram(tui.NewTasksView("Prefetching", c.keys), tea.WithOutput(f), tea.WithContext(ctx))
+	aggregator := tui.NewAggregator(view.Send)
+	pf.out = viewPrinter{view}
+	pf.task = aggregator.Task
+
+	done := make(chan error, 1)
+	go func() {
+		err := pf.run(ctx, pipelines)
+		view.Send(tui.TasksDoneMsg{})
+		done <- err
+	}()
+
+	m, err := view.Run()
+	if err != nil && !errors.Is(err, tea.ErrProgramKilled) {
+		cancel()
+		<-done
+		return err
+	}
+	if m, ok := m.(tui.TasksView); ok && m.Quit() {
+		cancel()
+	}
+	return <-done
+}
+
+// viewPrinter prints the lines written to it above a running program.
+type viewPrinter struct {
+	program *tea.Program
+}
+
+func (p viewPrinter) Write(b []byte) (int, error) {
+	p.program.Println(strings.TrimSuffix(string(b), "\n"))
+	return len(b), nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/blckfalcon/go-dl/tui"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestPrefetcherRun(t *testing.T) {
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n"})
+	sum := sha256.Sum256(archive)
+	good := hex.EncodeToString(sum[:])
+
+	files := []File{
+		{Filename: "go1.22.1.linux-amd64.tar.gz", Version: "go1.22.1", Sha256: good},
+		{Filename: "go1.22.1.linux-arm64.tar.gz", Version: "go1.22.1", Sha256: strings.Repeat("0", 64)},
+		{Filename: "go1.22.1.darwin-arm64.tar.gz", Version: "go1.22.1", Sha256: good},
+	}
+	storage := &diskStorage{dir: t.TempDir()}
+	paths := newTestPaths(t)
+	var pipelines []*pipeline
+	for _, f := range files {
+		p := newPipeline(newTestArchiveRepo(archive), storage, f, t.TempDir(), processOwner, paths)
+		p.statePath = filepath.Join(paths.State, "prefetch", f.Filename+".json")
+		pipelines = append(pipelines, p)
+	}
+
+	var out bytes.Buffer
+	pf := &prefetcher{jobs: 2, out: &out}
+	err := pf.run(context.Background(), pipelines)
+	if err == nil || !strings.Contains(err.Error(), "go1.22.1.linux-arm64.tar.gz") {
+		t.Fatalf("Expected the mismatch of the arm64 archive, got %v", err)
+	}
+	if strings.Contains(err.Error(), "amd64") || strings.Contains(err.Error(), "darwin") {
+		t.Errorf("Expected only the arm64 archive to fail, got %v", err)
+	}
+
+	for _, want := range []string{
+		"✓ go1.22.1.linux-amd64.tar.gz verified",
+		"✗ go1.22.1.linux-arm64.tar.gz:",
+		"✓ go1.22.1.darwin-arm64.tar.gz verified",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %q in the output, got:\n%s", want, out.String())
+		}
+	}
+	for _, f := range []string{"go1.22.1.linux-amd64.tar.gz", "go1.22.1.darwin-arm64.tar.gz"} {
+		if _, err := os.Stat(filepath.Join(storage.dir, f)); err != nil {
+			t.Errorf("Expected %s in the cache: %v", f, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(storage.dir, "go1.22.1.linux-arm64.tar.gz")); err == nil {
+		t.Errorf("Expected the mismatching archive out of the cache")
+	}
+	for _, p := range pipelines {
+		if _, err := os.Stat(p.state.Archive); err == nil {
+			t.Errorf("Expected %s removed", p.state.Archive)
+		}
+	}
+}
+
+func TestCachePrefetch(t *testing.T) {
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n"})
+	c, out := newTestCLI(t, archive)
+	c.storage = &diskStorage{dir: t.TempDir()}
+
+	// The flags may follow the versions.
+	if err := c.run([]string{"cache", "prefetch", "1.22", "1.22.1", "--jobs", "2"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(c.storage.(*diskStorage).dir, "go1.22.1.linux-amd64.tar.gz")); err != nil {
+		t.Errorf("Expected the archive in the cache: %v", err)
+	}
+	if !strings.Contains(out.String(), "Prefetched 1 archives") {
+		t.Errorf("Expected a single archive prefetched, got:\n%s", out.String())
+	}
+
+	if err := c.run([]string{"cache", "prefetch", "--platforms", "windows/amd64", "1.22.1"}); err == nil {
+		t.Errorf("Expected an error when the release has no file for the platform")
+	}
+}
+
+func TestPrefetcherProgress(t *testing.T) {
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n"})
+	sum := sha256.Sum256(archive)
+	files := []File{
+		{Filename: "go1.22.1.linux-amd64.tar.gz", Version: "go1.22.1", Sha256: hex.EncodeToString(sum[:]), Size: len(archive)},
+		{Filename: "go1.22.1.darwin-arm64.tar.gz", Version: "go1.22.1", Sha256: hex.EncodeToString(sum[:]), Size: len(archive)},
+	}
+	storage := &diskStorage{dir: t.TempDir()}
+	paths := newTestPaths(t)
+	repo := newTestArchiveRepo(archive)
+	var pipelines []*pipeline
+	for _, f := range files {
+		p := newPipeline(repo, storage, f, t.TempDir(), processOwner, paths)
+		p.statePath = filepath.Join(paths.State, "prefetch", f.Filename+".json")
+		pipelines = append(pipelines, p)
+	}
+	// The second one is read from the cache.
+	if err := storage.Put(context.Background(), files[1].Filename, bytes.NewReader(archive), int64(len(archive))); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var last tui.TasksMsg
+	aggregator := tui.NewAggregator(func(msg tea.Msg) {
+		mu.Lock()
+		defer mu.Unlock()
+		if msg := msg.(tui.TasksMsg); msg.Seq > last.Seq {
+			last = msg
+		}
+	})
+	pf := &prefetcher{jobs: 2, out: io.Discard, task: aggregator.Task}
+	if err := pf.run(context.Background(), pipelines); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(last.Tasks) != 2 || last.Overall != 1 {
+		t.Fatalf("Expected both downloads done, got %+v", last)
+	}
+	for i, task := range last.Tasks {
+		if task.Name != files[i].Filename || task.Ratio != 1 {
+			t.Errorf("Expected %s done, got %+v", files[i].Filename, task)
+		}
+	}
+}
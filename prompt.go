@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blckfalcon/go-dl/tui"
+	"github.com/blckfalcon/go-dl/versions"
+)
+
+// canRunTUI reports whether the interactive picker can take over the
+// terminal: standard input and output are terminals able to draw it.
+func canRunTUI(stdin, stdout *os.File, term string) bool {
+	return term != "dumb" && isTerminal(stdin) && isTerminal(stdout)
+}
+
+// promptPicker is the interactive picker of the consoles the TUI can't run
+// on, such as serial connections: the versions are numbered, and the choice
+// read as a line from in.
+type promptPicker struct {
+	in        *bufio.Reader
+	out       io.Writer
+	installer *pickerInstaller
+	versions  []string
+	labels    map[string][]string
+	files     map[string]tui.FileInfo
+	platform  string
+	size      func(int64) string
+}
+
+// promptPage is the number of versions listed at once, which fits the 24
+// lines of a serial console with the prompt.
+const promptPage = 20
+
+// choose lists the versions a page at a time and returns the one picked, by
+// number or by name, or an empty version when the user quit.
+func (p *promptPicker) choose() (string, error) {
+	listed := 0
+	list := func() {
+		end := min(listed+promptPage, len(p.versions))
+		for i, v := range p.versions[listed:end] {
+			line := fmt.Sprintf("%3d. %s", listed+i+1, v)
+			if l := p.labels[v]; len(l) > 0 {
+				line += fmt.Sprintf(" (%s)", strings.Join(l, ", "))
+			}
+			if f, ok := p.files[v]; ok && f.Size > 0 {
+				line += fmt.Sprintf("  %s %s", p.size(f.Size), f.Kind)
+			}
+			fmt.Fprintln(p.out, line)
+		}
+		listed = end
+	}
+	list()
+
+	for {
+		more := ""
+		if listed < len(p.versions) {
+			more = fmt.Sprintf(", m for %d more", len(p.versions)-listed)
+		}
+		fmt.Fprintf(p.out, "Version to install (number or version%s, empty to quit): ", more)
+		answer, err := p.in.ReadString('\n')
+		answer = strings.TrimSpace(answer)
+		if answer == "" {
+			if err != nil && !errors.Is(err, io.EOF) {
+				return "", err
+			}
+			return "", nil
+		}
+		if answer == "m" && more != "" {
+			list()
+			continue
+		}
+
+		// The versions not listed yet can be picked as well.
+		if n, errAtoi := strconv.Atoi(answer); errAtoi == nil && n >= 1 && n <= len(p.versions) {
+			return p.versions[n-1], nil
+		}
+		for _, v := range p.versions {
+			if v == versions.Normalize(answer) {
+				return v, nil
+			}
+		}
+		fmt.Fprintf(p.out, "%q is not listed\n", answer)
+		if err != nil {
+			return "", nil
+		}
+	}
+}
+
+// confirm asks a yes/no question, anything but yes is a no.
+func (p *promptPicker) confirm(question string) bool {
+	fmt.Fprintf(p.out, "%s [y/N] ", question)
+	answer, _ := p.in.ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// progress prints the ratio done of the current step every tenth, with the
+// speed of the transfer once known.
+func (p *promptPicker) progress() func(tui.ProgressMsg) {
+	last := 0
+	var rate tui.RateEstimator
+	return func(msg tui.ProgressMsg) {
+		if msg.Total > 0 {
+			rate.Observe(msg.Done, time.Now())
+		}
+		tenth := int(msg.Ratio * 10)
+		if tenth <= last {
+			return
+		}
+		last = tenth
+		if left, ok := rate.Remaining(msg.Total); ok {
+			fmt.Fprintf(p.out, "  %d%% (%s/s, %s left)\n", tenth*10, p.size(int64(rate.Rate())), left.Round(time.Second))
+			return
+		}
+		fmt.Fprintf(p.out, "  %d%%\n", tenth*10)
+	}
+}
+
+// run lets the user pick a version and installs it. It returns nil when the
+// user quit.
+func (p *promptPicker) run(ctx context.Context) error {
+	version, err := p.choose()
+	if err != nil || version == "" {
+		return err
+	}
+
+	fromSource, err := p.installer.Prepare(version)
+	if err != nil {
+		return err
+	}
+	if fromSource && !p.confirm(fmt.Sprintf("No %s archive for %s, build it from source?", p.platform, version)) {
+		return nil
+	}
+	// The space is not shown for source builds, which are refused over
+	// metered connections.
+	if !fromSource {
+		if space, err := p.installer.Space(); err == nil {
+			fmt.Fprintf(p.out, "Download %s, about %s once extracted\n", p.size(space.Download), p.size(space.Extracted))
+			for _, v := range space.Volumes {
+				free := "unknown"
+				if v.Free >= 0 {
+					free = p.size(v.Free)
+				}
+				short := ""
+				if v.Short() {
+					short = " (not enough space)"
+				}
+				fmt.Fprintf(p.out, "%s %s: %s free, %s needed%s\n", v.Name, v.Path, free, p.size(v.Needed), short)
+			}
+			if space.Metered {
+				fmt.Fprintln(p.out, tui.MeteredWarning)
+			}
+			if !p.confirm(fmt.Sprintf("Install %s?", version)) {
+				return nil
+			}
+		}
+	}
+
+	p.installer.repo.onReconnect = func(attempt, max int) {
+		fmt.Fprintf(p.out, "  stalled, reconnecting (%d/%d)\n", attempt, max)
+	}
+	for _, step := range []struct {
+		name string
+		run  func(context.Context) error
+	}{
+		{"Downloading", p.installer.Download},
+		{"Verifying", p.installer.Verify},
+		{"Extracting", p.installer.Extract},
+		{"Activating", p.installer.Activate},
+	} {
+		fmt.Fprintf(p.out, "%s %s\n", step.name, version)
+		p.installer.progress = p.progress()
+		if err := step.run(ctx); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(p.out, "Installed %s\n", version)
+	return nil
+}
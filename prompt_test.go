@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPromptPicker(t *testing.T) {
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n"})
+	c, _ := newTestCLI(t, archive)
+	c.prefix = t.TempDir()
+
+	releases, err := c.repo.GetVersions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	p := &promptPicker{
+		in:  bufio.NewReader(strings.NewReader("9\n1.22.1\ny\n")),
+		out: &out,
+		installer: &pickerInstaller{
+			repo:      c.repo,
+			versions:  releases,
+			selection: c.selection,
+			prefix:    c.prefix,
+			paths:     c.paths,
+			owner:     c.owner,
+			cli:       c,
+		},
+		versions: []string{"go1.22.1", "go1.21.0"},
+		labels:   releaseLabels(releases),
+		platform: "linux/amd64",
+		size:     decimalUnits.size,
+	}
+	if err := p.run(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v\n%s", err, out.String())
+	}
+	if v, err := installedVersion(c.goroot()); err != nil || v != "go1.22.1" {
+		t.Errorf("Expected go1.22.1 to be installed, got %q (%v)", v, err)
+	}
+	for _, want := range []string{"  1. go1.22.1", `"9" is not listed`, "Install go1.22.1? [y/N]", "Installed go1.22.1"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %q in the output, got %q", want, out.String())
+		}
+	}
+
+	// Declining the confirmation, or an empty answer, installs nothing.
+	for _, input := range []string{"", "1\nn\n"} {
+		p.in = bufio.NewReader(strings.NewReader(input))
+		p.installer.prefix = t.TempDir()
+		if err := p.run(context.Background()); err != nil {
+			t.Errorf("Unexpected error for %q: %v", input, err)
+		}
+	}
+}
+
+func TestPickerInstallerAfterInstall(t *testing.T) {
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n"})
+	c, _ := newTestCLI(t, archive)
+	c.prefix = t.TempDir()
+	c.webhooks = []WebhookConfig{{URL: "https://hooks.example.com/all"}}
+	c.hooks = installHooks{global: HookConfig{PostInstall: []string{"echo done >" + filepath.Join(c.prefix, "hook")}}}
+
+	var posted []map[string]string
+	c.webhookClient = NewTestClient(func(req *http.Request) *http.Response {
+		var body map[string]string
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			t.Errorf("Invalid webhook body: %v", err)
+		}
+		posted = append(posted, body)
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(nil))}
+	})
+
+	releases, err := c.repo.GetVersions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := &pickerInstaller{
+		repo:      c.repo,
+		versions:  releases,
+		selection: c.selection,
+		prefix:    c.prefix,
+		paths:     c.paths,
+		owner:     c.owner,
+		cli:       c,
+	}
+
+	// The failed steps are notified as for the command line.
+	if _, err := i.Prepare("go1.22.1"); err != nil {
+		t.Fatal(err)
+	}
+	client := c.repo.client
+	c.repo.client = NewTestClient(func(*http.Request) *http.Response {
+		return &http.Response{StatusCode: http.StatusNotFound, Status: "404 Not Found", Body: io.NopCloser(bytes.NewReader(nil))}
+	})
+	if err := i.Download(context.Background()); err == nil {
+		t.Fatal("Expected the missing download to fail")
+	}
+	if len(posted) != 1 || posted[0]["status"] != installFailure {
+		t.Errorf("Expected the failure posted, got %v", posted)
+	}
+
+	c.repo.client = client
+	ctx := context.Background()
+	for _, step := range []func(context.Context) error{i.Download, i.Verify, i.Extract, i.Activate} {
+		if err := step(ctx); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if len(posted) != 2 || posted[1]["status"] != installSuccess || posted[1]["version"] != "go1.22.1" {
+		t.Errorf("Expected the success posted once activated, got %v", posted)
+	}
+	if b, err := os.ReadFile(filepath.Join(c.prefix, "hook")); err != nil || strings.TrimSpace(string(b)) != "done" {
+		t.Errorf("Expected the post_install hook to run, got %q (%v)", b, err)
+	}
+}
+
+func TestPromptPickerPages(t *testing.T) {
+	var list []string
+	for i := 0; i < promptPage+5; i++ {
+		list = append(list, fmt.Sprintf("go1.%d.0", 40-i))
+	}
+
+	var out bytes.Buffer
+	p := &promptPicker{in: bufio.NewReader(strings.NewReader("m\n25\n")), out: &out, versions: list, size: decimalUnits.size}
+	v, err := p.choose()
+	if err != nil || v != list[24] {
+		t.Errorf("Expected %s, got %q (%v)", list[24], v, err)
+	}
+	if got := strings.Count(out.String(), ". go1."); got != len(list) {
+		t.Errorf("Expected the %d versions to be listed once, got %d in %q", len(list), got, out.String())
+	}
+	if !strings.Contains(out.String(), "m for 5 more") || strings.Count(out.String(), "more") != 1 {
+		t.Errorf("Expected the second page to be offered once, got %q", out.String())
+	}
+}
+
+func TestCanRunTUI(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "stdin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if canRunTUI(f, f, "xterm") {
+		t.Errorf("Expected no TUI on regular files")
+	}
+}
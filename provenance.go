@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/blckfalcon/go-dl/versions"
+)
+
+// provenanceFile is written in each installation, next to its VERSION.
+const provenanceFile = "PROVENANCE.json"
+
+// Provenance records where an installation comes from.
+type Provenance struct {
+	Version string `json:"version"`
+	// Source is the URL the archive was downloaded from, after redirects,
+	// Cached is set when it was taken from the cache instead.
+	Source string `json:"source,omitempty"`
+	Cached bool   `json:"cached,omitempty"`
+	// Feed is when the releases feed listing the archive was fetched.
+	Feed        string `json:"feed,omitempty"`
+	Sha256      string `json:"sha256"`
+	GoDL        string `json:"go_dl"`
+	InstalledAt string `json:"installed_at"`
+	// Filter is the files of the release left out of the installation.
+	Filter archiveFilter `json:"filter"`
+	// Dist is the distribution of the release, empty for go.dev.
+	Dist string `json:"dist,omitempty"`
+}
+
+// goDLVersion returns the version of the running go-dl, as recorded in its
+// build information.
+func goDLVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
+
+// writeProvenance records the provenance of the installation at goroot,
+// extracted from archive.
+func writeProvenance(goroot string, state pipelineState, archive string, owner Owner) error {
+	b, err := provenanceJSON(state, archive)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(goroot, provenanceFile)
+	if err := writeFileAtomic(path, b, 0644); err != nil {
+		return err
+	}
+	return chownTree(path, owner)
+}
+
+// provenanceJSON returns the provenance of the installation of state,
+// extracted from archive, as written in its PROVENANCE.json.
+func provenanceJSON(state pipelineState, archive string) ([]byte, error) {
+	sum, err := fileSum(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	provenance := Provenance{
+		Version:     state.Version,
+		Source:      state.Source,
+		Feed:        state.Feed,
+		Sha256:      fmt.Sprintf("%x", sum),
+		GoDL:        goDLVersion(),
+		InstalledAt: reproducible.now().UTC().Format(time.RFC3339),
+		Dist:        state.Dist,
+	}
+	// The builds from source extract the whole source.
+	if state.File.Kind != KindSource {
+		provenance.Filter = state.Filter
+	}
+	if state.Source == "cache" {
+		provenance.Source, provenance.Cached = "", true
+	}
+	// Where the archive came from, and when the feed was fetched, vary from
+	// a build to the next, unlike the archive.
+	if reproducible.enabled {
+		provenance.Source, provenance.Cached, provenance.Feed = "", false, ""
+	}
+
+	b, err := json.MarshalIndent(provenance, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// readProvenance returns the provenance recorded in the installation at
+// goroot.
+func readProvenance(goroot string) (Provenance, error) {
+	var provenance Provenance
+
+	b, err := os.ReadFile(filepath.Join(goroot, provenanceFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return provenance, fmt.Errorf("%s has no provenance, it was installed by another tool or an older go-dl", goroot)
+	}
+	if err != nil {
+		return provenance, err
+	}
+	if err := json.Unmarshal(b, &provenance); err != nil {
+		return provenance, fmt.Errorf("invalid provenance in %s: %w", goroot, err)
+	}
+	return provenance, nil
+}
+
+// info shows the provenance of an installed version, the active one by
+// default.
+func (c *cli) info(args []string) error {
+	fs := flag.NewFlagSet("info", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the provenance as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-dl info [--json] [version]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return errors.New("info expects at most one version")
+	}
+
+	goroot := c.goroot()
+	if fs.NArg() == 1 {
+		version := versions.Normalize(c.resolveAlias(fs.Arg(0)))
+		var ok bool
+		if goroot, ok = c.toolchain(version); !ok {
+			return fmt.Errorf("%s is not installed: %w", version, ErrVersionNotFound)
+		}
+	}
+
+	provenance, err := readProvenance(goroot)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(c.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(provenance)
+	}
+
+	source := provenance.Source
+	if provenance.Cached {
+		source = "cache"
+	}
+	feed := provenance.Feed
+	if feed == "" {
+		feed = "unknown"
+	}
+
+	w := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "version:\t%s\n", provenance.Version)
+	if provenance.Dist != "" {
+		fmt.Fprintf(w, "distribution:\t%s\n", provenance.Dist)
+	}
+	fmt.Fprintf(w, "location:\t%s\n", goroot)
+	fmt.Fprintf(w, "source:\t%s\n", source)
+	fmt.Fprintf(w, "feed fetched:\t%s\n", feed)
+	fmt.Fprintf(w, "sha256:\t%s\n", provenance.Sha256)
+	fmt.Fprintf(w, "installed:\t%s by go-dl %s\n", provenance.InstalledAt, provenance.GoDL)
+	if !provenance.Filter.empty() {
+		fmt.Fprintf(w, "left out:\t%s\n", strings.Join(provenance.Filter.Exclude, ","))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestProvenance(t *testing.T) {
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n"})
+	c, out := newTestCLI(t, archive)
+	c.prefix = t.TempDir()
+
+	if err := c.run([]string{"install", "1.22.1"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	out.Reset()
+	if err := c.run([]string{"info", "--json", "1.22.1"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var provenance Provenance
+	if err := json.Unmarshal(out.Bytes(), &provenance); err != nil {
+		t.Fatalf("Expected the provenance as JSON, got %q (%v)", out.String(), err)
+	}
+
+	sum := sha256.Sum256(archive)
+	if provenance.Version != "go1.22.1" || provenance.Source != "https://go.dev/dl/go1.22.1.linux-amd64.tar.gz" || provenance.Cached ||
+		provenance.Sha256 != hex.EncodeToString(sum[:]) || provenance.Feed == "" || provenance.GoDL == "" || provenance.InstalledAt == "" {
+		t.Errorf("Unexpected provenance %+v", provenance)
+	}
+
+	out.Reset()
+	if err := c.run([]string{"info"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "source:        https://go.dev/dl/go1.22.1.linux-amd64.tar.gz\n") {
+		t.Errorf("Expected the provenance of the active installation, got %q", out.String())
+	}
+
+	if err := c.run([]string{"info", "1.21.0"}); err == nil {
+		t.Errorf("Expected an error for a version not installed")
+	}
+}
+
+func TestProvenanceFilter(t *testing.T) {
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n", "go/doc/go_spec.html": "spec"})
+	c, out := newTestCLI(t, archive)
+	c.prefix = t.TempDir()
+
+	c.repo.archive.filter, _ = newArchiveFilter(true, "", "")
+	if err := c.run([]string{"install", "1.22.1"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// check without --minimal doesn't report the files left out.
+	c.repo.archive.filter = archiveFilter{}
+	if err := c.run([]string{"check", "1.22.1"}); err != nil {
+		t.Errorf("Unexpected error: %v\n%s", err, out)
+	}
+
+	out.Reset()
+	if err := c.run([]string{"info"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "left out:") || !strings.Contains(out.String(), "/doc") {
+		t.Errorf("Expected the files left out in the provenance, got %q", out.String())
+	}
+}
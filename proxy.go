@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+)
+
+// proxyAuthTransport authenticates to the proxy with the Proxy-Authorization
+// header printed by an external command, for the schemes net/http does not
+// implement (Negotiate, NTLM through a helper, ...).
+type proxyAuthTransport struct {
+	base    *http.Transport
+	command []string
+}
+
+// newProxyAuthTransport returns base unchanged without an auth command.
+func newProxyAuthTransport(base *http.Transport, command []string) http.RoundTripper {
+	if len(command) == 0 {
+		return base
+	}
+
+	t := &proxyAuthTransport{base: base, command: command}
+	base.GetProxyConnectHeader = func(ctx context.Context, proxy *url.URL, target string) (http.Header, error) {
+		auth, err := t.authorization(ctx, proxy)
+		if err != nil {
+			return nil, err
+		}
+		return http.Header{"Proxy-Authorization": {auth}}, nil
+	}
+	return t
+}
+
+// RoundTrip adds the header to plain http requests sent through a proxy,
+// https requests get it on the CONNECT request instead.
+func (t *proxyAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != "http" || t.base.Proxy == nil {
+		return t.base.RoundTrip(req)
+	}
+
+	proxy, err := t.base.Proxy(req)
+	if err != nil || proxy == nil || proxy.Scheme != "http" && proxy.Scheme != "https" {
+		return t.base.RoundTrip(req)
+	}
+
+	auth, err := t.authorization(req.Context(), proxy)
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Proxy-Authorization", auth)
+	return t.base.RoundTrip(req)
+}
+
+// authorization runs the auth command with the proxy url as last argument,
+// its output is the header value.
+func (t *proxyAuthTransport) authorization(ctx context.Context, proxy *url.URL) (string, error) {
+	redacted := *proxy
+	redacted.User = nil
+
+	args := append(append([]string{}, t.command[1:]...), redacted.String())
+	cmd := exec.CommandContext(ctx, t.command[0], args...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("proxy auth command %s failed: %w: %s", t.command[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	auth := strings.TrimSpace(string(out))
+	if auth == "" {
+		return "", fmt.Errorf("proxy auth command %s printed no credentials", t.command[0])
+	}
+	return auth, nil
+}
+
+// proxyFromEnvironment returns the proxy function of the transport. Like
+// http.ProxyFromEnvironment it honors HTTPS_PROXY, HTTP_PROXY and NO_PROXY,
+// ALL_PROXY is used for both schemes otherwise, socks5:// urls included.
+func proxyFromEnvironment(getenv func(string) string) func(*http.Request) (*url.URL, error) {
+	env := func(name string) string {
+		if v := getenv(name); v != "" {
+			return v
+		}
+		return getenv(strings.ToLower(name))
+	}
+
+	proxies := map[string]string{"https": env("HTTPS_PROXY"), "http": env("HTTP_PROXY")}
+	all := env("ALL_PROXY")
+	noProxy := env("NO_PROXY")
+
+	return func(req *http.Request) (*url.URL, error) {
+		raw := proxies[req.URL.Scheme]
+		if raw == "" {
+			raw = all
+		}
+		if raw == "" || bypassProxy(req.URL.Hostname(), noProxy) {
+			return nil, nil
+		}
+
+		if !strings.Contains(raw, "://") {
+			raw = "http://" + raw
+		}
+		proxy, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy address %q: %w", raw, err)
+		}
+		return proxy, nil
+	}
+}
+
+// bypassProxy reports whether host is local or matches one of the comma
+// separated NO_PROXY entries: *, domains matching their subdomains, ip
+// addresses and CIDR ranges.
+func bypassProxy(host, noProxy string) bool {
+	host = strings.ToLower(host)
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	if ip != nil && ip.IsLoopback() {
+		return true
+	}
+
+	for _, entry := range strings.Split(noProxy, ",") {
+		if matchHost(host, entry) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchHost reports whether host matches a NO_PROXY entry.
+func matchHost(host, entry string) bool {
+	host = strings.ToLower(host)
+	entry = strings.ToLower(strings.TrimSpace(entry))
+	if entry == "" {
+		return false
+	}
+	if entry == "*" {
+		return true
+	}
+
+	if _, network, err := net.ParseCIDR(entry); err == nil {
+		ip := net.ParseIP(host)
+		return ip != nil && network.Contains(ip)
+	}
+
+	if h, _, err := net.SplitHostPort(entry); err == nil {
+		entry = h
+	}
+	entry = strings.TrimPrefix(strings.TrimPrefix(entry, "*"), ".")
+	return host == entry || strings.HasSuffix(host, "."+entry)
+}
+
+// proxyDirect is the proxy override connecting without a proxy.
+const proxyDirect = "direct"
+
+// withProxyOverrides returns proxy with the per-host overrides of the
+// configuration applied first, keyed by NO_PROXY entries and giving either a
+// proxy url or direct. The most specific matching entry wins.
+func withProxyOverrides(proxy func(*http.Request) (*url.URL, error), overrides map[string]string) (func(*http.Request) (*url.URL, error), error) {
+	if len(overrides) == 0 {
+		return proxy, nil
+	}
+
+	parsed := map[string]*url.URL{}
+	for entry, raw := range overrides {
+		if raw == proxyDirect {
+			parsed[entry] = nil
+			continue
+		}
+		if !strings.Contains(raw, "://") {
+			raw = "http://" + raw
+		}
+		u, err := url.Parse(raw)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid proxy %q for %s, expected an url or %s", overrides[entry], entry, proxyDirect)
+		}
+		parsed[entry] = u
+	}
+
+	return func(req *http.Request) (*url.URL, error) {
+		best := ""
+		for entry := range parsed {
+			if matchHost(req.URL.Hostname(), entry) && (best == "" || len(entry) > len(best) || len(entry) == len(best) && entry < best) {
+				best = entry
+			}
+		}
+		if best == "" {
+			return proxy(req)
+		}
+		return parsed[best], nil
+	}, nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os/exec"
+	"testing"
+)
+
+func TestProxyAuthTransport(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is required to run the auth command")
+	}
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Proxy-Authorization"); got != "Negotiate dG9rZW4=" {
+			http.Error(w, "got "+got, http.StatusProxyAuthRequired)
+			return
+		}
+		io.WriteString(w, r.URL.Host)
+	}))
+	defer proxy.Close()
+
+	proxyURL, _ := url.Parse(proxy.URL)
+	base := &http.Transport{Proxy: http.ProxyURL(proxyURL)}
+	command := []string{"sh", "-c", `test "$0" = ` + proxy.URL + ` && echo "Negotiate dG9rZW4="`}
+	client := &http.Client{Transport: newProxyAuthTransport(base, command)}
+
+	resp, err := client.Get("http://go.dev.invalid/dl/")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	b, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(b) != "go.dev.invalid" {
+		t.Errorf("Expected the request to be proxied, got %d %s", resp.StatusCode, b)
+	}
+
+	failing := &http.Client{Transport: newProxyAuthTransport(&http.Transport{Proxy: http.ProxyURL(proxyURL)}, []string{"sh", "-c", "exit 1"})}
+	if _, err := failing.Get("http://go.dev.invalid/dl/"); err == nil {
+		t.Errorf("Expected a failing auth command to fail the request")
+	}
+}
+
+func TestProxyFromEnvironment(t *testing.T) {
+	env := map[string]string{
+		"https_proxy": "http://corp:3128",
+		"ALL_PROXY":   "socks5://localhost:1080",
+		"NO_PROXY":    "internal.example, .corp.example,10.0.0.0/8",
+	}
+	proxy := proxyFromEnvironment(func(name string) string { return env[name] })
+
+	for target, want := range map[string]string{
+		"https://go.dev/dl/":                  "http://corp:3128",
+		"http://go.dev/dl/":                   "socks5://localhost:1080",
+		"https://mirror.internal.example/dl/": "",
+		"https://dl.corp.example/":            "",
+		"https://corp.example/":               "",
+		"http://10.1.2.3/":                    "",
+		"http://127.0.0.1:8080/":              "",
+		"https://notinternal.example/go.tgz":  "http://corp:3128",
+	} {
+		req, _ := http.NewRequest(http.MethodGet, target, nil)
+		got, err := proxy(req)
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v", target, err)
+			continue
+		}
+
+		var s string
+		if got != nil {
+			s = got.String()
+		}
+		if s != want {
+			t.Errorf("Expected proxy %q for %s, got %q", want, target, s)
+		}
+	}
+}
+
+func TestProxyOverrides(t *testing.T) {
+	env := map[string]string{"HTTPS_PROXY": "http://corp:3128", "NO_PROXY": "bypassed.example"}
+	proxy, err := withProxyOverrides(proxyFromEnvironment(func(name string) string { return env[name] }), map[string]string{
+		"internal.example":         "direct",
+		"eu.mirror.example":        "http://eu-proxy:3128",
+		"mirror.example":           "direct",
+		"10.0.0.0/8":               "socks5://jump:1080",
+		"special.bypassed.example": "proxy.example:8080",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for target, want := range map[string]string{
+		"https://go.dev/dl/":                  "http://corp:3128",
+		"https://mirror.internal.example/dl/": "",
+		"https://mirror.example/dl/":          "",
+		"https://eu.mirror.example/dl/":       "http://eu-proxy:3128",
+		"https://10.1.2.3/go.tar.gz":          "socks5://jump:1080",
+		"https://bypassed.example/":           "",
+		"https://special.bypassed.example/":   "http://proxy.example:8080",
+	} {
+		req, _ := http.NewRequest(http.MethodGet, target, nil)
+		got, err := proxy(req)
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v", target, err)
+			continue
+		}
+
+		var s string
+		if got != nil {
+			s = got.String()
+		}
+		if s != want {
+			t.Errorf("Expected proxy %q for %s, got %q", want, target, s)
+		}
+	}
+
+	if _, err := withProxyOverrides(nil, map[string]string{"go.dev": "http://"}); err == nil {
+		t.Errorf("Expected an error for an invalid proxy")
+	}
+}
@@ -0,0 +1,7 @@
+//go:build race
+
+package main
+
+// raceEnabled is set when the tests run with the race detector, which
+// inflates the resident memory of the process.
+const raceEnabled = true
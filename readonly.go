@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// errReadOnly is returned by checkWritable for the directories of a
+// read-only file system, or immutable ones, as found on NixOS or ostree based
+// systems.
+var errReadOnly = errors.New("read-only file system")
+
+// userPrefix is where the installations go when the default prefix is
+// read-only, go-dl under the user data directory.
+func userPrefix() string {
+	return filepath.Join(userDataDir(), "go-dl")
+}
+
+// writablePrefix returns prefix, or the userPrefix when prefix is on a
+// read-only file system. A lack of permissions doesn't count, the
+// installation can still be run with enough privileges.
+func writablePrefix(prefix string) (string, bool) {
+	if !errors.Is(checkWritable(existingParent(prefix)), errReadOnly) {
+		return prefix, false
+	}
+	return userPrefix(), true
+}
+
+// existingParent returns path or its closest existing parent, the directories
+// of the installation can still have to be created.
+func existingParent(path string) string {
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package main
+
+// checkWritable leaves read-only targets to fail on their first write,
+// Windows has no read-only file systems to install on.
+func checkWritable(dir string) error {
+	return nil
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestWritablePrefix(t *testing.T) {
+	prefix := filepath.Join(t.TempDir(), "usr", "local")
+	if got, fallback := writablePrefix(prefix); got != prefix || fallback {
+		t.Errorf("Expected the writable prefix to be kept, got %q", got)
+	}
+
+	if runtime.GOOS != "linux" {
+		return
+	}
+	data := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", data)
+	if got := userPrefix(); got != filepath.Join(data, "go-dl") {
+		t.Errorf("Expected the user prefix under XDG_DATA_HOME, got %q", got)
+	}
+}
@@ -0,0 +1,19 @@
+//go:build unix
+
+package main
+
+import (
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+// checkWritable asks the kernel whether dir is writable, access reports
+// EROFS on read-only file systems and EPERM on immutable directories.
+func checkWritable(dir string) error {
+	err := unix.Access(dir, unix.W_OK)
+	if errors.Is(err, unix.EROFS) || errors.Is(err, unix.EPERM) {
+		return errReadOnly
+	}
+	return err
+}
@@ -0,0 +1,9 @@
+package main
+
+import "golang.org/x/sys/unix"
+
+// cloneFile creates dst as a clone of src, sharing its blocks until either is
+// modified, on APFS.
+func cloneFile(src, dst string) error {
+	return unix.Clonefile(src, dst, unix.CLONE_NOFOLLOW)
+}
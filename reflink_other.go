@@ -0,0 +1,11 @@
+//go:build !linux && !darwin
+
+package main
+
+import "errors"
+
+// cloneFile is not supported on this platform, the files are hardlinked
+// instead.
+func cloneFile(src, dst string) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"path"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/blckfalcon/go-dl/tui"
+)
+
+// fileInfo describes dlf for the picker.
+func fileInfo(dlf File) tui.FileInfo {
+	sum := dlf.Checksum()
+	info := tui.FileInfo{Name: dlf.Filename, Size: int64(dlf.Size), Kind: dlf.Kind, Algorithm: sum.Algorithm, Sum: sum.Sum}
+	if dlf.Os != "" {
+		info.Platform = dlf.Os + "/" + dlf.Arch
+	}
+	return info
+}
+
+// matchFiles returns the files of release matching one of the
+// comma-separated shell patterns, every file when empty.
+func matchFiles(files Files, patterns string) (Files, error) {
+	if patterns == "" {
+		return files, nil
+	}
+
+	var matched Files
+	for _, f := range files {
+		for _, pattern := range strings.Split(patterns, ",") {
+			ok, err := path.Match(strings.TrimSpace(pattern), f.Filename)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+			}
+			if ok {
+				matched = append(matched, f)
+				break
+			}
+		}
+	}
+	if len(matched) == 0 {
+		return nil, fmt.Errorf("%w matching %s", ErrNoMatchingFile, patterns)
+	}
+	return matched, nil
+}
+
+// release lists every file of a release, archives, installers and source,
+// and downloads them for mirrors of complete releases.
+func (c *cli) release(args []string) error {
+	fs := flag.NewFlagSet("release", flag.ContinueOnError)
+	download := fs.Bool("download", false, "download and verify the files listed")
+	files := fs.String("files", "", "comma-separated patterns of the files, e.g. '*.linux-*,*.src.tar.gz' (default all)")
+	output := fs.String("output", ".", "directory receiving the verified files with --download")
+	jobs := fs.Int("jobs", runtime.NumCPU(), "number of files verified at once with --download, while the next ones download")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-dl release [--download] [--files patterns] [--output dir] <version constraint>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("release expects a single version")
+	}
+
+	releases, err := c.repo.GetVersions(c.ctx)
+	if err != nil {
+		return err
+	}
+	release, err := resolveRelease(releases, c.resolveAlias(fs.Arg(0)))
+	if err != nil {
+		return err
+	}
+	policy, err := c.policy.resolve(releases)
+	if err != nil {
+		return err
+	}
+	if err := policy.Allow(release.Version); err != nil {
+		return err
+	}
+
+	matched, err := matchFiles(release.Files, *files)
+	if err != nil {
+		return err
+	}
+
+	if !*download {
+		w := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+		for _, f := range matched {
+			info := fileInfo(f)
+			platform := info.Platform
+			if platform == "" {
+				platform = "-"
+			}
+			size := "-"
+			if info.Size > 0 {
+				size = c.units.size(info.Size)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", f.Filename, platform, f.Kind, size, f.Checksum())
+		}
+		return w.Flush()
+	}
+
+	var pipelines []*pipeline
+	for _, f := range matched {
+		pipelines = append(pipelines, c.newPrefetchPipeline(f))
+	}
+	pf := &prefetcher{jobs: *jobs, output: *output, out: c.stdout}
+	return pf.run(c.ctx, pipelines)
+}
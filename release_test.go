@@ -0,0 +1,33 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReleaseCommand(t *testing.T) {
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n"})
+	c, out := newTestCLI(t, archive)
+	c.prefix = t.TempDir()
+
+	if err := c.run([]string{"release", "1.22.1"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := out.String(); !strings.HasPrefix(got, "go1.22.1.linux-amd64.tar.gz  linux/amd64  archive  -  sha256 ") {
+		t.Errorf("Expected the files of the release, got %q", got)
+	}
+
+	output := t.TempDir()
+	if err := c.run([]string{"release", "--download", "--files", "*.linux-*", "--output", output, "1.22.1"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if b, err := os.ReadFile(filepath.Join(output, "go1.22.1.linux-amd64.tar.gz")); err != nil || string(b) != string(archive) {
+		t.Errorf("Expected the verified archive in %s, got %v", output, err)
+	}
+
+	if err := c.run([]string{"release", "--files", "*.windows-*", "1.22.1"}); err == nil {
+		t.Errorf("Expected an error when no file matches")
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// remoteTarget is an installation prefix on another machine, reached over
+// SSH, or in a Docker container, a volume mounted in it included.
+type remoteTarget struct {
+	scheme string
+	// host is user@host[:port] for ssh and the container for docker.
+	host   string
+	prefix string
+}
+
+// validHost matches the user@host[:port] of ssh and the containers of
+// docker, a leading dash would be read as an option.
+var validHost = regexp.MustCompile(`^([A-Za-z0-9_][A-Za-z0-9._-]*@)?[A-Za-z0-9_][A-Za-z0-9._-]*(:[0-9]+)?$`)
+
+// parseRemoteTarget parses ssh://user@host[:port]:/prefix or
+// docker://container:/prefix, the prefix defaults to /usr/local.
+func parseRemoteTarget(s string) (remoteTarget, error) {
+	scheme, rest, ok := strings.Cut(s, "://")
+	if !ok || scheme != "ssh" && scheme != "docker" {
+		return remoteTarget{}, fmt.Errorf("invalid target %q, expected ssh://user@host:/prefix or docker://container:/prefix", s)
+	}
+
+	t := remoteTarget{scheme: scheme, host: rest, prefix: defaultPrefix}
+	if i := strings.Index(rest, ":/"); i >= 0 {
+		t.host, t.prefix = rest[:i], path.Clean(rest[i+1:])
+	}
+	if t.host == "" {
+		return remoteTarget{}, fmt.Errorf("invalid target %q: missing host", s)
+	}
+	if !validHost.MatchString(t.host) {
+		return remoteTarget{}, fmt.Errorf("invalid target %q: invalid host %q", s, t.host)
+	}
+	return t, nil
+}
+
+func (t remoteTarget) String() string {
+	return t.scheme + "://" + t.host + ":" + t.prefix
+}
+
+// command returns the command running script on the target.
+func (t remoteTarget) command(script string) []string {
+	if t.scheme == "docker" {
+		return []string{"docker", "exec", "-i", t.host, "sh", "-c", script}
+	}
+
+	args := []string{"ssh"}
+	host := t.host
+	if h, port, ok := strings.Cut(host[strings.LastIndex(host, "@")+1:], ":"); ok {
+		host = host[:strings.LastIndex(host, "@")+1] + h
+		args = append(args, "-p", port)
+	}
+	// ssh hands a single string to the remote shell.
+	return append(args, "--", host, "sh -c "+shellQuote(script))
+}
+
+// remoteExtractScript extracts the archive read on stdin next to the
+// installation under prefix, which is only replaced once it succeeded, and
+// records its provenance.
+func remoteExtractScript(prefix string, provenance []byte) string {
+	return strings.Join([]string{
+		"set -e",
+		"prefix=" + shellQuote(prefix),
+		`staging="$prefix/.go-dl-extract"`,
+		`rm -rf "$staging" && mkdir -p "$staging"`,
+		// The owners and the modes were sanitized already, root's tar would
+		// restore them otherwise.
+		`tar --no-same-owner --no-same-permissions -xzf - -C "$staging"`,
+		`test -x "$staging/go/bin/go"`,
+		`printf '%s' ` + shellQuote(string(provenance)) + ` > "$staging/go/` + provenanceFile + `"`,
+		`if [ -e "$prefix/go" ]; then rm -rf "$prefix/go.old" && mv "$prefix/go" "$prefix/go.old"; fi`,
+		`mv "$staging/go" "$prefix/go"`,
+		`rm -rf "$prefix/go.old" "$staging"`,
+		`cat "$prefix/go/VERSION"`,
+	}, "\n")
+}
+
+// installRemote streams the verified archive of dlf to the target, where it
+// is extracted with tar. The stream is sanitized as the local extractions
+// first, without the files left out by the filter.
+func (c *cli) installRemote(dlf File, target remoteTarget) error {
+	if !isExtractable(dlf) {
+		return fmt.Errorf("%w: %s can't be extracted remotely", ErrNoMatchingFile, dlf.Filename)
+	}
+
+	fmt.Fprintf(c.stdout, "Installing %s on %s\n", dlf.Version, target)
+	archive, err := c.openArchive(dlf)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+	if err := runScanner(c.ctx, c.scanner, archive.Name()); err != nil {
+		return err
+	}
+
+	state := pipelineState{Version: dlf.Version, File: dlf, Source: archive.source, Feed: feedFetched(c.repo), Filter: c.repo.archive.filter, Dist: c.repo.dist}
+	provenance, err := provenanceJSON(state, archive.Name())
+	if err != nil {
+		return err
+	}
+
+	argv := target.command(remoteExtractScript(target.prefix, provenance))
+	cmd := exec.CommandContext(c.ctx, argv[0], argv[1:]...)
+	pr, pw := io.Pipe()
+	defer pr.Close()
+	go func() { pw.CloseWithError(sanitizeArchive(pw, archive, c.repo.archive)) }()
+	cmd.Stdin = pr
+
+	var out, stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("installing on %s failed: %w: %s", target, err, strings.TrimSpace(stderr.String()))
+	}
+
+	if v, _, _ := strings.Cut(out.String(), "\n"); v != dlf.Version {
+		return fmt.Errorf("installing on %s failed: found version %q instead of %s", target, v, dlf.Version)
+	}
+	fmt.Fprintf(c.stdout, "Installed %s in %s/go on %s\n", dlf.Version, target.prefix, target.host)
+	return nil
+}
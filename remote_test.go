@@ -0,0 +1,144 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestParseRemoteTarget(t *testing.T) {
+	for s, want := range map[string][]string{
+		"ssh://deploy@build1:/opt":       {"ssh", "-p", "", "deploy@build1", "/opt"},
+		"ssh://build1":                   {"ssh", "", "", "build1", "/usr/local"},
+		"ssh://deploy@build1:2222:/opt/": {"ssh", "-p", "2222", "deploy@build1", "/opt"},
+		"docker://ci-cache:/toolchains":  {"docker", "", "", "ci-cache", "/toolchains"},
+	} {
+		target, err := parseRemoteTarget(s)
+		if err != nil {
+			t.Errorf("Unexpected error for %s: %v", s, err)
+			continue
+		}
+		if target.scheme != want[0] || target.prefix != want[4] {
+			t.Errorf("Expected %s to target %s %s, got %+v", s, want[0], want[4], target)
+		}
+
+		argv := target.command("true")
+		if want[0] == "ssh" {
+			host := argv[len(argv)-2]
+			if host != want[3] || argv[len(argv)-3] != "--" || (want[2] != "") != reflect.DeepEqual(argv[1:3], []string{"-p", want[2]}) {
+				t.Errorf("Expected %s to run ssh on %s, got %v", s, want[3], argv)
+			}
+		}
+		if want[0] == "docker" && !reflect.DeepEqual(argv[:4], []string{"docker", "exec", "-i", "ci-cache"}) {
+			t.Errorf("Expected %s to run docker exec, got %v", s, argv)
+		}
+	}
+
+	for _, invalid := range []string{"build1:/opt", "ftp://build1:/opt", "ssh://:/opt", "ssh://-oProxyCommand=id:/opt", "ssh://deploy@-build1", "ssh://build1:22x:/opt", "docker://-e:/opt"} {
+		if _, err := parseRemoteTarget(invalid); err == nil {
+			t.Errorf("Expected an error for %s", invalid)
+		}
+	}
+}
+
+func TestInstallRemote(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake ssh is a shell script")
+	}
+
+	// The fake ssh runs the remote command locally.
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "ssh"), []byte("#!/bin/sh\nfor a; do last=$a; done\nexec sh -c \"$last\"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	c, out := newTestCLI(t, newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n", "go/bin/go": "#!/bin/sh\n"}))
+	c.prefix = t.TempDir()
+	remote := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(remote, "go"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(remote, "go", "VERSION"), []byte("go1.21.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.run([]string{"install", "--target", "ssh://deploy@build1:" + remote, "--platform", "linux/amd64", "1.22.1"}); err != nil {
+		t.Fatalf("Unexpected error: %v\n%s", err, out)
+	}
+	if v, err := installedVersion(filepath.Join(remote, "go")); err != nil || v != "go1.22.1" {
+		t.Errorf("Expected go1.22.1 on the target, got %q (%v)", v, err)
+	}
+	if provenance, err := readProvenance(filepath.Join(remote, "go")); err != nil || provenance.Version != "go1.22.1" || provenance.Sha256 == "" {
+		t.Errorf("Expected the provenance of go1.22.1 on the target, got %+v (%v)", provenance, err)
+	}
+	if entries, _ := os.ReadDir(remote); len(entries) != 1 {
+		t.Errorf("Expected the staging directory and the previous version to be removed, got %v", entries)
+	}
+	if _, err := os.Stat(filepath.Join(c.prefix, "go")); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing to be installed locally")
+	}
+	if !strings.Contains(out.String(), "on deploy@build1") {
+		t.Errorf("Expected the target in the output, got %q", out)
+	}
+
+	// The files left out never reach the target.
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n", "go/bin/go": "#!/bin/sh\n", "go/doc/go_spec.html": "spec"})
+	remote = t.TempDir()
+	c, out = newTestCLI(t, archive)
+	c.repo.archive.filter, _ = newArchiveFilter(true, "", "")
+	if err := c.run([]string{"install", "--target", "ssh://deploy@build1:" + remote, "--platform", "linux/amd64", "1.22.1"}); err != nil {
+		t.Fatalf("Unexpected error: %v\n%s", err, out)
+	}
+	if _, err := os.Stat(filepath.Join(remote, "go", "bin", "go")); err != nil {
+		t.Errorf("Expected go/bin/go on the target: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(remote, "go", "doc")); !os.IsNotExist(err) {
+		t.Errorf("Expected go/doc to be left out of the target, got %v", err)
+	}
+
+	// The target's tar restores neither the owners nor the special bits.
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gzw)
+	for _, f := range []struct {
+		name, content string
+		mode          int64
+	}{{"go/VERSION", "go1.22.1\n", 0644}, {"go/bin/go", "#!/bin/sh\n", 06755}} {
+		if err := tw.WriteHeader(&tar.Header{Name: f.name, Mode: f.mode, Size: int64(len(f.content)), Typeflag: tar.TypeReg, Uid: 1234, Gid: 1234}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(f.content))
+	}
+	tw.Close()
+	gzw.Close()
+	remote = t.TempDir()
+	c, out = newTestCLI(t, buf.Bytes())
+	if err := c.run([]string{"install", "--target", "ssh://deploy@build1:" + remote, "--platform", "linux/amd64", "1.22.1"}); err != nil {
+		t.Fatalf("Unexpected error: %v\n%s", err, out)
+	}
+	info, err := os.Stat(filepath.Join(remote, "go", "bin", "go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&specialModes != 0 {
+		t.Errorf("Expected the special bits to be stripped on the target, got %v", info.Mode())
+	}
+	if owner := fileOwner(info); owner.Uid != os.Getuid() {
+		t.Errorf("Expected the files to be owned by the remote user, got uid %d", owner.Uid)
+	}
+
+	// The archive is scanned before reaching the target.
+	c.scanner = []string{"sh", "-c", "echo infected; exit 1"}
+	err = c.run([]string{"install", "--target", "ssh://deploy@build1:" + remote, "--platform", "linux/amd64", "1.22.1"})
+	if !errors.Is(err, ErrScanRejected) {
+		t.Errorf("Expected ErrScanRejected, got %v", err)
+	}
+}
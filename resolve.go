@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"go/version"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/blckfalcon/go-dl/versions"
+)
+
+const goVersionFile = ".go-version"
+
+// Channels are aliases of releases, following the feed: stable is the latest
+// release, oldstable the latest patch of the previous minor version and
+// unstable the latest release candidate or beta, stable when there is none.
+var channels = []string{"stable", "oldstable", "unstable"}
+
+func isChannel(query string) bool {
+	for _, c := range channels {
+		if strings.TrimSpace(query) == c {
+			return true
+		}
+	}
+	return false
+}
+
+// channelRelease returns the current release of the channel name.
+func channelRelease(releases []Release, name string) (Release, error) {
+	stable, ok := latestStable(releases)
+	if !ok {
+		return Release{}, fmt.Errorf("no stable release available: %w", ErrVersionNotFound)
+	}
+
+	switch strings.TrimSpace(name) {
+	case "stable":
+		return stable, nil
+
+	case "oldstable":
+		var old Release
+		for _, r := range releases {
+			if r.Stable && version.Lang(r.Version) != version.Lang(stable.Version) && versions.IsNewer(stable.Version, r.Version) &&
+				(old.Version == "" || versions.IsNewer(r.Version, old.Version)) {
+				old = r
+			}
+		}
+		if old.Version == "" {
+			return Release{}, fmt.Errorf("no release in channel oldstable: %w", ErrVersionNotFound)
+		}
+		return old, nil
+
+	case "unstable":
+		unstable := stable
+		for _, r := range releases {
+			if !r.Stable && versions.IsNewer(r.Version, unstable.Version) {
+				unstable = r
+			}
+		}
+		return unstable, nil
+	}
+	return Release{}, fmt.Errorf("unknown channel %q", name)
+}
+
+// releaseChannels returns the channels of each release, by version.
+func releaseChannels(releases []Release) map[string][]string {
+	labels := map[string][]string{}
+	for _, c := range channels {
+		if r, err := channelRelease(releases, c); err == nil {
+			labels[r.Version] = append(labels[r.Version], c)
+		}
+	}
+	return labels
+}
+
+// resolveRelease returns the newest release satisfying query, stable
+// releases are preferred over unstable ones. query can also be a channel.
+func resolveRelease(releases []Release, query string) (Release, error) {
+	if isChannel(query) {
+		return channelRelease(releases, query)
+	}
+
+	c, err := versions.ParseConstraint(query)
+	if err != nil {
+		return Release{}, err
+	}
+
+	sorted := make([]Release, len(releases))
+	copy(sorted, releases)
+	sort.Stable(ByRelease(sorted))
+
+	var candidate *Release
+	for i, r := range sorted {
+		if !c.Check(r.Version) {
+			continue
+		}
+		if r.Stable {
+			return sorted[i], nil
+		}
+		if candidate == nil {
+			candidate = &sorted[i]
+		}
+	}
+
+	if candidate == nil {
+		return Release{}, fmt.Errorf("no release matching %q: %w", query, ErrVersionNotFound)
+	}
+	return *candidate, nil
+}
+
+// latestStable returns the newest stable release.
+func latestStable(releases []Release) (Release, bool) {
+	var latest Release
+	for _, r := range releases {
+		if r.Stable && (latest.Version == "" || versions.IsNewer(r.Version, latest.Version)) {
+			latest = r
+		}
+	}
+	return latest, latest.Version != ""
+}
+
+// findGoVersionFile looks for a .go-version file in dir and its parents and
+// returns its path along with the version constraint it holds.
+func findGoVersionFile(dir string) (string, string, error) {
+	for {
+		path := filepath.Join(dir, goVersionFile)
+
+		b, err := os.ReadFile(path)
+		if err == nil {
+			return path, strings.TrimSpace(string(b)), nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", "", err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", fmt.Errorf("no %s file found", goVersionFile)
+		}
+		dir = parent
+	}
+}
+
+// installedVersion returns the version of the Go installation at goroot, as
+// recorded on the first line of its VERSION file.
+func installedVersion(goroot string) (string, error) {
+	f, err := os.Open(filepath.Join(goroot, "VERSION"))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	s.Scan()
+	if err := s.Err(); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(s.Text()), nil
+}
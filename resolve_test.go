@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestResolveRelease(t *testing.T) {
+	releases := []Release{
+		{Version: "go1.23rc1", Stable: false},
+		{Version: "go1.22.1", Stable: true},
+		{Version: "go1.21.10", Stable: true},
+		{Version: "go1.21.9", Stable: true},
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"~1.21", "go1.21.10"},
+		{"1.21.9", "go1.21.9"},
+		{">=1.21", "go1.22.1"},
+		{"1.23", "go1.23rc1"},
+		{"stable", "go1.22.1"},
+		{"oldstable", "go1.21.10"},
+		{"unstable", "go1.23rc1"},
+	}
+
+	for _, tt := range tests {
+		got, err := resolveRelease(releases, tt.query)
+		if err != nil {
+			t.Fatalf("resolveRelease(%s) unexpected error: %v", tt.query, err)
+		}
+		if got.Version != tt.want {
+			t.Errorf("resolveRelease(%s) want %s, got %s", tt.query, tt.want, got.Version)
+		}
+	}
+
+	if _, err := resolveRelease(releases, "1.19"); !errors.Is(err, ErrVersionNotFound) {
+		t.Errorf("Expected ErrVersionNotFound when no release matches, got %v", err)
+	}
+}
+
+func TestReleaseChannels(t *testing.T) {
+	releases := []Release{
+		{Version: "go1.22.1", Stable: true},
+		{Version: "go1.22rc2", Stable: false},
+		{Version: "go1.21.10", Stable: true},
+	}
+
+	got := releaseChannels(releases)
+	want := map[string][]string{
+		"go1.22.1":  {"stable", "unstable"},
+		"go1.21.10": {"oldstable"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected channels %v, got %v", want, got)
+	}
+
+	policy, err := Policy{MinimumVersion: "oldstable"}.resolve(releases)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := policy.Allow("go1.20.14"); !errors.Is(err, ErrPolicy) {
+		t.Errorf("Expected releases older than oldstable to be blocked, got %v", err)
+	}
+}
+
+func TestFindGoVersionFile(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "a", "b")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, goVersionFile), []byte("~1.21\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	path, got, err := findGoVersionFile(nested)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got != "~1.21" || path != filepath.Join(root, goVersionFile) {
+		t.Errorf("findGoVersionFile() want ~1.21 from %s, got %s from %s", root, got, path)
+	}
+}
+
+func TestInstalledVersion(t *testing.T) {
+	goroot := t.TempDir()
+	content := "go1.22.1\ntime 2024-02-29T18:18:48Z\n"
+	if err := os.WriteFile(filepath.Join(goroot, "VERSION"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := installedVersion(goroot)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got != "go1.22.1" {
+		t.Errorf("installedVersion() want go1.22.1, got %s", got)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// commandExit is the exit status of a command run by go-dl, which exits with
+// the same status.
+type commandExit struct{ code int }
+
+func (e commandExit) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+// toolchainsDir is where go-dl run keeps the versions it installed on
+// demand, apart from the active installation.
+func (c *cli) toolchainsDir() string {
+	return filepath.Join(c.paths.Cache, "toolchains")
+}
+
+// toolchain returns the GOROOT of an installed version, either in the
+// prefix or among the toolchains installed by go-dl run.
+func (c *cli) toolchain(version string) (string, bool) {
+	if dir, err := c.installation(version); err == nil {
+		return dir, true
+	}
+	goroot := filepath.Join(c.toolchainsDir(), version, "go")
+	if v, err := installedVersion(goroot); err == nil && v == version {
+		return goroot, true
+	}
+	return "", false
+}
+
+// runToolchain runs a command with the environment of a version, which is
+// installed apart from the active one when missing.
+func (c *cli) runToolchain(args []string) error {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-dl run <version constraint> [--] <command> [arguments]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	args = fs.Args()
+	if len(args) > 1 && args[1] == "--" {
+		args = append(args[:1:1], args[2:]...)
+	}
+	if len(args) < 2 {
+		fs.Usage()
+		return errors.New("run expects a version and a command")
+	}
+
+	query := c.resolveAlias(args[0])
+	goroot, err := c.resolveToolchain(query)
+	if err != nil {
+		return err
+	}
+
+	cmd := c.toolchainCommand(goroot, args[1:])
+	cmd.Stdin = c.stdin
+	cmd.Stdout = c.stdout
+	cmd.Stderr = os.Stderr
+
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return commandExit{exitErr.ExitCode()}
+	}
+	return err
+}
+
+// toolchainCommand returns the command args run with the environment of
+// the installation at goroot: a command of the toolchain, such as go or
+// gofmt, or a path. Anything else is arguments of its go command, as in
+// go-dl run go1.22.1 -- build ./...
+func (c *cli) toolchainCommand(goroot string, args []string) *exec.Cmd {
+	name := args[0]
+	if filepath.Base(name) == name {
+		if path, err := exec.LookPath(filepath.Join(goroot, "bin", name)); err == nil {
+			name, args = path, args[1:]
+		} else {
+			name = filepath.Join(goroot, "bin", exeName("go"))
+		}
+	} else {
+		args = args[1:]
+	}
+	cmd := exec.CommandContext(c.ctx, name, args...)
+	cmd.Env = toolchainEnv(os.Environ(), goroot)
+	return cmd
+}
+
+// installToolchain installs the release matching query among the toolchains
+// of go-dl run and returns its GOROOT.
+func (c *cli) installToolchain(query string) (string, error) {
+	releases, err := c.repo.GetVersions(c.ctx)
+	if err != nil {
+		return "", err
+	}
+	release, err := resolveRelease(releases, query)
+	if err != nil {
+		return "", err
+	}
+	policy, err := c.policy.resolve(releases)
+	if err != nil {
+		return "", err
+	}
+	if err := policy.Allow(release.Version); err != nil {
+		return "", err
+	}
+	if goroot, ok := c.toolchain(release.Version); ok {
+		return goroot, nil
+	}
+
+	dlf, ok := c.selection.Pick(release.Files)
+	if !ok || !isExtractable(dlf) {
+		return "", fmt.Errorf("%w for %s on %s/%s", ErrNoMatchingFile, release.Version, c.selection.Os, c.selection.Arch)
+	}
+
+	// The output belongs to the command, progress goes to stderr.
+	fmt.Fprintf(os.Stderr, "Installing %s in %s\n", release.Version, c.toolchainsDir())
+	prefix := filepath.Join(c.toolchainsDir(), release.Version)
+	p := newPipeline(c.repo, c.storage, dlf, prefix, processOwner, c.paths)
+	p.events = c.events
+	p.scanner = c.scanner
+	// Keep the state of an interrupted installation, this one is not resumable.
+	p.statePath = filepath.Join(c.paths.State, "run.json")
+	if err := p.run(c.ctx); err != nil {
+		os.RemoveAll(prefix)
+		return "", err
+	}
+	return filepath.Join(prefix, "go"), nil
+}
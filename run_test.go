@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestRunToolchain(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test toolchain is a shell script")
+	}
+
+	archive := newTestArchive(t, map[string]string{
+		"go/VERSION": "go1.22.1\n",
+		"go/bin/go":  "#!/bin/sh\necho \"$GOTOOLCHAIN $GOROOT $*\"\n",
+	})
+	c, out := newTestCLI(t, archive)
+	c.prefix = t.TempDir()
+
+	if err := c.runToolchain([]string{"1.22", "--", "go", "version"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	goroot := filepath.Join(c.toolchainsDir(), "go1.22.1", "go")
+	if got, want := strings.TrimSpace(out.String()), "local "+goroot+" version"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	if _, err := installedVersion(c.goroot()); err == nil {
+		t.Errorf("Expected the active installation to be left alone")
+	}
+
+	out.Reset()
+	if err := c.runToolchain([]string{"go1.22.1", "--", "build", "./..."}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got, want := strings.TrimSpace(out.String()), "local "+goroot+" build ./..."; got != want {
+		t.Errorf("Expected the arguments to go to the go command, %q, got %q", want, got)
+	}
+
+	err := c.runToolchain([]string{"go1.22.1", "/bin/sh", "-c", "exit 3"})
+	var exit commandExit
+	if !errors.As(err, &exit) || exit.code != 3 {
+		t.Errorf("Expected the exit status 3, got %v", err)
+	}
+
+	c.policy = Policy{MinimumVersion: "go1.23.0"}
+	if err := c.runToolchain([]string{"1.22", "go", "version"}); !errors.Is(err, ErrPolicy) {
+		t.Errorf("Expected ErrPolicy for an installed version older than the minimum, got %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// runScanner runs the configured scanner command with the archive path as
+// last argument, a non-zero exit status rejects the archive.
+func runScanner(ctx context.Context, scanner []string, archive string) error {
+	if len(scanner) == 0 {
+		return nil
+	}
+
+	args := append(append([]string{}, scanner[1:]...), archive)
+	cmd := exec.CommandContext(ctx, scanner[0], args...)
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	err := cmd.Run()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return fmt.Errorf("%s %w (exit status %d): %s", scanner[0], ErrScanRejected, exitErr.ExitCode(), strings.TrimSpace(out.String()))
+	}
+	if err != nil {
+		return fmt.Errorf("unable to run scanner: %w", err)
+	}
+	return nil
+}
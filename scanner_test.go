@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+)
+
+func TestRunScanner(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is required to run the scanner test")
+	}
+	ctx := context.Background()
+
+	if err := runScanner(ctx, nil, "archive.tar.gz"); err != nil {
+		t.Errorf("Expected no scanner to accept the archive, got %v", err)
+	}
+
+	if err := runScanner(ctx, []string{"sh", "-c", `test "$0" = archive.tar.gz`}, "archive.tar.gz"); err != nil {
+		t.Errorf("Expected the scanner to accept the archive, got %v", err)
+	}
+
+	err := runScanner(ctx, []string{"sh", "-c", "echo infected; exit 1"}, "archive.tar.gz")
+	if !errors.Is(err, ErrScanRejected) {
+		t.Errorf("Expected ErrScanRejected, got %v", err)
+	}
+}
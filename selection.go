@@ -0,0 +1,70 @@
+package main
+
+import "strings"
+
+const (
+	KindArchive   = "archive"
+	KindInstaller = "installer"
+	KindSource    = "source"
+)
+
+// Selection describes which artifact of a release should be downloaded for a
+// given platform.
+type Selection struct {
+	Os        string
+	Arch      string
+	Installer bool
+}
+
+// Kind returns the file kind preferred by the selection. Installers are only
+// published for windows (msi) and darwin (pkg), every other OS falls back to
+// the archive.
+func (s Selection) Kind() string {
+	if s.Installer && (s.Os == "windows" || s.Os == "darwin") {
+		return KindInstaller
+	}
+	return KindArchive
+}
+
+// Extension returns the filename suffix expected for the preferred kind.
+func (s Selection) Extension() string {
+	switch {
+	case s.Kind() == KindInstaller && s.Os == "windows":
+		return ".msi"
+	case s.Kind() == KindInstaller:
+		return ".pkg"
+	case s.Os == "windows":
+		return ".zip"
+	default:
+		return ".tar.gz"
+	}
+}
+
+// Specs returns the filters to apply on a release files with Files.Filter.
+func (s Selection) Specs() []func(f File) bool {
+	return []func(f File) bool{
+		func(f File) bool { return f.Os == s.Os },
+		func(f File) bool { return f.Arch == s.Arch },
+		func(f File) bool { return f.Kind == s.Kind() },
+		func(f File) bool { return strings.HasSuffix(f.Filename, s.Extension()) },
+	}
+}
+
+// Pick returns the first file matching the selection.
+func (s Selection) Pick(files Files) (File, bool) {
+	l := files.Filter(s.Specs()...)
+	if len(l) == 0 {
+		return File{}, false
+	}
+	return l[0], true
+}
+
+// Source returns the source tarball of a release, used when no archive is
+// published for the platform.
+func (s Selection) Source(files Files) (File, bool) {
+	l := files.Filter(func(f File) bool { return f.Kind == KindSource })
+	if len(l) == 0 {
+		return File{}, false
+	}
+	return l[0], true
+}
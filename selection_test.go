@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestSelectionPick(t *testing.T) {
+	files := Files{
+		{Filename: "go1.22.1.src.tar.gz", Kind: KindSource},
+		{Filename: "go1.22.1.darwin-arm64.tar.gz", Os: "darwin", Arch: "arm64", Kind: KindArchive},
+		{Filename: "go1.22.1.darwin-arm64.pkg", Os: "darwin", Arch: "arm64", Kind: KindInstaller},
+		{Filename: "go1.22.1.linux-amd64.tar.gz", Os: "linux", Arch: "amd64", Kind: KindArchive},
+		{Filename: "go1.22.1.windows-amd64.msi", Os: "windows", Arch: "amd64", Kind: KindInstaller},
+		{Filename: "go1.22.1.windows-amd64.zip", Os: "windows", Arch: "amd64", Kind: KindArchive},
+	}
+
+	tests := []struct {
+		name      string
+		selection Selection
+		want      string
+	}{
+		{"linux archive", Selection{Os: "linux", Arch: "amd64"}, "go1.22.1.linux-amd64.tar.gz"},
+		{"linux has no installer", Selection{Os: "linux", Arch: "amd64", Installer: true}, "go1.22.1.linux-amd64.tar.gz"},
+		{"darwin archive", Selection{Os: "darwin", Arch: "arm64"}, "go1.22.1.darwin-arm64.tar.gz"},
+		{"darwin installer", Selection{Os: "darwin", Arch: "arm64", Installer: true}, "go1.22.1.darwin-arm64.pkg"},
+		{"windows archive", Selection{Os: "windows", Arch: "amd64"}, "go1.22.1.windows-amd64.zip"},
+		{"windows installer", Selection{Os: "windows", Arch: "amd64", Installer: true}, "go1.22.1.windows-amd64.msi"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.selection.Pick(files)
+			if !ok {
+				t.Fatalf("expected a file to be selected")
+			}
+			if got.Filename != tt.want {
+				t.Errorf("Pick() want %s, got %s", tt.want, got.Filename)
+			}
+		})
+	}
+}
+
+func TestSelectionPickNoMatch(t *testing.T) {
+	files := Files{
+		{Filename: "go1.22.1.linux-amd64.tar.gz", Os: "linux", Arch: "amd64", Kind: KindArchive},
+	}
+
+	_, ok := Selection{Os: "linux", Arch: "riscv64"}.Pick(files)
+	if ok {
+		t.Errorf("expected no file to be selected")
+	}
+}
+
+func TestSelectionSource(t *testing.T) {
+	files := Files{
+		{Filename: "go1.22.1.linux-amd64.tar.gz", Os: "linux", Arch: "amd64", Kind: KindArchive},
+		{Filename: "go1.22.1.src.tar.gz", Kind: KindSource},
+	}
+
+	got, ok := Selection{Os: "linux", Arch: "riscv64"}.Source(files)
+	if !ok || got.Filename != "go1.22.1.src.tar.gz" {
+		t.Errorf("Expected the source tarball to be selected, got %v", got)
+	}
+}
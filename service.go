@@ -0,0 +1,193 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// serviceName identifies the system unit, user and files of the service
+// mode, which manages the toolchain of every user of the host.
+const serviceName = "go-dl"
+
+// serviceBinary is where the service mode installs go-dl, the service can't
+// run a binary under /home, which it doesn't see.
+const serviceBinary = "/usr/local/bin/" + serviceName
+
+// serviceOptions configure the files of the service mode.
+type serviceOptions struct {
+	exe      string
+	user     string
+	group    string
+	prefix   string
+	schedule string
+}
+
+// serviceFile is a file of the service mode, at its absolute path.
+type serviceFile struct {
+	path    string
+	mode    os.FileMode
+	content string
+}
+
+// serviceFiles returns the files running go-dl as the unprivileged service
+// user: the systemd service and timer, the user and prefix created by
+// systemd-sysusers and systemd-tmpfiles, the PATH of the login shells, and
+// the polkit rule and sudoers snippet letting group run the upgrades. The
+// sudoers snippet only allows latest and install, the other commands, such
+// as run, would let group run anything as the owner of the toolchain.
+func serviceFiles(o serviceOptions) []serviceFile {
+	run := fmt.Sprintf("%s --prefix %s", systemdQuote(o.exe), systemdQuote(o.prefix))
+	sudo := sudoersEscape(o.exe) + " --prefix " + sudoersEscape(o.prefix)
+
+	service := fmt.Sprintf(`[Unit]
+Description=Upgrade the Go toolchain of the host to the latest stable release
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+Type=oneshot
+User=%[1]s
+Group=%[1]s
+Environment=GO_DL_CONFIG_DIR=/etc/go-dl GO_DL_CACHE_DIR=/var/cache/go-dl GO_DL_STATE_DIR=/var/lib/go-dl
+ExecStart=%[2]s latest --quiet
+StateDirectory=go-dl
+CacheDirectory=go-dl
+ReadWritePaths=%[3]s
+ProtectSystem=strict
+ProtectHome=yes
+PrivateTmp=yes
+NoNewPrivileges=yes
+`, o.user, run, systemdQuote(o.prefix))
+
+	timer := fmt.Sprintf(`[Unit]
+Description=Upgrade the Go toolchain of the host %[1]s
+
+[Timer]
+OnCalendar=%[1]s
+Persistent=true
+RandomizedDelaySec=15m
+
+[Install]
+WantedBy=timers.target
+`, o.schedule)
+
+	polkit := fmt.Sprintf(`// Lets the members of %[1]s start the upgrades of the Go toolchain.
+polkit.addRule(function(action, subject) {
+	if (action.id == "org.freedesktop.systemd1.manage-units" &&
+		action.lookup("unit") == "%[2]s.service" &&
+		subject.isInGroup("%[1]s")) {
+		return polkit.Result.YES;
+	}
+});
+`, o.group, serviceName)
+
+	return []serviceFile{
+		{"/etc/systemd/system/" + serviceName + ".service", 0644, service},
+		{"/etc/systemd/system/" + serviceName + ".timer", 0644, timer},
+		{"/usr/lib/sysusers.d/" + serviceName + ".conf", 0644, fmt.Sprintf("u %s - \"Go toolchain manager\" /var/lib/go-dl\n", o.user)},
+		{"/usr/lib/tmpfiles.d/" + serviceName + ".conf", 0644, fmt.Sprintf("d %s 0755 %s %s -\n", o.prefix, o.user, o.user)},
+		{"/etc/profile.d/" + serviceName + ".sh", 0644, fmt.Sprintf("export PATH=\"$PATH:%s\"\n", filepath.Join(o.prefix, "go", "bin"))},
+		{"/etc/polkit-1/rules.d/50-" + serviceName + ".rules", 0644, polkit},
+		{"/etc/sudoers.d/" + serviceName, 0440, fmt.Sprintf("%%%s ALL=(%s:%s) NOPASSWD: %[4]s latest, %[4]s latest *, %[4]s install *\n", o.group, o.user, o.user, sudo)},
+	}
+}
+
+// sudoersEscape escapes the characters special in the commands of sudoers.
+func sudoersEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, " ", `\ `, ",", `\,`, ":", `\:`, "=", `\=`).Replace(s)
+}
+
+func (c *cli) service(args []string) error {
+	fs := flag.NewFlagSet("service", flag.ContinueOnError)
+	user := fs.String("user", serviceName, "system user running the upgrades and owning the installation")
+	group := fs.String("group", "wheel", "group allowed to run the upgrades and go-dl as the service user")
+	prefix := fs.String("prefix", "/opt/go-dl", "directory the go installation is created in")
+	schedule := fs.String("schedule", "daily", "how often to upgrade: hourly, daily or weekly")
+	root := fs.String("root", "", "write the files under this directory, such as the root of a package build")
+	install := fs.Bool("install", false, "write the files to the system, create the user and enable the timer")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-dl service [--user go-dl] [--prefix /opt/go-dl] [--root dir | --install]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if !schedules[*schedule] {
+		return fmt.Errorf("unknown schedule %q, expected hourly, daily or weekly", *schedule)
+	}
+	if !filepath.IsAbs(*prefix) {
+		return fmt.Errorf("the prefix of the service %q is not an absolute path", *prefix)
+	}
+	if *install && *root != "" {
+		return fmt.Errorf("--install and --root are exclusive")
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	files := serviceFiles(serviceOptions{exe: serviceBinary, user: *user, group: *group, prefix: *prefix, schedule: *schedule})
+
+	if !*install && *root == "" {
+		fmt.Fprintf(c.stdout, "# %s\ncopy of %s\n", serviceBinary, exe)
+		for _, f := range files {
+			fmt.Fprintf(c.stdout, "\n# %s\n%s", f.path, f.content)
+		}
+		return nil
+	}
+
+	if *install {
+		if runtime.GOOS != "linux" {
+			return fmt.Errorf("service is not supported on %s", runtime.GOOS)
+		}
+		*root = "/"
+	}
+	if err := copyExecutable(exe, filepath.Join(*root, filepath.FromSlash(serviceBinary))); err != nil {
+		return wrapPermission(err)
+	}
+	fmt.Fprintln(c.stdout, "Wrote", filepath.Join(*root, filepath.FromSlash(serviceBinary)))
+	for _, f := range files {
+		path := filepath.Join(*root, filepath.FromSlash(f.path))
+		if err := writeFile(path, strings.NewReader(f.content), f.mode); err != nil {
+			return wrapPermission(err)
+		}
+		fmt.Fprintln(c.stdout, "Wrote", path)
+	}
+	if !*install {
+		return nil
+	}
+
+	for _, cmd := range [][]string{
+		{"systemd-sysusers"},
+		{"systemd-tmpfiles", "--create", "/usr/lib/tmpfiles.d/" + serviceName + ".conf"},
+		{"systemctl", "daemon-reload"},
+		{"systemctl", "enable", "--now", serviceName + ".timer"},
+	} {
+		if err := runAutomation(cmd[0], cmd[1:]...); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(c.stdout, "Go will be upgraded %s into %s by the %s user\n", *schedule, *prefix, *user)
+	return nil
+}
+
+// copyExecutable copies the executable exe to target, unless it runs from
+// there already.
+func copyExecutable(exe, target string) error {
+	if src, err := os.Stat(exe); err == nil {
+		if dst, err := os.Stat(target); err == nil && os.SameFile(src, dst) {
+			return nil
+		}
+	}
+
+	f, err := os.Open(exe)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return writeFile(target, f, 0755)
+}
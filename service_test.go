@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestServiceFiles(t *testing.T) {
+	files := serviceFiles(serviceOptions{exe: "/usr/local/bin/go-dl", user: "go-dl", group: "devs", prefix: "/opt/go dl", schedule: "weekly"})
+
+	contents := map[string]string{}
+	for _, f := range files {
+		contents[f.path] = f.content
+	}
+	for path, want := range map[string]string{
+		"/etc/systemd/system/go-dl.service":    `ExecStart=/usr/local/bin/go-dl --prefix "/opt/go dl" latest --quiet`,
+		"/etc/systemd/system/go-dl.timer":      "OnCalendar=weekly\n",
+		"/usr/lib/sysusers.d/go-dl.conf":       "u go-dl - ",
+		"/usr/lib/tmpfiles.d/go-dl.conf":       "d /opt/go dl 0755 go-dl go-dl -\n",
+		"/etc/profile.d/go-dl.sh":              "/opt/go dl/go/bin",
+		"/etc/polkit-1/rules.d/50-go-dl.rules": `subject.isInGroup("devs")`,
+		"/etc/sudoers.d/go-dl":                 `%devs ALL=(go-dl:go-dl) NOPASSWD: /usr/local/bin/go-dl --prefix /opt/go\ dl latest, /usr/local/bin/go-dl --prefix /opt/go\ dl latest *, /usr/local/bin/go-dl --prefix /opt/go\ dl install *` + "\n",
+	} {
+		if !strings.Contains(contents[path], want) {
+			t.Errorf("Expected %q in %s, got:\n%s", want, path, contents[path])
+		}
+	}
+	if !strings.Contains(contents["/etc/systemd/system/go-dl.service"], "User=go-dl\n") {
+		t.Errorf("Expected the service to run as the service user")
+	}
+}
+
+func TestServiceRoot(t *testing.T) {
+	c, out := newTestCLI(t, nil)
+	root := t.TempDir()
+
+	if err := c.run([]string{"service", "--root", root}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	info, err := os.Stat(filepath.Join(root, "etc", "sudoers.d", "go-dl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0440 {
+		t.Errorf("Expected the sudoers snippet to be read-only, got %v", info.Mode())
+	}
+	// The service runs its own copy of go-dl, out of /home.
+	if info, err := os.Stat(filepath.Join(root, "usr", "local", "bin", "go-dl")); err != nil || info.Mode().Perm() != 0755 {
+		t.Errorf("Expected go-dl to be copied to /usr/local/bin, got %v (%v)", info, err)
+	}
+	if !strings.Contains(out.String(), filepath.Join(root, "etc", "systemd", "system", "go-dl.timer")) {
+		t.Errorf("Expected the files written to be listed, got %q", out.String())
+	}
+
+	if err := c.run([]string{"service", "--prefix", "opt"}); err == nil {
+		t.Errorf("Expected a relative prefix to be rejected")
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/version"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/blckfalcon/go-dl/versions"
+)
+
+// The orders of the versions list, newest first by default.
+var sortModes = []string{"newest", "oldest", "minor", "unstable-last"}
+
+// sortReleases sorts releases in place according to mode. newest and oldest
+// follow ByRelease, minor lists the minor series newest first with their
+// patches in ascending order, unstable-last moves release candidates and
+// betas after the stable releases.
+func sortReleases(releases []Release, mode string) error {
+	switch mode {
+	case "", "newest":
+		sort.Stable(ByRelease(releases))
+	case "oldest":
+		sort.Stable(sort.Reverse(ByRelease(releases)))
+	case "minor":
+		sort.SliceStable(releases, func(i, j int) bool {
+			a, b := version.Lang(releases[i].Version), version.Lang(releases[j].Version)
+			if a != b {
+				return version.Compare(a, b) > 0
+			}
+			return ByRelease(releases).Less(j, i)
+		})
+	case "unstable-last":
+		sort.SliceStable(releases, func(i, j int) bool {
+			if releases[i].Stable != releases[j].Stable {
+				return releases[i].Stable
+			}
+			return ByRelease(releases).Less(i, j)
+		})
+	default:
+		return fmt.Errorf("unknown sort mode %q, expected %s", mode, strings.Join(sortModes, ", "))
+	}
+	return nil
+}
+
+func (c *cli) list(args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-dl list [--sort mode] [--supported-only] [--long] [version constraint]")
+		fs.PrintDefaults()
+	}
+	mode := fs.String("sort", "newest", "order of the releases: "+strings.Join(sortModes, ", "))
+	supported := fs.Bool("supported-only", false, "hide the end of life releases")
+	long := fs.Bool("long", false, "show the size, kind and checksum of the file installing each release")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var constraint versions.Constraint
+	if fs.NArg() > 0 {
+		var err error
+		if constraint, err = versions.ParseConstraint(strings.Join(fs.Args(), " ")); err != nil {
+			return err
+		}
+	}
+
+	releases, err := c.repo.GetVersions(c.ctx)
+	if err != nil {
+		return err
+	}
+	if err := sortReleases(releases, *mode); err != nil {
+		return err
+	}
+
+	labels := releaseLabels(releases)
+	if *supported {
+		releases = supportedOnly(releases)
+	}
+	files := pickerFiles(releases, c.selection)
+	w := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	for _, r := range releases {
+		if fs.NArg() > 0 && !constraint.Check(r.Version) {
+			continue
+		}
+		name := r.Version
+		if l := labels[r.Version]; len(l) > 0 {
+			name = fmt.Sprintf("%s (%s)", r.Version, strings.Join(l, ", "))
+		}
+		if !*long {
+			fmt.Fprintln(c.stdout, name)
+			continue
+		}
+
+		size, kind, sum := "-", "-", "-"
+		if f, ok := files[r.Version]; ok {
+			if f.Size > 0 {
+				size = c.units.size(f.Size)
+			}
+			kind = f.Kind
+			if f.Sum != "" {
+				sum = f.Algorithm + ":" + f.Sum[:min(8, len(f.Sum))]
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, size, kind, sum)
+	}
+	return w.Flush()
+}
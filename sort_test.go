@@ -0,0 +1,71 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSortReleases(t *testing.T) {
+	releases := []Release{
+		{Version: "go1.21.0", Stable: true},
+		{Version: "go1.22.1", Stable: true},
+		{Version: "go1.23rc1"},
+		{Version: "go1.21.10", Stable: true},
+		{Version: "go1.22.0", Stable: true},
+	}
+
+	for mode, want := range map[string][]string{
+		"newest":        {"go1.23rc1", "go1.22.1", "go1.22.0", "go1.21.10", "go1.21.0"},
+		"oldest":        {"go1.21.0", "go1.21.10", "go1.22.0", "go1.22.1", "go1.23rc1"},
+		"minor":         {"go1.23rc1", "go1.22.0", "go1.22.1", "go1.21.0", "go1.21.10"},
+		"unstable-last": {"go1.22.1", "go1.22.0", "go1.21.10", "go1.21.0", "go1.23rc1"},
+	} {
+		sorted := append([]Release(nil), releases...)
+		if err := sortReleases(sorted, mode); err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, r := range sorted {
+			got = append(got, r.Version)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected %s order %v, got %v", mode, want, got)
+		}
+	}
+
+	if err := sortReleases(releases, "random"); err == nil {
+		t.Errorf("Expected an error for an unknown sort mode")
+	}
+}
+
+func TestListCommand(t *testing.T) {
+	c, out := newTestCLI(t, nil)
+
+	if err := c.run([]string{"list", "--sort", "oldest"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "go1.21.0 (oldstable)\ngo1.22.1 (stable)\ngo1.23rc1 (unstable)\n"; out.String() != want {
+		t.Errorf("Expected %q, got %q", want, out.String())
+	}
+
+	out.Reset()
+	if err := c.run([]string{"list", ">=1.22"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); strings.Contains(got, "go1.21.0") || !strings.HasPrefix(got, "go1.23rc1") {
+		t.Errorf("Expected the releases matching the constraint, newest first, got %q", got)
+	}
+
+	out.Reset()
+	if err := c.run([]string{"list", "--long", "1.22.1"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "go1.22.1 (stable)  -  archive  sha256:e3b0c442\n"; out.String() != want {
+		t.Errorf("Expected %q, got %q", want, out.String())
+	}
+
+	if err := c.run([]string{"list", "--sort", "random"}); err == nil {
+		t.Errorf("Expected an error for an unknown sort mode")
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"path/filepath"
+
+	"github.com/blckfalcon/go-dl/tui"
+)
+
+// extractedRatio estimates the size of an installation from the size of its
+// archive, the 68 MB of go1.22.1.linux-amd64.tar.gz extract to 230 MB.
+const extractedRatio = 3.4
+
+// installSpace returns the disk space installing dlf under prefix needs, the
+// archive being downloaded to archive. Both share a volume when they are on
+// the same file system.
+func installSpace(dlf File, archive, prefix string) tui.Space {
+	size := int64(dlf.Size)
+	space := tui.Space{Download: size, Extracted: int64(float64(size) * extractedRatio)}
+
+	devices := map[uint64]int{}
+	for _, v := range []tui.Volume{
+		{Name: "download", Path: filepath.Dir(archive), Needed: space.Download},
+		{Name: "installation", Path: prefix, Needed: space.Extracted},
+	} {
+		free, device, err := diskSpace(existingParent(v.Path))
+		if err != nil {
+			v.Free = -1
+			space.Volumes = append(space.Volumes, v)
+			continue
+		}
+		v.Free = free
+
+		if i, ok := devices[device]; ok {
+			space.Volumes[i].Name += " and " + v.Name
+			space.Volumes[i].Needed += v.Needed
+			continue
+		}
+		devices[device] = len(space.Volumes)
+		space.Volumes = append(space.Volumes, v)
+	}
+	return space
+}
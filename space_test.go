@@ -0,0 +1,27 @@
+package main
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestInstallSpace(t *testing.T) {
+	dir := t.TempDir()
+	if _, _, err := diskSpace(dir); err != nil {
+		t.Skipf("free space is not measured on %s: %v", runtime.GOOS, err)
+	}
+
+	space := installSpace(File{Size: 1000}, filepath.Join(dir, "cache", "tmp", "go.tar.gz"), filepath.Join(dir, "prefix"))
+	if space.Download != 1000 || space.Extracted != 3400 {
+		t.Errorf("Expected the archive and estimated sizes, got %+v", space)
+	}
+
+	// Both directories are on the file system of the temporary directory.
+	if len(space.Volumes) != 1 || space.Volumes[0].Needed != 4400 || space.Volumes[0].Free <= 0 {
+		t.Fatalf("Expected one volume needing both sizes, got %+v", space.Volumes)
+	}
+	if name := space.Volumes[0].Name; name != "download and installation" {
+		t.Errorf("Expected the volume to be named after both uses, got %q", name)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// spoolMemory is the size up to which a spool keeps its content in memory.
+const spoolMemory = 1 << 20
+
+// spool holds a stream until it has been fully written, in memory up to limit
+// bytes and in a temporary file beyond, so its content can be read back
+// without holding large files in memory.
+type spool struct {
+	limit int
+	buf   bytes.Buffer
+	file  *os.File
+}
+
+func (s *spool) Write(p []byte) (int, error) {
+	if s.file == nil && s.buf.Len()+len(p) <= s.limit {
+		return s.buf.Write(p)
+	}
+
+	if s.file == nil {
+		f, err := createTemp("", "go-dl-spool-*")
+		if err != nil {
+			return 0, err
+		}
+		s.file = f
+	}
+	return s.file.Write(p)
+}
+
+// Reader returns the content written since the last Reset.
+func (s *spool) Reader() (io.Reader, error) {
+	if s.file == nil {
+		return bytes.NewReader(s.buf.Bytes()), nil
+	}
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return io.MultiReader(bytes.NewReader(s.buf.Bytes()), s.file), nil
+}
+
+// Reset empties the spool, keeping its buffer and file for the next stream.
+func (s *spool) Reset() error {
+	s.buf.Reset()
+	if s.file == nil {
+		return nil
+	}
+	if err := s.file.Truncate(0); err != nil {
+		return err
+	}
+	_, err := s.file.Seek(0, io.SeekStart)
+	return err
+}
+
+// Close removes the temporary file of the spool.
+func (s *spool) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	s.file.Close()
+	return os.Remove(s.file.Name())
+}
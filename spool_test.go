@@ -0,0 +1,255 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var memCap = flag.String("memcap", "8MiB", "resident memory the streaming tests may grow by while processing large files")
+
+func TestSpool(t *testing.T) {
+	s := &spool{limit: 4}
+	defer s.Close()
+
+	for _, p := range []string{"ab", "cdef", "gh"} {
+		if _, err := s.Write([]byte(p)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s.file == nil {
+		t.Fatalf("Expected writes beyond the limit to go to a file")
+	}
+
+	if got := readSpool(t, s); got != "abcdefgh" {
+		t.Errorf("Expected abcdefgh, got %q", got)
+	}
+
+	if err := s.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	s.Write([]byte("x"))
+	if got := readSpool(t, s); got != "x" {
+		t.Errorf("Expected x after Reset, got %q", got)
+	}
+
+	name := s.file.Name()
+	s.Close()
+	if _, err := os.Stat(name); !os.IsNotExist(err) {
+		t.Errorf("Expected the spool file to be removed")
+	}
+}
+
+func readSpool(t *testing.T, s *spool) string {
+	t.Helper()
+
+	r, err := s.Reader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// patternReader returns size bytes of a repeating pattern, so large archives
+// compress to a few kilobytes and never have to be held in memory.
+type patternReader struct{ size int64 }
+
+func (r *patternReader) Read(p []byte) (int, error) {
+	if r.size <= 0 {
+		return 0, io.EOF
+	}
+	n := int64(len(p))
+	if n > r.size {
+		n = r.size
+	}
+	for i := range p[:n] {
+		p[i] = byte(i % 251)
+	}
+	r.size -= n
+	return int(n), nil
+}
+
+// makeScript stands for make.bash in the large archives, so they can also be
+// built from source.
+const makeScript = "#!/bin/sh\nmkdir -p ../bin && echo built > ../bin/go\n"
+
+// newLargeArchive returns a release archive holding a size bytes long file.
+func newLargeArchive(tb testing.TB, version string, size int64) []byte {
+	tb.Helper()
+
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gzw)
+
+	for _, dir := range []string{"go/", "go/pkg/"} {
+		if err := tw.WriteHeader(&tar.Header{Name: dir, Mode: 0755, Typeflag: tar.TypeDir}); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	for _, entry := range []struct {
+		name string
+		r    io.Reader
+		size int64
+		mode int64
+	}{
+		{"go/VERSION", bytes.NewReader([]byte(version + "\n")), int64(len(version) + 1), 0644},
+		{"go/src/make.bash", strings.NewReader(makeScript), int64(len(makeScript)), 0755},
+		{"go/pkg/large.bin", &patternReader{size: size}, size, 0644},
+	} {
+		if err := tw.WriteHeader(&tar.Header{Name: entry.name, Mode: entry.mode, Size: entry.size, Typeflag: tar.TypeReg}); err != nil {
+			tb.Fatal(err)
+		}
+		if _, err := io.Copy(tw, entry.r); err != nil {
+			tb.Fatal(err)
+		}
+	}
+
+	tw.Close()
+	gzw.Close()
+	return buf.Bytes()
+}
+
+// peakRSS returns how much the resident memory of the process peaked above
+// its level before running f, from the high water mark kept by linux.
+func peakRSS(f func()) (int64, error) {
+	runtime.GC()
+	if err := os.WriteFile("/proc/self/clear_refs", []byte("5"), 0); err != nil {
+		return 0, err
+	}
+	before, err := procStatus("VmRSS")
+	if err != nil {
+		return 0, err
+	}
+	f()
+	peak, err := procStatus("VmHWM")
+	return peak - before, err
+}
+
+// procStatus returns a memory field of /proc/self/status, in bytes.
+func procStatus(field string) (int64, error) {
+	b, err := os.ReadFile("/proc/self/status")
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		if value, ok := strings.CutPrefix(line, field+":"); ok {
+			kb, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimSpace(value), " kB"), 10, 64)
+			return kb << 10, err
+		}
+	}
+	return 0, fmt.Errorf("no %s in /proc/self/status", field)
+}
+
+func TestStreamingMemory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("processes a 64MiB file")
+	}
+	if runtime.GOOS != "linux" {
+		t.Skip("the peak resident memory is read from /proc")
+	}
+	if raceEnabled {
+		t.Skip("the race detector inflates the resident memory of the process")
+	}
+	limit, err := parseSize(*memCap)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const size = 64 << 20
+	archive := newLargeArchive(t, "go1.22.1", size)
+	old := newLargeArchive(t, "go1.22.0", 1)
+
+	large := filepath.Join(t.TempDir(), "large.bin")
+	h := sha256.New()
+	f, err := os.Create(large)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.MultiWriter(f, h), &patternReader{size: size})
+	f.Close()
+	sum := hex.EncodeToString(h.Sum(nil))
+
+	tests := []struct {
+		name string
+		run  func() error
+	}{
+		{"verify", func() error { return verifyChecksum(large, Checksum{Algorithm: "sha256", Sum: sum}) }},
+		{"scan", func() error { _, _, err := scanArchive(bytes.NewReader(archive)); return err }},
+		{"extract", func() error { return Decompress(t.TempDir(), bytes.NewReader(archive), func(float64) {}) }},
+		{"delta", func() error {
+			_, err := generateDelta(io.Discard, bytes.NewReader(old), bytes.NewReader(archive))
+			return err
+		}},
+		{"build", func() error {
+			return buildFromSource(context.Background(), t.TempDir(), t.TempDir(), bytes.NewReader(archive), processOwner, extractProgress{}, archiveOptions{})
+		}},
+	}
+
+	for _, tt := range tests {
+		var err error
+		n, errRSS := peakRSS(func() { err = tt.run() })
+		if errRSS != nil {
+			t.Skipf("unable to measure the resident memory: %v", errRSS)
+		}
+		if n > limit {
+			t.Errorf("%s: resident memory grew by %s for a %s file, more than %s", tt.name, binaryUnits.size(n), binaryUnits.size(size), *memCap)
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+		}
+	}
+}
+
+func BenchmarkScanArchive(b *testing.B) {
+	archive := newLargeArchive(b, "go1.22.1", 16<<20)
+	b.SetBytes(16 << 20)
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if _, _, err := scanArchive(bytes.NewReader(archive)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecompress(b *testing.B) {
+	archive := newLargeArchive(b, "go1.22.1", 16<<20)
+	dst := b.TempDir()
+	b.SetBytes(16 << 20)
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if err := Decompress(dst, bytes.NewReader(archive), func(float64) {}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGenerateDelta(b *testing.B) {
+	old := newLargeArchive(b, "go1.22.0", 1)
+	archive := newLargeArchive(b, "go1.22.1", 16<<20)
+	b.SetBytes(16 << 20)
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := generateDelta(io.Discard, bytes.NewReader(old), bytes.NewReader(archive)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
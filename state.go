@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+
+	"github.com/blckfalcon/go-dl/versions"
+)
+
+// stateSnapshot describes the toolchains of a machine, to set up the same
+// ones on another with go-dl state import.
+type stateSnapshot struct {
+	Active     string          `json:"active,omitempty"`
+	Managed    bool            `json:"managed,omitempty"`
+	Versions   []string        `json:"versions,omitempty"`
+	Toolchains []string        `json:"toolchains,omitempty"`
+	Config     json.RawMessage `json:"config,omitempty"`
+}
+
+// snapshot describes the installation under the prefix, the toolchains of
+// go-dl run and the configuration.
+func (c *cli) snapshot() (stateSnapshot, error) {
+	var s stateSnapshot
+
+	goroot := c.goroot()
+	s.Active, _ = installedVersion(goroot)
+	s.Managed = isManaged(goroot)
+
+	installed := map[string]bool{}
+	if s.Active != "" {
+		installed[s.Active] = true
+	}
+	entries, _ := os.ReadDir(versionsDir(c.prefix))
+	for _, e := range entries {
+		if _, err := c.installation(e.Name()); err == nil {
+			installed[e.Name()] = true
+		}
+	}
+	for v := range installed {
+		s.Versions = append(s.Versions, v)
+	}
+	sortVersions(s.Versions)
+
+	entries, _ = os.ReadDir(c.toolchainsDir())
+	for _, e := range entries {
+		if _, ok := c.toolchain(e.Name()); ok && !installed[e.Name()] {
+			s.Toolchains = append(s.Toolchains, e.Name())
+		}
+	}
+	sortVersions(s.Toolchains)
+
+	b, err := os.ReadFile(c.config)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return s, err
+	}
+	if len(b) > 0 {
+		if !json.Valid(b) {
+			return s, fmt.Errorf("invalid config %s", c.config)
+		}
+		s.Config = b
+	}
+	return s, nil
+}
+
+// sortVersions sorts vs oldest first.
+func sortVersions(vs []string) {
+	sort.Slice(vs, func(i, j int) bool { return versions.Compare(vs[i], vs[j]) < 0 })
+}
+
+// restore installs the versions and toolchains of s, the active one last,
+// and writes its configuration unless one exists.
+func (c *cli) restore(s stateSnapshot) error {
+	if len(s.Config) > 0 {
+		if _, err := os.Stat(c.config); errors.Is(err, os.ErrNotExist) {
+			if err := writeFileAtomic(c.config, s.Config, 0600); err != nil {
+				return wrapPermission(err)
+			}
+			fmt.Fprintf(c.stdout, "Wrote the configuration to %s\n", c.config)
+
+			// Its mirrors, policy, credentials and scanner only apply to a
+			// new run.
+			if c.restart != nil {
+				s.Config = nil
+				b, err := json.Marshal(s)
+				if err != nil {
+					return err
+				}
+				return c.restart([]string{"state", "import", "-"}, bytes.NewReader(b))
+			}
+		} else {
+			fmt.Fprintf(c.stdout, "Keeping the existing configuration %s\n", c.config)
+		}
+	}
+
+	var pending []string
+	for _, v := range s.Versions {
+		if v != s.Active {
+			pending = append(pending, v)
+		}
+	}
+	if s.Active != "" {
+		pending = append(pending, s.Active)
+	}
+
+	for _, v := range pending {
+		if _, err := c.installation(v); err != nil {
+			if err := c.install([]string{v}); err != nil {
+				return err
+			}
+		}
+		// The managed layout keeps the versions side by side.
+		if s.Managed && !isManaged(c.goroot()) {
+			if _, err := migrateInstallation(c.ctx, c.goroot(), false); err != nil {
+				return wrapPermission(err)
+			}
+		}
+	}
+	if s.Managed && s.Active != "" {
+		if v, err := installedVersion(c.goroot()); err != nil || v != s.Active {
+			if err := c.use([]string{s.Active}); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, v := range s.Toolchains {
+		if _, ok := c.toolchain(v); !ok {
+			if _, err := c.installToolchain(v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// restart runs the executable of go-dl again with args, reading stdin, and
+// exits with its status.
+func restart(ctx context.Context, args []string, stdin io.Reader) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, exe, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = stdin, os.Stdout, os.Stderr
+	err = cmd.Run()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		return commandExit{exit.ExitCode()}
+	}
+	return err
+}
+
+// state exports the description of the toolchains of the machine, or sets
+// them up from such a description.
+func (c *cli) state(args []string) error {
+	fs := flag.NewFlagSet("state", flag.ContinueOnError)
+	out := fs.String("out", "-", "file receiving the export, - for the standard output")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-dl state export [--out file] | go-dl state import <file>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// The flags may also follow the command.
+	command := fs.Arg(0)
+	if err := fs.Parse(fs.Args()[min(1, fs.NArg()):]); err != nil {
+		return err
+	}
+
+	switch command {
+	case "export":
+		s, err := c.snapshot()
+		if err != nil {
+			return err
+		}
+		b, err := json.MarshalIndent(s, "", "  ")
+		if err != nil {
+			return err
+		}
+		b = append(b, '\n')
+		if *out == "-" {
+			_, err = c.stdout.Write(b)
+			return err
+		}
+		// The configuration can hold credentials.
+		if err := writeFileAtomic(*out, b, 0600); err != nil {
+			return wrapPermission(err)
+		}
+		fmt.Fprintf(c.stdout, "Exported %d versions to %s\n", len(s.Versions)+len(s.Toolchains), *out)
+		return nil
+
+	case "import":
+		var r io.Reader = c.stdin
+		if name := fs.Arg(0); name != "" && name != "-" {
+			f, err := os.Open(name)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			r = f
+		}
+
+		var s stateSnapshot
+		if err := json.NewDecoder(r).Decode(&s); err != nil {
+			return fmt.Errorf("invalid state export: %w", err)
+		}
+		return c.restore(s)
+	}
+
+	fs.Usage()
+	return fmt.Errorf("unknown state command %q", command)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestStateExportImport(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the managed layout is not supported on windows")
+	}
+
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n"})
+	source, out := newTestCLI(t, archive)
+	source.prefix = t.TempDir()
+	source.config = filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(source.config, []byte(`{"units": "binary"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, goroot := range []string{filepath.Join(versionsDir(source.prefix), "go1.21.0"), filepath.Join(source.toolchainsDir(), "go1.20.14", "go")} {
+		if err := os.MkdirAll(goroot, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(goroot, "VERSION"), []byte(filepath.Base(strings.TrimSuffix(goroot, "/go"))+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := source.run([]string{"install", "1.22.1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrateInstallation(context.Background(), source.goroot(), false); err != nil {
+		t.Fatal(err)
+	}
+
+	out.Reset()
+	if err := source.run([]string{"state", "export"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var s stateSnapshot
+	if err := json.Unmarshal(out.Bytes(), &s); err != nil {
+		t.Fatalf("Expected a JSON export, got %v", err)
+	}
+	exported := filepath.Join(t.TempDir(), "state.json")
+	if err := source.run([]string{"state", "export", "--out", exported}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if info, err := os.Stat(exported); err != nil {
+		t.Fatal(err)
+	} else if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the export to be private, got %v", info.Mode())
+	}
+	want := stateSnapshot{Active: "go1.22.1", Managed: true, Versions: []string{"go1.21.0", "go1.22.1"}, Toolchains: []string{"go1.20.14"}, Config: s.Config}
+	if !reflect.DeepEqual(s, want) || !bytes.Contains(s.Config, []byte(`"binary"`)) {
+		t.Errorf("Expected the export %+v, got %+v", want, s)
+	}
+
+	// The test feed only has go1.22.1, which the new machine gets.
+	target, _ := newTestCLI(t, archive)
+	target.prefix = t.TempDir()
+	target.config = filepath.Join(t.TempDir(), "go-dl", "config.json")
+	export := filepath.Join(t.TempDir(), "state.json")
+	b, _ := json.Marshal(stateSnapshot{Active: "go1.22.1", Managed: true, Versions: []string{"go1.22.1"}, Config: s.Config})
+	if err := os.WriteFile(export, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The installations run again with the imported configuration.
+	var restarted []string
+	target.restart = func(args []string, stdin io.Reader) error {
+		restarted = args
+		target.stdin = stdin
+		return target.run(args)
+	}
+	if err := target.run([]string{"state", "import", export}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(restarted, []string{"state", "import", "-"}) {
+		t.Errorf("Expected the import to restart once the configuration was written, got %v", restarted)
+	}
+	if v, err := installedVersion(target.goroot()); err != nil || v != "go1.22.1" || !isManaged(target.goroot()) {
+		t.Errorf("Expected go1.22.1 to be active in the managed layout, got %s (%v)", v, err)
+	}
+	if config, err := loadConfig(target.config); err != nil || config.Units != "binary" {
+		t.Errorf("Expected the configuration to be restored, got %+v (%v)", config, err)
+	}
+}
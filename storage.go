@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Storage keeps verified archives so they can be shared between installs,
+// and between machines for remote backends.
+type Storage interface {
+	// Get writes the object stored under key to w, it returns ErrCacheMiss
+	// when there is no such object.
+	Get(ctx context.Context, key string, w io.Writer) error
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	// Delete removes the object stored under key, a missing object is not
+	// an error.
+	Delete(ctx context.Context, key string) error
+}
+
+type StorageConfig struct {
+	Type     string `json:"type,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Bucket   string `json:"bucket,omitempty"`
+	Prefix   string `json:"prefix,omitempty"`
+	Region   string `json:"region,omitempty"`
+	Endpoint string `json:"endpoint,omitempty"`
+	MaxSize  string `json:"max_size,omitempty"`
+	MaxAge   string `json:"max_age,omitempty"`
+}
+
+// newStorage returns the storage backend described by config, archives are
+// kept on disk under cacheDir by default. A nil Storage disables caching.
+func newStorage(config StorageConfig, cacheDir string, client *http.Client) (Storage, error) {
+	r, err := parseRetention(config)
+	if err != nil {
+		return nil, err
+	}
+	if r != (retention{}) && config.Type != "" && config.Type != "disk" {
+		return nil, fmt.Errorf("max_size and max_age are only supported by the disk cache")
+	}
+
+	// The archives are large, their transfers are bounded by the context
+	// and not by the timeout of the client, as the downloads are.
+	if client != nil && client.Timeout != 0 {
+		transfers := *client
+		transfers.Timeout = 0
+		client = &transfers
+	}
+
+	switch config.Type {
+	case "", "disk":
+		dir := config.Path
+		if dir == "" {
+			dir = filepath.Join(cacheDir, "archives")
+		}
+		return &diskStorage{dir: dir, retention: r}, nil
+	case "none":
+		return nil, nil
+	case "s3":
+		return newS3Storage(config, client)
+	case "gcs":
+		return newGCSStorage(config, client)
+	}
+	return nil, fmt.Errorf("unknown cache storage type %q", config.Type)
+}
+
+type diskStorage struct {
+	dir       string
+	retention retention
+}
+
+func (d *diskStorage) Get(_ context.Context, key string, w io.Writer) error {
+	path := filepath.Join(d.dir, key)
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrCacheMiss
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// The modification time tracks the last use for the retention policy.
+	now := time.Now()
+	os.Chtimes(path, now, now)
+
+	_, err = copyBuffered(w, f)
+	return err
+}
+
+func (d *diskStorage) Put(_ context.Context, key string, r io.Reader, _ int64) error {
+	if err := os.MkdirAll(d.dir, 0755); err != nil {
+		return err
+	}
+
+	f, err := createTemp(d.dir, key+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := copyBuffered(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), filepath.Join(d.dir, key))
+}
+
+func (d *diskStorage) Delete(_ context.Context, key string) error {
+	err := os.Remove(filepath.Join(d.dir, key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// gcsStorage stores archives in a Google Cloud Storage bucket through its JSON
+// API, authenticating with the OAuth2 access token found in
+// GOOGLE_OAUTH_ACCESS_TOKEN (e.g. from `gcloud auth print-access-token`).
+type gcsStorage struct {
+	client   *http.Client
+	endpoint string
+	bucket   string
+	prefix   string
+	token    string
+}
+
+func newGCSStorage(config StorageConfig, client *http.Client) (*gcsStorage, error) {
+	if config.Bucket == "" {
+		return nil, fmt.Errorf("gcs cache storage requires a bucket")
+	}
+
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = "https://storage.googleapis.com"
+	}
+
+	s := &gcsStorage{
+		client:   client,
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		bucket:   config.Bucket,
+		prefix:   config.Prefix,
+		token:    os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"),
+	}
+	if s.token == "" {
+		return nil, fmt.Errorf("gcs cache storage requires GOOGLE_OAUTH_ACCESS_TOKEN")
+	}
+	return s, nil
+}
+
+func (s *gcsStorage) Get(ctx context.Context, key string, w io.Writer) error {
+	u := s.endpoint + "/storage/v1/b/" + url.PathEscape(s.bucket) + "/o/" + url.PathEscape(s.prefix+key) + "?alt=media"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.token)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrCacheMiss
+	}
+	if status := resp.StatusCode; status < 200 || status >= 300 {
+		return fmt.Errorf("gcs get %s: unexpected status %s", key, resp.Status)
+	}
+
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+func (s *gcsStorage) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	u := s.endpoint + "/upload/storage/v1/b/" + url.PathEscape(s.bucket) + "/o?uploadType=media&name=" + url.QueryEscape(s.prefix+key)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, r)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Authorization", "Bearer "+s.token)
+	req.Header.Set("Content-Type", "application/gzip")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if status := resp.StatusCode; status < 200 || status >= 300 {
+		return fmt.Errorf("gcs put %s: unexpected status %s", key, resp.Status)
+	}
+	return nil
+}
+
+func (s *gcsStorage) Delete(ctx context.Context, key string) error {
+	u := s.endpoint + "/storage/v1/b/" + url.PathEscape(s.bucket) + "/o/" + url.PathEscape(s.prefix+key)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.token)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if status := resp.StatusCode; status != http.StatusNotFound && (status < 200 || status >= 300) {
+		return fmt.Errorf("gcs delete %s: unexpected status %s", key, resp.Status)
+	}
+	return nil
+}
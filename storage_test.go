@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDiskStorage(t *testing.T) {
+	s := &diskStorage{dir: filepath.Join(t.TempDir(), "archives")}
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	if err := s.Get(ctx, "go1.22.1.linux-amd64.tar.gz", &buf); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("Expected ErrCacheMiss, got %v", err)
+	}
+
+	if err := s.Put(ctx, "go1.22.1.linux-amd64.tar.gz", strings.NewReader("archive"), 7); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := s.Get(ctx, "go1.22.1.linux-amd64.tar.gz", &buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if buf.String() != "archive" {
+		t.Errorf("Expected archive content, got %s", buf.String())
+	}
+}
+
+// newFakeBucket serves objects stored in memory under the name returned by
+// key, rejecting requests that do not pass the authorize check.
+func newFakeBucket(t *testing.T, authorize func(r *http.Request) bool, key func(r *http.Request) string) *httptest.Server {
+	var mu sync.Mutex
+	objects := map[string][]byte{}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !authorize(r) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.Method {
+		case http.MethodGet:
+			b, ok := objects[key(r)]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(b)
+		case http.MethodPut, http.MethodPost:
+			b, _ := io.ReadAll(r.Body)
+			objects[key(r)] = b
+		case http.MethodDelete:
+			delete(objects, key(r))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func testStorageRoundTrip(t *testing.T, s Storage) {
+	t.Helper()
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	if err := s.Get(ctx, "go1.22.1.linux-amd64.tar.gz", &buf); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("Expected ErrCacheMiss, got %v", err)
+	}
+
+	if err := s.Put(ctx, "go1.22.1.linux-amd64.tar.gz", strings.NewReader("archive"), 7); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := s.Get(ctx, "go1.22.1.linux-amd64.tar.gz", &buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if buf.String() != "archive" {
+		t.Errorf("Expected archive content, got %s", buf.String())
+	}
+
+	if err := s.Delete(ctx, "go1.22.1.linux-amd64.tar.gz"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := s.Get(ctx, "go1.22.1.linux-amd64.tar.gz", &buf); !errors.Is(err, ErrCacheMiss) {
+		t.Errorf("Expected ErrCacheMiss once deleted, got %v", err)
+	}
+}
+
+func TestS3Storage(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	srv := newFakeBucket(t, func(r *http.Request) bool {
+		auth := r.Header.Get("Authorization")
+		return strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20240301/eu-west-1/s3/aws4_request") &&
+			strings.Contains(auth, "SignedHeaders=host;x-amz-content-sha256;x-amz-date") &&
+			r.Header.Get("X-Amz-Date") == "20240301T120000Z"
+	}, func(r *http.Request) string {
+		return r.URL.Path
+	})
+
+	s, err := newS3Storage(StorageConfig{Bucket: "toolchains", Prefix: "go/", Region: "eu-west-1", Endpoint: srv.URL}, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.now = func() time.Time { return time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC) }
+
+	testStorageRoundTrip(t, s)
+}
+
+func TestGCSStorage(t *testing.T) {
+	t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "token")
+
+	srv := newFakeBucket(t, func(r *http.Request) bool {
+		return r.Header.Get("Authorization") == "Bearer token"
+	}, func(r *http.Request) string {
+		if name := r.URL.Query().Get("name"); name != "" {
+			return name
+		}
+		return strings.TrimPrefix(r.URL.Path, "/storage/v1/b/toolchains/o/")
+	})
+
+	s, err := newGCSStorage(StorageConfig{Bucket: "toolchains", Endpoint: srv.URL}, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testStorageRoundTrip(t, s)
+}
+
+func TestPipelineFromStorage(t *testing.T) {
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n"})
+	dlf := File{Filename: "go1.22.1.linux-amd64.tar.gz", Version: "go1.22.1"}
+
+	storage := &diskStorage{dir: t.TempDir()}
+	if err := os.WriteFile(filepath.Join(storage.dir, dlf.Filename), archive, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The repository has no client, any download attempt would fail.
+	repo := &GoRepository{onProgress: func(ratio float64) {}}
+
+	prefix := t.TempDir()
+	if err := newPipeline(repo, storage, dlf, prefix, processOwner, newTestPaths(t)).run(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if v, err := installedVersion(filepath.Join(prefix, "go")); err != nil || v != "go1.22.1" {
+		t.Errorf("Expected go1.22.1 to be installed, got %s (%v)", v, err)
+	}
+}
+
+func TestPipelineEvictsCorruptedCache(t *testing.T) {
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n"})
+	sum := sha256.Sum256(archive)
+	dlf := File{Filename: "go1.22.1.linux-amd64.tar.gz", Version: "go1.22.1", Sha256: hex.EncodeToString(sum[:])}
+
+	storage := &diskStorage{dir: t.TempDir()}
+	if err := os.WriteFile(filepath.Join(storage.dir, dlf.Filename), []byte("corrupted"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	prefix := t.TempDir()
+	if err := newPipeline(newTestArchiveRepo(archive), storage, dlf, prefix, processOwner, newTestPaths(t)).run(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if v, err := installedVersion(filepath.Join(prefix, "go")); err != nil || v != "go1.22.1" {
+		t.Errorf("Expected go1.22.1 to be installed, got %s (%v)", v, err)
+	}
+	if b, err := os.ReadFile(filepath.Join(storage.dir, dlf.Filename)); err != nil || !bytes.Equal(b, archive) {
+		t.Errorf("Expected the cache entry to be replaced by the downloaded archive (%v)", err)
+	}
+}
+
+func TestNewStorageClientTimeout(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	s, err := newStorage(StorageConfig{Type: "s3", Bucket: "toolchains"}, t.TempDir(), client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timeout := s.(*s3Storage).client.Timeout; timeout != 0 {
+		t.Errorf("Expected the archives to be transferred without the timeout of the client, got %s", timeout)
+	}
+	if client.Timeout != 30*time.Second {
+		t.Errorf("Expected the shared client to be left as is")
+	}
+}
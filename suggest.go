@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/blckfalcon/go-dl/versions"
+)
+
+// goModRequirement is a go or toolchain directive of a go.mod file.
+type goModRequirement struct {
+	path      string
+	directive string
+	version   string
+}
+
+// query returns the version constraint satisfying the directive: the exact
+// toolchain or prerelease, or the latest patch of the go version.
+func (r goModRequirement) query() string {
+	v := versions.Normalize(r.version)
+	if r.directive == "toolchain" || strings.ContainsAny(strings.TrimPrefix(v, "go"), "abcdefghijklmnopqrstuvwxyz") {
+		return v
+	}
+	return "~" + strings.TrimPrefix(v, "go")
+}
+
+func (r goModRequirement) String() string {
+	return fmt.Sprintf("%s (%s %s)", r.path, r.directive, r.version)
+}
+
+// readGoMod returns the go and toolchain directives of the go.mod at path.
+func readGoMod(path string) ([]goModRequirement, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var reqs []goModRequirement
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "//")
+		fields := strings.Fields(line)
+		if len(fields) != 2 || (fields[0] != "go" && fields[0] != "toolchain") || fields[1] == "default" {
+			continue
+		}
+		reqs = append(reqs, goModRequirement{path: path, directive: fields[0], version: fields[1]})
+	}
+	return reqs, scanner.Err()
+}
+
+// findGoModRequirements collects the directives of the go.mod files under
+// root, skipping the directories ignored by the go command.
+func findGoModRequirements(root string) ([]goModRequirement, error) {
+	var reqs []goModRequirement
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata" || name == "vendor" || name == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() != "go.mod" {
+			return nil
+		}
+
+		r, err := readGoMod(path)
+		reqs = append(reqs, r...)
+		return err
+	})
+	return reqs, err
+}
+
+// suggest installs every release required by the go.mod files of a tree.
+func (c *cli) suggest(args []string) error {
+	fs := flag.NewFlagSet("suggest", flag.ContinueOnError)
+	yes := fs.Bool("yes", false, "install the suggested versions without asking")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-dl suggest [--yes] [directory]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	root := "."
+	if fs.NArg() > 0 {
+		root = fs.Arg(0)
+	}
+
+	reqs, err := findGoModRequirements(root)
+	if err != nil {
+		return err
+	}
+	if len(reqs) == 0 {
+		return fmt.Errorf("no go.mod found under %s", root)
+	}
+
+	releases, err := c.repo.GetVersions(c.ctx)
+	if err != nil {
+		return err
+	}
+	policy, err := c.policy.resolve(releases)
+	if err != nil {
+		return err
+	}
+
+	required := map[string][]goModRequirement{}
+	for _, r := range reqs {
+		release, err := resolveRelease(releases, r.query())
+		if err != nil {
+			return fmt.Errorf("%s: %w", r, err)
+		}
+		required[release.Version] = append(required[release.Version], r)
+	}
+
+	all := make([]string, 0, len(required))
+	for v := range required {
+		all = append(all, v)
+	}
+	sort.Slice(all, func(i, j int) bool { return versions.Compare(all[i], all[j]) < 0 })
+
+	var missing []string
+	for _, v := range all {
+		var from []string
+		for _, r := range required[v] {
+			from = append(from, r.String())
+		}
+
+		status := ""
+		if _, err := c.installation(v); err == nil {
+			status = ", installed"
+		} else if err := policy.Allow(v); err != nil {
+			status = ", blocked by policy"
+		} else {
+			missing = append(missing, v)
+		}
+		fmt.Fprintf(c.stdout, "%s%s: %s\n", v, status, strings.Join(from, ", "))
+	}
+
+	if len(missing) == 0 {
+		fmt.Fprintln(c.stdout, "Nothing to install")
+		return nil
+	}
+	if len(missing) > 1 && !isManaged(c.goroot()) {
+		return fmt.Errorf("installing %s replaces each by the next, run go-dl migrate first to keep them side by side", strings.Join(missing, ", "))
+	}
+	if !*yes && !c.confirm(fmt.Sprintf("Install %s?", strings.Join(missing, ", "))) {
+		return nil
+	}
+
+	// The newest version is installed last and stays active.
+	for _, v := range missing {
+		if err := c.install([]string{v}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
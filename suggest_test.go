@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindGoModRequirements(t *testing.T) {
+	root := t.TempDir()
+	for path, content := range map[string]string{
+		"go.mod":             "module example.com/a\n\ngo 1.22.0\n\ntoolchain go1.22.1 // pinned\n",
+		"tools/go.mod":       "module example.com/tools\n\ngo 1.21\n",
+		"vendor/x/go.mod":    "module example.com/x\n\ngo 1.19\n",
+		".git/go.mod":        "module example.com/git\n\ngo 1.18\n",
+		"next/go.mod":        "module example.com/next\n\ngo 1.23rc1\ntoolchain default\n",
+		"testdata/m/go.mod":  "module example.com/m\n\ngo 1.17\n",
+		"tools/other/README": "go 1.16\n",
+	} {
+		if err := writeFile(filepath.Join(root, filepath.FromSlash(path)), strings.NewReader(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reqs, err := findGoModRequirements(root)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var got []string
+	for _, r := range reqs {
+		rel, _ := filepath.Rel(root, r.path)
+		got = append(got, filepath.ToSlash(rel)+" "+r.query())
+	}
+	want := []string{"go.mod ~1.22.0", "go.mod go1.22.1", "next/go.mod go1.23rc1", "tools/go.mod ~1.21"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n"})
+	c, out := newTestCLI(t, archive)
+	c.prefix = t.TempDir()
+
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/a\n\ngo 1.22\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.suggest([]string{"--yes", root}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if v, err := installedVersion(c.goroot()); err != nil || v != "go1.22.1" {
+		t.Errorf("Expected go1.22.1 to be installed, got %s (%v)", v, err)
+	}
+
+	out.Reset()
+	if err := c.suggest([]string{root}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "go1.22.1, installed") || !strings.Contains(out.String(), "Nothing to install") {
+		t.Errorf("Expected go1.22.1 to be reported as installed, got %q", out.String())
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/blckfalcon/go-dl/versions"
+)
+
+// SystemPolicy is the policy of the organization, deployed by configuration
+// management in a file users can't write. Its settings take precedence over
+// the configuration of the user and the command line.
+type SystemPolicy struct {
+	// Mirrors are the only base URLs the releases can be fetched from, the
+	// first one is the default.
+	Mirrors []string `json:"mirrors,omitempty"`
+	// MinimumVersion replaces the minimum_version of the user, and can
+	// only be bypassed with --override-policy when AllowOverride is set.
+	MinimumVersion string `json:"minimum_version,omitempty"`
+	AllowOverride  bool   `json:"allow_override,omitempty"`
+
+	// RequireChecksum rejects the files without a published checksum,
+	// StrictArchive and ConfineExtract enforce --strict-archive and
+	// --confine-extract, and Scanner replaces the scanner of the user.
+	RequireChecksum bool     `json:"require_checksum,omitempty"`
+	StrictArchive   bool     `json:"strict_archive,omitempty"`
+	ConfineExtract  bool     `json:"confine_extract,omitempty"`
+	Scanner         []string `json:"scanner,omitempty"`
+}
+
+// systemPolicyFile returns the path of the system policy: /etc/go-dl on Unix
+// systems, /Library/Application Support/go-dl on macOS, where MDM profiles
+// install it, and %ProgramData%\go-dl on Windows.
+func systemPolicyFile() string {
+	switch runtime.GOOS {
+	case "windows":
+		dir := os.Getenv("ProgramData")
+		if dir == "" {
+			dir = `C:\ProgramData`
+		}
+		return filepath.Join(dir, "go-dl", "policy.json")
+	case "darwin":
+		return "/Library/Application Support/go-dl/policy.json"
+	}
+	return "/etc/go-dl/policy.json"
+}
+
+// loadSystemPolicy reads the system policy at path, a missing file leaves
+// every decision to the user.
+func loadSystemPolicy(path string) (SystemPolicy, error) {
+	var policy SystemPolicy
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return policy, nil
+	}
+	if err != nil {
+		return policy, err
+	}
+
+	if err := json.Unmarshal(b, &policy); err != nil {
+		return policy, fmt.Errorf("invalid system policy %s: %w", path, err)
+	}
+	if v := policy.MinimumVersion; v != "" && !versions.IsValid(v) && !isChannel(v) {
+		return policy, fmt.Errorf("invalid system policy %s: minimum_version %q is not a valid version", path, v)
+	}
+	for i, m := range policy.Mirrors {
+		policy.Mirrors[i] = strings.TrimSuffix(m, "/")
+	}
+	return policy, nil
+}
+
+// mirror returns the mirror used instead of url, chosen by the user when
+// set: the default mirror of the policy, or an error when the policy
+// doesn't allow url.
+func (s SystemPolicy) mirror(url string, chosen bool) (string, error) {
+	url = strings.TrimSuffix(url, "/")
+	switch {
+	case len(s.Mirrors) == 0 || slices.Contains(s.Mirrors, url):
+		return url, nil
+	case !chosen:
+		return s.Mirrors[0], nil
+	}
+	return "", fmt.Errorf("the mirror %s is not allowed by the system policy, use one of %s: %w", url, strings.Join(s.Mirrors, ", "), ErrPolicy)
+}
+
+// manifest returns an error when the policy locks the mirrors, whose
+// checksums a manifest would replace.
+func (s SystemPolicy) manifest(path string) error {
+	if len(s.Mirrors) == 0 {
+		return nil
+	}
+	return fmt.Errorf("the manifest %s is not allowed by the system policy, which locks the mirrors: %w", path, ErrPolicy)
+}
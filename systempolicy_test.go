@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadSystemPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	if policy, err := loadSystemPolicy(path); err != nil || !reflect.DeepEqual(policy, SystemPolicy{}) {
+		t.Errorf("Expected no policy without file, got %+v (%v)", policy, err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"mirrors":["https://mirror.corp/go/"],"minimum_version":"1.22","require_checksum":true}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := loadSystemPolicy(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if want := (SystemPolicy{Mirrors: []string{"https://mirror.corp/go"}, MinimumVersion: "1.22", RequireChecksum: true}); !reflect.DeepEqual(policy, want) {
+		t.Errorf("Expected %+v, got %+v", want, policy)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"minimum_version":"latest-ish"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadSystemPolicy(path); err == nil {
+		t.Errorf("Expected an invalid minimum version to be rejected")
+	}
+}
+
+func TestSystemPolicyMirror(t *testing.T) {
+	system := SystemPolicy{Mirrors: []string{"https://mirror.corp/go", "https://backup.corp/go"}}
+
+	for _, tt := range []struct {
+		url    string
+		chosen bool
+		want   string
+	}{
+		{defaultMirror, false, "https://mirror.corp/go"},
+		{"https://backup.corp/go/", true, "https://backup.corp/go"},
+		{"https://evil.example.com", true, ""},
+	} {
+		got, err := system.mirror(tt.url, tt.chosen)
+		if got != tt.want || (tt.want == "") != errors.Is(err, ErrPolicy) {
+			t.Errorf("mirror(%q, %v) = %q, %v, want %q", tt.url, tt.chosen, got, err, tt.want)
+		}
+	}
+
+	if got, err := (SystemPolicy{}).mirror("https://mirror.example.com/", true); got != "https://mirror.example.com" || err != nil {
+		t.Errorf("Expected any mirror without system policy, got %q (%v)", got, err)
+	}
+
+	if err := system.manifest("releases.txt"); !errors.Is(err, ErrPolicy) {
+		t.Errorf("Expected the manifests to be rejected with locked mirrors, got %v", err)
+	}
+	if err := (SystemPolicy{}).manifest("releases.txt"); err != nil {
+		t.Errorf("Expected the manifests to be allowed without system policy, got %v", err)
+	}
+}
+
+func TestSystemPolicyMinimumVersion(t *testing.T) {
+	config := Config{MinimumVersion: "1.18"}
+
+	if err := newPolicy(config, SystemPolicy{}, false).Allow("go1.17.13"); !errors.Is(err, ErrPolicy) {
+		t.Errorf("Expected the minimum version of the user, got %v", err)
+	}
+
+	system := SystemPolicy{MinimumVersion: "1.21"}
+	if err := newPolicy(config, system, true).Allow("go1.20.14"); !errors.Is(err, ErrPolicy) {
+		t.Errorf("Expected the system policy not to be overridden, got %v", err)
+	}
+	if err := newPolicy(config, system, false).Allow("go1.21.0"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	system.AllowOverride = true
+	if err := newPolicy(config, system, true).Allow("go1.20.14"); err != nil {
+		t.Errorf("Expected the system policy to allow overrides, got %v", err)
+	}
+}
+
+func TestSystemPolicyRequireChecksum(t *testing.T) {
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n"})
+	dlf := File{Filename: "go1.22.1.linux-amd64.tar.gz", Version: "go1.22.1"}
+
+	repo := newTestArchiveRepo(archive)
+	repo.requireChecksum = true
+	err := newPipeline(repo, nil, dlf, t.TempDir(), processOwner, newTestPaths(t)).run(context.Background())
+	if !errors.Is(err, ErrPolicy) {
+		t.Errorf("Expected the file without checksum to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// staleTempAge is how old a leftover temporary file has to be before it is
+// removed at startup.
+const staleTempAge = 24 * time.Hour
+
+// tempPrefixes are the names of the temporary files created by go-dl.
+var tempPrefixes = []string{"go-dl-tmp", "go-dl-spool-", "go-dl-layer-"}
+
+// processLock is held, shared, for the lifetime of the process.
+var processLock *os.File
+
+// acquireProcessLock opens the lock file at path and reports whether no
+// other go-dl process runs, the lock is left exclusive in that case and
+// has to be shared with lockShared once done.
+func acquireProcessLock(path string) (*os.File, bool, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, false, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, false, err
+	}
+
+	alone, err := tryLockExclusive(f)
+	if err != nil {
+		f.Close()
+		return nil, false, err
+	}
+	if !alone {
+		if err := lockShared(f); err != nil {
+			f.Close()
+			return nil, false, err
+		}
+	}
+	return f, alone, nil
+}
+
+// shareProcessLock holds the lock file at path shared, for the read-only
+// commands, nil when no go-dl created it yet.
+func shareProcessLock(path string) (*os.File, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := lockShared(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// resumableArchives returns the archives of the interrupted installations
+// recorded in the state directory, which are kept for resume.
+func resumableArchives(state string) map[string]bool {
+	keep := map[string]bool{}
+
+	files, _ := filepath.Glob(filepath.Join(state, "*.json"))
+	for _, name := range files {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			continue
+		}
+		var s pipelineState
+		if json.Unmarshal(b, &s) == nil && s.Archive != "" {
+			keep[filepath.Clean(s.Archive)] = true
+		}
+	}
+	return keep
+}
+
+// removeStaleTemp removes the go-dl temporary files in dirs last modified
+// before cutoff, except keep, it returns how many files were removed and
+// the space reclaimed. Only the files of the user running go-dl are
+// removed, the temporary directory can be shared with other users, whose
+// go-dl processes the lock does not know about.
+func removeStaleTemp(dirs []string, keep map[string]bool, cutoff time.Time) (int, int64) {
+	var count int
+	var reclaimed int64
+
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			name := filepath.Join(dir, e.Name())
+			if !isTempName(e.Name()) || keep[name] {
+				continue
+			}
+			info, err := e.Info()
+			if err != nil || info.ModTime().After(cutoff) || !ownedByProcess(info) {
+				continue
+			}
+
+			size, err := treeSize(name)
+			if err != nil {
+				continue
+			}
+			if err := os.RemoveAll(name); err != nil {
+				continue
+			}
+			count++
+			reclaimed += size
+		}
+	}
+	return count, reclaimed
+}
+
+// ownedByProcess reports whether the file described by info belongs to the
+// user running go-dl, which is assumed where owners are unknown.
+func ownedByProcess(info os.FileInfo) bool {
+	owner := fileOwner(info)
+	return !owner.isSet() || owner.Uid == os.Geteuid()
+}
+
+func isTempName(name string) bool {
+	for _, p := range tempPrefixes {
+		if strings.HasPrefix(name, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// treeSize returns the size of the regular files under name.
+func treeSize(name string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(name, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestRemoveStaleTemp(t *testing.T) {
+	dir := t.TempDir()
+	state := t.TempDir()
+	old := time.Now().Add(-2 * staleTempAge)
+
+	files := map[string]int{
+		"go-dl-tmp-123-go1.22.1.linux-amd64.tar.gz": 100,
+		"go-dl-spool-456":                       20,
+		"go-dl-tmp-go1.21.0.linux-amd64.tar.gz": 50,
+		"go-dl-tmp-fresh.tar.gz":                10,
+		"unrelated":                             5,
+	}
+	for name, size := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if name != "go-dl-tmp-fresh.tar.gz" {
+			os.Chtimes(path, old, old)
+		}
+	}
+
+	interrupted := `{"version":"go1.21.0","archive":"` + filepath.Join(dir, "go-dl-tmp-go1.21.0.linux-amd64.tar.gz") + `"}`
+	if err := os.WriteFile(filepath.Join(state, "pipeline.json"), []byte(interrupted), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	count, reclaimed := removeStaleTemp([]string{dir, filepath.Join(dir, "missing")}, resumableArchives(state), time.Now().Add(-staleTempAge))
+	if count != 2 || reclaimed != 120 {
+		t.Errorf("Expected 2 files and 120 bytes removed, got %d and %d", count, reclaimed)
+	}
+
+	for name := range files {
+		_, err := os.Stat(filepath.Join(dir, name))
+		removed := name == "go-dl-tmp-123-go1.22.1.linux-amd64.tar.gz" || name == "go-dl-spool-456"
+		if removed != os.IsNotExist(err) {
+			t.Errorf("Expected %s removed to be %v, got %v", name, removed, err)
+		}
+	}
+}
+
+func TestProcessLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "go-dl.lock")
+
+	first, alone, err := acquireProcessLock(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	if !alone {
+		t.Errorf("Expected the first process to be alone")
+	}
+	if err := lockShared(first); err != nil {
+		t.Fatal(err)
+	}
+
+	second, alone, err := acquireProcessLock(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	if alone && lockSupported {
+		t.Errorf("Expected the second process to see the first one")
+	}
+}
+
+func TestRemoveStaleTempOtherUser(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Geteuid() != 0 {
+		t.Skip("giving a file to another user requires root")
+	}
+
+	dir := t.TempDir()
+	old := time.Now().Add(-2 * staleTempAge)
+	path := filepath.Join(dir, "go-dl-tmp-123-go1.22.1.linux-amd64.tar.gz")
+	if err := os.WriteFile(path, make([]byte, 10), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chown(path, 65534, 65534); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(path, old, old)
+
+	if count, _ := removeStaleTemp([]string{dir}, nil, time.Now().Add(-staleTempAge)); count != 0 {
+		t.Errorf("Expected the files of other users to be kept, %d removed", count)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Expected the file of another user to be kept: %v", err)
+	}
+}
+
+func TestShareProcessLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "go-dl.lock")
+
+	f, err := shareProcessLock(path)
+	if err != nil || f != nil {
+		t.Fatalf("Expected no lock without a state directory, got %v (%v)", f, err)
+	}
+	if _, err := os.Stat(filepath.Dir(path)); !os.IsNotExist(err) {
+		t.Errorf("Expected the state directory not to be created, got %v", err)
+	}
+
+	first, _, err := acquireProcessLock(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	if err := lockShared(first); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := shareProcessLock(path)
+	if err != nil || reader == nil {
+		t.Fatalf("Expected the existing lock to be shared, got %v", err)
+	}
+	defer reader.Close()
+	first.Close()
+
+	second, alone, err := acquireProcessLock(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	if alone && lockSupported {
+		t.Errorf("Expected a read-only command to keep the cleanup from running")
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// phaseTimeouts bounds the phases of an installation, zero durations leave
+// them unbounded.
+type phaseTimeouts struct {
+	// feed bounds the fetch of the releases feed.
+	feed time.Duration
+	// stall bounds the wait for the next bytes of a download.
+	stall time.Duration
+	// extract bounds the extraction, or the build from source.
+	extract time.Duration
+}
+
+// withPhaseTimeout returns ctx bounded by timeout when set.
+func withPhaseTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// phaseError names the phase which ran out of time in err, when ctx is the
+// one which expired.
+func phaseError(ctx context.Context, phase string, timeout time.Duration, err error) error {
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) && errors.Is(err, context.DeadlineExceeded) {
+		if deadline, ok := ctx.Deadline(); ok && timeout > 0 && !deadline.After(time.Now()) {
+			return fmt.Errorf("%s timed out after %s: %w", phase, timeout, err)
+		}
+	}
+	return err
+}
+
+// contextReader fails the reads of an io.ReadSeeker once ctx is done, so the
+// extractions reading it stop with the context.
+type contextReader struct {
+	ctx context.Context
+	io.ReadSeeker
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.ReadSeeker.Read(p)
+}
+
+// stallWatch closes the body of a download when a read waits for more than
+// timeout, the read then fails with ErrStalled.
+type stallWatch struct {
+	timeout time.Duration
+	stalled atomic.Bool
+}
+
+func (w *stallWatch) read(body io.ReadCloser, buf []byte) (int, error) {
+	if w.timeout <= 0 {
+		return body.Read(buf)
+	}
+
+	timer := time.AfterFunc(w.timeout, func() {
+		w.stalled.Store(true)
+		body.Close()
+	})
+	n, err := body.Read(buf)
+	if !timer.Stop() && w.stalled.Load() {
+		return n, fmt.Errorf("%w: no data received for %s", ErrStalled, w.timeout)
+	}
+	return n, err
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stallingBody returns its data, then blocks until closed.
+type stallingBody struct {
+	data   io.Reader
+	closed chan struct{}
+}
+
+func (b *stallingBody) Read(p []byte) (int, error) {
+	if n, err := b.data.Read(p); err == nil {
+		return n, nil
+	}
+	<-b.closed
+	return 0, errors.New("read on closed body")
+}
+
+func (b *stallingBody) Close() error {
+	select {
+	case <-b.closed:
+	default:
+		close(b.closed)
+	}
+	return nil
+}
+
+func TestDownloadStall(t *testing.T) {
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n"})
+	dlf := File{Filename: "go1.22.1.linux-amd64.tar.gz", Version: "go1.22.1"}
+
+	repo := newTestArchiveRepo(archive)
+	repo.client = NewTestClient(func(*http.Request) *http.Response {
+		return &http.Response{
+			StatusCode:    http.StatusOK,
+			Body:          &stallingBody{data: bytes.NewReader(archive[:len(archive)/2]), closed: make(chan struct{})},
+			ContentLength: int64(len(archive)),
+		}
+	})
+	repo.timeouts.stall = 50 * time.Millisecond
+
+	err := newPipeline(repo, nil, dlf, t.TempDir(), processOwner, newTestPaths(t)).download(context.Background())
+	if !errors.Is(err, ErrStalled) {
+		t.Errorf("Expected the download to stall, got %v", err)
+	}
+}
+
+func TestDownloadReconnect(t *testing.T) {
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n"})
+	dlf := File{Filename: "go1.22.1.linux-amd64.tar.gz", Version: "go1.22.1"}
+	half := len(archive) / 2
+
+	repo := newTestArchiveRepo(archive)
+	repo.client = NewTestClient(func(req *http.Request) *http.Response {
+		if req.Header.Get("Range") == "" {
+			return &http.Response{
+				StatusCode:    http.StatusOK,
+				Body:          &stallingBody{data: bytes.NewReader(archive[:half]), closed: make(chan struct{})},
+				ContentLength: int64(len(archive)),
+			}
+		}
+		if want := fmt.Sprintf("bytes=%d-", half); req.Header.Get("Range") != want {
+			t.Errorf("Expected the download to resume with %s, got %s", want, req.Header.Get("Range"))
+		}
+		return &http.Response{
+			StatusCode:    http.StatusPartialContent,
+			Header:        http.Header{"Content-Range": {fmt.Sprintf("bytes %d-%d/%d", half, len(archive)-1, len(archive))}},
+			Body:          io.NopCloser(bytes.NewReader(archive[half:])),
+			ContentLength: int64(len(archive) - half),
+		}
+	})
+	repo.timeouts.stall = 50 * time.Millisecond
+	repo.reconnects = 1
+	var attempts []int
+	repo.onReconnect = func(attempt, max int) { attempts = append(attempts, attempt, max) }
+
+	var out bytes.Buffer
+	if err := repo.Download(context.Background(), dlf, &out); err != nil {
+		t.Fatalf("Expected the stalled download to resume, got %v", err)
+	}
+	if !bytes.Equal(out.Bytes(), archive) {
+		t.Errorf("Expected the whole archive once resumed, got %d of %d bytes", out.Len(), len(archive))
+	}
+	if !reflect.DeepEqual(attempts, []int{1, 1}) {
+		t.Errorf("Expected a single reconnection, got %v", attempts)
+	}
+}
+
+func TestFeedTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	repo := &GoRepository{client: server.Client(), url: server.URL, timeouts: phaseTimeouts{feed: 50 * time.Millisecond}}
+	_, err := repo.GetVersions(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "fetching the releases timed out after 50ms") {
+		t.Errorf("Expected the feed fetch to time out, got %v", err)
+	}
+}
+
+func TestExtractTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := extractArchive(t.TempDir(), contextReader{ctx, bytes.NewReader(newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n"}))}, processOwner, extractProgress{}, archiveOptions{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the extraction to stop with the context, got %v", err)
+	}
+}
+
+func TestDownloadClient(t *testing.T) {
+	repo := &GoRepository{client: &http.Client{Timeout: time.Second}}
+	if repo.downloadClient().Timeout != time.Second {
+		t.Errorf("Expected the client timeout without stall detection")
+	}
+
+	repo.timeouts.stall = time.Second
+	if repo.downloadClient().Timeout != 0 || repo.client.Timeout != time.Second {
+		t.Errorf("Expected the downloads to be bounded by the stall detection only")
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// TransportConfig tunes the connections to go.dev and the mirrors, for
+// networks where the defaults misbehave.
+type TransportConfig struct {
+	// HTTP1 disables HTTP/2, which some middleboxes break midway through
+	// large downloads.
+	HTTP1 bool `json:"http1,omitempty"`
+	// MaxIdleConns bounds the idle connections kept for reuse, in total and
+	// per host. DisableKeepAlives opens a new connection for each request.
+	MaxIdleConns        int  `json:"max_idle_conns,omitempty"`
+	MaxIdleConnsPerHost int  `json:"max_idle_conns_per_host,omitempty"`
+	DisableKeepAlives   bool `json:"disable_keep_alives,omitempty"`
+	// KeepAlive is the period of the TCP keepalive probes, such as 15s, or
+	// -1s to disable them.
+	KeepAlive string `json:"keep_alive,omitempty"`
+	// DoHURL is a DNS over HTTPS resolver, such as https://1.1.1.1/dns-query,
+	// resolving the host names the system DNS fails to, or all of them with
+	// DoHOnly.
+	DoHURL  string `json:"doh_url,omitempty"`
+	DoHOnly bool   `json:"doh_only,omitempty"`
+}
+
+// newTransport returns the default transport tuned by config, http1 forces
+// HTTP/1.1 whatever the configuration says.
+func newTransport(config TransportConfig, http1 bool) (*http.Transport, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+
+	if config.HTTP1 || http1 {
+		t.ForceAttemptHTTP2 = false
+		t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	if config.MaxIdleConns < 0 || config.MaxIdleConnsPerHost < 0 {
+		return nil, fmt.Errorf("invalid transport: idle connection limits can't be negative")
+	}
+	if config.MaxIdleConns > 0 {
+		t.MaxIdleConns = config.MaxIdleConns
+	}
+	if config.MaxIdleConnsPerHost > 0 {
+		t.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost
+	}
+	t.DisableKeepAlives = config.DisableKeepAlives
+
+	if config.KeepAlive != "" {
+		d, err := time.ParseDuration(config.KeepAlive)
+		if err != nil {
+			return nil, fmt.Errorf("invalid transport keep_alive: %w", err)
+		}
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: d}
+		t.DialContext = dialer.DialContext
+	}
+
+	if config.DoHURL != "" {
+		r, err := newDoHResolver(config.DoHURL)
+		if err != nil {
+			return nil, err
+		}
+		t.DialContext = r.dialContext(t.DialContext, config.DoHOnly)
+	}
+	return t, nil
+}
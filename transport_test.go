@@ -0,0 +1,60 @@
+package main
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTransportHTTP1(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Proto)
+	}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	protocol := func(config TransportConfig, http1 bool) string {
+		tr, err := newTransport(config, http1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tr.TLSClientConfig = &tls.Config{RootCAs: srv.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs}
+
+		resp, err := (&http.Client{Transport: tr}).Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return string(b)
+	}
+
+	if p := protocol(TransportConfig{}, false); p != "HTTP/2.0" {
+		t.Errorf("Expected HTTP/2 by default, got %s", p)
+	}
+	if p := protocol(TransportConfig{}, true); p != "HTTP/1.1" {
+		t.Errorf("Expected HTTP/1.1 with --http1, got %s", p)
+	}
+	if p := protocol(TransportConfig{HTTP1: true, KeepAlive: "15s", MaxIdleConns: 2}, false); p != "HTTP/1.1" {
+		t.Errorf("Expected HTTP/1.1 when configured, got %s", p)
+	}
+}
+
+func TestTransportConfig(t *testing.T) {
+	tr, err := newTransport(TransportConfig{MaxIdleConns: 4, MaxIdleConnsPerHost: 1, DisableKeepAlives: true}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.MaxIdleConns != 4 || tr.MaxIdleConnsPerHost != 1 || !tr.DisableKeepAlives {
+		t.Errorf("Expected the connection limits to be applied, got %d, %d and %v", tr.MaxIdleConns, tr.MaxIdleConnsPerHost, tr.DisableKeepAlives)
+	}
+
+	for _, invalid := range []TransportConfig{{KeepAlive: "often"}, {MaxIdleConns: -1}} {
+		if _, err := newTransport(invalid, false); err == nil {
+			t.Errorf("Expected an error for %+v", invalid)
+		}
+	}
+}
@@ -1,252 +1,211 @@
 package main
 
 import (
+	"bytes"
 	"context"
-	"errors"
 	"fmt"
-	"io"
 	"os"
+	"path/filepath"
 	"strings"
-	"time"
 
-	"github.com/charmbracelet/bubbles/list"
-	"github.com/charmbracelet/bubbles/progress"
-	tea "github.com/charmbracelet/bubbletea"
-	"github.com/charmbracelet/lipgloss"
+	"github.com/blckfalcon/go-dl/tui"
 )
 
-var (
-	titleStyle        = lipgloss.NewStyle().MarginLeft(2)
-	itemStyle         = lipgloss.NewStyle().PaddingLeft(4)
-	selectedItemStyle = lipgloss.NewStyle().PaddingLeft(2).Foreground(lipgloss.Color("027"))
-	paginationStyle   = list.DefaultStyles().PaginationStyle.PaddingLeft(4)
-	helpStyle         = list.DefaultStyles().HelpStyle.PaddingLeft(4).PaddingBottom(1)
-	quitTextStyle     = lipgloss.NewStyle().Margin(1, 0, 1, 4)
-	progressStyle     = lipgloss.NewStyle().MarginLeft(4)
-)
-
-type State int
-
-const (
-	Choosing State = iota
-	Downloading
-	Extracting
-	Quitting
-	Completed
-)
+// pickerInstaller installs the versions chosen in the interactive picker
+// with the installation pipeline.
+type pickerInstaller struct {
+	repo      *GoRepository
+	versions  []Release
+	selection Selection
+	policy    Policy
+	prefix    string
+	paths     Paths
+	storage   Storage
+	owner     Owner
+	scanner   []string
+	hooks     installHooks
+	// cli runs the steps following the installations and notifies their
+	// outcome, as for the command line.
+	cli      *cli
+	pipeline *pipeline
+	// previous is the version replaced by the installation.
+	previous string
+	// metered is set once the space shown for confirmation warned about the
+	// metered connection.
+	metered bool
+	// progress, when set, receives the progress of the steps tagged with
+	// their phase.
+	progress func(tui.ProgressMsg)
+}
 
-type item string
-type doneMsg struct{}
-type progressMsg float64
-type statusMsg State
-type errMsg struct{ err error }
-
-func downloadCmd(m *model) tea.Cmd {
-	return func() tea.Msg {
-		var err error
-		var dlf File
-
-		for _, v := range m.versions {
-			if m.choice == v.Version {
-				l := v.Files.Filter(
-					func(f File) bool { return f.Os == "linux" },
-					func(f File) bool { return f.Arch == "amd64" },
-				)
-				if len(l) > 0 {
-					dlf = l[0]
-				}
+// pickerFiles describes the file the picker installs for each release, the
+// source tarball when the platform has no archive.
+func pickerFiles(releases []Release, selection Selection) map[string]tui.FileInfo {
+	files := map[string]tui.FileInfo{}
+	for _, r := range releases {
+		dlf, ok := selection.Pick(r.Files)
+		if !ok {
+			if dlf, ok = selection.Source(r.Files); !ok {
+				continue
 			}
 		}
-
-		if dlf == (File{}) {
-			return errMsg{errors.New("did not found a matching file")}
-		}
-
-		m.file, err = os.CreateTemp("", "go-dl-tmp.tar.gz")
-		if err != nil {
-			return errMsg{err}
-		}
-
-		err = m.repo.Download(m.ctx, dlf, m.file)
-		if err != nil {
-			return errMsg{err}
-		}
-		return nil
+		files[r.Version] = fileInfo(dlf)
 	}
+	return files
 }
 
-func extractCmd(m *model) tea.Cmd {
-	return func() tea.Msg {
-		var err error
-
-		defer m.file.Close()
+func (i *pickerInstaller) Prepare(version string) (bool, error) {
+	if err := i.policy.Allow(version); err != nil {
+		return false, err
+	}
+	if err := checkForeignLink(filepath.Join(i.prefix, "go")); err != nil {
+		return false, err
+	}
+	if err := checkPackageOwner(context.Background(), filepath.Join(i.prefix, "go")); err != nil {
+		return false, err
+	}
 
-		_, err = m.file.Seek(0, io.SeekStart)
-		if err != nil {
-			return errMsg{err}
+	var files Files
+	for _, v := range i.versions {
+		if version == v.Version {
+			files = v.Files
 		}
+	}
 
-		err = os.RemoveAll("/usr/local/go")
-		if err != nil {
-			return errMsg{err}
+	fromSource := false
+	dlf, ok := i.selection.Pick(files)
+	if !ok {
+		if dlf, ok = i.selection.Source(files); !ok {
+			return false, fmt.Errorf("%w for %s on %s/%s", ErrNoMatchingFile, version, i.selection.Os, i.selection.Arch)
 		}
+		fromSource = true
+	}
 
-		err = Decompress("/usr/local", m.file, m.repo.onProgress)
-		if err != nil {
-			return errMsg{err}
+	i.previous, _ = installedVersion(filepath.Join(i.prefix, "go"))
+	i.pipeline = newPipeline(i.repo, i.storage, dlf, i.prefix, i.owner, i.paths)
+	i.pipeline.scanner = i.scanner
+	i.pipeline.preInstall = func(ctx context.Context) error {
+		var out bytes.Buffer
+		env := append(os.Environ(), "GO_DL_VERSION="+version)
+		if err := runHooks(ctx, "pre_install", i.hooks.of(version).PreInstall, env, i.owner, &out); err != nil {
+			return fmt.Errorf("%w: %s", err, strings.TrimSpace(out.String()))
 		}
-		return doneMsg{}
+		return nil
 	}
+	return fromSource, nil
 }
 
-func statusCmd(s State) tea.Cmd {
-	return func() tea.Msg {
-		return statusMsg(s)
+// phase tags the progress reported by the repository with phase.
+func (i *pickerInstaller) phase(phase tui.State) {
+	if i.progress == nil {
+		return
+	}
+	var done, total int64
+	i.repo.onTransfer = func(d, t int64) {
+		done, total = d, t
+	}
+	i.repo.onProgress = func(ratio float64) {
+		i.progress(tui.ProgressMsg{Phase: phase, Ratio: ratio, Done: done, Total: total})
 	}
 }
 
-func finalPause() tea.Cmd {
-	return tea.Tick(time.Millisecond*750, func(_ time.Time) tea.Msg {
-		return nil
-	})
+func (i *pickerInstaller) Download(ctx context.Context) error {
+	i.phase(tui.Downloading)
+	if i.metered {
+		i.repo.metered.allow()
+	}
+	return i.failed(wrapPermission(i.pipeline.download(ctx)))
 }
 
-func (i item) FilterValue() string { return "" }
+func (i *pickerInstaller) Verify(ctx context.Context) error {
+	i.phase(tui.Verifying)
+	return i.failed(wrapPermission(i.pipeline.verify(ctx)))
+}
 
-type itemDelegate struct{}
+func (i *pickerInstaller) Extract(ctx context.Context) error {
+	i.phase(tui.Extracting)
+	return i.failed(wrapPermission(i.pipeline.extract(ctx)))
+}
 
-func (d itemDelegate) Height() int                             { return 1 }
-func (d itemDelegate) Spacing() int                            { return 0 }
-func (d itemDelegate) Update(_ tea.Msg, _ *list.Model) tea.Cmd { return nil }
-func (d itemDelegate) Render(w io.Writer, m list.Model, index int, listItem list.Item) {
-	i, ok := listItem.(item)
-	if !ok {
-		return
+// failed notifies the failure err of a step to the webhooks.
+func (i *pickerInstaller) failed(err error) error {
+	if err == nil {
+		return nil
 	}
+	return i.cli.notifyInstall(i.pipeline.state.Version, i.previous, err)
+}
 
-	str := fmt.Sprintf("%d. %s", index+1, i)
-
-	fn := itemStyle.Render
-	if index == m.Index() {
-		fn = func(s ...string) string {
-			return selectedItemStyle.Render("> " + strings.Join(s, " "))
-		}
+// Activate runs the steps following the installation, their output is only
+// shown when they fail.
+func (i *pickerInstaller) Activate(ctx context.Context) error {
+	var out bytes.Buffer
+	if err := i.cli.afterInstall(i.pipeline.state.Version, i.previous, i.prefix, &out); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(out.String()))
 	}
-
-	fmt.Fprint(w, fn(str))
+	return nil
 }
 
-type model struct {
-	err      error
-	ctx      context.Context
-	list     list.Model
-	choice   string
-	progress progress.Model
-	repo     *GoRepository
-	versions []Release
-	file     *os.File
-	status   State
+// Space returns the disk space needed by the prepared installation.
+func (i *pickerInstaller) Space() (tui.Space, error) {
+	space := installSpace(i.pipeline.state.File, i.pipeline.state.Archive, i.prefix)
+	space.Metered = i.repo.metered.pending(context.Background(), space.Download)
+	// The space is shown for confirmation, which allows the download.
+	i.metered = space.Metered
+	return space, nil
 }
 
-func (m model) Init() tea.Cmd {
-	return nil
+// CanRollback reports whether an installation is there to be replaced, and
+// kept as a backup, by the extraction.
+func (i *pickerInstaller) CanRollback() bool {
+	_, err := installedVersion(filepath.Join(i.prefix, "go"))
+	return err == nil
 }
 
-func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
-	switch msg := msg.(type) {
-	case tea.WindowSizeMsg:
-		m.list.SetWidth(msg.Width)
-		return m, nil
-
-	case tea.KeyMsg:
-		switch keypress := msg.String(); keypress {
-		case "ctrl+c":
-			m.status = Quitting
-			return m, tea.Quit
+// Rollback restores the installation replaced by the extraction.
+func (i *pickerInstaller) Rollback(ctx context.Context) error {
+	_, err := rollbackInstallation(filepath.Join(i.prefix, "go"), i.policy.Allow)
+	return wrapPermission(err)
+}
 
-		case "enter":
-			i, ok := m.list.SelectedItem().(item)
-			if ok {
-				m.choice = string(i)
+// ReleaseFiles lists every file of version, for the release detail view.
+func (i *pickerInstaller) ReleaseFiles(version string) []tui.FileInfo {
+	var files []tui.FileInfo
+	for _, v := range i.versions {
+		if v.Version == version {
+			for _, f := range v.Files {
+				files = append(files, fileInfo(f))
 			}
-
-			return m, tea.Sequence(
-				statusCmd(Downloading),
-				downloadCmd(&m),
-				statusCmd(Extracting),
-				extractCmd(&m),
-			)
-		}
-
-	case statusMsg:
-		m.status = State(msg)
-		return m, nil
-
-	case errMsg:
-		m.err = msg.err
-		return m, tea.Quit
-
-	case doneMsg:
-		m.status = Completed
-		return m, tea.Sequence(finalPause(), tea.Quit)
-
-	case progressMsg:
-		var cmds []tea.Cmd
-
-		if msg >= 1.0 {
-			cmds = append(cmds, tea.Sequence(finalPause()))
 		}
-
-		cmds = append(cmds, m.progress.SetPercent(float64(msg)))
-		return m, tea.Batch(cmds...)
-
-	case progress.FrameMsg:
-		progressModel, cmd := m.progress.Update(msg)
-		m.progress = progressModel.(progress.Model)
-		return m, cmd
-
 	}
-
-	var cmd tea.Cmd
-	m.list, cmd = m.list.Update(msg)
-	return m, cmd
+	return files
 }
 
-func (m model) View() string {
-	if m.err != nil {
-		return quitTextStyle.Render(fmt.Sprintf("something went wrong: %v", m.err))
-	}
-
-	if m.status == Downloading {
-		return lipgloss.JoinVertical(
-			lipgloss.Left,
-			quitTextStyle.Render(fmt.Sprintf("Downloading: %s", m.choice)),
-			progressStyle.Render(m.progress.View()),
-			"",
-		)
-	}
-
-	if m.status == Extracting {
-		return lipgloss.JoinVertical(
-			lipgloss.Left,
-			quitTextStyle.Render(fmt.Sprintf("Extracting: %s", m.choice)),
-			progressStyle.Render(m.progress.View()),
-			"",
-		)
+// DownloadFile downloads and verifies the file name of version to the
+// current directory.
+func (i *pickerInstaller) DownloadFile(ctx context.Context, version, name string) (string, error) {
+	if err := i.policy.Allow(version); err != nil {
+		return "", err
 	}
-
-	if m.status == Completed {
-		return lipgloss.JoinVertical(
-			lipgloss.Left,
-			quitTextStyle.Render(fmt.Sprintf("Completed download and extraction of %s !", m.choice)),
-			progressStyle.Render(m.progress.View()),
-			"",
-		)
+	for _, v := range i.versions {
+		if v.Version != version {
+			continue
+		}
+		for _, f := range v.Files {
+			if f.Filename == name {
+				i.phase(tui.Browsing)
+				p := newPipeline(i.repo, i.storage, f, i.prefix, i.owner, i.paths)
+				p.scanner = i.scanner
+				return downloadVerified(ctx, p, ".")
+			}
+		}
 	}
+	return "", fmt.Errorf("%w: no file %s in %s", ErrNoMatchingFile, name, version)
+}
 
-	if m.status == Quitting {
-		return quitTextStyle.Render("exiting..")
-	}
+func (i *pickerInstaller) TogglePause() {
+	i.repo.gate.Toggle()
+}
 
-	return "\n" + m.list.View()
+func (i *pickerInstaller) Paused() bool {
+	return i.repo.gate.Paused()
 }
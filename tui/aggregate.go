@@ -0,0 +1,156 @@
+package tui
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/progress"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// TaskProgress is the progress of one of the tasks of an Aggregator.
+type TaskProgress struct {
+	Name  string
+	Ratio float64
+}
+
+// TasksMsg reports the progress of concurrent tasks and the overall
+// progress, weighted by the size of each task. Seq orders the messages, the
+// Model ignores the ones older than the last it received.
+type TasksMsg struct {
+	Seq     uint64
+	Tasks   []TaskProgress
+	Overall float64
+}
+
+// Aggregator combines the progress of tasks running in parallel, such as
+// several downloads or extractions, into TasksMsg for the multi-bar view.
+// Its callbacks can be called from any goroutine.
+type Aggregator struct {
+	send func(tea.Msg)
+
+	mu      sync.Mutex
+	seq     uint64
+	tasks   []TaskProgress
+	weights []float64
+}
+
+// NewAggregator returns an Aggregator sending its messages with send,
+// usually tea.Program.Send.
+func NewAggregator(send func(tea.Msg)) *Aggregator {
+	return &Aggregator{send: send}
+}
+
+// Task adds a task of the given weight, such as its size in bytes, and
+// returns the callback receiving its progress between 0 and 1.
+func (a *Aggregator) Task(name string, weight float64) func(float64) {
+	a.mu.Lock()
+	i := len(a.tasks)
+	a.tasks = append(a.tasks, TaskProgress{Name: name})
+	a.weights = append(a.weights, max(weight, 0))
+	msg := a.snapshot()
+	a.mu.Unlock()
+
+	a.send(msg)
+	return func(ratio float64) {
+		a.mu.Lock()
+		a.tasks[i].Ratio = min(max(ratio, 0), 1)
+		msg := a.snapshot()
+		a.mu.Unlock()
+
+		a.send(msg)
+	}
+}
+
+// snapshot returns a copy of the progress, the messages never share the
+// state of the Aggregator. a.mu must be held.
+func (a *Aggregator) snapshot() TasksMsg {
+	a.seq++
+	msg := TasksMsg{Seq: a.seq, Tasks: append([]TaskProgress(nil), a.tasks...)}
+
+	var done, total float64
+	for i, t := range a.tasks {
+		w := a.weights[i]
+		if w == 0 {
+			w = 1
+		}
+		done += t.Ratio * w
+		total += w
+	}
+	if total > 0 {
+		msg.Overall = done / total
+	}
+	return msg
+}
+
+// tasksView renders one bar per task followed by the overall progress, in a
+// terminal width columns wide once known.
+func tasksView(bar progress.Model, width int, tasks TasksMsg) string {
+	names := len("total")
+	for _, t := range tasks.Tasks {
+		names = max(names, len(t.Name))
+	}
+	if width > 0 {
+		bar.Width = progressWidth(width - names - 1)
+	}
+	var rows []string
+	for _, t := range tasks.Tasks {
+		rows = append(rows, fmt.Sprintf("%-*s %s", names, t.Name, bar.ViewAs(t.Ratio)))
+	}
+	rows = append(rows, fmt.Sprintf("%-*s %s", names, "total", bar.ViewAs(tasks.Overall)))
+	return progressStyle.Render(lipgloss.JoinVertical(lipgloss.Left, rows...))
+}
+
+// TasksDoneMsg ends a TasksView once its tasks are done.
+type TasksDoneMsg struct{}
+
+// TasksView shows the progress of the tasks of an Aggregator, for the
+// commands running several of them outside of the picker, such as
+// prefetching archives.
+type TasksView struct {
+	title    string
+	keys     KeyMap
+	progress progress.Model
+	tasks    TasksMsg
+	width    int
+	quit     bool
+}
+
+// NewTasksView returns a TasksView showing title above the bars.
+func NewTasksView(title string, keys KeyMap) TasksView {
+	return TasksView{title: title, keys: keys, progress: progress.New(progress.WithGradient("#000000", "#FFFFFF"))}
+}
+
+// Quit reports whether the user quit before the tasks were done.
+func (v TasksView) Quit() bool {
+	return v.quit
+}
+
+func (v TasksView) Init() tea.Cmd {
+	return nil
+}
+
+func (v TasksView) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case TasksMsg:
+		if msg.Seq > v.tasks.Seq {
+			v.tasks = msg
+		}
+	case TasksDoneMsg:
+		return v, tea.Quit
+	case tea.WindowSizeMsg:
+		v.width = msg.Width
+	case tea.KeyMsg:
+		if key.Matches(msg, v.keys.Quit) || key.Matches(msg, v.keys.ForceQuit) {
+			v.quit = true
+			return v, tea.Quit
+		}
+	}
+	return v, nil
+}
+
+func (v TasksView) View() string {
+	return lipgloss.JoinVertical(lipgloss.Left, titleStyle.Render(v.title), tasksView(v.progress, v.width, v.tasks)) + "\n"
+}
@@ -0,0 +1,89 @@
+package tui
+
+import (
+	"math/rand"
+	"strings"
+	"sync"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestAggregator(t *testing.T) {
+	var mu sync.Mutex
+	var msgs []TasksMsg
+	a := NewAggregator(func(msg tea.Msg) {
+		mu.Lock()
+		msgs = append(msgs, msg.(TasksMsg))
+		mu.Unlock()
+	})
+
+	small := a.Task("go1.21.10", 1)
+	large := a.Task("go1.22.1", 3)
+	large(0.5)
+	if last := msgs[len(msgs)-1]; last.Overall != 0.375 {
+		t.Errorf("Expected the overall progress to be weighted, got %v", last.Overall)
+	}
+
+	var wg sync.WaitGroup
+	for _, task := range []func(float64){small, large, a.Task("go1.23rc1", 0)} {
+		wg.Add(1)
+		go func(progress func(float64)) {
+			defer wg.Done()
+			for i := 0; i <= 100; i++ {
+				progress(float64(i) / 100)
+			}
+		}(task)
+	}
+	wg.Wait()
+
+	// The program may receive the messages in any order.
+	rand.Shuffle(len(msgs), func(i, j int) { msgs[i], msgs[j] = msgs[j], msgs[i] })
+	m := newTestModel(&fakeInstaller{})
+	m, _ = update(t, m, statusMsg(Downloading))
+	for _, msg := range msgs {
+		m, _ = update(t, m, msg)
+	}
+
+	if m.tasks.Overall != 1 || len(m.tasks.Tasks) != 3 {
+		t.Errorf("Expected the last progress to be kept, got %+v", m.tasks)
+	}
+	for _, task := range m.tasks.Tasks {
+		if task.Ratio != 1 {
+			t.Errorf("Expected %s to be done, got %v", task.Name, task.Ratio)
+		}
+	}
+
+	view := m.View()
+	for _, name := range []string{"go1.21.10", "go1.22.1", "go1.23rc1", "total"} {
+		if !strings.Contains(view, name) {
+			t.Errorf("Expected a bar for %s, got %q", name, view)
+		}
+	}
+}
+
+func TestTasksView(t *testing.T) {
+	var v tea.Model = NewTasksView("Prefetching", DefaultKeyMap())
+	a := NewAggregator(func(msg tea.Msg) { v, _ = v.Update(msg) })
+	a.Task("go1.22.1.linux-amd64.tar.gz", 2)(1)
+	a.Task("go1.22.1.darwin-arm64.tar.gz", 2)
+
+	view := v.View()
+	for _, want := range []string{"Prefetching", "go1.22.1.linux-amd64.tar.gz", "go1.22.1.darwin-arm64.tar.gz", "total"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected %q in the view, got %q", want, view)
+		}
+	}
+	if v.(TasksView).tasks.Overall != 0.5 {
+		t.Errorf("Expected half of the tasks done, got %v", v.(TasksView).tasks.Overall)
+	}
+
+	v, cmd := v.Update(TasksDoneMsg{})
+	if cmd == nil || cmd() != tea.Quit() || v.(TasksView).Quit() {
+		t.Errorf("Expected the view to end once the tasks are done")
+	}
+	v, cmd = v.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+	if cmd == nil || !v.(TasksView).Quit() {
+		t.Errorf("Expected ctrl+c to quit the view")
+	}
+}
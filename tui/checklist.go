@@ -0,0 +1,90 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Activator is implemented by the installers with a last step making the
+// installed version the active one, such as running post-install hooks. It
+// is listed as Activating in the checklist.
+type Activator interface {
+	Activate(ctx context.Context) error
+}
+
+// checklistStep is a step of the installation, named while it runs and or
+// is pending and once done.
+type checklistStep struct {
+	state   State
+	running string
+	done    string
+}
+
+var checklistSteps = []checklistStep{
+	{Downloading, "Downloading", "Downloaded"},
+	{Verifying, "Verifying", "Verified"},
+	{Extracting, "Extracting", "Extracted"},
+	{Activating, "Activating", "Activated"},
+}
+
+var (
+	doneStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("028"))
+	pendingStyle = lipgloss.NewStyle().Faint(true)
+)
+
+// steps returns the steps of the installation with the installer.
+func (m Model) steps() []checklistStep {
+	if _, ok := m.opts.Installer.(Activator); ok {
+		return checklistSteps
+	}
+	return checklistSteps[:3]
+}
+
+// checklistView renders the steps done, running and pending, followed by the
+// progress of the running step when it reports one.
+func (m Model) checklistView(title string) string {
+	current := len(m.steps())
+	for i, step := range m.steps() {
+		if step.state == m.status {
+			current = i
+		}
+	}
+
+	var lines []string
+	for i, step := range m.steps() {
+		switch {
+		case i < current:
+			lines = append(lines, doneStyle.Render("✓ "+step.done))
+		case i == current:
+			lines = append(lines, fmt.Sprintf("%s %s", m.spinner.View(), step.running))
+		default:
+			lines = append(lines, pendingStyle.Render("☐ "+step.running))
+		}
+	}
+
+	rows := []string{m.text(title), progressStyle.Render(strings.Join(lines, "\n"))}
+	if m.status == Downloading || m.status == Extracting {
+		rows = append(rows, "", m.progressView())
+	}
+	if speed := m.rateView(); m.status == Downloading && speed != "" {
+		rows = append(rows, progressStyle.Render(speed))
+	}
+	if m.status == Completed {
+		rows = append(rows, "", progressStyle.Render(m.progress.View()))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, append(rows, "")...)
+}
+
+// rateView renders the smoothed speed of the running phase and the time it
+// has left, empty while unknown.
+func (m Model) rateView() string {
+	left, ok := m.rate.Remaining(m.total)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%s/s, %s left", m.size(int64(m.rate.Rate())), left.Round(time.Second))
+}
@@ -0,0 +1,35 @@
+package tui
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+type activatingInstaller struct{ fakeInstaller }
+
+func (a *activatingInstaller) Activate(context.Context) error { return nil }
+
+func TestChecklist(t *testing.T) {
+	m := newTestModel(&fakeInstaller{})
+	m, _ = update(t, m, statusMsg(Verifying))
+	view := m.View()
+	if !strings.Contains(view, "✓ Downloaded") || !strings.Contains(view, "Verifying") || !strings.Contains(view, "☐ Extracting") {
+		t.Errorf("Expected the download done and the extraction pending, got %q", view)
+	}
+	if strings.Contains(view, "Activating") {
+		t.Errorf("Expected no activation step without an Activator, got %q", view)
+	}
+
+	installer := &activatingInstaller{}
+	m = newTestModel(installer)
+	m, _ = update(t, m, statusMsg(Extracting))
+	if view := m.View(); !strings.Contains(view, "✓ Verified") || !strings.Contains(view, "☐ Activating") {
+		t.Errorf("Expected the activation step to be pending, got %q", view)
+	}
+
+	m, _ = update(t, m, doneMsg{})
+	if view := m.View(); !strings.Contains(view, "✓ Activated") {
+		t.Errorf("Expected every step done, got %q", view)
+	}
+}
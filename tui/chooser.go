@@ -0,0 +1,85 @@
+package tui
+
+import (
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Chooser picks a version from a list without installing it, for commands
+// such as pinning the version of a project.
+type Chooser struct {
+	list   list.Model
+	keys   KeyMap
+	choice string
+}
+
+// NewChooser returns a Chooser offering versions, newest first, under title.
+// The Select binding is described as action in the help.
+func NewChooser(title, action string, versions []string, labels map[string][]string, keys KeyMap) Chooser {
+	keys.Select.SetHelp(keys.Select.Help().Key, action)
+
+	l := list.New(listItems(versions), itemDelegate{labels: labels}, 20, listHeight)
+	l.KeyMap = keys.listKeyMap()
+	l.AdditionalShortHelpKeys = func() []key.Binding { return []key.Binding{keys.Select} }
+	l.Title = title
+	l.SetShowStatusBar(false)
+	l.SetFilteringEnabled(false)
+	l.Styles.Title = titleStyle
+	l.Styles.PaginationStyle = paginationStyle
+	l.Styles.HelpStyle = helpStyle
+
+	return Chooser{list: l, keys: keys}
+}
+
+// Select moves the selection to version, when it is listed.
+func (c Chooser) Select(version string) Chooser {
+	for i, v := range c.list.Items() {
+		if v.(item) == item(version) {
+			c.list.Select(i)
+		}
+	}
+	return c
+}
+
+// Choice returns the chosen version, empty until one is chosen or when the
+// user quit.
+func (c Chooser) Choice() string {
+	return c.choice
+}
+
+func (c Chooser) Init() tea.Cmd {
+	return nil
+}
+
+func (c Chooser) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		c.list.SetWidth(msg.Width)
+		c.list.SetHeight(min(listHeight, max(msg.Height-1, 1)))
+		return c, nil
+
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, c.keys.Quit), key.Matches(msg, c.keys.ForceQuit):
+			return c, tea.Quit
+
+		case key.Matches(msg, c.keys.Select):
+			if i, ok := c.list.SelectedItem().(item); ok {
+				c.choice = string(i)
+			}
+			return c, tea.Quit
+		}
+	}
+
+	var cmd tea.Cmd
+	c.list, cmd = c.list.Update(msg)
+	return c, cmd
+}
+
+func (c Chooser) View() string {
+	if c.choice != "" {
+		return ""
+	}
+	return "\n" + c.list.View()
+}
@@ -0,0 +1,27 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestChooser(t *testing.T) {
+	versions := []string{"go1.22.1", "go1.21.10", "go1.21.0"}
+	c := NewChooser("Pin", "pin", versions, nil, DefaultKeyMap()).Select("go1.21.10")
+	if !strings.Contains(c.View(), "enter pin") {
+		t.Errorf("Expected the action in the help, got %q", c.View())
+	}
+
+	updated, _ := c.Update(tea.KeyMsg{Type: tea.KeyDown})
+	updated, cmd := updated.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if got := updated.(Chooser).Choice(); got != "go1.21.0" || cmd == nil {
+		t.Errorf("Expected go1.21.0 to be chosen, got %q", got)
+	}
+
+	updated, cmd = c.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+	if got := updated.(Chooser).Choice(); got != "" || cmd == nil {
+		t.Errorf("Expected no choice once quit, got %q", got)
+	}
+}
@@ -0,0 +1,72 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// FileInfo describes the file installing a version.
+type FileInfo struct {
+	Name string
+	// Platform is the os/arch of the file, empty for the source.
+	Platform string
+	// Size is in bytes, zero when unknown.
+	Size int64
+	// Kind is archive, installer or source.
+	Kind string
+	// Algorithm and Sum are the published checksum of the file, in hex.
+	Algorithm string
+	Sum       string
+}
+
+// sumPrefix is the length of the checksums shown in the list.
+const sumPrefix = 8
+
+var detailStyle = lipgloss.NewStyle().PaddingLeft(4).Foreground(lipgloss.Color("241"))
+
+// formatSize formats n bytes with size, as a count of bytes when nil.
+func formatSize(size func(int64) string, n int64) string {
+	if size == nil {
+		return fmt.Sprintf("%d B", n)
+	}
+	return size(n)
+}
+
+// summary returns the size, kind and checksum prefix of f, shown next to
+// its version in the list.
+func (f FileInfo) summary(size func(int64) string) string {
+	var fields []string
+	if f.Size > 0 {
+		fields = append(fields, formatSize(size, f.Size))
+	}
+	if f.Kind != "" {
+		fields = append(fields, f.Kind)
+	}
+	if f.Sum != "" {
+		fields = append(fields, f.Algorithm+":"+f.Sum[:min(sumPrefix, len(f.Sum))])
+	}
+	return strings.Join(fields, " ")
+}
+
+// details renders the file of the selected version below the list.
+func (m Model) details() string {
+	selected, ok := m.list.SelectedItem().(item)
+	if !ok {
+		return ""
+	}
+	f, ok := m.opts.Files[string(selected)]
+	if !ok {
+		return ""
+	}
+
+	rows := []string{f.Name}
+	if f.Size > 0 {
+		rows[0] += fmt.Sprintf(", %s (%d bytes)", m.size(f.Size), f.Size)
+	}
+	if f.Sum != "" {
+		rows = append(rows, f.Algorithm+" "+f.Sum)
+	}
+	return detailStyle.Render(strings.Join(rows, "\n"))
+}
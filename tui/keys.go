@@ -0,0 +1,246 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+)
+
+// KeyMap holds the bindings of the picker and the pager.
+type KeyMap struct {
+	Up       key.Binding
+	Down     key.Binding
+	PrevPage key.Binding
+	NextPage key.Binding
+	Top      key.Binding
+	Bottom   key.Binding
+	Select   key.Binding
+	Confirm  key.Binding
+	// Files opens the files of the selected release, where Download and
+	// DownloadAll download them.
+	Files       key.Binding
+	Download    key.Binding
+	DownloadAll key.Binding
+	Cancel      key.Binding
+	Pause       key.Binding
+	Rollback    key.Binding
+	Sort        key.Binding
+	Help        key.Binding
+	Quit        key.Binding
+	// ForceQuit quits in every state, an extraction included, without
+	// confirmation.
+	ForceQuit key.Binding
+}
+
+// DefaultKeyMap returns the default bindings.
+func DefaultKeyMap() KeyMap {
+	return KeyMap{
+		Up: key.NewBinding(
+			key.WithKeys("up", "k"),
+			key.WithHelp("↑/k", "up"),
+		),
+		Down: key.NewBinding(
+			key.WithKeys("down", "j"),
+			key.WithHelp("↓/j", "down"),
+		),
+		PrevPage: key.NewBinding(
+			key.WithKeys("left", "h", "pgup", "b", "u"),
+			key.WithHelp("←/h/pgup", "prev page"),
+		),
+		NextPage: key.NewBinding(
+			key.WithKeys("right", "l", "pgdown", "f", "d"),
+			key.WithHelp("→/l/pgdn", "next page"),
+		),
+		Top: key.NewBinding(
+			key.WithKeys("home", "g"),
+			key.WithHelp("g/home", "go to start"),
+		),
+		Bottom: key.NewBinding(
+			key.WithKeys("end", "G"),
+			key.WithHelp("G/end", "go to end"),
+		),
+		Select: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "install"),
+		),
+		Files: key.NewBinding(
+			key.WithKeys("i"),
+			key.WithHelp("i", "release files"),
+		),
+		Download: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "download"),
+		),
+		DownloadAll: key.NewBinding(
+			key.WithKeys("a"),
+			key.WithHelp("a", "download all"),
+		),
+		Confirm: key.NewBinding(
+			key.WithKeys("y"),
+			key.WithHelp("y", "confirm"),
+		),
+		Cancel: key.NewBinding(
+			key.WithKeys("n"),
+			key.WithHelp("n", "cancel"),
+		),
+		Pause: key.NewBinding(
+			key.WithKeys("p"),
+			key.WithHelp("p", "pause/resume"),
+		),
+		Rollback: key.NewBinding(
+			key.WithKeys("r"),
+			key.WithHelp("r", "roll back"),
+		),
+		Sort: key.NewBinding(
+			key.WithKeys("s"),
+			key.WithHelp("s", "change order"),
+		),
+		Help: key.NewBinding(
+			key.WithKeys("?"),
+			key.WithHelp("?", "help"),
+		),
+		Quit: key.NewBinding(
+			key.WithKeys("q", "esc", "ctrl+c"),
+			key.WithHelp("q", "quit"),
+		),
+		ForceQuit: key.NewBinding(
+			key.WithKeys(`ctrl+\`),
+			key.WithHelp(`ctrl+\`, "force quit"),
+		),
+	}
+}
+
+// binding returns the binding of a configurable action.
+func (k *KeyMap) binding(action string) *key.Binding {
+	switch action {
+	case "up":
+		return &k.Up
+	case "down":
+		return &k.Down
+	case "prev_page":
+		return &k.PrevPage
+	case "next_page":
+		return &k.NextPage
+	case "top":
+		return &k.Top
+	case "bottom":
+		return &k.Bottom
+	case "select":
+		return &k.Select
+	case "files":
+		return &k.Files
+	case "download":
+		return &k.Download
+	case "download_all":
+		return &k.DownloadAll
+	case "confirm":
+		return &k.Confirm
+	case "cancel":
+		return &k.Cancel
+	case "pause":
+		return &k.Pause
+	case "rollback":
+		return &k.Rollback
+	case "sort":
+		return &k.Sort
+	case "help":
+		return &k.Help
+	case "quit":
+		return &k.Quit
+	case "force_quit":
+		return &k.ForceQuit
+	}
+	return nil
+}
+
+// stateActions are the actions available in each state, a key can't be
+// bound to two of them.
+var stateActions = map[State][]string{
+	Choosing:       {"up", "down", "prev_page", "next_page", "top", "bottom", "select", "sort", "files", "help", "quit", "force_quit"},
+	Browsing:       {"up", "down", "prev_page", "next_page", "download", "download_all", "help", "quit", "force_quit"},
+	Downloading:    {"pause", "help", "quit", "force_quit"},
+	ConfirmSource:  {"confirm", "cancel", "help", "quit", "force_quit"},
+	ConfirmInstall: {"confirm", "cancel", "help", "quit", "force_quit"},
+	ConfirmCancel:  {"confirm", "rollback", "help", "quit", "force_quit"},
+}
+
+// checkConflicts reports a key bound to two actions of the same state.
+func (k *KeyMap) checkConflicts() error {
+	for _, actions := range stateActions {
+		bound := map[string]string{}
+		for _, action := range actions {
+			for _, key := range k.binding(action).Keys() {
+				if other, ok := bound[key]; ok && other != action {
+					return fmt.Errorf("key %q is bound to both %s and %s", key, other, action)
+				}
+				bound[key] = action
+			}
+		}
+	}
+	return nil
+}
+
+// NewKeyMap returns the default key map with the keys of the actions found
+// in overrides replaced.
+func NewKeyMap(overrides map[string][]string) (KeyMap, error) {
+	k := DefaultKeyMap()
+
+	for action, keys := range overrides {
+		b := k.binding(action)
+		if b == nil {
+			return k, fmt.Errorf("unknown key action %q", action)
+		}
+		if len(keys) == 0 {
+			return k, fmt.Errorf("no keys bound to action %q", action)
+		}
+
+		b.SetKeys(keys...)
+		b.SetHelp(strings.Join(keys, "/"), b.Help().Desc)
+	}
+
+	return k, k.checkConflicts()
+}
+
+// listKeyMap returns the bindings used by the versions list. Help and quit
+// are handled by the model so they can be used in every state.
+func (k KeyMap) listKeyMap() list.KeyMap {
+	km := list.DefaultKeyMap()
+	km.CursorUp = k.Up
+	km.CursorDown = k.Down
+	km.PrevPage = k.PrevPage
+	km.NextPage = k.NextPage
+	km.GoToStart = k.Top
+	km.GoToEnd = k.Bottom
+	km.ShowFullHelp = k.Help
+	km.CloseFullHelp = key.NewBinding()
+	km.Quit = k.Quit
+	km.ForceQuit = k.ForceQuit
+	return km
+}
+
+// ForState returns the bindings available in state s, grouped in columns.
+func (k KeyMap) ForState(s State) [][]key.Binding {
+	switch s {
+	case Choosing:
+		return [][]key.Binding{
+			{k.Up, k.Down, k.PrevPage, k.NextPage},
+			{k.Top, k.Bottom, k.Select, k.Sort},
+			{k.Files, k.Help, k.Quit},
+		}
+	case Browsing:
+		return [][]key.Binding{
+			{k.Up, k.Down, k.PrevPage, k.NextPage},
+			{k.Download, k.DownloadAll},
+			{k.Help, k.Quit},
+		}
+	case Downloading:
+		return [][]key.Binding{{k.Pause}, {k.Help, k.Quit}}
+	case ConfirmSource, ConfirmInstall:
+		return [][]key.Binding{{k.Confirm, k.Cancel}, {k.Help, k.Quit}}
+	case ConfirmCancel:
+		return [][]key.Binding{{k.Confirm, k.Rollback}, {k.Help, k.Quit}}
+	}
+	return [][]key.Binding{{k.Help, k.Quit}}
+}
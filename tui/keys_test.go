@@ -0,0 +1,46 @@
+package tui
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewKeyMap(t *testing.T) {
+	k, err := NewKeyMap(map[string][]string{"up": {"ctrl+p"}, "select": {"enter", " "}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if got := k.Up.Keys(); !reflect.DeepEqual(got, []string{"ctrl+p"}) {
+		t.Errorf("Expected up bound to ctrl+p, got %v", got)
+	}
+	if got := k.Select.Help().Key; got != "enter/ " {
+		t.Errorf("Expected select help to list the new keys, got %q", got)
+	}
+	if got := k.Down.Keys(); !reflect.DeepEqual(got, DefaultKeyMap().Down.Keys()) {
+		t.Errorf("Expected down to keep its default keys, got %v", got)
+	}
+}
+
+func TestNewKeyMapInvalid(t *testing.T) {
+	if _, err := NewKeyMap(map[string][]string{"jump": {"J"}}); err == nil {
+		t.Errorf("Expected an unknown action to fail")
+	}
+	if _, err := NewKeyMap(map[string][]string{"up": {}}); err == nil {
+		t.Errorf("Expected an action without keys to fail")
+	}
+	if _, err := NewKeyMap(map[string][]string{"select": {"enter", "l"}}); err == nil {
+		t.Errorf("Expected select bound to the l of next_page to fail")
+	}
+	// The files of a release are downloaded with the enter selecting it.
+	if _, err := NewKeyMap(map[string][]string{"download": {"enter", "o"}, "select": {"o"}}); err != nil {
+		t.Errorf("Unexpected error for keys of different states: %v", err)
+	}
+}
+
+func TestDefaultKeyMapConflicts(t *testing.T) {
+	k := DefaultKeyMap()
+	if err := k.checkConflicts(); err != nil {
+		t.Error(err)
+	}
+}
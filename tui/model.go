@@ -0,0 +1,653 @@
+// Package tui is the interactive version picker of go-dl, as a bubbletea
+// component. It runs on its own or is embedded as a sub-model by other
+// applications offering to install a Go version, the installation itself is
+// left to an Installer.
+package tui
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/progress"
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	titleStyle        = lipgloss.NewStyle().MarginLeft(2)
+	itemStyle         = lipgloss.NewStyle().PaddingLeft(4)
+	selectedItemStyle = lipgloss.NewStyle().PaddingLeft(2).Foreground(lipgloss.Color("027"))
+	paginationStyle   = list.DefaultStyles().PaginationStyle.PaddingLeft(4)
+	helpStyle         = list.DefaultStyles().HelpStyle.PaddingLeft(4).PaddingBottom(1)
+	quitTextStyle     = lipgloss.NewStyle().Margin(1, 0, 1, 4)
+	progressStyle     = lipgloss.NewStyle().MarginLeft(4)
+)
+
+// State is the step of the installation shown by the Model.
+type State int
+
+const (
+	Choosing State = iota
+	Downloading
+	Verifying
+	Extracting
+	Quitting
+	Completed
+	ConfirmSource
+	Activating
+	// ConfirmCancel asks what to do with an extraction the user wants to
+	// quit, the installation keeps running meanwhile.
+	ConfirmCancel
+	// ConfirmInstall shows the disk space needed before installing.
+	ConfirmInstall
+	// Browsing lists every file of the chosen release, to download them.
+	Browsing
+)
+
+// Installer installs the version chosen in the picker, one step at a time.
+type Installer interface {
+	// Prepare selects the file installing version, it reports whether the
+	// version has to be built from source, which is confirmed first.
+	Prepare(version string) (fromSource bool, err error)
+	Download(ctx context.Context) error
+	Verify(ctx context.Context) error
+	Extract(ctx context.Context) error
+
+	// TogglePause pauses or resumes the download and Paused reports whether
+	// it is paused.
+	TogglePause()
+	Paused() bool
+}
+
+// Rollbacker is implemented by the installers able to restore the
+// installation replaced by the extraction, offered when quitting during it
+// if CanRollback reports there is one.
+type Rollbacker interface {
+	CanRollback() bool
+	Rollback(ctx context.Context) error
+}
+
+// Options configures a Model.
+type Options struct {
+	// Versions are offered newest first, with their Labels, such as their
+	// channels, next to them.
+	Versions []string
+	Labels   map[string][]string
+	// Files, when set, describes the file installing each version, its
+	// size, kind and checksum are shown in the list and below it.
+	Files map[string]FileInfo
+	// Orders, when set, replace Versions with alternative orders of the
+	// versions, cycled through with the Sort key starting from the first.
+	Orders []Order
+
+	Installer Installer
+	Keys      KeyMap
+
+	// Platform, as os/arch, is shown when asking to build from source.
+	Platform string
+	// Hint returns a suggestion on how to recover from an error, if any.
+	Hint func(error) string
+	// Size formats the sizes in bytes shown with the disk space needed.
+	Size func(int64) string
+	// Standalone quits the program once the installation is over, instead
+	// of leaving it to the embedding application.
+	Standalone bool
+}
+
+// Order is a named order of the versions, such as oldest first.
+type Order struct {
+	Name     string
+	Versions []string
+}
+
+// ProgressMsg reports the progress, between 0 and 1, of the Phase of the
+// installation: Downloading, Verifying or Extracting, or Browsing for the
+// files downloaded from the release detail view. The application forwards it
+// to the Model, usually through tea.Program.Send from the progress callback
+// of the Installer. The progress of another phase than the running one is
+// ignored. Done and Total are the bytes transferred, when known, from which
+// the download shows its speed and the time left.
+type ProgressMsg struct {
+	Phase State
+	Ratio float64
+	Done  int64
+	Total int64
+}
+
+// ReconnectingMsg reports that the download stalled and is resumed, for the
+// Attempt-th time out of Max. The next ProgressMsg clears it.
+type ReconnectingMsg struct{ Attempt, Max int }
+
+// InstalledMsg is sent once Version is installed.
+type InstalledMsg struct{ Version string }
+
+// FailedMsg is sent when the installation of Version failed.
+type FailedMsg struct {
+	Version string
+	Err     error
+}
+
+// CanceledMsg is sent when the user quits the picker.
+type CanceledMsg struct{}
+
+type item string
+type doneMsg struct{}
+type statusMsg State
+type errMsg struct{ err error }
+type extractedMsg struct{}
+type rolledBackMsg struct{}
+
+// Model picks a version and follows its installation.
+type Model struct {
+	err      error
+	ctx      context.Context
+	opts     Options
+	list     list.Model
+	choice   string
+	progress progress.Model
+	status   State
+	keys     KeyMap
+	help     help.Model
+	showHelp bool
+	order    int
+	tasks    TasksMsg
+	width    int
+	spinner  spinner.Model
+	// confirmCancel is set while asking whether to quit the extraction,
+	// rollback once the user chose to restore the previous installation.
+	confirmCancel bool
+	rollback      bool
+	// space is the disk space needed by the chosen version, if known.
+	space *Space
+	// reconnecting is set while a stalled download is resumed.
+	reconnecting *ReconnectingMsg
+	// rate estimates the speed of the running phase from the bytes it
+	// transferred out of total, at the times given by now.
+	rate  RateEstimator
+	total int64
+	now   func() time.Time
+	// release lists the files of the chosen release while browsing it,
+	// downloads holds the state of their downloads by name, and fetching is
+	// set while some run.
+	release   list.Model
+	downloads map[string]string
+	fetching  bool
+}
+
+// New returns a Model offering opts.Versions, the installations run with ctx.
+func New(ctx context.Context, opts Options) Model {
+	if len(opts.Orders) > 0 {
+		opts.Versions = opts.Orders[0].Versions
+	}
+
+	const defaultWidth = 20
+
+	l := list.New(listItems(opts.Versions), itemDelegate{labels: opts.Labels, files: opts.Files, size: opts.Size}, defaultWidth, listHeight)
+	l.KeyMap = opts.Keys.listKeyMap()
+	l.AdditionalShortHelpKeys = func() []key.Binding { return []key.Binding{opts.Keys.Select} }
+	l.Title = listTitle
+	l.SetShowStatusBar(false)
+	l.SetFilteringEnabled(false)
+	l.Styles.Title = titleStyle
+	l.Styles.PaginationStyle = paginationStyle
+	l.Styles.HelpStyle = helpStyle
+
+	return Model{
+		ctx:      ctx,
+		opts:     opts,
+		list:     l,
+		progress: progress.New(progress.WithGradient("#000000", "#FFFFFF")),
+		keys:     opts.Keys,
+		help:     help.New(),
+		spinner:  spinner.New(spinner.WithSpinner(spinner.Dot)),
+		now:      time.Now,
+	}
+}
+
+const listTitle = "What version of Go do you to download?"
+
+// listHeight is the height of the versions list, unless the terminal is
+// shorter.
+const listHeight = 14
+
+func listItems(versions []string) []list.Item {
+	items := []list.Item{}
+	for _, v := range versions {
+		items = append(items, item(v))
+	}
+	return items
+}
+
+// nextOrder lists the versions in the next order, keeping the selected one.
+func (m Model) nextOrder() (Model, tea.Cmd) {
+	selected, _ := m.list.SelectedItem().(item)
+
+	m.order = (m.order + 1) % len(m.opts.Orders)
+	order := m.opts.Orders[m.order]
+	cmd := m.list.SetItems(listItems(order.Versions))
+	for i, v := range order.Versions {
+		if v == string(selected) {
+			m.list.Select(i)
+		}
+	}
+
+	m.list.Title = listTitle
+	if m.order > 0 {
+		m.list.Title += fmt.Sprintf(" (%s)", order.Name)
+	}
+	return m, cmd
+}
+
+// Err returns the error which ended the installation, if any.
+func (m Model) Err() error {
+	return m.err
+}
+
+// State returns the current step of the installation.
+func (m Model) State() State {
+	if m.confirmCancel {
+		return ConfirmCancel
+	}
+	return m.status
+}
+
+// install runs the installation steps of the chosen version up to the
+// extraction, with the spinner of the checklist.
+func (m Model) install() tea.Cmd {
+	steps := []tea.Cmd{
+		statusCmd(Downloading),
+		stepCmd(m.ctx, m.opts.Installer.Download, nil),
+		statusCmd(Verifying),
+		stepCmd(m.ctx, m.opts.Installer.Verify, nil),
+		statusCmd(Extracting),
+		stepCmd(m.ctx, m.opts.Installer.Extract, extractedMsg{}),
+	}
+	return tea.Batch(tea.Sequence(steps...), m.spinner.Tick)
+}
+
+// canRollback reports whether the installer can restore the installation
+// replaced by the extraction.
+func (m Model) canRollback() bool {
+	r, ok := m.opts.Installer.(Rollbacker)
+	return ok && r.CanRollback()
+}
+
+// activate runs the steps following the extraction.
+func (m Model) activate() tea.Cmd {
+	var steps []tea.Cmd
+	if a, ok := m.opts.Installer.(Activator); ok {
+		steps = append(steps, statusCmd(Activating), stepCmd(m.ctx, a.Activate, nil))
+	}
+	steps = append(steps, func() tea.Msg { return doneMsg{} })
+	return tea.Sequence(steps...)
+}
+
+// installing reports whether an installation step runs.
+func (m Model) installing() bool {
+	switch m.status {
+	case Downloading, Verifying, Extracting, Activating:
+		return true
+	}
+	return false
+}
+
+// stepCmd runs step and returns done once it succeeded.
+func stepCmd(ctx context.Context, step func(context.Context) error, done tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		if err := step(ctx); err != nil {
+			return errMsg{err}
+		}
+		return done
+	}
+}
+
+func statusCmd(s State) tea.Cmd {
+	return func() tea.Msg {
+		return statusMsg(s)
+	}
+}
+
+func finalPause() tea.Cmd {
+	return tea.Tick(time.Millisecond*750, func(_ time.Time) tea.Msg {
+		return nil
+	})
+}
+
+// finish sends msg to the embedding application, then quits when standalone.
+func (m Model) finish(msg tea.Msg) tea.Cmd {
+	send := func() tea.Msg { return msg }
+	if m.opts.Standalone {
+		return tea.Sequence(send, tea.Quit)
+	}
+	return send
+}
+
+func (i item) FilterValue() string { return "" }
+
+type itemDelegate struct {
+	labels map[string][]string
+	files  map[string]FileInfo
+	size   func(int64) string
+}
+
+func (d itemDelegate) Height() int                             { return 1 }
+func (d itemDelegate) Spacing() int                            { return 0 }
+func (d itemDelegate) Update(_ tea.Msg, _ *list.Model) tea.Cmd { return nil }
+func (d itemDelegate) Render(w io.Writer, m list.Model, index int, listItem list.Item) {
+	i, ok := listItem.(item)
+	if !ok {
+		return
+	}
+
+	str := fmt.Sprintf("%d. %s", index+1, i)
+	if c := d.labels[string(i)]; len(c) > 0 {
+		str += fmt.Sprintf(" (%s)", strings.Join(c, ", "))
+	}
+	if f, ok := d.files[string(i)]; ok {
+		if summary := f.summary(d.size); summary != "" {
+			str += "  " + summary
+		}
+	}
+
+	fn := itemStyle.Render
+	if index == m.Index() {
+		fn = func(s ...string) string {
+			return selectedItemStyle.Render("> " + strings.Join(s, " "))
+		}
+	}
+
+	fmt.Fprint(w, fn(str))
+}
+
+func (m Model) Init() tea.Cmd {
+	return nil
+}
+
+func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.list.SetWidth(msg.Width)
+		m.list.SetHeight(min(listHeight, max(msg.Height-1, 1)))
+		m.help.Width = max(msg.Width-progressMargin, 1)
+		m.progress.Width = progressWidth(msg.Width)
+		if m.status == Browsing {
+			m.release.SetWidth(msg.Width)
+			m.release.SetHeight(m.list.Height())
+		}
+		return m, nil
+
+	case fileStartedMsg, fileDoneMsg, filesDoneMsg:
+		return m.updateRelease(msg)
+
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keys.ForceQuit):
+			m.confirmCancel = false
+			m.status = Quitting
+			return m, m.finish(CanceledMsg{})
+
+		case key.Matches(msg, m.keys.Help):
+			m.showHelp = !m.showHelp
+			return m, nil
+
+		case m.status == Browsing:
+			return m.updateRelease(msg)
+
+		case m.confirmCancel && key.Matches(msg, m.keys.Confirm):
+			m.confirmCancel = false
+			return m, nil
+
+		case m.confirmCancel && key.Matches(msg, m.keys.Rollback):
+			m.confirmCancel = false
+			m.rollback = true
+			return m, nil
+
+		case !m.confirmCancel && m.status == Extracting && key.Matches(msg, m.keys.Quit):
+			m.confirmCancel = true
+			m.keys.Rollback.SetEnabled(m.canRollback())
+			return m, nil
+
+		case key.Matches(msg, m.keys.Quit):
+			m.confirmCancel = false
+			m.status = Quitting
+			return m, m.finish(CanceledMsg{})
+
+		case m.status == Choosing && key.Matches(msg, m.keys.Select):
+			i, ok := m.list.SelectedItem().(item)
+			if ok {
+				m.choice = string(i)
+			}
+
+			fromSource, err := m.opts.Installer.Prepare(m.choice)
+			if err != nil {
+				m.err = err
+				return m, m.finish(FailedMsg{Version: m.choice, Err: err})
+			}
+			m.space = nil
+			if space, ok := m.estimate(); ok {
+				m.space = &space
+			}
+			if fromSource {
+				m.status = ConfirmSource
+				return m, nil
+			}
+			if m.space != nil {
+				m.status = ConfirmInstall
+				return m, nil
+			}
+			return m, m.install()
+
+		case m.status == Choosing && key.Matches(msg, m.keys.Files):
+			return m.browseRelease()
+
+		case m.status == Choosing && len(m.opts.Orders) > 1 && key.Matches(msg, m.keys.Sort):
+			return m.nextOrder()
+
+		case (m.status == ConfirmSource || m.status == ConfirmInstall) && key.Matches(msg, m.keys.Confirm):
+			return m, m.install()
+
+		case (m.status == ConfirmSource || m.status == ConfirmInstall) && key.Matches(msg, m.keys.Cancel):
+			m.status = Choosing
+			return m, nil
+
+		case m.status == Downloading && key.Matches(msg, m.keys.Pause):
+			m.opts.Installer.TogglePause()
+			return m, nil
+		}
+
+	case statusMsg:
+		if m.status == State(msg) {
+			return m, nil
+		}
+		m.status = State(msg)
+		m.rate.Reset()
+		m.total = 0
+		return m, m.progress.SetPercent(0)
+
+	case errMsg:
+		if m.err != nil {
+			return m, nil
+		}
+		m.err = msg.err
+		return m, m.finish(FailedMsg{Version: m.choice, Err: msg.err})
+
+	case extractedMsg:
+		m.confirmCancel = false
+		if r, ok := m.opts.Installer.(Rollbacker); ok && m.rollback {
+			m.status = Quitting
+			return m, stepCmd(m.ctx, r.Rollback, rolledBackMsg{})
+		}
+		return m, m.activate()
+
+	case rolledBackMsg:
+		return m, m.finish(CanceledMsg{})
+
+	case doneMsg:
+		m.status = Completed
+		return m, tea.Sequence(finalPause(), m.finish(InstalledMsg{Version: m.choice}))
+
+	case ReconnectingMsg:
+		m.reconnecting = &msg
+		return m, nil
+
+	case ProgressMsg:
+		if msg.Phase != m.status {
+			return m, nil
+		}
+		var cmds []tea.Cmd
+		m.reconnecting = nil
+		if msg.Total > 0 {
+			m.rate.Observe(msg.Done, m.now())
+			m.total = msg.Total
+		}
+
+		if msg.Ratio >= 1.0 {
+			cmds = append(cmds, tea.Sequence(finalPause()))
+		}
+
+		cmds = append(cmds, m.progress.SetPercent(msg.Ratio))
+		return m, tea.Batch(cmds...)
+
+	case TasksMsg:
+		if msg.Seq > m.tasks.Seq {
+			m.tasks = msg
+		}
+		return m, nil
+
+	case spinner.TickMsg:
+		if !m.installing() {
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+
+	case progress.FrameMsg:
+		progressModel, cmd := m.progress.Update(msg)
+		m.progress = progressModel.(progress.Model)
+		return m, cmd
+
+	}
+
+	if m.status == Browsing {
+		return m.updateRelease(msg)
+	}
+	var cmd tea.Cmd
+	m.list, cmd = m.list.Update(msg)
+	return m, cmd
+}
+
+// Margins of the text and of the progress bars, see quitTextStyle and
+// progressStyle.
+const (
+	textMargin     = 4
+	progressMargin = 4
+
+	minProgressWidth = 10
+	maxProgressWidth = 80
+)
+
+// progressWidth returns the width of the progress bar in a terminal width
+// columns wide.
+func progressWidth(width int) int {
+	return min(max(width-progressMargin-2, minProgressWidth), maxProgressWidth)
+}
+
+// text renders s wrapped to the width of the terminal, once known.
+func (m Model) text(s string) string {
+	if m.width == 0 {
+		return quitTextStyle.Render(s)
+	}
+	return quitTextStyle.Width(max(m.width-textMargin, 1)).Render(s)
+}
+
+// progressView renders the progress bar, or one bar per task followed by the
+// overall progress once TasksMsg were received.
+func (m Model) progressView() string {
+	if len(m.tasks.Tasks) == 0 {
+		return progressStyle.Render(m.progress.View())
+	}
+	return tasksView(m.progress, m.width, m.tasks)
+}
+
+func (m Model) View() string {
+	if m.err != nil {
+		msg := fmt.Sprintf("something went wrong: %v", m.err)
+		if m.opts.Hint != nil {
+			if hint := m.opts.Hint(m.err); hint != "" {
+				msg += "\n" + hint
+			}
+		}
+		return m.text(msg)
+	}
+
+	if m.showHelp {
+		return lipgloss.JoinVertical(
+			lipgloss.Left,
+			m.text("Keybindings"),
+			progressStyle.Render(m.help.FullHelpView(m.keys.ForState(m.State()))),
+			"",
+		)
+	}
+
+	if m.status == ConfirmSource || m.status == ConfirmInstall {
+		question := fmt.Sprintf("Install %s? (%s/%s)", m.choice, m.keys.Confirm.Help().Key, m.keys.Cancel.Help().Key)
+		if m.status == ConfirmSource {
+			question = fmt.Sprintf(
+				"No %s archive for %s, build it from source? (%s/%s)",
+				m.opts.Platform, m.choice, m.keys.Confirm.Help().Key, m.keys.Cancel.Help().Key,
+			)
+		}
+		rows := []string{m.text(question)}
+		if m.space != nil {
+			rows = append(rows, m.spaceView())
+		}
+		return lipgloss.JoinVertical(lipgloss.Left, append(rows, "")...)
+	}
+
+	if m.confirmCancel {
+		question := fmt.Sprintf(
+			"%s is replacing the installation, quitting now can leave the system without a working Go. Finish the installation (%s)",
+			m.choice, m.keys.Confirm.Help().Key,
+		)
+		if m.canRollback() {
+			question += fmt.Sprintf(", roll it back once extracted (%s)", m.keys.Rollback.Help().Key)
+		}
+		question += fmt.Sprintf(" or quit anyway (%s)?", m.keys.Quit.Help().Key)
+		return lipgloss.JoinVertical(lipgloss.Left, m.text(question), "")
+	}
+
+	if m.installing() || m.status == Completed {
+		title := fmt.Sprintf("Installing %s", m.choice)
+		if m.status == Downloading && m.opts.Installer.Paused() {
+			title = fmt.Sprintf("Paused: %s (press %s to resume)", m.choice, m.keys.Pause.Help().Key)
+		} else if m.status == Downloading && m.reconnecting != nil {
+			title = fmt.Sprintf("Installing %s: stalled, reconnecting… (%d/%d)", m.choice, m.reconnecting.Attempt, m.reconnecting.Max)
+		}
+		if m.status == Completed {
+			title = fmt.Sprintf("Completed download and extraction of %s !", m.choice)
+		}
+		return m.checklistView(title)
+	}
+
+	if m.status == Quitting {
+		return m.text("exiting..")
+	}
+
+	if m.status == Browsing {
+		return m.releaseView()
+	}
+
+	if details := m.details(); details != "" {
+		return "\n" + m.list.View() + "\n" + details + "\n"
+	}
+	return "\n" + m.list.View()
+}
@@ -0,0 +1,330 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type fakeInstaller struct {
+	prepared   string
+	fromSource bool
+	err        error
+	paused     bool
+}
+
+func (f *fakeInstaller) Prepare(version string) (bool, error) {
+	f.prepared = version
+	return f.fromSource, f.err
+}
+
+func (f *fakeInstaller) Download(context.Context) error { return nil }
+func (f *fakeInstaller) Verify(context.Context) error   { return nil }
+func (f *fakeInstaller) Extract(context.Context) error  { return nil }
+func (f *fakeInstaller) TogglePause()                   { f.paused = !f.paused }
+func (f *fakeInstaller) Paused() bool                   { return f.paused }
+
+func newTestModel(installer Installer) Model {
+	return New(context.Background(), Options{
+		Versions:  []string{"go1.22.1", "go1.21.10"},
+		Installer: installer,
+		Keys:      DefaultKeyMap(),
+		Platform:  "linux/amd64",
+	})
+}
+
+func update(t *testing.T, m Model, msg tea.Msg) (Model, tea.Cmd) {
+	t.Helper()
+
+	updated, cmd := m.Update(msg)
+	return updated.(Model), cmd
+}
+
+func TestModelInstall(t *testing.T) {
+	installer := &fakeInstaller{}
+	m := newTestModel(installer)
+
+	m, cmd := update(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	if installer.prepared != "go1.22.1" || cmd == nil {
+		t.Fatalf("Expected go1.22.1 to be installed, prepared %q", installer.prepared)
+	}
+
+	m, _ = update(t, m, statusMsg(Downloading))
+	m, _ = update(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("p")})
+	if !installer.paused {
+		t.Errorf("Expected p to pause the download")
+	}
+
+	m, _ = update(t, m, doneMsg{})
+	if m.State() != Completed {
+		t.Errorf("Expected the installation to be completed, got state %d", m.State())
+	}
+}
+
+func TestModelReconnecting(t *testing.T) {
+	m := newTestModel(&fakeInstaller{})
+
+	m, _ = update(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	m, _ = update(t, m, statusMsg(Downloading))
+	m, _ = update(t, m, ReconnectingMsg{Attempt: 1, Max: 3})
+	if view := m.View(); !strings.Contains(view, "stalled, reconnecting… (1/3)") {
+		t.Errorf("Expected the reconnection to be shown, got %q", view)
+	}
+
+	m, _ = update(t, m, ProgressMsg{Phase: Downloading, Ratio: 0.5})
+	if view := m.View(); strings.Contains(view, "reconnecting") {
+		t.Errorf("Expected the progress to clear the reconnection, got %q", view)
+	}
+}
+
+func TestModelProgressRate(t *testing.T) {
+	m := newTestModel(&fakeInstaller{})
+	now := time.Unix(0, 0)
+	m.now = func() time.Time { return now }
+
+	m, _ = update(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	m, _ = update(t, m, statusMsg(Downloading))
+	m, _ = update(t, m, ProgressMsg{Phase: Downloading, Ratio: 0.1, Done: 1000, Total: 10000})
+	if view := m.View(); strings.Contains(view, "left") {
+		t.Errorf("Expected no speed before a second sample, got %q", view)
+	}
+
+	now = now.Add(time.Second)
+	m, _ = update(t, m, ProgressMsg{Phase: Downloading, Ratio: 0.2, Done: 2000, Total: 10000})
+	if view := m.View(); !strings.Contains(view, "1000 B/s, 8s left") {
+		t.Errorf("Expected the speed and the time left, got %q", view)
+	}
+
+	m, _ = update(t, m, statusMsg(Verifying))
+	if view := m.View(); strings.Contains(view, "left") {
+		t.Errorf("Expected the speed cleared with the step, got %q", view)
+	}
+}
+
+func TestModelProgressPhase(t *testing.T) {
+	m := newTestModel(&fakeInstaller{})
+
+	m, _ = update(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	m, _ = update(t, m, statusMsg(Downloading))
+	m, _ = update(t, m, ProgressMsg{Phase: Downloading, Ratio: 0.5})
+
+	// The extraction progress sent before the step starts is ignored.
+	m, _ = update(t, m, ProgressMsg{Phase: Extracting, Ratio: 0.9})
+	if got := m.progress.Percent(); got != 0.5 {
+		t.Errorf("Expected the progress of another phase to be ignored, got %v", got)
+	}
+
+	m, _ = update(t, m, statusMsg(Extracting))
+	if got := m.progress.Percent(); got != 0 {
+		t.Errorf("Expected the bar to restart with the step, got %v", got)
+	}
+	m, _ = update(t, m, ProgressMsg{Phase: Extracting, Ratio: 0.9})
+	if got := m.progress.Percent(); got != 0.9 {
+		t.Errorf("Expected the progress of the extraction, got %v", got)
+	}
+}
+
+func TestModelConfirmSource(t *testing.T) {
+	m := newTestModel(&fakeInstaller{fromSource: true})
+
+	m, _ = update(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.State() != ConfirmSource {
+		t.Fatalf("Expected the build from source to be confirmed first, got state %d", m.State())
+	}
+
+	m, _ = update(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	if m.State() != Choosing {
+		t.Errorf("Expected n to go back to the list, got state %d", m.State())
+	}
+}
+
+func TestModelMessages(t *testing.T) {
+	errBlocked := errors.New("blocked")
+	m := newTestModel(&fakeInstaller{err: errBlocked})
+
+	m, cmd := update(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	if msg, ok := cmd().(FailedMsg); !ok || msg.Version != "go1.22.1" || !errors.Is(msg.Err, errBlocked) {
+		t.Errorf("Expected a FailedMsg for go1.22.1, got %#v", cmd())
+	}
+	if !errors.Is(m.Err(), errBlocked) {
+		t.Errorf("Expected the error to be kept, got %v", m.Err())
+	}
+
+	_, cmd = update(t, newTestModel(&fakeInstaller{}), tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+	if _, ok := cmd().(CanceledMsg); !ok {
+		t.Errorf("Expected a CanceledMsg, got %#v", cmd())
+	}
+}
+
+func TestModelOrders(t *testing.T) {
+	installer := &fakeInstaller{}
+	m := New(context.Background(), Options{
+		Orders: []Order{
+			{Name: "newest", Versions: []string{"go1.22.1", "go1.21.10", "go1.21.0"}},
+			{Name: "oldest", Versions: []string{"go1.21.0", "go1.21.10", "go1.22.1"}},
+		},
+		Installer: installer,
+		Keys:      DefaultKeyMap(),
+	})
+
+	m, _ = update(t, m, tea.KeyMsg{Type: tea.KeyDown})
+	m, _ = update(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("s")})
+	if !strings.Contains(m.list.Title, "oldest") {
+		t.Errorf("Expected the title to show the order, got %q", m.list.Title)
+	}
+
+	m, _ = update(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	if installer.prepared != "go1.21.10" {
+		t.Errorf("Expected the selection to follow the new order, prepared %q", installer.prepared)
+	}
+}
+
+func TestModelResize(t *testing.T) {
+	installer := &fakeInstaller{fromSource: true}
+	m := newTestModel(installer)
+
+	const width = 30
+	m, _ = update(t, m, tea.WindowSizeMsg{Width: width, Height: 8})
+	if m.progress.Width > width-progressMargin {
+		t.Errorf("Expected the progress bar to fit in %d columns, got %d", width, m.progress.Width)
+	}
+
+	fits := func(view string) {
+		t.Helper()
+		for _, line := range strings.Split(view, "\n") {
+			if w := lipgloss.Width(line); w > width {
+				t.Errorf("Expected the view to fit in %d columns, got %d for %q", width, w, line)
+			}
+		}
+	}
+
+	m, _ = update(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	fits(m.View())
+
+	m, _ = update(t, m, statusMsg(Downloading))
+	fits(m.View())
+
+	m, _ = update(t, m, errMsg{errors.New("unable to resume the download of go1.22.1.linux-amd64.tar.gz: 416 Requested Range Not Satisfiable")})
+	fits(m.View())
+
+	m, _ = update(t, m, tea.WindowSizeMsg{Width: 200, Height: 50})
+	if m.progress.Width != maxProgressWidth {
+		t.Errorf("Expected the progress bar to stop growing at %d, got %d", maxProgressWidth, m.progress.Width)
+	}
+}
+
+type rollbackInstaller struct {
+	fakeInstaller
+	rolledBack bool
+	// none is set when there is no installation to restore.
+	none bool
+}
+
+func (r *rollbackInstaller) CanRollback() bool {
+	return !r.none
+}
+
+func (r *rollbackInstaller) Rollback(context.Context) error {
+	r.rolledBack = true
+	return nil
+}
+
+func TestModelConfirmCancel(t *testing.T) {
+	installer := &rollbackInstaller{}
+	m := newTestModel(installer)
+	quit := tea.KeyMsg{Type: tea.KeyCtrlC}
+
+	m, _ = update(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	m, _ = update(t, m, statusMsg(Extracting))
+	m, cmd := update(t, m, quit)
+	if m.State() != ConfirmCancel || cmd != nil {
+		t.Fatalf("Expected quitting the extraction to be confirmed first, got state %d", m.State())
+	}
+	if view := m.View(); !strings.Contains(view, "without a working Go") {
+		t.Errorf("Expected the consequences to be explained, got %q", view)
+	}
+
+	m, _ = update(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+	if m.State() != Extracting {
+		t.Errorf("Expected y to finish the installation, got state %d", m.State())
+	}
+
+	m, _ = update(t, m, quit)
+	m, _ = update(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	m, cmd = update(t, m, extractedMsg{})
+	if msg := cmd(); msg != (rolledBackMsg{}) || !installer.rolledBack {
+		t.Fatalf("Expected the installation to be rolled back once extracted, got %#v", msg)
+	}
+	if _, cmd = update(t, m, rolledBackMsg{}); cmd == nil {
+		t.Fatalf("Expected the picker to finish")
+	}
+	if _, ok := cmd().(CanceledMsg); !ok {
+		t.Errorf("Expected a CanceledMsg once rolled back")
+	}
+
+	// Without an installation to restore, r isn't offered.
+	installer = &rollbackInstaller{none: true}
+	m = newTestModel(installer)
+	m, _ = update(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	m, _ = update(t, m, statusMsg(Extracting))
+	m, _ = update(t, m, quit)
+	if view := m.View(); strings.Contains(view, "roll it back") {
+		t.Errorf("Expected no rollback to be offered, got %q", view)
+	}
+	m, _ = update(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	if m.State() != ConfirmCancel || m.rollback {
+		t.Errorf("Expected r to be ignored, got state %d", m.State())
+	}
+
+	m = newTestModel(&fakeInstaller{})
+	m, _ = update(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	m, _ = update(t, m, statusMsg(Extracting))
+	m, _ = update(t, m, quit)
+	m, cmd = update(t, m, quit)
+	if m.State() != Quitting || cmd == nil {
+		t.Errorf("Expected quitting again to abort anyway, got state %d", m.State())
+	}
+}
+
+func TestModelFiles(t *testing.T) {
+	m := New(context.Background(), Options{
+		Versions: []string{"go1.22.1", "go1.21.10"},
+		Files: map[string]FileInfo{
+			"go1.22.1": {Name: "go1.22.1.linux-amd64.tar.gz", Size: 68988925, Kind: "archive", Algorithm: "sha256", Sum: "aab8e15785c997ae20f9c88422ee35d962c4562212bb0f879d052a35c8307c7f"},
+		},
+		Installer: &fakeInstaller{},
+		Keys:      DefaultKeyMap(),
+	})
+
+	view := m.View()
+	for _, want := range []string{
+		"go1.22.1  68988925 B archive sha256:aab8e157",
+		"go1.22.1.linux-amd64.tar.gz, 68988925 B (68988925 bytes)",
+		"sha256 aab8e15785c997ae20f9c88422ee35d962c4562212bb0f879d052a35c8307c7f",
+	} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected %q in the view, got %q", want, view)
+		}
+	}
+
+	m, _ = update(t, m, tea.KeyMsg{Type: tea.KeyDown})
+	if view := m.View(); strings.Contains(view, "go1.22.1.linux-amd64.tar.gz") {
+		t.Errorf("Expected no details for a version without file, got %q", view)
+	}
+}
+
+func TestModelForceQuit(t *testing.T) {
+	m := newTestModel(&rollbackInstaller{})
+	m, _ = update(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	m, _ = update(t, m, statusMsg(Extracting))
+
+	m, cmd := update(t, m, tea.KeyMsg{Type: tea.KeyCtrlBackslash})
+	if m.State() != Quitting || cmd == nil {
+		t.Fatalf("Expected force quit to skip the confirmation, got state %d", m.State())
+	}
+}
@@ -0,0 +1,74 @@
+package tui
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Pager shows a long text in a scrollable pane.
+type Pager struct {
+	title    string
+	content  string
+	viewport viewport.Model
+	keys     KeyMap
+	help     help.Model
+	ready    bool
+}
+
+// NewPager returns a Pager showing content under title.
+func NewPager(title, content string, keys KeyMap) Pager {
+	return Pager{title: title, content: content, keys: keys, help: help.New()}
+}
+
+func (p Pager) Init() tea.Cmd {
+	return nil
+}
+
+func (p Pager) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if key.Matches(msg, p.keys.Quit) || key.Matches(msg, p.keys.ForceQuit) {
+			return p, tea.Quit
+		}
+
+	case tea.WindowSizeMsg:
+		height := msg.Height - lipgloss.Height(p.headerView()) - lipgloss.Height(p.footerView())
+		if !p.ready {
+			p.viewport = viewport.New(msg.Width, height)
+			p.viewport.KeyMap.Up = p.keys.Up
+			p.viewport.KeyMap.Down = p.keys.Down
+			p.viewport.KeyMap.PageUp = p.keys.PrevPage
+			p.viewport.KeyMap.PageDown = p.keys.NextPage
+			p.viewport.SetContent(p.content)
+			p.ready = true
+		} else {
+			p.viewport.Width = msg.Width
+			p.viewport.Height = height
+		}
+	}
+
+	var cmd tea.Cmd
+	p.viewport, cmd = p.viewport.Update(msg)
+	return p, cmd
+}
+
+func (p Pager) headerView() string {
+	return titleStyle.Render(p.title)
+}
+
+func (p Pager) footerView() string {
+	return helpStyle.Render(fmt.Sprintf("%3.f%%  ", p.viewport.ScrollPercent()*100) +
+		p.help.ShortHelpView([]key.Binding{p.keys.Up, p.keys.Down, p.keys.PrevPage, p.keys.NextPage, p.keys.Quit}))
+}
+
+func (p Pager) View() string {
+	if !p.ready {
+		return ""
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, p.headerView(), p.viewport.View(), p.footerView())
+}
@@ -0,0 +1,74 @@
+package tui
+
+import (
+	"math"
+	"time"
+)
+
+// DefaultHalfLife is the half-life of the rate of a RateEstimator without
+// one.
+const DefaultHalfLife = 2 * time.Second
+
+// minRateSample is the shortest time a rate sample spans, the chunks read
+// sooner are accumulated into the next sample.
+const minRateSample = 200 * time.Millisecond
+
+// RateEstimator smooths the transfer rate of a download with an exponentially
+// weighted moving average, so the speed and the time left don't jump with
+// each chunk read. The zero value is ready to use.
+type RateEstimator struct {
+	// HalfLife is the age at which a sample weighs half as much in the rate,
+	// DefaultHalfLife when zero.
+	HalfLife time.Duration
+
+	rate float64
+	done int64
+	last time.Time
+}
+
+// Observe records done bytes transferred at now. A count lower than the
+// previous one, such as a download starting over, resets the estimate.
+func (e *RateEstimator) Observe(done int64, now time.Time) {
+	if e.last.IsZero() || done < e.done {
+		e.rate, e.done, e.last = 0, done, now
+		return
+	}
+	elapsed := now.Sub(e.last)
+	if elapsed < minRateSample {
+		return
+	}
+
+	sample := float64(done-e.done) / elapsed.Seconds()
+	halfLife := e.HalfLife
+	if halfLife <= 0 {
+		halfLife = DefaultHalfLife
+	}
+	if e.rate == 0 {
+		e.rate = sample
+	} else {
+		alpha := 1 - math.Exp2(-elapsed.Seconds()/halfLife.Seconds())
+		e.rate += alpha * (sample - e.rate)
+	}
+	e.done, e.last = done, now
+}
+
+// Reset forgets the transfer observed so far.
+func (e *RateEstimator) Reset() {
+	e.rate, e.done, e.last = 0, 0, time.Time{}
+}
+
+// Rate returns the smoothed rate in bytes per second, zero until a sample
+// was taken.
+func (e *RateEstimator) Rate() float64 {
+	return e.rate
+}
+
+// Remaining returns the time left to transfer total bytes at the current
+// rate, false while the rate is unknown.
+func (e *RateEstimator) Remaining(total int64) (time.Duration, bool) {
+	if e.rate <= 0 || total <= 0 {
+		return 0, false
+	}
+	left := float64(max(total-e.done, 0)) / e.rate
+	return time.Duration(left * float64(time.Second)), true
+}
@@ -0,0 +1,48 @@
+package tui
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRateEstimator(t *testing.T) {
+	var e RateEstimator
+	start := time.Unix(0, 0)
+	if _, ok := e.Remaining(100); ok {
+		t.Errorf("Expected no time left before any sample")
+	}
+
+	// 1 MB/s, read in 32 KB chunks every 32ms: the chunks shorter than a
+	// sample are accumulated.
+	const chunk = 32 * 1000
+	at := start
+	done := int64(0)
+	for range 100 {
+		done += chunk
+		at = at.Add(32 * time.Millisecond)
+		e.Observe(done, at)
+	}
+	if got := e.Rate(); math.Abs(got-1e6) > 1e3 {
+		t.Errorf("Expected about 1 MB/s, got %v", got)
+	}
+
+	// A burst moves the rate toward the new speed without jumping to it.
+	e.Observe(done+4e6, at.Add(time.Second))
+	if got := e.Rate(); got <= 1e6 || got >= 5e6 {
+		t.Errorf("Expected the rate smoothed between 1 and 5 MB/s, got %v", got)
+	}
+
+	var steady RateEstimator
+	steady.Observe(0, start)
+	steady.Observe(2e6, start.Add(time.Second))
+	if left, ok := steady.Remaining(10e6); !ok || left != 4*time.Second {
+		t.Errorf("Expected 4s left, got %v (%v)", left, ok)
+	}
+
+	// The download starting over resets the estimate.
+	steady.Observe(0, start.Add(2*time.Second))
+	if steady.Rate() != 0 {
+		t.Errorf("Expected the rate reset, got %v", steady.Rate())
+	}
+}
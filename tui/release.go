@@ -0,0 +1,187 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ReleaseBrowser is implemented by the installers able to download any file
+// of a release, listed with their download actions in the release detail
+// view, for mirroring whole releases.
+type ReleaseBrowser interface {
+	// ReleaseFiles lists the files of version.
+	ReleaseFiles(version string) []FileInfo
+	// DownloadFile downloads and verifies the file name of version, it
+	// returns where the file was saved.
+	DownloadFile(ctx context.Context, version, name string) (string, error)
+}
+
+// fileStartedMsg and fileDoneMsg report the download of a file of the
+// release detail view, filesDoneMsg the end of the downloads started
+// together.
+type fileStartedMsg struct{ name string }
+type fileDoneMsg struct {
+	name string
+	path string
+	err  error
+}
+type filesDoneMsg struct{}
+
+type fileItem FileInfo
+
+func (i fileItem) FilterValue() string { return "" }
+
+// fileDelegate renders the files of a release with the state of their
+// download.
+type fileDelegate struct {
+	size      func(int64) string
+	downloads map[string]string
+}
+
+func (d fileDelegate) Height() int                             { return 1 }
+func (d fileDelegate) Spacing() int                            { return 0 }
+func (d fileDelegate) Update(_ tea.Msg, _ *list.Model) tea.Cmd { return nil }
+func (d fileDelegate) Render(w io.Writer, m list.Model, index int, listItem list.Item) {
+	f, ok := listItem.(fileItem)
+	if !ok {
+		return
+	}
+
+	str := fmt.Sprintf("%d. %s", index+1, f.Name)
+	if f.Platform != "" {
+		str += "  " + f.Platform
+	}
+	if summary := FileInfo(f).summary(d.size); summary != "" {
+		str += "  " + summary
+	}
+	if status := d.downloads[f.Name]; status != "" {
+		str += "  " + status
+	}
+
+	fn := itemStyle.Render
+	if index == m.Index() {
+		fn = func(s ...string) string {
+			return selectedItemStyle.Render("> " + strings.Join(s, " "))
+		}
+	}
+	fmt.Fprint(w, fn(str))
+}
+
+// browseRelease opens the detail view of the selected version, when the
+// installer can download its files.
+func (m Model) browseRelease() (Model, tea.Cmd) {
+	b, ok := m.opts.Installer.(ReleaseBrowser)
+	if !ok {
+		return m, nil
+	}
+	i, ok := m.list.SelectedItem().(item)
+	if !ok {
+		return m, nil
+	}
+
+	var items []list.Item
+	for _, f := range b.ReleaseFiles(string(i)) {
+		items = append(items, fileItem(f))
+	}
+	m.choice = string(i)
+	if m.downloads == nil {
+		m.downloads = map[string]string{}
+	}
+
+	l := list.New(items, fileDelegate{size: m.opts.Size, downloads: m.downloads}, m.list.Width(), m.list.Height())
+	l.KeyMap = m.keys.listKeyMap()
+	l.AdditionalShortHelpKeys = func() []key.Binding { return []key.Binding{m.keys.Download, m.keys.DownloadAll} }
+	l.Title = fmt.Sprintf("Files of %s", m.choice)
+	l.SetShowStatusBar(false)
+	l.SetFilteringEnabled(false)
+	l.Styles.Title = titleStyle
+	l.Styles.PaginationStyle = paginationStyle
+	l.Styles.HelpStyle = helpStyle
+	m.release = l
+	m.status = Browsing
+	return m, nil
+}
+
+// downloadFiles downloads the files one after the other, it does nothing
+// while other downloads run.
+func (m Model) downloadFiles(names ...string) (Model, tea.Cmd) {
+	b, ok := m.opts.Installer.(ReleaseBrowser)
+	if !ok || m.fetching || len(names) == 0 {
+		return m, nil
+	}
+	m.fetching = true
+
+	version := m.choice
+	var steps []tea.Cmd
+	for _, name := range names {
+		steps = append(steps,
+			func() tea.Msg { return fileStartedMsg{name} },
+			func() tea.Msg {
+				path, err := b.DownloadFile(m.ctx, version, name)
+				return fileDoneMsg{name: name, path: path, err: err}
+			},
+		)
+	}
+	steps = append(steps, func() tea.Msg { return filesDoneMsg{} })
+	return m, tea.Sequence(steps...)
+}
+
+// updateRelease handles the messages of the release detail view.
+func (m Model) updateRelease(msg tea.Msg) (Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keys.Quit):
+			m.status = Choosing
+			return m, nil
+
+		case key.Matches(msg, m.keys.Download):
+			if f, ok := m.release.SelectedItem().(fileItem); ok {
+				return m.downloadFiles(f.Name)
+			}
+			return m, nil
+
+		case key.Matches(msg, m.keys.DownloadAll):
+			var names []string
+			for _, i := range m.release.Items() {
+				names = append(names, i.(fileItem).Name)
+			}
+			return m.downloadFiles(names...)
+		}
+
+	case fileStartedMsg:
+		m.downloads[msg.name] = "downloading…"
+		return m, m.progress.SetPercent(0)
+
+	case fileDoneMsg:
+		m.downloads[msg.name] = "saved to " + msg.path
+		if msg.err != nil {
+			m.downloads[msg.name] = shortStyle.Render("failed: " + msg.err.Error())
+		}
+		return m, nil
+
+	case filesDoneMsg:
+		m.fetching = false
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.release, cmd = m.release.Update(msg)
+	return m, cmd
+}
+
+// releaseView renders the files of the release, with the progress of the
+// running download.
+func (m Model) releaseView() string {
+	view := "\n" + m.release.View()
+	if m.fetching {
+		view += "\n" + progressStyle.Render(m.progress.View()) + "\n"
+	}
+	return view
+}
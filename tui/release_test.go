@@ -0,0 +1,67 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type fakeBrowser struct {
+	fakeInstaller
+	files []FileInfo
+}
+
+func (f *fakeBrowser) ReleaseFiles(string) []FileInfo { return f.files }
+
+func (f *fakeBrowser) DownloadFile(_ context.Context, _, name string) (string, error) {
+	return name, nil
+}
+
+func TestModelRelease(t *testing.T) {
+	m := newTestModel(&fakeBrowser{files: []FileInfo{
+		{Name: "go1.22.1.linux-amd64.tar.gz", Platform: "linux/amd64", Kind: "archive", Algorithm: "sha256", Sum: "aab8e15785c997ae"},
+		{Name: "go1.22.1.src.tar.gz", Kind: "source"},
+	}})
+	m, _ = update(t, m, tea.WindowSizeMsg{Width: 100, Height: 20})
+
+	m, _ = update(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("i")})
+	if m.State() != Browsing {
+		t.Fatalf("Expected the files of the release to be listed, got state %d", m.State())
+	}
+	if view := m.View(); !strings.Contains(view, "Files of go1.22.1") || !strings.Contains(view, "go1.22.1.linux-amd64.tar.gz  linux/amd64  archive sha256:aab8e157") ||
+		!strings.Contains(view, "go1.22.1.src.tar.gz  source") {
+		t.Errorf("Expected every file of the release, got %q", view)
+	}
+
+	m, cmd := update(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")})
+	if cmd == nil || !m.fetching {
+		t.Fatalf("Expected a to download every file")
+	}
+	if _, cmd = update(t, m, tea.KeyMsg{Type: tea.KeyEnter}); cmd != nil {
+		t.Errorf("Expected no other download while some run")
+	}
+
+	m, _ = update(t, m, fileStartedMsg{"go1.22.1.linux-amd64.tar.gz"})
+	m, _ = update(t, m, fileDoneMsg{name: "go1.22.1.src.tar.gz", err: errors.New("boom")})
+	view := m.View()
+	if !strings.Contains(view, "downloading…") || !strings.Contains(view, "failed: boom") {
+		t.Errorf("Expected the state of the downloads, got %q", view)
+	}
+	m, _ = update(t, m, fileDoneMsg{name: "go1.22.1.linux-amd64.tar.gz", path: "./go1.22.1.linux-amd64.tar.gz"})
+	m, _ = update(t, m, filesDoneMsg{})
+	if view := m.View(); !strings.Contains(view, "saved to ./go1.22.1.linux-amd64.tar.gz") || m.fetching {
+		t.Errorf("Expected the downloads to be over, got %q", view)
+	}
+
+	m, _ = update(t, m, tea.KeyMsg{Type: tea.KeyEsc})
+	if m.State() != Choosing {
+		t.Errorf("Expected esc to go back to the versions, got state %d", m.State())
+	}
+
+	if m, _ := update(t, newTestModel(&fakeInstaller{}), tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("i")}); m.State() != Choosing {
+		t.Errorf("Expected the files to be offered only by installers downloading them, got state %d", m.State())
+	}
+}
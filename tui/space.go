@@ -0,0 +1,90 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// SpaceEstimator is implemented by the installers able to tell the disk space
+// the prepared installation needs, which is shown for confirmation before
+// installing.
+type SpaceEstimator interface {
+	Space() (Space, error)
+}
+
+// Space is the disk space an installation needs.
+type Space struct {
+	// Download is the size of the archive, Extracted an estimate of the size
+	// of the installation.
+	Download  int64
+	Extracted int64
+	// Volumes are the file systems written by the installation.
+	Volumes []Volume
+	// Metered is set when the download goes over a metered connection,
+	// confirming the installation allows it.
+	Metered bool
+}
+
+// Volume is a file system written by the installation, such as the one of
+// the temporary files or of the installation.
+type Volume struct {
+	Name string
+	Path string
+	// Free is negative when unknown.
+	Free   int64
+	Needed int64
+}
+
+// Short reports whether the volume lacks the space needed.
+func (v Volume) Short() bool {
+	return v.Free >= 0 && v.Free < v.Needed
+}
+
+// MeteredWarning is shown along with the space of the installations
+// downloaded over a metered connection.
+const MeteredWarning = "The connection is metered, confirming downloads over it anyway"
+
+var shortStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+
+// estimate returns the space needed by the prepared installation, if the
+// installer tells it.
+func (m Model) estimate() (Space, bool) {
+	e, ok := m.opts.Installer.(SpaceEstimator)
+	if !ok {
+		return Space{}, false
+	}
+	space, err := e.Space()
+	return space, err == nil
+}
+
+// size formats n bytes with the Size option.
+func (m Model) size(n int64) string {
+	return formatSize(m.opts.Size, n)
+}
+
+// spaceView renders the sizes of the installation and the space free on
+// each volume, the volumes lacking space in red.
+func (m Model) spaceView() string {
+	rows := []string{fmt.Sprintf("Download %s, about %s once extracted", m.size(m.space.Download), m.size(m.space.Extracted))}
+	width := 0
+	for _, v := range m.space.Volumes {
+		width = max(width, len(v.Name))
+	}
+	for _, v := range m.space.Volumes {
+		free := "unknown"
+		if v.Free >= 0 {
+			free = m.size(v.Free)
+		}
+		row := fmt.Sprintf("%-*s %s: %s free, %s needed", width, v.Name, v.Path, free, m.size(v.Needed))
+		if v.Short() {
+			row = shortStyle.Render(row + " (not enough space)")
+		}
+		rows = append(rows, row)
+	}
+	if m.space.Metered {
+		rows = append(rows, shortStyle.Render(MeteredWarning))
+	}
+	return progressStyle.Render(strings.Join(rows, "\n"))
+}
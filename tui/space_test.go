@@ -0,0 +1,64 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type spaceInstaller struct {
+	fakeInstaller
+	space Space
+}
+
+func (s *spaceInstaller) Space() (Space, error) { return s.space, nil }
+
+func TestModelConfirmSpace(t *testing.T) {
+	installer := &spaceInstaller{space: Space{
+		Download:  68,
+		Extracted: 230,
+		Volumes: []Volume{
+			{Name: "download", Path: "/tmp", Free: 1000, Needed: 68},
+			{Name: "installation", Path: "/usr/local", Free: 100, Needed: 230},
+		},
+	}}
+	m := newTestModel(installer)
+
+	m, cmd := update(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.State() != ConfirmInstall || cmd != nil {
+		t.Fatalf("Expected the disk space to be confirmed first, got state %d", m.State())
+	}
+
+	view := m.View()
+	for _, want := range []string{"Download 68 B, about 230 B once extracted", "/tmp: 1000 B free, 68 B needed", "/usr/local: 100 B free, 230 B needed (not enough space)"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected %q in the confirmation, got %q", want, view)
+		}
+	}
+	if strings.Contains(view, "/tmp: 1000 B free, 68 B needed (not enough space)") {
+		t.Errorf("Expected only the volume lacking space to be flagged, got %q", view)
+	}
+	if strings.Contains(view, MeteredWarning) {
+		t.Errorf("Expected no metered warning, got %q", view)
+	}
+
+	if _, cmd = update(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")}); cmd == nil {
+		t.Errorf("Expected y to start the installation")
+	}
+	if m, _ = update(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")}); m.State() != Choosing {
+		t.Errorf("Expected n to go back to the list, got state %d", m.State())
+	}
+}
+
+func TestModelConfirmMetered(t *testing.T) {
+	m := newTestModel(&spaceInstaller{space: Space{Download: 68, Extracted: 230, Metered: true}})
+
+	m, _ = update(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.State() != ConfirmInstall {
+		t.Fatalf("Expected the installation to be confirmed first, got state %d", m.State())
+	}
+	if view := m.View(); !strings.Contains(view, MeteredWarning) {
+		t.Errorf("Expected the metered connection to be shown, got %q", view)
+	}
+}
@@ -0,0 +1,224 @@
+// Package versions provides helpers to compare Go release versions and match
+// them against constraints such as "~1.21" or ">=1.21 <1.23".
+//
+// Versions may be written with or without the "go" prefix, all comparisons
+// are delegated to go/version.
+package versions
+
+import (
+	"fmt"
+	"go/version"
+	"strings"
+)
+
+// Normalize adds the "go" prefix to v when missing.
+func Normalize(v string) string {
+	v = strings.TrimSpace(v)
+	if v == "" || strings.HasPrefix(v, "go") {
+		return v
+	}
+	return "go" + v
+}
+
+// IsValid reports whether v is a valid Go version.
+func IsValid(v string) bool {
+	return version.IsValid(Normalize(v))
+}
+
+// Compare returns -1, 0, or +1 depending on whether a < b, a == b, or a > b.
+func Compare(a, b string) int {
+	return version.Compare(Normalize(a), Normalize(b))
+}
+
+// IsNewer reports whether a is a newer release than b.
+func IsNewer(a, b string) bool {
+	return Compare(a, b) > 0
+}
+
+type operator string
+
+const (
+	opEqual        operator = "="
+	opNotEqual     operator = "!="
+	opGreater      operator = ">"
+	opGreaterEqual operator = ">="
+	opLess         operator = "<"
+	opLessEqual    operator = "<="
+	opTilde        operator = "~"
+	opCaret        operator = "^"
+	opSeries       operator = ""
+)
+
+type term struct {
+	op      operator
+	version string
+}
+
+func (t term) check(v string) bool {
+	c := Compare(v, t.version)
+
+	switch t.op {
+	case opEqual:
+		return c == 0
+	case opNotEqual:
+		return c != 0
+	case opGreater:
+		return c > 0
+	case opGreaterEqual:
+		return c >= 0
+	case opLess:
+		return c < 0
+	case opLessEqual:
+		return c <= 0
+	case opTilde:
+		return c >= 0 && Compare(v, nextMinor(t.version)) < 0
+	case opCaret:
+		return c >= 0 && Compare(v, nextMajor(t.version)) < 0
+	case opSeries:
+		return version.Lang(Normalize(v)) == t.version
+	}
+	return false
+}
+
+func (t term) String() string {
+	if t.op == opSeries {
+		return strings.TrimPrefix(t.version, "go")
+	}
+	return string(t.op) + strings.TrimPrefix(t.version, "go")
+}
+
+// Constraint is a set of alternatives separated by "||", each alternative
+// being a list of terms that all have to be satisfied.
+type Constraint struct {
+	alternatives [][]term
+}
+
+// ParseConstraint parses a version constraint.
+//
+// Supported forms are exact versions ("1.22.1", "=go1.22.1"), comparisons
+// (">=1.21", "<1.23", "!=1.22.0"), tilde ranges allowing patch releases
+// ("~1.21", "~1.21.3"), caret ranges allowing minor releases ("^1.21") and
+// bare language versions that match a whole minor series ("1.21") or
+// major series ("1").
+// Terms separated by spaces or commas are combined with AND, "||" combines
+// alternatives with OR.
+func ParseConstraint(s string) (Constraint, error) {
+	var c Constraint
+
+	if strings.TrimSpace(s) == "" {
+		return c, fmt.Errorf("empty version constraint")
+	}
+
+	for _, alt := range strings.Split(s, "||") {
+		var terms []term
+
+		fields := strings.FieldsFunc(alt, func(r rune) bool { return r == ' ' || r == ',' })
+		if len(fields) == 0 {
+			return c, fmt.Errorf("invalid version constraint %q", s)
+		}
+
+		for _, f := range fields {
+			t, err := parseTerm(f)
+			if err != nil {
+				return c, err
+			}
+			terms = append(terms, t)
+		}
+		c.alternatives = append(c.alternatives, terms)
+	}
+
+	return c, nil
+}
+
+func parseTerm(s string) (term, error) {
+	var t term
+
+	for _, op := range []operator{opGreaterEqual, opLessEqual, opNotEqual, opGreater, opLess, opEqual, opTilde, opCaret} {
+		if strings.HasPrefix(s, string(op)) {
+			t.op = op
+			s = strings.TrimPrefix(s, string(op))
+			break
+		}
+	}
+
+	t.version = Normalize(s)
+	if !version.IsValid(t.version) {
+		return t, fmt.Errorf("invalid version %q in constraint", s)
+	}
+
+	if t.op == "" {
+		switch {
+		case !strings.Contains(t.version, "."):
+			t.op = opCaret
+		case version.Lang(t.version) == t.version:
+			t.op = opSeries
+		default:
+			t.op = opEqual
+		}
+	}
+
+	return t, nil
+}
+
+// Check reports whether v satisfies the constraint.
+func (c Constraint) Check(v string) bool {
+	if !IsValid(v) {
+		return false
+	}
+
+	for _, terms := range c.alternatives {
+		ok := true
+		for _, t := range terms {
+			ok = ok && t.check(v)
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Constraint) String() string {
+	var alts []string
+	for _, terms := range c.alternatives {
+		var s []string
+		for _, t := range terms {
+			s = append(s, t.String())
+		}
+		alts = append(alts, strings.Join(s, " "))
+	}
+	return strings.Join(alts, " || ")
+}
+
+// Matches reports whether v satisfies the given constraint.
+func Matches(v, constraint string) (bool, error) {
+	c, err := ParseConstraint(constraint)
+	if err != nil {
+		return false, err
+	}
+	return c.Check(v), nil
+}
+
+// Latest returns the newest version in vs satisfying the constraint.
+func Latest(vs []string, c Constraint) (string, bool) {
+	var latest string
+
+	for _, v := range vs {
+		if c.Check(v) && (latest == "" || IsNewer(v, latest)) {
+			latest = v
+		}
+	}
+	return latest, latest != ""
+}
+
+func nextMinor(v string) string {
+	var major, minor int
+	fmt.Sscanf(strings.TrimPrefix(version.Lang(v), "go"), "%d.%d", &major, &minor)
+	return fmt.Sprintf("go%d.%d", major, minor+1)
+}
+
+func nextMajor(v string) string {
+	var major int
+	fmt.Sscanf(strings.TrimPrefix(version.Lang(v), "go"), "%d", &major)
+	return fmt.Sprintf("go%d", major+1)
+}
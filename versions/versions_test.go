@@ -0,0 +1,77 @@
+package versions
+
+import "testing"
+
+func TestIsNewer(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"go1.22.1", "go1.22.0", true},
+		{"1.22.0", "go1.21.10", true},
+		{"go1.22rc1", "go1.22.0", false},
+		{"go1.21.0", "go1.21.0", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsNewer(tt.a, tt.b); got != tt.want {
+			t.Errorf("IsNewer(%s, %s) want %v, got %v", tt.a, tt.b, tt.want, got)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		version    string
+		constraint string
+		want       bool
+	}{
+		{"go1.21.5", "~1.21", true},
+		{"go1.22.0", "~1.21", false},
+		{"go1.21.2", "~1.21.3", false},
+		{"go1.21.3", "~1.21.3", true},
+		{"go1.23.1", "^1.21", true},
+		{"go1.20.14", "^1.21", false},
+		{"go1.21.10", "1.21", true},
+		{"go1.22.0", "1.21", false},
+		{"go1.22.1", "1", true},
+		{"go1.22.1", "1.22.1", true},
+		{"go1.22.1", "go1.22.0", false},
+		{"go1.22.1", ">=1.21 <1.23", true},
+		{"go1.23.0", ">=1.21, <1.23", false},
+		{"go1.22.0", "!=1.22.0", false},
+		{"go1.19.1", "1.19 || >=1.22", true},
+		{"go1.20.1", "1.19 || >=1.22", false},
+		{"invalid", ">=1.21", false},
+	}
+
+	for _, tt := range tests {
+		got, err := Matches(tt.version, tt.constraint)
+		if err != nil {
+			t.Fatalf("Matches(%s, %s) unexpected error: %v", tt.version, tt.constraint, err)
+		}
+		if got != tt.want {
+			t.Errorf("Matches(%s, %s) want %v, got %v", tt.version, tt.constraint, tt.want, got)
+		}
+	}
+}
+
+func TestParseConstraintInvalid(t *testing.T) {
+	for _, s := range []string{"", ">=", "~one", "1.21 ||", ">=1.21 <"} {
+		if _, err := ParseConstraint(s); err == nil {
+			t.Errorf("ParseConstraint(%q) expected an error", s)
+		}
+	}
+}
+
+func TestLatest(t *testing.T) {
+	c, err := ParseConstraint("~1.21")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok := Latest([]string{"go1.20.14", "go1.21.2", "go1.21.10", "go1.22.0"}, c)
+	if !ok || got != "go1.21.10" {
+		t.Errorf("Latest() want go1.21.10, got %s", got)
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// waitRelease fetches the releases until one matches query, every interval,
+// for the versions not published yet. The feed failing meanwhile is only
+// logged, the wait ends with ctx.
+func (c *cli) waitRelease(query string, interval time.Duration) ([]Release, Release, error) {
+	for {
+		releases, err := c.repo.GetVersions(c.ctx)
+		if err == nil {
+			release, err := resolveRelease(releases, query)
+			if !errors.Is(err, ErrVersionNotFound) {
+				return releases, release, err
+			}
+			fmt.Fprintf(c.stdout, "No release matching %s yet, checking again in %s\n", query, interval)
+		} else {
+			slog.Warn("unable to fetch the releases, checking again later", "err", err, "interval", interval)
+		}
+
+		select {
+		case <-c.ctx.Done():
+			return nil, Release{}, fmt.Errorf("waiting for a release matching %s: %w", query, c.ctx.Err())
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInstallWait(t *testing.T) {
+	archive := newTestArchive(t, map[string]string{"go/VERSION": "go1.22.1\n"})
+	c, out := newTestCLI(t, archive)
+	c.prefix = t.TempDir()
+
+	// The release is only published on the third fetch of the feed.
+	fetches := 0
+	published := c.repo.client.Transport
+	c.repo.client = NewTestClient(func(req *http.Request) *http.Response {
+		if req.URL.Query().Get("mode") == "json" {
+			if fetches++; fetches < 3 {
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`[]`))}
+			}
+		}
+		resp, _ := published.RoundTrip(req)
+		return resp
+	})
+
+	// The flags may follow the version, as in the README.
+	if err := c.run([]string{"install", "go1.22.1", "--wait", "--wait-interval", "10ms"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if v, err := installedVersion(c.goroot()); err != nil || v != "go1.22.1" {
+		t.Errorf("Expected go1.22.1 to be installed once published, got %q (%v)", v, err)
+	}
+	if got := strings.Count(out.String(), "No release matching go1.22.1 yet"); got != 2 {
+		t.Errorf("Expected two checks before the release, got %d in %q", got, out.String())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	c.ctx = ctx
+	c.stdout = &bytes.Buffer{}
+	if err := c.run([]string{"install", "--wait", "--wait-interval", "10ms", "go1.30.0"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the wait to end with the context, got %v", err)
+	}
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// announcedVersion matches the versions named by the titles of release
+// announcements, such as "Go 1.22.2 and Go 1.21.9 are released".
+var announcedVersion = regexp.MustCompile(`(?i)\bgo ?(\d+\.\d+(?:\.\d+)?(?:rc\d+|beta\d+)?)\b`)
+
+// announcement is an entry of an RSS or Atom feed of release announcements.
+type announcement struct {
+	ID    string
+	Title string
+	Link  string
+}
+
+// Versions returns the versions the announcement names.
+func (a announcement) Versions() []string {
+	var found []string
+	for _, m := range announcedVersion.FindAllStringSubmatch(a.Title, -1) {
+		if v := "go" + m[1]; !slices.Contains(found, v) {
+			found = append(found, v)
+		}
+	}
+	return found
+}
+
+// syndicationFeed holds the entries of an Atom feed or the items of an RSS
+// one, whichever the document is.
+type syndicationFeed struct {
+	Entries []struct {
+		ID    string `xml:"id"`
+		Title string `xml:"title"`
+		Links []struct {
+			Href string `xml:"href,attr"`
+		} `xml:"link"`
+	} `xml:"entry"`
+	Items []struct {
+		GUID  string `xml:"guid"`
+		Title string `xml:"title"`
+		Link  string `xml:"link"`
+	} `xml:"channel>item"`
+}
+
+// parseAnnouncements returns the entries of the RSS or Atom feed b, in the
+// order of the feed.
+func parseAnnouncements(b []byte) ([]announcement, error) {
+	var feed syndicationFeed
+	if err := xml.Unmarshal(b, &feed); err != nil {
+		return nil, fmt.Errorf("invalid announcements feed: %w", err)
+	}
+	var entries []announcement
+	for _, e := range feed.Entries {
+		a := announcement{ID: e.ID, Title: strings.TrimSpace(e.Title)}
+		if len(e.Links) > 0 {
+			a.Link = e.Links[0].Href
+		}
+		entries = append(entries, a)
+	}
+	for _, item := range feed.Items {
+		a := announcement{ID: item.GUID, Title: strings.TrimSpace(item.Title), Link: strings.TrimSpace(item.Link)}
+		if a.ID == "" {
+			a.ID = a.Link
+		}
+		entries = append(entries, a)
+	}
+	return entries, nil
+}
+
+// fetchAnnouncements fetches the RSS or Atom feed at url.
+func (c *cli) fetchAnnouncements(url string) ([]announcement, error) {
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.repo.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("fetching the announcements: %s", resp.Status)
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return nil, err
+	}
+	return parseAnnouncements(b)
+}
+
+// watchState is what go-dl watch already notified of, kept between runs.
+type watchState struct {
+	Versions      []string `json:"versions"`
+	Announcements []string `json:"announcements,omitempty"`
+}
+
+func (c *cli) watchStatePath() string {
+	return filepath.Join(c.paths.State, "watch.json")
+}
+
+// loadWatchState returns the state of the previous runs, false on the
+// first one.
+func (c *cli) loadWatchState() (watchState, bool, error) {
+	var state watchState
+	b, err := os.ReadFile(c.watchStatePath())
+	if errors.Is(err, os.ErrNotExist) {
+		return state, false, nil
+	}
+	if err != nil {
+		return state, false, err
+	}
+	if err := json.Unmarshal(b, &state); err != nil {
+		return state, false, fmt.Errorf("invalid watch state %s: %w", c.watchStatePath(), err)
+	}
+	return state, true, nil
+}
+
+// watchEvent is the JSON body posted to the webhooks of go-dl watch.
+type watchEvent struct {
+	Event   string `json:"event"`
+	Version string `json:"version,omitempty"`
+	Title   string `json:"title"`
+	URL     string `json:"url,omitempty"`
+	Host    string `json:"host,omitempty"`
+}
+
+// watchOptions are the flags of go-dl watch.
+type watchOptions struct {
+	announcements string
+	unstable      bool
+	desktop       bool
+	// webhook is the one of --webhook, notified along with the configured
+	// webhooks listing the event.
+	webhook WebhookConfig
+	upgrade bool
+}
+
+// notify delivers event to the standard output, the desktop and the webhooks
+// of opts and of the configuration. The failed deliveries are only logged.
+func (c *cli) notify(opts watchOptions, event watchEvent) {
+	metrics.notifications.Add(1)
+	fmt.Fprintln(c.stdout, event.Title)
+	if opts.desktop {
+		if err := notifyDesktop("go-dl", event.Title); err != nil {
+			slog.Warn("unable to show the desktop notification", "err", err)
+		}
+	}
+
+	event.Host, _ = os.Hostname()
+	webhooks := c.webhooks
+	if opts.webhook.URL != "" {
+		webhooks = append(slices.Clip(webhooks), opts.webhook)
+	}
+	for _, w := range webhooks {
+		if !w.wants(event.Event) {
+			continue
+		}
+		if err := postWebhook(c.ctx, c.webhookClient, w.URL, w.watchPayload(event)); err != nil {
+			slog.Warn("unable to notify the webhook", "err", err)
+		}
+	}
+}
+
+// checkReleases notifies of the releases and announcements published since
+// the last check, then upgrades to the latest stable release with
+// opts.upgrade. The first check only records what is already published.
+func (c *cli) checkReleases(opts watchOptions) error {
+	state, watched, err := c.loadWatchState()
+	if err != nil {
+		return err
+	}
+
+	releases, err := c.repo.GetVersions(c.ctx)
+	if err != nil {
+		return err
+	}
+	var published []string
+	stable := map[string]bool{}
+	for _, r := range releases {
+		if r.Stable || opts.unstable {
+			published = append(published, r.Version)
+			stable[r.Version] = r.Stable
+		}
+	}
+	sortVersions(published)
+
+	var shipped []string
+	for _, v := range published {
+		if !slices.Contains(state.Versions, v) {
+			shipped = append(shipped, v)
+			state.Versions = append(state.Versions, v)
+		}
+	}
+
+	var announced []announcement
+	if opts.announcements != "" {
+		entries, err := c.fetchAnnouncements(opts.announcements)
+		if err != nil {
+			return err
+		}
+		for _, a := range entries {
+			if a.ID != "" && !slices.Contains(state.Announcements, a.ID) {
+				announced = append(announced, a)
+				state.Announcements = append(state.Announcements, a.ID)
+			}
+		}
+	}
+
+	b, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(c.watchStatePath(), append(b, '\n'), 0644); err != nil {
+		return wrapPermission(err)
+	}
+	if !watched {
+		latest := "none"
+		if len(published) > 0 {
+			latest = published[len(published)-1]
+		}
+		fmt.Fprintf(c.stdout, "Watching the releases, the latest is %s\n", latest)
+		return nil
+	}
+
+	for _, v := range shipped {
+		c.notify(opts, watchEvent{Event: watchRelease, Version: v, Title: fmt.Sprintf("Go %s is released", strings.TrimPrefix(v, "go")), URL: c.repo.url + "/#" + v})
+	}
+	for _, a := range announced {
+		event := watchEvent{Event: watchAnnouncement, Title: a.Title, URL: a.Link}
+		if found := a.Versions(); len(found) > 0 {
+			event.Version = found[0]
+		}
+		c.notify(opts, event)
+	}
+
+	if !opts.upgrade || !slices.ContainsFunc(shipped, func(v string) bool { return stable[v] }) {
+		return nil
+	}
+	// Nobody answers the prompts of a watcher.
+	return c.latest([]string{"--quiet"})
+}
+
+// watch notifies of the new releases of Go, checking every interval.
+func (c *cli) watch(args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	var opts watchOptions
+	interval := fs.Duration("interval", time.Hour, "time between two checks")
+	once := fs.Bool("once", false, "check once and exit, for schedulers")
+	fs.StringVar(&opts.announcements, "announcements", "", "URL of an RSS or Atom feed of release announcements, also notified of")
+	fs.BoolVar(&opts.unstable, "unstable", false, "also notify of the release candidates and betas")
+	fs.BoolVar(&opts.desktop, "desktop", false, "show a desktop notification")
+	fs.StringVar(&opts.webhook.URL, "webhook", "", "URL an event is posted to for each notification")
+	fs.StringVar(&opts.webhook.Format, "webhook-format", "", "format of the --webhook events, json or slack (default json)")
+	fs.BoolVar(&opts.upgrade, "upgrade", false, "run go-dl latest when a stable release ships")
+	debugAddr := fs.String("debug-addr", "", "serve pprof and expvar on this loopback address, e.g. 127.0.0.1:6060")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go-dl watch [--interval d] [--once] [--announcements url] [--desktop] [--webhook url [--webhook-format slack]] [--upgrade] [--debug-addr addr]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("invalid interval %s", *interval)
+	}
+	if opts.webhook.URL != "" {
+		opts.webhook.Events = []string{watchRelease, watchAnnouncement}
+		if err := opts.webhook.check(); err != nil {
+			return err
+		}
+	}
+	if *debugAddr != "" {
+		addr, err := serveDebug(*debugAddr)
+		if err != nil {
+			return fmt.Errorf("starting the debug endpoint: %w", err)
+		}
+		fmt.Fprintf(c.stdout, "Debug endpoint on http://%s/debug/pprof/\n", addr)
+	}
+
+	for {
+		err := c.checkReleases(opts)
+		metrics.check(err)
+		if *once {
+			return err
+		}
+		if err != nil {
+			slog.Warn("unable to check the releases, checking again later", "err", err, "interval", *interval)
+		}
+
+		select {
+		case <-c.ctx.Done():
+			return fmt.Errorf("watching the releases: %w", c.ctx.Err())
+		case <-time.After(*interval):
+		}
+	}
+}
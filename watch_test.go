@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseAnnouncements(t *testing.T) {
+	atom := `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <entry><id>tag:a,1</id><title>[security] Go 1.22.2 and Go 1.21.9 are released</title><link href="https://example.com/1"/></entry>
+  <entry><id>tag:a,2</id><title>Go 1.23rc1 is released</title></entry>
+</feed>`
+	entries, err := parseAnnouncements([]byte(atom))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := []announcement{
+		{ID: "tag:a,1", Title: "[security] Go 1.22.2 and Go 1.21.9 are released", Link: "https://example.com/1"},
+		{ID: "tag:a,2", Title: "Go 1.23rc1 is released"},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("Expected %v, got %v", want, entries)
+	}
+	if got := entries[0].Versions(); !reflect.DeepEqual(got, []string{"go1.22.2", "go1.21.9"}) {
+		t.Errorf("Expected the versions of the title, got %v", got)
+	}
+	if got := entries[1].Versions(); !reflect.DeepEqual(got, []string{"go1.23rc1"}) {
+		t.Errorf("Expected the release candidate, got %v", got)
+	}
+
+	rss := `<rss version="2.0"><channel><item><title> Go 1.22.3 is released </title><link>https://example.com/3</link></item></channel></rss>`
+	entries, err = parseAnnouncements([]byte(rss))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if want := []announcement{{ID: "https://example.com/3", Title: "Go 1.22.3 is released", Link: "https://example.com/3"}}; !reflect.DeepEqual(entries, want) {
+		t.Errorf("Expected %v, got %v", want, entries)
+	}
+
+	if _, err := parseAnnouncements([]byte("not xml <")); err == nil {
+		t.Errorf("Expected an error for an invalid feed")
+	}
+}
+
+func TestCheckReleases(t *testing.T) {
+	c, out := newTestCLI(t, nil)
+	feed := `[{"version":"go1.22.1","stable":true,"files":[]}]`
+	var posted []watchEvent
+	c.repo.client = NewTestClient(func(req *http.Request) *http.Response {
+		body := feed
+		if req.Method == http.MethodPost {
+			var event watchEvent
+			if err := json.NewDecoder(req.Body).Decode(&event); err != nil {
+				t.Errorf("Invalid webhook body: %v", err)
+			}
+			posted = append(posted, event)
+			body = "ok"
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}
+	})
+	c.webhookClient = c.repo.client
+	opts := watchOptions{webhook: WebhookConfig{URL: "https://hooks.example.com/secret", Events: []string{watchRelease}}}
+
+	if err := c.checkReleases(opts); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "Watching the releases, the latest is go1.22.1") || len(posted) != 0 {
+		t.Errorf("Expected the first check to only record the releases, got %q and %v", out.String(), posted)
+	}
+
+	feed = `[{"version":"go1.23rc1","stable":false,"files":[]},{"version":"go1.22.2","stable":true,"files":[]},{"version":"go1.22.1","stable":true,"files":[]}]`
+	out.Reset()
+	if err := c.checkReleases(opts); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if out.String() != "Go 1.22.2 is released\n" {
+		t.Errorf("Expected the new stable release notified, got %q", out.String())
+	}
+	if len(posted) != 1 || posted[0].Event != "release" || posted[0].Version != "go1.22.2" || posted[0].URL != "https://go.dev/dl/#go1.22.2" {
+		t.Errorf("Expected the release posted to the webhook, got %v", posted)
+	}
+
+	out.Reset()
+	if err := c.checkReleases(opts); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if out.Len() != 0 || len(posted) != 1 {
+		t.Errorf("Expected no notification twice, got %q", out.String())
+	}
+}
+
+func TestWatchWebhooks(t *testing.T) {
+	c, _ := newTestCLI(t, nil)
+	posted := map[string]string{}
+	c.webhookClient = NewTestClient(func(req *http.Request) *http.Response {
+		b, _ := io.ReadAll(req.Body)
+		posted[req.URL.Path] = string(b)
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("ok"))}
+	})
+	// The configured webhooks only get the watch events they list.
+	c.webhooks = []WebhookConfig{
+		{URL: "https://hooks.example.com/installs"},
+		{URL: "https://hooks.example.com/slack", Format: "slack", Events: []string{watchRelease}},
+	}
+
+	c.notify(watchOptions{}, watchEvent{Event: watchRelease, Version: "go1.22.2", Title: "Go 1.22.2 is released", URL: "https://go.dev/dl/#go1.22.2"})
+	if _, ok := posted["/installs"]; ok {
+		t.Errorf("Expected the webhook of the installations not to be notified of the release")
+	}
+	if want := `{"text":":mega: Go 1.22.2 is released https://go.dev/dl/#go1.22.2"}`; posted["/slack"] != want {
+		t.Errorf("Expected the Slack message %s, got %s", want, posted["/slack"])
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// zipMagic starts the local file headers of zip archives.
+var zipMagic = []byte("PK\x03\x04")
+
+// isZip reports whether f is a zip archive, as published for windows.
+func isZip(f File) bool {
+	return strings.HasSuffix(f.Filename, ".zip")
+}
+
+// isZipArchive reports whether the archive r is a zip archive rather than a
+// tar.gz one, r is left at its start.
+func isZipArchive(r io.ReadSeeker) (bool, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return false, err
+	}
+	magic := make([]byte, len(zipMagic))
+	_, err := io.ReadFull(r, magic)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return false, err
+	}
+	return bytes.Equal(magic, zipMagic), nil
+}
+
+// readerAt reads an io.ReadSeeker at offsets, for the archive/zip reader,
+// the reads go through r so a contextReader still stops them.
+type readerAt struct {
+	r io.ReadSeeker
+}
+
+func (a readerAt) ReadAt(p []byte, off int64) (int, error) {
+	if _, err := a.r.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return io.ReadFull(a.r, p)
+}
+
+// zipHeader returns the tar header of the zip entry f, so it is checked by
+// checkEntry as the entries of the tar.gz archives.
+func zipHeader(f *zip.File) (*tar.Header, error) {
+	header, err := tar.FileInfoHeader(f.FileInfo(), "")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrUnsafeArchive, f.Name, err)
+	}
+	header.Name = f.Name
+	return header, nil
+}
+
+// decompressZip extracts the zip archive r to dst, the entries are checked by
+// checkEntry as with decompress.
+func decompressZip(dst string, r io.ReadSeeker, progress extractProgress, opts archiveOptions) error {
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	zr, err := zip.NewReader(readerAt{r}, size)
+	if err != nil {
+		return err
+	}
+
+	dir, err := openExtractDir(dst, opts)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+
+	totalFiles := 0
+	for _, f := range zr.File {
+		if f.Mode().IsRegular() && !opts.filter.Skip(f.Name) {
+			totalFiles++
+		}
+	}
+
+	countFiles := 0
+	var read int64
+	for _, f := range zr.File {
+		header, err := zipHeader(f)
+		if err != nil {
+			return err
+		}
+		mode, ok, err := checkEntry(header, opts.strict)
+		if err != nil {
+			return err
+		}
+		if ok && !opts.filter.Skip(header.Name) {
+			if err := extractZipEntry(dir, f, header, mode); err != nil {
+				return err
+			}
+			if header.Typeflag == tar.TypeReg {
+				countFiles++
+			}
+		}
+
+		read += int64(f.CompressedSize64)
+		progress.report(min(read, size), size, countFiles, totalFiles)
+	}
+	progress.report(size, size, countFiles, totalFiles)
+	return nil
+}
+
+// extractZipEntry creates the directory or the file of the zip entry f.
+func extractZipEntry(dir extractDir, f *zip.File, header *tar.Header, mode os.FileMode) error {
+	if header.Typeflag == tar.TypeDir {
+		return dir.MkdirAll(header.Name)
+	}
+
+	if err := dir.MkdirAll(filepath.Dir(header.Name)); err != nil {
+		return err
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	out, err := dir.Create(header.Name, mode)
+	if err != nil {
+		return err
+	}
+	// The reader of the entry fails on a CRC mismatch once it reaches its
+	// end.
+	if _, err := copyBuffered(out, rc); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// checkZip reads every entry of the zip archive at path, so truncated or
+// corrupted archives fail the verification with their CRC before the
+// installation is replaced.
+func checkZip(path string) error {
+	err := func() error {
+		zr, err := zip.OpenReader(path)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+
+		for _, f := range zr.File {
+			rc, err := f.Open()
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(io.Discard, rc)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}()
+	if err != nil {
+		return fmt.Errorf("%w %s: %w", ErrCorruptArchive, filepath.Base(path), err)
+	}
+	return nil
+}
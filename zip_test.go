@@ -0,0 +1,139 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// newTestZip returns a zip archive of files, as the windows releases of Go,
+// with the modes of modes.
+func newTestZip(t *testing.T, files map[string]string, modes map[string]os.FileMode) []byte {
+	t.Helper()
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range names {
+		header := &zip.FileHeader{Name: name, Method: zip.Deflate}
+		mode, ok := modes[name]
+		if !ok {
+			mode = 0644
+		}
+		header.SetMode(mode)
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(w, files[name]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// newTestZipCLI returns a cli installing the windows/amd64 archive of
+// go1.22.1, served as archive.
+func newTestZipCLI(t *testing.T, archive []byte) *cli {
+	c, _ := newTestCLI(t, archive)
+	sum := sha256.Sum256(archive)
+	feed := fmt.Sprintf(`[{"version":"go1.22.1","stable":true,"files":[{"filename":"go1.22.1.windows-amd64.zip","os":"windows","arch":"amd64","version":"go1.22.1","sha256":%q,"kind":"archive"}]}]`, hex.EncodeToString(sum[:]))
+	c.repo.client = NewTestClient(func(req *http.Request) *http.Response {
+		body := archive
+		if req.URL.Query().Get("mode") == "json" {
+			body = []byte(feed)
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(body)), ContentLength: int64(len(body))}
+	})
+	c.selection = Selection{Os: "windows", Arch: "amd64"}
+	return c
+}
+
+func TestInstallZip(t *testing.T) {
+	archive := newTestZip(t, map[string]string{"go/VERSION": "go1.22.1\n", "go/bin/go.exe": "binary"}, nil)
+	c := newTestZipCLI(t, archive)
+	c.prefix = t.TempDir()
+
+	if err := c.install([]string{"1.22.1"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if v, err := installedVersion(c.goroot()); err != nil || v != "go1.22.1" {
+		t.Errorf("Expected go1.22.1 to be installed from the zip archive, got %s (%v)", v, err)
+	}
+	if b, err := os.ReadFile(filepath.Join(c.goroot(), "bin", "go.exe")); err != nil || string(b) != "binary" {
+		t.Errorf("Expected the binary to be extracted, got %q (%v)", b, err)
+	}
+	if _, err := os.Stat(filepath.Join(c.prefix, ".go-dl-extract")); !os.IsNotExist(err) {
+		t.Errorf("Expected the staging directory to be removed, got %v", err)
+	}
+}
+
+func TestDecompressZip(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no setuid bits on windows")
+	}
+
+	archive := newTestZip(t, map[string]string{
+		"go/VERSION": "go1.22.1\n",
+		"go/bin/go":  "binary",
+		"go/link":    "VERSION",
+	}, map[string]os.FileMode{"go/bin/go": 0755 | os.ModeSetuid, "go/link": 0777 | os.ModeSymlink})
+
+	dst := t.TempDir()
+	if err := decompressZip(dst, bytes.NewReader(archive), extractProgress{}, archiveOptions{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if info, err := os.Stat(filepath.Join(dst, "go/bin/go")); err != nil || info.Mode() != 0755 {
+		t.Errorf("Expected the special bits to be stripped, got %v (%v)", info, err)
+	}
+	if _, err := os.Lstat(filepath.Join(dst, "go/link")); !os.IsNotExist(err) {
+		t.Errorf("Expected the symbolic link to be skipped, got %v", err)
+	}
+
+	if err := decompressZip(t.TempDir(), bytes.NewReader(archive), extractProgress{}, archiveOptions{strict: true}); !errors.Is(err, ErrUnsafeArchive) {
+		t.Errorf("Expected strict extractions to reject the setuid binary, got %v", err)
+	}
+
+	outside := newTestZip(t, map[string]string{"go/../../etc/passwd": "root"}, nil)
+	if err := decompressZip(t.TempDir(), bytes.NewReader(outside), extractProgress{}, archiveOptions{}); !errors.Is(err, ErrUnsafeArchive) {
+		t.Errorf("Expected a name outside of the archive to be rejected, got %v", err)
+	}
+}
+
+func TestCheckZip(t *testing.T) {
+	archive := newTestZip(t, map[string]string{"go/VERSION": strings.Repeat("go1.22.1\n", 100)}, nil)
+
+	path := filepath.Join(t.TempDir(), "go1.22.1.windows-amd64.zip")
+	if err := os.WriteFile(path, archive, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkZip(path); err != nil {
+		t.Errorf("Expected the archive to be readable, got %v", err)
+	}
+
+	if err := os.WriteFile(path, archive[:len(archive)-32], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkZip(path); !errors.Is(err, ErrCorruptArchive) {
+		t.Errorf("Expected the truncated archive to be corrupt, got %v", err)
+	}
+}